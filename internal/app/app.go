@@ -2,6 +2,8 @@ package app
 
 import (
 	"context"
+	"expvar"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/pprof"
@@ -12,6 +14,7 @@ import (
 
 	"crypto-analytics/internal/config"
 	"crypto-analytics/internal/handlers"
+	"crypto-analytics/internal/models"
 	"crypto-analytics/internal/services"
 	"crypto-analytics/internal/storage"
 )
@@ -24,24 +27,50 @@ type App struct {
 }
 
 type Services struct {
-	notifier services.Notifier
-	crypto   *services.CryptoService
-	news     *services.NewsService
-	users    *services.UserService
-	pairs    *services.CryptoPairsService
-	analysis *services.AnalysisService
-	sysStat  *services.SystemMonitor
-	posts    *services.PostsService
+	notifier    *services.NotificationService
+	crypto      *services.CryptoService
+	news        *services.NewsService
+	users       *services.UserService
+	pairs       *services.CryptoPairsService
+	analysis    *services.AnalysisService
+	sysStat     *services.SystemMonitor
+	posts       *services.PostsService
+	feeds       *services.FeedService
+	apiTokens   *services.APITokenService
+	twoFactor   *services.TwoFactorService
+	limiter     *services.RedisRateLimiter
+	guard       *services.LoginGuardService
+	attachments *services.AttachmentService
+	moderation  *services.ModerationService
+	search      *services.SearchService
+	tickets     *services.TicketService
+	formGuard   *services.FormGuard
+	webhooks    *services.WebhookService
 }
 
 type Storages struct {
 	contacts     storage.FormStorage
+	tickets      storage.TicketStorage
 	users        storage.UserStorage
+	tokens       storage.TokenStorage
+	sessions     storage.SessionStorage
+	apiTokens    storage.APITokenStorage
+	twoFactor    storage.TwoFactorStorage
+	authAudit    storage.AuthAuditStorage
+	moderation   storage.ModerationLogStorage
+	reports      storage.ReportStorage
+	search       storage.SearchEngine
+	rateLimits   storage.RateLimitStorage
 	news         storage.NewsStorage
+	feedStates   storage.FeedStateStorage
 	pairs        storage.CacheStorage
 	anslysis     storage.AnalysisStorage
 	analysisTemp storage.AnalysisTempStorage
 	posts        storage.PostStorage
+	attachments  storage.AttachmentStorage
+	blobs        storage.BlobStore
+	outbox       storage.NotificationStorage
+	webhooks     storage.WebhookStorage
 }
 
 func NewApp(cfg *config.Config) *App {
@@ -100,24 +129,119 @@ func (a *App) initStorages() {
 	contactsStorage := storage.NewContactPostgresStorage(poolPG)
 
 	usersStorage := storage.NewUserPostgresStorage(poolPG)
+	tokensStorage := storage.NewTokenPostgresStorage(poolPG)
+	apiTokensStorage := storage.NewAPITokenPostgresStorage(poolPG)
+	twoFactorStorage := storage.NewTwoFactorPostgresStorage(poolPG)
+	authAuditStorage := storage.NewAuthAuditPostgresStorage(poolPG)
+	moderationStorage := storage.NewModerationLogPostgresStorage(poolPG)
+	reportStorage := storage.NewReportPostgresStorage(poolPG)
+	notificationStorage := storage.NewNotificationPostgresStorage(poolPG)
+	webhookStorage := storage.NewWebhookPostgresStorage(poolPG)
 
 	postStorage := storage.NewPostsMongoStorage(clientMG)
+	a.preparePostStorage(postStorage)
+	searchStorage := storage.NewSearchMongoStorage(clientMG)
+	a.prepareSearchStorage(searchStorage)
+	attachmentStorage := storage.NewAttachmentMongoStorage(clientMG)
+	a.prepareAttachmentStorage(attachmentStorage)
 	reddisAnalysis := storage.NewAnalysisTempStorage(redisClient)
+	sessionStorage := storage.NewSessionRedisStorage(redisClient)
+	rateLimitStorage := storage.NewRateLimitRedisStorage(redisClient)
 
 	newsStorage := storage.NewNewsFileStorage("storage/news_cache.json")
+	feedStateStorage := storage.NewFeedStateFileStorage("storage/feeds_state.json")
 
 	pairsStorage := storage.NewPairsFileStorage("storage/pairs_cache.json")
 
 	analysisStorage := storage.NewAnalysisFileStorage("storage/analysis_cache.json")
 
+	blobStorage, err := a.newBlobStore()
+	if err != nil {
+		slog.Error("Failed to initialize blob store", "driver", a.cfg.BlobDriver, "error", err)
+		os.Exit(1)
+	}
+
 	a.storages = &Storages{
 		contacts:     contactsStorage,
+		tickets:      contactsStorage,
 		users:        usersStorage,
+		tokens:       tokensStorage,
+		sessions:     sessionStorage,
+		apiTokens:    apiTokensStorage,
+		twoFactor:    twoFactorStorage,
+		authAudit:    authAuditStorage,
+		moderation:   moderationStorage,
+		reports:      reportStorage,
+		search:       searchStorage,
+		rateLimits:   rateLimitStorage,
 		news:         newsStorage,
+		feedStates:   feedStateStorage,
 		pairs:        pairsStorage,
 		anslysis:     analysisStorage,
 		analysisTemp: reddisAnalysis,
 		posts:        postStorage,
+		attachments:  attachmentStorage,
+		blobs:        blobStorage,
+		outbox:       notificationStorage,
+		webhooks:     webhookStorage,
+	}
+}
+
+// preparePostStorage создаёт индексы Mongo для выдачи постов и дозаполняет
+// счётчики старых документов. Ошибки не фатальны: выдача работает и без них, медленнее.
+func (a *App) preparePostStorage(posts *storage.PostMongoStorage) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := posts.EnsureIndexes(ctx); err != nil {
+		slog.Error("Failed to create post indexes", "error", err)
+	}
+	if n, err := posts.BackfillCounters(ctx); err != nil {
+		slog.Error("Failed to backfill post counters", "error", err)
+	} else if n > 0 {
+		slog.Info("Backfilled post counters", "updated", n)
+	}
+}
+
+// prepareSearchStorage создаёт текстовые индексы; без них поиск отвечает ошибкой
+func (a *App) prepareSearchStorage(search *storage.SearchMongoStorage) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := search.EnsureIndexes(ctx); err != nil {
+		slog.Error("Failed to create search indexes", "error", err)
+	}
+}
+
+func (a *App) prepareAttachmentStorage(attachments *storage.AttachmentMongoStorage) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := attachments.EnsureIndexes(ctx); err != nil {
+		slog.Error("Failed to create attachment indexes", "error", err)
+	}
+}
+
+// newBlobStore выбирает хранилище картинок. Неверные настройки — ошибка запуска:
+// молча писать в локальный каталог вместо S3 значит потерять картинки при переезде.
+func (a *App) newBlobStore() (storage.BlobStore, error) {
+	switch a.cfg.BlobDriver {
+	case "s3":
+		s3, err := storage.NewS3BlobStore(
+			a.cfg.S3Endpoint,
+			a.cfg.S3Bucket,
+			a.cfg.S3Region,
+			a.cfg.S3AccessKey,
+			a.cfg.S3SecretKey,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("configure s3 blob store: %w", err)
+		}
+		return s3, nil
+	case "fs":
+		return storage.NewFSBlobStore(a.cfg.BlobDir), nil
+	default:
+		return nil, fmt.Errorf("unknown blob driver %q", a.cfg.BlobDriver)
 	}
 }
 
@@ -128,30 +252,184 @@ func (a *App) initServices() {
 	} else {
 		IsItProd = false
 	}
+	mailer := a.newMailSender()
+	// Вебхуки создаются первыми: в них публикуют события остальные сервисы
+	webhooks := services.NewWebhookService(a.storages.webhooks, a.storages.users, a.cfg.TokenSecret, a.cfg.WebhookAllowPrivate)
 	a.services = &Services{
-		notifier: services.NewNotifier(),
+		notifier: services.NewNotificationService(a.storages.outbox, a.storages.users, a.newNotificationChannels(mailer), webhooks),
 		crypto:   services.NewCryptoService(IsItProd, "storage/crypto_cache.json"),
-		news:     services.NewNewsService(a.storages.news, IsItProd),
-		users:    services.NewUserService(a.storages.users),
-		pairs:    services.NewCryptoPairsService(a.storages.pairs, IsItProd),
-		analysis: services.NewAnalysisService(IsItProd, a.storages.anslysis, a.storages.analysisTemp),
-		sysStat:  services.NewSystemMonitor(),
-		posts:    services.NewPostService(a.storages.posts),
+		news:     services.NewNewsService(a.storages.news, a.storages.feedStates, IsItProd, webhooks),
+		users: services.NewUserService(
+			a.storages.users,
+			a.storages.tokens,
+			mailer,
+			services.NewTokenSigner(a.cfg.TokenSecret),
+			a.cfg.PublicBaseURL,
+			a.newBreachChecker(),
+		),
+		pairs:     services.NewCryptoPairsService(a.storages.pairs, IsItProd, webhooks),
+		analysis:  services.NewAnalysisService(IsItProd, a.storages.anslysis, a.storages.analysisTemp),
+		sysStat:   services.NewSystemMonitor(),
+		apiTokens: services.NewAPITokenService(a.storages.apiTokens),
+		twoFactor: services.NewTwoFactorService(a.storages.users, a.storages.twoFactor, a.cfg.TokenSecret),
+		limiter:   services.NewRedisRateLimiter(a.storages.rateLimits),
+
+		attachments: services.NewAttachmentService(a.storages.attachments, a.storages.blobs),
+		search:      services.NewSearchService(a.storages.search),
+		formGuard:   a.newFormGuard(),
+		webhooks:    webhooks,
+	}
+	a.services.guard = services.NewLoginGuardService(a.storages.rateLimits, a.storages.authAudit, a.storages.users, a.services.notifier)
+	if err := a.services.users.EnsureAdmins(a.cfg.AdminEmails); err != nil {
+		slog.Error("Failed to promote configured admins", "error", err)
+	}
+
+	a.services.posts = services.NewPostService(
+		a.storages.posts,
+		a.storages.moderation,
+		a.services.crypto,
+		a.services.pairs,
+		a.services.analysis,
+		a.storages.users,
+		services.NewMailCommentNotifier(mailer, a.cfg.PublicBaseURL),
+		a.storages.attachments,
+		a.newContentFilter(),
+		a.services.limiter,
+		webhooks,
+	)
+	a.services.tickets = services.NewTicketService(a.storages.tickets, a.storages.users, mailer)
+	a.services.moderation = services.NewModerationService(
+		a.services.posts,
+		a.storages.reports,
+		a.storages.users,
+		a.storages.moderation,
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if n, err := a.services.posts.BackfillAuthors(ctx, a.storages.users); err != nil {
+		slog.Error("Failed to backfill post authors", "error", err)
+	} else if n > 0 {
+		slog.Info("Backfilled post authors", "updated", n)
+	}
+
+	a.services.feeds = services.NewFeedService(
+		a.services.news,
+		a.services.crypto,
+		a.services.posts,
+		a.cfg.PublicBaseURL,
+	)
+}
+
+func (a *App) newMailSender() services.MailSender {
+	switch a.cfg.MailDriver {
+	case "smtp":
+		return services.NewSMTPMailSender(
+			a.cfg.SMTPHost,
+			a.cfg.SMTPPort,
+			a.cfg.SMTPUser,
+			a.cfg.SMTPPassword,
+			a.cfg.MailFrom,
+		)
+	case "file":
+		return services.NewFileMailSender(a.cfg.MailFileDir, a.cfg.MailFrom)
+	default:
+		slog.Warn("Unknown mail driver, falling back to file", "driver", a.cfg.MailDriver)
+		return services.NewFileMailSender(a.cfg.MailFileDir, a.cfg.MailFrom)
+	}
+}
+
+func (a *App) newBreachChecker() services.BreachChecker {
+	var online, offline services.BreachChecker
+	if a.cfg.BreachAPIURL != "" {
+		online = services.NewRangeBreachChecker(a.cfg.BreachAPIURL)
+	}
+	if a.cfg.BreachHashFile != "" {
+		list, err := services.LoadHashListBreachChecker(a.cfg.BreachHashFile)
+		if err != nil {
+			slog.Error("Failed to load breached password list", "path", a.cfg.BreachHashFile, "error", err)
+		} else {
+			offline = list
+		}
+	}
+	return services.NewFallbackBreachChecker(online, offline)
+}
+
+// newFormGuard — защита публичных форм. CAPTCHA включается, только если известен
+// провайдер и задан ключ сайта: без виджета ни одна форма не прошла бы проверку.
+func (a *App) newFormGuard() *services.FormGuard {
+	if a.cfg.CaptchaVerifyURL == "" {
+		slog.Warn("CAPTCHA is disabled: CAPTCHA_VERIFY_URL is empty")
+		return services.NewFormGuard(a.cfg.TokenSecret, services.NoopCaptchaVerifier{}, nil)
+	}
+	provider := services.CaptchaProvider(a.cfg.CaptchaVerifyURL)
+	if provider == "" || a.cfg.CaptchaSiteKey == "" {
+		slog.Error("CAPTCHA needs a known provider URL and CAPTCHA_SITE_KEY", "verify_url", a.cfg.CaptchaVerifyURL)
+		os.Exit(1)
+	}
+	return services.NewFormGuard(
+		a.cfg.TokenSecret,
+		services.NewSiteVerifyCaptcha(a.cfg.CaptchaVerifyURL, a.cfg.CaptchaSecret),
+		&services.CaptchaWidget{Provider: provider, SiteKey: a.cfg.CaptchaSiteKey},
+	)
+}
+
+// newNotificationChannels — адаптеры каналов уведомлений. Telegram доступен только с токеном бота.
+func (a *App) newNotificationChannels(mailer services.MailSender) map[models.NotificationChannel]services.ChannelSender {
+	channels := map[models.NotificationChannel]services.ChannelSender{
+		models.ChannelEmail:   services.NewEmailChannel(mailer),
+		models.ChannelWebhook: services.NewWebhookChannel(),
+	}
+	if a.cfg.TgBotToken != "" {
+		channels[models.ChannelTelegram] = services.NewTelegramChannel(a.cfg.TelegramAPIURL, a.cfg.TgBotToken)
+	} else {
+		slog.Warn("Telegram notifications are disabled: TG_BOT_TOKEN is empty")
 	}
+	return channels
+}
+
+// newContentFilter загружает BANNED_WORDS_FILE. Заданный, но нечитаемый файл — ошибка
+// запуска: иначе посты молча публиковались бы без фильтра.
+func (a *App) newContentFilter() *services.ContentFilter {
+	filter, err := services.LoadContentFilter(a.cfg.BannedWordsFile)
+	if err != nil {
+		slog.Error("Failed to load banned words", "path", a.cfg.BannedWordsFile, "error", err)
+		os.Exit(1)
+	}
+	return filter
 }
 
 func (a *App) initHTTP() {
 	go a.services.sysStat.StartStatsReporter()
+	go a.services.attachments.StartOrphanCleanup()
+	go a.services.notifier.StartDelivery()
+	go a.services.webhooks.StartDelivery()
+	if a.cfg.LaunchLoc == "prod" {
+		// Новые листинги замечаются только при свежем списке пар
+		go a.services.pairs.StartRefresh()
+	}
 	handler, err := handlers.NewHandler(
 		a.storages.contacts,
 		a.services.notifier,
 		a.services.crypto,
 		a.services.users,
-		a.cfg.KeyUsersGorilla,
+		services.NewRedisSessionStore(a.storages.sessions, a.cfg.CookieSecure, a.cfg.SessionKeys...),
 		a.services.news,
 		a.services.pairs,
 		a.services.analysis,
 		a.services.posts,
+		a.services.feeds,
+		a.services.apiTokens,
+		a.services.twoFactor,
+		a.services.limiter,
+		a.services.guard,
+		a.services.attachments,
+		a.services.moderation,
+		a.services.search,
+		a.services.tickets,
+		a.services.formGuard,
+		a.services.notifier,
+		a.services.webhooks,
 	)
 	if err != nil {
 		slog.Error("Failed to create handler", "error", err)
@@ -176,22 +454,69 @@ func (a *App) setupRoutes(handler *handlers.Handler) http.Handler {
 	fs := http.FileServer(http.Dir("static"))
 	mux.Handle("/static/", http.StripPrefix("/static/", fs))
 
+	// Лимиты на IP для входа и всего, что шлёт письма
+	authLimit := services.RateLimit{Requests: 20, Window: time.Minute}
+	mailLimit := services.RateLimit{Requests: 5, Window: 15 * time.Minute}
+	uploadLimit := services.RateLimit{Requests: 30, Window: time.Hour}
+	reportLimit := services.RateLimit{Requests: 20, Window: time.Hour}
+	searchLimit := services.RateLimit{Requests: 30, Window: time.Minute}
+	// Публичные формы: с одного IP больше не пишут и не регистрируются
+	contactLimit := services.RateLimit{Requests: 5, Window: time.Hour}
+	registerLimit := services.RateLimit{Requests: 10, Window: time.Hour}
+	webhookTestLimit := services.RateLimit{Requests: 10, Window: time.Minute}
+
 	// API routes
 	apiRoutes := map[string]http.HandlerFunc{
-		"/api/allFavoriteCoin":    handler.GetFavorites,
-		"/api/changeFavoriteCoin": handler.ChangeFavorite,
-		"/api/all-pairs":          handler.GetAllPairsHandler,
-		"/api/select-pair":        handler.SelectPairHandler,
-		"/api/pair":               handler.GetPairInfo,
-		"/api/available":          handler.GetAvailablePairs,
-		"/api/posts/create":       handler.CreatePostHandler,
-		"/api/comments/create":    handler.CreateCommentHandler,
-		"/api/posts":              handler.GetPostsHandler,
-		"/api/comments":           handler.GetCommentsHandler,
-		"/api/posts/update":       handler.UpdatePostHandler,
-		"/api/posts/delete":       handler.UpdatePostHandler,
-		"/api/comments/update":    handler.UpdateCommentHandler,
-		"/api/comments/delete":    handler.DeleteCommentHandler,
+		"/api/allFavoriteCoin":     handler.RequireScope(models.ScopeReadFavorites, handler.GetFavorites),
+		"/api/changeFavoriteCoin":  handler.RequireScope(models.ScopeWriteFavorites, handler.ChangeFavorite),
+		"/api/all-pairs":           handler.RequireScope(models.ScopeReadMarket, handler.GetAllPairsHandler),
+		"/api/select-pair":         handler.SelectPairHandler,
+		"/api/pair":                handler.RequireScope(models.ScopeReadMarket, handler.GetPairInfo),
+		"/api/available":           handler.RequireScope(models.ScopeReadMarket, handler.GetAvailablePairs),
+		"/api/posts/create":        handler.RequireScope(models.ScopeWritePosts, handler.CreatePostHandler),
+		"/api/comments/create":     handler.RequireScope(models.ScopeWritePosts, handler.CreateCommentHandler),
+		"/api/posts":               handler.RequireScope(models.ScopeReadPosts, handler.GetPostsHandler),
+		"/api/posts/related":       handler.RequireScope(models.ScopeReadPosts, handler.RelatedPostsHandler),
+		"/api/search":              handler.RateLimit("search", searchLimit, handler.RequireScope(models.ScopeReadPosts, handler.SearchHandler)),
+		"/api/votes":               handler.RequireScope(models.ScopeWritePosts, handler.VoteHandler),
+		"/api/reactions":           handler.RequireScope(models.ScopeWritePosts, handler.ReactHandler),
+		"/api/reactions/list":      handler.ReactionsHandler,
+		"/api/comments":            handler.RequireScope(models.ScopeReadPosts, handler.GetCommentsHandler),
+		"/api/comments/tree":       handler.RequireScope(models.ScopeReadPosts, handler.CommentTreeHandler),
+		"/api/posts/update":        handler.RequireScope(models.ScopeWritePosts, handler.UpdatePostHandler),
+		"/api/posts/delete":        handler.RequireScope(models.ScopeWritePosts, handler.DeletePostHandler),
+		"/api/comments/update":     handler.RequireScope(models.ScopeWritePosts, handler.UpdateCommentHandler),
+		"/api/posts/revisions":     handler.RequireScope(models.ScopeReadPosts, handler.PostRevisionsHandler),
+		"/api/comments/revisions":  handler.RequireScope(models.ScopeReadPosts, handler.CommentRevisionsHandler),
+		"/api/comments/delete":     handler.RequireScope(models.ScopeWritePosts, handler.DeleteCommentHandler),
+		"/api/attachments/upload":  handler.RateLimit("upload", uploadLimit, handler.RequireScope(models.ScopeWritePosts, handler.UploadAttachmentHandler)),
+		"/api/attachments":         handler.AttachmentHandler,
+		"/api/form-token":          handler.FormTokenHandler,
+		"/api/reports":             handler.RateLimit("report", reportLimit, handler.RequireScope(models.ScopeWritePosts, handler.ReportHandler)),
+		"/api/profile":             handler.ProfileHandler,
+		"/api/profile/update":      handler.UpdateProfileHandler,
+		"/api/profile/password":    handler.ChangePasswordHandler,
+		"/api/verify-email/resend": handler.RateLimit("verify-resend", mailLimit, handler.ResendVerificationHandler),
+		"/api/sessions":            handler.ListSessionsHandler,
+		"/api/sessions/revoke":     handler.RevokeSessionHandler,
+		"/api/sessions/revoke-all": handler.RevokeAllSessionsHandler,
+		// Управление токенами — только из браузерной сессии
+		"/api/tokens":             handler.ListAPITokensHandler,
+		"/api/tokens/create":      handler.CreateAPITokenHandler,
+		"/api/tokens/revoke":      handler.RevokeAPITokenHandler,
+		"/api/2fa/setup":          handler.TwoFactorSetupHandler,
+		"/api/2fa/confirm":        handler.TwoFactorConfirmHandler,
+		"/api/2fa/disable":        handler.TwoFactorDisableHandler,
+		"/api/2fa/recovery-codes": handler.TwoFactorRecoveryCodesHandler,
+
+		// Исходящие вебхуки — тоже только из браузерной сессии
+		"/api/webhooks":               handler.ListWebhooksHandler,
+		"/api/webhooks/create":        handler.CreateWebhookHandler,
+		"/api/webhooks/update":        handler.UpdateWebhookHandler,
+		"/api/webhooks/delete":        handler.DeleteWebhookHandler,
+		"/api/webhooks/rotate-secret": handler.RotateWebhookSecretHandler,
+		"/api/webhooks/test":          handler.RateLimit("webhook-test", webhookTestLimit, handler.TestWebhookHandler),
+		"/api/webhooks/deliveries":    handler.WebhookDeliveriesHandler,
 	}
 
 	for path, handlerFunc := range apiRoutes {
@@ -200,19 +525,66 @@ func (a *App) setupRoutes(handler *handlers.Handler) http.Handler {
 
 	// Web routes
 	webRoutes := map[string]http.HandlerFunc{
-		"/news":          handler.NewsPage,
-		"/pairs":         handler.CryptoPairsPageHandler,
-		"/logout":        handler.LogoutHandler,
-		"/login":         handler.LoginHandler,
-		"/check-Sess-Id": handler.CheckAuthHandler,
-		"/register":      handler.AuthUserFormHandler,
-		"/contact":       handler.ContactFormHandler,
-		"/crypto-top":    handler.CryptoTopHandler,
+		"/news":                   handler.NewsPage,
+		"/pairs":                  handler.CryptoPairsPageHandler,
+		"/logout":                 handler.LogoutHandler,
+		"/login":                  handler.RateLimit("login", authLimit, handler.LoginHandler),
+		"/login/2fa":              handler.RateLimit("login-2fa", authLimit, handler.LoginTwoFactorHandler),
+		"/check-Sess-Id":          handler.CheckAuthHandler,
+		"/register":               handler.RateLimit("register", registerLimit, handler.AuthUserFormHandler),
+		"/contact":                handler.RateLimit("contact", contactLimit, handler.ContactFormHandler),
+		"/crypto-top":             handler.CryptoTopHandler,
+		"/verify-email":           handler.VerifyEmailHandler,
+		"/password-reset":         handler.RateLimit("password-reset", mailLimit, handler.PasswordResetRequestHandler),
+		"/password-reset/confirm": handler.RateLimit("password-reset-confirm", authLimit, handler.PasswordResetConfirmHandler),
 	}
 
 	for path, handlerFunc := range webRoutes {
 		mux.HandleFunc(path, handlerFunc)
 	}
+	// Исходящие ленты (RSS / Atom / JSON Feed)
+	feedRoutes := map[string]http.HandlerFunc{
+		"/feeds/news.rss":               handler.NewsFeedHandler,
+		"/feeds/news.atom":              handler.NewsFeedHandler,
+		"/feeds/news.json":              handler.NewsFeedHandler,
+		"/feeds/coins/{coin}/news.rss":  handler.NewsFeedHandler,
+		"/feeds/coins/{coin}/news.atom": handler.NewsFeedHandler,
+		"/feeds/coins/{coin}/news.json": handler.NewsFeedHandler,
+		"/feeds/posts.rss":              handler.PostsFeedHandler,
+		"/feeds/posts.atom":             handler.PostsFeedHandler,
+		"/feeds/posts.json":             handler.PostsFeedHandler,
+	}
+
+	for path, handlerFunc := range feedRoutes {
+		mux.HandleFunc(path, handlerFunc)
+	}
+
+	// Админские API: требования к роли объявлены прямо в таблице маршрутов
+	adminRoutes := map[string]http.HandlerFunc{
+		"/admin/api/users/export":    handler.RequireRole(models.RoleAdmin, handler.AdminExportUsersHandler),
+		"/admin/api/users/role":      handler.RequireRole(models.RoleAdmin, handler.AdminSetRoleHandler),
+		"/admin/api/contacts/export": handler.RequireRole(models.RoleAdmin, handler.AdminExportContactsHandler),
+		"/admin/api/contacts/stats":  handler.RequireRole(models.RoleAdmin, handler.AdminContactsStatsHandler),
+		"/admin/api/tickets":         handler.RequireRole(models.RoleAdmin, handler.AdminTicketsHandler),
+		"/admin/api/tickets/get":     handler.RequireRole(models.RoleAdmin, handler.AdminTicketHandler),
+		"/admin/api/tickets/update":  handler.RequireRole(models.RoleAdmin, handler.AdminUpdateTicketHandler),
+		"/admin/api/metrics":         handler.RequireRole(models.RoleAdmin, expvar.Handler().ServeHTTP),
+		"/admin/api/moderation":      handler.RequireRole(models.RoleModerator, handler.ModerateHandler),
+		"/admin/api/moderation/log":  handler.RequireRole(models.RoleModerator, handler.ModerationLogHandler),
+		"/admin/api/reports":         handler.RequireRole(models.RoleModerator, handler.ReportQueueHandler),
+		"/admin/api/reports/resolve": handler.RequireRole(models.RoleModerator, handler.ResolveReportsHandler),
+		"/admin/api/users/ban":       handler.RequireRole(models.RoleModerator, handler.BanUserHandler),
+
+		// Каналы уведомлений администратора
+		"/admin/api/notifications/channels":        handler.RequireRole(models.RoleAdmin, handler.NotificationChannelsHandler),
+		"/admin/api/notifications/channels/save":   handler.RequireRole(models.RoleAdmin, handler.SaveNotificationChannelHandler),
+		"/admin/api/notifications/channels/delete": handler.RequireRole(models.RoleAdmin, handler.DeleteNotificationChannelHandler),
+	}
+
+	for path, handlerFunc := range adminRoutes {
+		mux.HandleFunc(path, handlerFunc)
+	}
+
 	if a.cfg.ProfFlag == 1 {
 		mux.HandleFunc("/debug/pprof/", pprof.Index)
 		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
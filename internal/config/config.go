@@ -33,6 +33,42 @@ type Config struct {
 	RedisPoolSize   int    `env:"REDIS_POOL_SIZE" envDefault:"10"`
 	RedisPort       string `env:"REDIS_PORT" envDefault:"6379"`
 	ProfFlag        int    `env:"PROF_FLAG" envDefault:"0"`
+	PublicBaseURL   string `env:"PUBLIC_BASE_URL" envDefault:"http://localhost:8080"`
+	TokenSecret     string `env:"TOKEN_SECRET" envDefault:""` // шифрует и TOTP-секреты: при смене 2FA подключается заново
+	MailDriver      string `env:"MAIL_DRIVER" envDefault:"file"`
+	MailFileDir     string `env:"MAIL_FILE_DIR" envDefault:"storage/mail"`
+	MailFrom        string `env:"MAIL_FROM" envDefault:"Crypto Analytics <no-reply@localhost>"`
+	SMTPHost        string `env:"SMTP_HOST" envDefault:""`
+	SMTPPort        string `env:"SMTP_PORT" envDefault:"587"`
+	SMTPUser        string `env:"SMTP_USER" envDefault:""`
+	SMTPPassword    string `env:"SMTP_PASSWORD" envDefault:""`
+	// Ключи подписи сессионных кук через запятую: первый — текущий, остальные принимаются при ротации
+	SessionKeys  []string `env:"SESSION_KEYS" envSeparator:","`
+	CookieSecure bool     `env:"COOKIE_SECURE" envDefault:"true"`
+	// Аккаунты с этими email получают роль admin при старте, если email подтверждён
+	AdminEmails []string `env:"ADMIN_EMAILS" envSeparator:","`
+	// Проверка паролей по утечкам: range API (пусто — выключено) и офлайн-список SHA-1 на случай его недоступности
+	BreachAPIURL   string `env:"BREACH_API_URL" envDefault:"https://api.pwnedpasswords.com/range/"`
+	BreachHashFile string `env:"BREACH_HASH_FILE" envDefault:""`
+	// Картинки постов: fs — каталог BLOB_DIR, s3 — любое S3-совместимое хранилище (AWS, MinIO)
+	BlobDriver  string `env:"BLOB_DRIVER" envDefault:"fs"`
+	BlobDir     string `env:"BLOB_DIR" envDefault:"storage/blobs"`
+	S3Endpoint  string `env:"S3_ENDPOINT" envDefault:""`
+	S3Region    string `env:"S3_REGION" envDefault:"us-east-1"`
+	S3Bucket    string `env:"S3_BUCKET" envDefault:""`
+	S3AccessKey string `env:"S3_ACCESS_KEY" envDefault:""`
+	S3SecretKey string `env:"S3_SECRET_KEY" envDefault:""`
+	// Файл с запрещёнными словами и /регулярками/ для постов и комментариев (пусто — фильтра нет)
+	BannedWordsFile string `env:"BANNED_WORDS_FILE" envDefault:""`
+	// CAPTCHA на формах регистрации и обратной связи: siteverify-адрес reCAPTCHA,
+	// hCaptcha или Turnstile (пусто — проверки нет), секретный ключ и публичный ключ сайта для виджета
+	CaptchaVerifyURL string `env:"CAPTCHA_VERIFY_URL" envDefault:""`
+	CaptchaSecret    string `env:"CAPTCHA_SECRET" envDefault:""`
+	CaptchaSiteKey   string `env:"CAPTCHA_SITE_KEY" envDefault:""`
+	// Bot API для канала уведомлений telegram; бот тот же, что TG_BOT_TOKEN (без токена канал недоступен)
+	TelegramAPIURL string `env:"TELEGRAM_API_URL" envDefault:"https://api.telegram.org"`
+	// Разрешить вебхукам http и адреса во внутренней сети — только для разработки
+	WebhookAllowPrivate bool `env:"WEBHOOK_ALLOW_PRIVATE" envDefault:"false"`
 }
 
 func getLogLevelFromString(levelStr string) slog.Level {
@@ -66,6 +102,17 @@ func MustLoad() *Config {
 		logger.Error("Failed to parse environment variables", "error", err)
 		panic("configuration error: " + err.Error())
 	}
+	if len(cfg.SessionKeys) == 0 {
+		cfg.SessionKeys = []string{cfg.KeyUsersGorilla}
+	}
+	if cfg.LaunchLoc == "prod" && cfg.SessionKeys[0] == "my-super-secret-key-12345" {
+		logger.Warn("Session cookies are signed with the default key, set SESSION_KEYS")
+	}
+	// Секретом подписаны API-токены и ссылки из писем: публичная константа по умолчанию недопустима
+	if cfg.TokenSecret == "" {
+		logger.Error("TOKEN_SECRET is not set")
+		panic("configuration error: TOKEN_SECRET is required")
+	}
 	logger.Info("Application started", "mode", cfg.LaunchLoc)
 
 	return &cfg
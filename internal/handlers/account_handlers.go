@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"crypto-analytics/internal/services"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// VerifyEmailHandler обрабатывает ссылку из письма и возвращает на страницу входа
+func (h *Handler) VerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, err := h.userService.VerifyEmail(r.URL.Query().Get("token"))
+	if err != nil {
+		if !errors.Is(err, services.ErrInvalidToken) {
+			slog.Error("Failed to verify email", "error", err)
+		}
+		http.Redirect(w, r, "/static/FormRegUser.html?err=verifyToken", http.StatusSeeOther)
+		return
+	}
+
+	slog.Info("Email verified", "user_id", user.ID)
+	http.Redirect(w, r, "/static/FormRegUser.html?verified=1", http.StatusSeeOther)
+}
+
+// ResendVerificationHandler повторно отправляет письмо с подтверждением текущему пользователю
+func (h *Handler) ResendVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := h.getCurrentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.userService.SendVerificationEmail(userID); err != nil {
+		if errors.Is(err, services.ErrAlreadyVerified) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		slog.Error("Failed to resend verification email", "user_id", userID, "error", err)
+		http.Error(w, "Failed to send email", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "Verification email sent",
+	})
+}
+
+// PasswordResetRequestHandler отправляет ссылку для сброса пароля.
+// Ответ одинаковый для известных и неизвестных адресов.
+func (h *Handler) PasswordResetRequestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Email == "" {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.userService.RequestPasswordReset(request.Email); err != nil {
+		slog.Error("Failed to request password reset", "error", err)
+		http.Error(w, "Failed to send email", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "If this email is registered, a reset link has been sent",
+	})
+}
+
+// PasswordResetConfirmHandler устанавливает новый пароль по токену из письма
+func (h *Handler) PasswordResetConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := h.userService.ResetPassword(request.Token, request.Password)
+	if err != nil {
+		var verr *services.ValidationError
+		switch {
+		case errors.As(err, &verr):
+			writeFieldErrors(w, http.StatusUnprocessableEntity, verr.Fields)
+		case errors.Is(err, services.ErrEmptyPassword):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrInvalidToken):
+			http.Error(w, "Reset link is invalid or has expired", http.StatusBadRequest)
+		default:
+			slog.Error("Failed to reset password", "error", err)
+			http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Сброс часто означает, что пароль или cookie украдены: выходим со всех устройств
+	if _, err := h.storeSessions.RevokeAllSessions(userID, ""); err != nil {
+		slog.Error("Failed to revoke sessions after password reset", "user_id", userID, "error", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "Password has been reset",
+	})
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/services"
+	"crypto-analytics/internal/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// AdminExportUsersHandler отдаёт выгрузку пользователей файлом JSON
+func (h *Handler) AdminExportUsersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var buf bytes.Buffer
+	count, err := h.userService.ExportUsers(&buf)
+	if err != nil {
+		slog.Error("Failed to export users", "error", err)
+		http.Error(w, "Failed to export users", http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("Users exported via admin API", "amount", count)
+	writeJSONAttachment(w, "users", buf.Bytes())
+}
+
+// AdminExportContactsHandler отдаёт выгрузку обращений из формы обратной связи
+func (h *Handler) AdminExportContactsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var buf bytes.Buffer
+	count, err := h.storage.ExportContacts(&buf)
+	if err != nil {
+		slog.Error("Failed to export contacts", "error", err)
+		http.Error(w, "Failed to export contacts", http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("Contacts exported via admin API", "amount", count)
+	writeJSONAttachment(w, "contacts", buf.Bytes())
+}
+
+// AdminContactsStatsHandler возвращает сводку по обращениям
+func (h *Handler) AdminContactsStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats, err := h.storage.GetContactsStats()
+	if err != nil {
+		slog.Error("Failed to get contacts stats", "error", err)
+		http.Error(w, "Failed to get contacts stats", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Data:    stats,
+	})
+}
+
+// AdminSetRoleHandler назначает пользователю роль
+func (h *Handler) AdminSetRoleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	admin, ok := userFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		UserID int64  `json:"userId"`
+		Role   string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.UserID <= 0 {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	err := h.userService.SetRole(admin.ID, request.UserID, models.Role(request.Role))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidRole),
+			errors.Is(err, services.ErrOwnRoleChange):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, storage.ErrUserNotFound):
+			http.Error(w, "User not found", http.StatusNotFound)
+		default:
+			slog.Error("Failed to set role", "user_id", request.UserID, "error", err)
+			http.Error(w, "Failed to set role", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "Role updated",
+	})
+}
+
+func writeJSONAttachment(w http.ResponseWriter, name string, data []byte) {
+	filename := fmt.Sprintf("%s-%s.json", name, time.Now().UTC().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(data)
+}
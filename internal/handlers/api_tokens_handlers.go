@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/services"
+	"crypto-analytics/internal/storage"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// ListAPITokensHandler возвращает активные API-токены пользователя (без самих значений)
+func (h *Handler) ListAPITokensHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := h.getCurrentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := h.apiTokens.ListTokens(userID)
+	if err != nil {
+		slog.Error("Failed to list api tokens", "user_id", userID, "error", err)
+		http.Error(w, "Failed to list tokens", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"tokens":          tokens,
+			"availableScopes": models.KnownScopes,
+		},
+	})
+}
+
+// CreateAPITokenHandler выпускает токен; его значение показывается только в этом ответе
+func (h *Handler) CreateAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := h.getCurrentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		Name          string         `json:"name"`
+		Scopes        []models.Scope `json:"scopes"`
+		ExpiresInDays int            `json:"expiresInDays"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	ttl := time.Duration(request.ExpiresInDays) * 24 * time.Hour
+	plain, token, err := h.apiTokens.CreateToken(userID, request.Name, request.Scopes, ttl)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAPITokenName),
+			errors.Is(err, services.ErrAPITokenScopes),
+			errors.Is(err, services.ErrAPITokenTTL):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrTooManyAPITokens):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			slog.Error("Failed to create api token", "user_id", userID, "error", err)
+			http.Error(w, "Failed to create token", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "Copy the token now, it will not be shown again",
+		Data: map[string]interface{}{
+			"token":   plain,
+			"details": token,
+		},
+	})
+}
+
+// RevokeAPITokenHandler отзывает токен пользователя
+func (h *Handler) RevokeAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := h.getCurrentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.ID <= 0 {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.apiTokens.RevokeToken(userID, request.ID); err != nil {
+		if errors.Is(err, storage.ErrAPITokenNotFound) {
+			http.Error(w, "Token not found", http.StatusNotFound)
+			return
+		}
+		slog.Error("Failed to revoke api token", "user_id", userID, "error", err)
+		http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "Token revoked",
+	})
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// maxUploadRequestBytes — лимит сервиса на файл плюс запас на заголовки multipart
+const maxUploadRequestBytes = 9 << 20
+
+// UploadAttachmentHandler принимает картинку (multipart, поле file) и возвращает её ID
+// для поля attachments при создании поста
+func (h *Handler) UploadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	owner, ok := h.currentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadRequestBytes)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "Expected multipart/form-data", http.StatusBadRequest)
+		return
+	}
+	var file io.Reader
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		if part.FormName() == "file" {
+			file = part
+			break
+		}
+	}
+	if file == nil {
+		http.Error(w, "File is required", http.StatusBadRequest)
+		return
+	}
+
+	attachment, err := h.attachments.Upload(r.Context(), owner, file)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "File is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		writePostError(w, err, "Failed to upload attachment")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Data:    attachment,
+	})
+}
+
+// AttachmentHandler отдаёт картинку по id, с thumb=1 — превью
+func (h *Handler) AttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := bson.ObjectIDFromHex(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+	thumb := r.URL.Query().Get("thumb") == "1"
+
+	attachment, body, err := h.attachments.Open(r.Context(), id, thumb)
+	if err != nil {
+		writePostError(w, err, "Failed to get attachment")
+		return
+	}
+	defer body.Close()
+
+	contentType := attachment.ContentType
+	if thumb {
+		contentType = attachment.ThumbContentType
+	} else {
+		w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	// Файл под ID не меняется никогда
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+
+	if _, err := io.Copy(w, body); err != nil {
+		slog.Warn("Failed to send attachment", "attachment_id", id.Hex(), "error", err)
+	}
+}
@@ -2,20 +2,37 @@ package handlers
 
 import (
 	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/services"
+	"crypto-analytics/internal/storage"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
-	"strings"
+	"time"
 
 	"github.com/gorilla/sessions"
 )
 
 type APIResponse struct {
-	Success bool        `json:"success"`
-	Message string      `json:"message,omitempty"`
-	Data    interface{} `json:"data,omitempty"`
+	Success bool              `json:"success"`
+	Message string            `json:"message,omitempty"`
+	Data    interface{}       `json:"data,omitempty"`
+	Errors  map[string]string `json:"errors,omitempty"`
 }
 
+// writeFieldErrors отдаёт ошибки по полям формы: {"errors": {"password": "..."}}
+func writeFieldErrors(w http.ResponseWriter, status int, fields map[string]string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: false,
+		Message: "Validation failed",
+		Errors:  fields,
+	})
+}
+
+// AuthUserFormHandler регистрирует пользователя из формы. Ответ — JSON:
+// ошибки приходят по полям, при успехе в data.redirect адрес страницы входа.
 func (h *Handler) AuthUserFormHandler(w http.ResponseWriter, r *http.Request) {
 
 	if r.Method != http.MethodPost {
@@ -28,63 +45,68 @@ func (h *Handler) AuthUserFormHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Error parsing form", http.StatusBadRequest)
 		return
 	}
-
-	contact := &models.User{
-		Username:      r.FormValue("username"),
-		Email:         r.FormValue("email"),
-		Password:      r.FormValue("password"),
-		FavoriteCoins: make([]string, 0),
+	if !h.checkForm(w, r, services.FormRegister) {
+		return
 	}
 
-	if contact.Username == "" || contact.Email == "" || contact.Password == "" {
-		slog.Warn("Невалидные данные:", "contact", contact)
-		http.Error(w, "All fields are required", http.StatusBadRequest)
-		return
+	contact := &models.User{
+		Username:        r.FormValue("username"),
+		DisplayName:     r.FormValue("username"),
+		Email:           r.FormValue("email"),
+		Password:        r.FormValue("password"),
+		FavoriteCoins:   make([]string, 0),
+		SignupIP:        services.ClientIP(r),
+		SignupUserAgent: userAgent(r),
 	}
 
 	err := h.userService.RegisterUser(contact)
 	if err != nil {
-
-		errText := err.Error()
+		var verr *services.ValidationError
 		switch {
-		case strings.Contains(errText, "user already exists"):
-			http.Redirect(w, r, "/static/FormNewUser.html?err=alreadyExistsName", http.StatusSeeOther)
-			return
-		case strings.Contains(errText, "user name already exists"):
-			http.Redirect(w, r, "/static/FormNewUser.html?err=alreadyExistsAcc", http.StatusSeeOther)
-			return
+		case errors.As(err, &verr):
+			writeFieldErrors(w, http.StatusUnprocessableEntity, verr.Fields)
+		case errors.Is(err, storage.ErrEmailTaken):
+			writeFieldErrors(w, http.StatusConflict, map[string]string{
+				"email": "an account with this email already exists",
+			})
+		case errors.Is(err, storage.ErrUsernameTaken):
+			writeFieldErrors(w, http.StatusConflict, map[string]string{
+				"username": "this username is already taken",
+			})
 		default:
 			slog.Warn("Ошибка сохранения", "error", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
-			return
 		}
+		return
 	}
 	go h.notifier.NotifyAdmNewUserForm(contact)
 
-	http.Redirect(w, r, "/static/FormRegUser.html", http.StatusSeeOther)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "Registration successful, check your email to verify the address",
+		Data:    map[string]string{"redirect": "/static/FormRegUser.html"},
+	})
 }
 
 func (h *Handler) CheckAuthHandler(w http.ResponseWriter, r *http.Request) {
-	session, err := h.storeSessions.Get(r, "user-session")
-	if err != nil {
-		// Если ошибка - считаем что не авторизован
+	w.Header().Set("Content-Type", "application/json")
+
+	user, ok := h.currentUser(r)
+	if !ok {
 		json.NewEncoder(w).Encode(map[string]interface{}{"authenticated": false})
 		return
 	}
 
-	if auth, ok := session.Values["loggedIn"].(bool); ok && auth {
-		username := session.Values["username"].(string)
-		response := map[string]interface{}{
-			"authenticated": true,
-			"username":      username,
-		}
-		json.NewEncoder(w).Encode(response)
-	} else {
-		response := map[string]interface{}{
-			"authenticated": false,
-		}
-		json.NewEncoder(w).Encode(response)
+	response := map[string]interface{}{
+		"authenticated": true,
+		"userId":        user.ID,
+		"username":      user.Username,
+		"displayName":   user.DisplayName,
+		"role":          user.Role,
 	}
+	json.NewEncoder(w).Encode(response)
 }
 
 func (h *Handler) LoginHandler(w http.ResponseWriter, r *http.Request) {
@@ -94,24 +116,80 @@ func (h *Handler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	username := r.FormValue("username")
+	login := r.FormValue("username")
 	password := r.FormValue("password")
 
-	err := h.userService.LoginUser(username, password)
+	ip := services.ClientIP(r)
+	if lockedFor, err := h.loginGuard.Check(login, ip); err != nil {
+		slog.Error("Failed to check login lock", "error", err)
+	} else if lockedFor > 0 {
+		setRetryAfter(w, lockedFor)
+		http.Redirect(w, r, "/static/FormRegUser.html?err=locked", http.StatusSeeOther)
+		return
+	}
+
+	user, err := h.userService.LoginUser(login, password)
 	if err != nil {
+		if !errors.Is(err, services.ErrInvalidCredentials) {
+			slog.Error("Login failed", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		lockedFor, guardErr := h.loginGuard.Failure(models.AuthEvent{
+			Login:     login,
+			IP:        ip,
+			UserAgent: r.UserAgent(),
+		})
+		if guardErr != nil {
+			slog.Error("Failed to record login failure", "error", guardErr)
+		}
+		if lockedFor > 0 {
+			setRetryAfter(w, lockedFor)
+			http.Redirect(w, r, "/static/FormRegUser.html?err=locked", http.StatusSeeOther)
+			return
+		}
 		http.Redirect(w, r, "/static/FormRegUser.html?err=password", http.StatusSeeOther)
 		return
 	}
+	// С 2FA счётчики сбросит второй шаг: верный пароль ещё не означает верный код
+	if !user.TwoFactorEnabled() {
+		if err := h.loginGuard.Success(login); err != nil {
+			slog.Error("Failed to reset login failures", "error", err)
+		}
+	}
 
 	session, _ := h.storeSessions.Get(r, "user-session")
+	// Новый ID при входе: ID, полученный до логина, не должен стать авторизованным
+	if err := h.storeSessions.Discard(session); err != nil {
+		slog.Error("Failed to discard pre-login session", "error", err)
+	}
+
+	// С включённой 2FA пароль — только первый шаг: сессия ждёт код и ещё не авторизована
+	if user.TwoFactorEnabled() {
+		session.Values[pending2FAUserKey] = user.ID
+		session.Values[pending2FAAtKey] = time.Now().Unix()
+		if err := session.Save(r, w); err != nil {
+			slog.Error("Failed to save session", "user_id", user.ID, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/static/TwoFactor.html", http.StatusSeeOther)
+		return
+	}
+
+	h.completeLogin(w, r, session, user.ID)
+}
+
+// completeLogin помечает сессию авторизованной и отправляет на главную
+func (h *Handler) completeLogin(w http.ResponseWriter, r *http.Request, session *sessions.Session, userID int64) {
 	session.Values["loggedIn"] = true
-	session.Values["username"] = username
-	session.Options = &sessions.Options{
-		HttpOnly: true,  // Защита от XSS
-		MaxAge:   86400, // Сессия на 1 день
-		Path:     "/",   // Действует для всего сайта
+	session.Values["userID"] = userID
+	if err := session.Save(r, w); err != nil {
+		slog.Error("Failed to save session", "user_id", userID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
 	}
-	session.Save(r, w)
 
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
@@ -123,13 +201,11 @@ func (h *Handler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	session, _ := h.storeSessions.Get(r, "user-session")
-	// Полностью удаляем куку
-	session.Options = &sessions.Options{
-		Path:     "/",
-		MaxAge:   -1,
-		HttpOnly: true,
+	// Удаляем запись на сервере и куку в браузере
+	session.Options.MaxAge = -1
+	if err := session.Save(r, w); err != nil {
+		slog.Error("Failed to delete session", "error", err)
 	}
-	session.Save(r, w)
 
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
@@ -139,7 +215,7 @@ func (h *Handler) ChangeFavorite(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	username, authenticated := h.getCurrentUser(r)
+	userID, authenticated := h.getCurrentUser(r)
 	if !authenticated {
 		http.Error(w, "Not authenticated", http.StatusUnauthorized)
 		return
@@ -153,14 +229,14 @@ func (h *Handler) ChangeFavorite(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if req.Action == "add" {
-		err := h.userService.AddFavorite(username, req.CoinID)
+		err := h.userService.AddFavorite(userID, req.CoinID)
 		if err != nil {
 			slog.Warn("Ошибка", "error", err)
 			http.Error(w, "Cant create", http.StatusBadRequest)
 			return
 		}
 	} else {
-		err := h.userService.RemoveFavorite(username, req.CoinID)
+		err := h.userService.RemoveFavorite(userID, req.CoinID)
 		if err != nil {
 			slog.Warn("Ошибка", "error", err)
 			http.Error(w, "Cant create", http.StatusBadRequest)
@@ -184,16 +260,17 @@ func (h *Handler) GetFavorites(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	username, authenticated := h.getCurrentUser(r)
+	userID, authenticated := h.getCurrentUser(r)
 	if !authenticated {
 		http.Error(w, "Not authenticated", http.StatusUnauthorized)
 		return
 	}
 
-	favorites, err := h.userService.GetFavorites(username)
+	favorites, err := h.userService.GetFavorites(userID)
 	if err != nil {
 		slog.Warn("Ошибка", "error", err)
 		http.Error(w, "Cant create", http.StatusBadRequest)
+		return
 	}
 
 	response := APIResponse{
@@ -206,17 +283,37 @@ func (h *Handler) GetFavorites(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// Вспомогательный метод для получения текущего пользователя
-func (h *Handler) getCurrentUser(r *http.Request) (string, bool) {
+// Вспомогательный метод для получения ID текущего пользователя
+func (h *Handler) getCurrentUser(r *http.Request) (int64, bool) {
+	// Запрос по API-токену, уже проверенному в RequireScope
+	if token, ok := apiTokenFromContext(r.Context()); ok {
+		return token.UserID, true
+	}
+
 	session, err := h.storeSessions.Get(r, "user-session")
 	if err != nil {
-		return "", false
+		return 0, false
 	}
 
 	if auth, ok := session.Values["loggedIn"].(bool); !ok || !auth {
-		return "", false
+		return 0, false
+	}
+
+	if userID, ok := session.Values["userID"].(int64); ok && userID > 0 {
+		return userID, true
 	}
+	return 0, false
+}
 
-	username, ok := session.Values["username"].(string)
-	return username, ok
+// currentUser загружает полную запись текущего пользователя
+func (h *Handler) currentUser(r *http.Request) (*models.User, bool) {
+	userID, ok := h.getCurrentUser(r)
+	if !ok {
+		return nil, false
+	}
+	user, err := h.userService.GetUserByID(userID)
+	if err != nil {
+		return nil, false
+	}
+	return user, true
 }
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/services"
 	"log/slog"
 	"net/http"
 )
@@ -18,11 +19,16 @@ func (h *Handler) ContactFormHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Error parsing form", http.StatusBadRequest)
 		return
 	}
+	if !h.checkForm(w, r, services.FormContact) {
+		return
+	}
 
 	contact := models.ContactForm{
-		Name:    r.FormValue("name"),
-		Email:   r.FormValue("email"),
-		Message: r.FormValue("message"),
+		Name:      r.FormValue("name"),
+		Email:     r.FormValue("email"),
+		Message:   r.FormValue("message"),
+		IP:        services.ClientIP(r),
+		UserAgent: userAgent(r),
 	}
 
 	if contact.Name == "" || contact.Email == "" || contact.Message == "" {
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/services"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+const feedCacheControl = "public, max-age=900"
+
+// NewsFeedHandler отдаёт ленту новостей (общую или по монете) в формате по расширению пути
+func (h *Handler) NewsFeedHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	feed, err := h.feeds.NewsFeed(r.PathValue("coin"))
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownCoin) {
+			http.Error(w, "Unknown coin", http.StatusNotFound)
+			return
+		}
+		slog.Error("Failed to build news feed", "error", err)
+		http.Error(w, "Failed to build feed", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeFeed(w, r, feed)
+}
+
+// PostsFeedHandler отдаёт ленту постов сообщества
+func (h *Handler) PostsFeedHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	feed, err := h.feeds.PostsFeed(r.Context())
+	if err != nil {
+		slog.Error("Failed to build posts feed", "error", err)
+		http.Error(w, "Failed to build feed", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeFeed(w, r, feed)
+}
+
+func (h *Handler) writeFeed(w http.ResponseWriter, r *http.Request, feed *models.Feed) {
+	var (
+		body        []byte
+		contentType string
+		err         error
+	)
+	switch path.Ext(r.URL.Path) {
+	case ".rss":
+		body, err = services.RenderRSS(feed)
+		contentType = "application/rss+xml; charset=utf-8"
+	case ".atom":
+		body, err = services.RenderAtom(feed)
+		contentType = "application/atom+xml; charset=utf-8"
+	case ".json":
+		body, err = services.RenderJSONFeed(feed)
+		contentType = "application/feed+json; charset=utf-8"
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to render feed", "path", r.URL.Path, "error", err)
+		http.Error(w, "Failed to render feed", http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("Cache-Control", feedCacheControl)
+	w.Header().Set("ETag", etag)
+	if !feed.Updated.IsZero() {
+		w.Header().Set("Last-Modified", feed.Updated.UTC().Format(http.TimeFormat))
+	}
+
+	if feedNotModified(r, etag, feed.Updated) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(body)
+}
+
+func feedNotModified(r *http.Request, etag string, updated time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == etag || candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !updated.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil {
+			return !updated.Truncate(time.Second).After(t)
+		}
+	}
+	return false
+}
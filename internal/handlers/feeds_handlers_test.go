@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/services"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type MockFeedBuilder struct {
+	Feed  *models.Feed
+	Error error
+	Coin  string
+}
+
+func (m *MockFeedBuilder) NewsFeed(coin string) (*models.Feed, error) {
+	m.Coin = coin
+	return m.Feed, m.Error
+}
+
+func (m *MockFeedBuilder) PostsFeed(ctx context.Context) (*models.Feed, error) {
+	return m.Feed, m.Error
+}
+
+func testFeed() *models.Feed {
+	published := time.Date(2025, 10, 1, 15, 59, 12, 0, time.UTC)
+	return &models.Feed{
+		Title:   "Crypto Analytics — news",
+		HomeURL: "https://example.com/news",
+		FeedURL: "https://example.com/feeds/news",
+		Updated: published,
+		Items: []models.FeedItem{{
+			ID:        "id-1",
+			Title:     "BTC <rally>",
+			URL:       "https://example.com/1",
+			Summary:   "Bitcoin up",
+			Author:    "cointelegraph",
+			Published: published,
+		}},
+	}
+}
+
+func TestHandler_NewsFeed_Formats(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		check       func(t *testing.T, body []byte)
+	}{
+		{
+			name:        "rss",
+			path:        "/feeds/news.rss",
+			contentType: "application/rss+xml; charset=utf-8",
+			check: func(t *testing.T, body []byte) {
+				var doc struct {
+					Items []struct {
+						Title string `xml:"title"`
+					} `xml:"channel>item"`
+				}
+				if err := xml.Unmarshal(body, &doc); err != nil {
+					t.Fatalf("invalid rss: %v", err)
+				}
+				if len(doc.Items) != 1 || doc.Items[0].Title != "BTC <rally>" {
+					t.Errorf("unexpected items: %+v", doc.Items)
+				}
+			},
+		},
+		{
+			name:        "atom",
+			path:        "/feeds/news.atom",
+			contentType: "application/atom+xml; charset=utf-8",
+			check: func(t *testing.T, body []byte) {
+				var doc struct {
+					XMLName xml.Name
+					Entries []struct {
+						ID string `xml:"id"`
+					} `xml:"entry"`
+				}
+				if err := xml.Unmarshal(body, &doc); err != nil {
+					t.Fatalf("invalid atom: %v", err)
+				}
+				if doc.XMLName.Space != "http://www.w3.org/2005/Atom" || len(doc.Entries) != 1 {
+					t.Errorf("unexpected atom document: %+v", doc)
+				}
+			},
+		},
+		{
+			name:        "json feed",
+			path:        "/feeds/news.json",
+			contentType: "application/feed+json; charset=utf-8",
+			check: func(t *testing.T, body []byte) {
+				var doc struct {
+					Version string `json:"version"`
+					Items   []struct {
+						DatePublished string `json:"date_published"`
+					} `json:"items"`
+				}
+				if err := json.Unmarshal(body, &doc); err != nil {
+					t.Fatalf("invalid json feed: %v", err)
+				}
+				if doc.Version != "https://jsonfeed.org/version/1.1" || doc.Items[0].DatePublished != "2025-10-01T15:59:12Z" {
+					t.Errorf("unexpected json feed: %+v", doc)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{feeds: &MockFeedBuilder{Feed: testFeed()}}
+			req := httptest.NewRequest("GET", tt.path, nil)
+			rr := httptest.NewRecorder()
+
+			h.NewsFeedHandler(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("unexpected status: %d", rr.Code)
+			}
+			if ct := rr.Header().Get("Content-Type"); ct != tt.contentType {
+				t.Errorf("unexpected content type: %q", ct)
+			}
+			if rr.Header().Get("ETag") == "" || rr.Header().Get("Cache-Control") == "" {
+				t.Errorf("caching headers missing: %v", rr.Header())
+			}
+			tt.check(t, rr.Body.Bytes())
+		})
+	}
+}
+
+func TestHandler_NewsFeed_Conditional(t *testing.T) {
+	h := &Handler{feeds: &MockFeedBuilder{Feed: testFeed()}}
+
+	rr := httptest.NewRecorder()
+	h.NewsFeedHandler(rr, httptest.NewRequest("GET", "/feeds/news.rss", nil))
+	etag := rr.Header().Get("ETag")
+
+	req := httptest.NewRequest("GET", "/feeds/news.rss", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	h.NewsFeedHandler(rr, req)
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for matching ETag, got %d", rr.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/feeds/news.rss", nil)
+	req.Header.Set("If-Modified-Since", "Wed, 01 Oct 2025 16:00:00 GMT")
+	rr = httptest.NewRecorder()
+	h.NewsFeedHandler(rr, req)
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for If-Modified-Since, got %d", rr.Code)
+	}
+}
+
+func TestHandler_NewsFeed_UnknownCoin(t *testing.T) {
+	h := &Handler{feeds: &MockFeedBuilder{Error: services.ErrUnknownCoin}}
+	req := httptest.NewRequest("GET", "/feeds/coins/nope/news.rss", nil)
+	req.SetPathValue("coin", "nope")
+	rr := httptest.NewRecorder()
+
+	h.NewsFeedHandler(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"crypto-analytics/internal/services"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"unicode/utf8"
+)
+
+const maxUserAgentLen = 512
+
+// FormTokenHandler выдаёт токен для формы: страница запрашивает его при открытии,
+// а сервер по нему проверяет, что форму не отправили мгновенно
+func (h *Handler) FormTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	form := services.FormKind(r.URL.Query().Get("form"))
+	if form != services.FormContact && form != services.FormRegister {
+		http.Error(w, "Unknown form", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	data := map[string]interface{}{"token": h.formGuard.IssueToken(form)}
+	if widget := h.formGuard.Widget(); widget != nil {
+		data["captcha"] = widget
+	}
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Data:    data,
+	})
+}
+
+// checkForm проверяет служебные поля разобранной формы и сам отвечает клиенту при отказе
+func (h *Handler) checkForm(w http.ResponseWriter, r *http.Request, form services.FormKind) bool {
+	err := h.formGuard.Check(r.Context(), form, services.FormSubmission{
+		Honeypot: r.FormValue(services.HoneypotField),
+		Token:    r.FormValue("form_token"),
+		Captcha:  captchaResponse(r),
+		IP:       services.ClientIP(r),
+	})
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, services.ErrFormRejected):
+		http.Error(w, "Submission rejected, reload the page and try again", http.StatusBadRequest)
+	default:
+		slog.Error("Failed to check form", "form", form, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+	return false
+}
+
+// captchaResponse — ответ виджета: каждый провайдер кладёт его в своё поле
+func captchaResponse(r *http.Request) string {
+	for _, field := range services.CaptchaResponseFields {
+		if v := r.FormValue(field); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func userAgent(r *http.Request) string {
+	ua := r.UserAgent()
+	if utf8.RuneCountInString(ua) > maxUserAgentLen {
+		ua = string([]rune(ua)[:maxUserAgentLen])
+	}
+	return ua
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/services"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ctxKey int
+
+const (
+	userCtxKey ctxKey = iota
+	apiTokenCtxKey
+)
+
+// RequireRole пропускает запрос, только если у текущего пользователя роль не ниже required.
+// Загруженный пользователь кладётся в контекст запроса (см. userFromContext).
+func (h *Handler) RequireRole(required models.Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := h.currentUser(r)
+		if !ok {
+			http.Error(w, "Not authenticated", http.StatusUnauthorized)
+			return
+		}
+		if !user.Role.AtLeast(required) {
+			slog.Warn("Access denied",
+				"user_id", user.ID,
+				"role", user.Role,
+				"required", required,
+				"path", r.URL.Path)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), userCtxKey, user)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+func userFromContext(ctx context.Context) (*models.User, bool) {
+	user, ok := ctx.Value(userCtxKey).(*models.User)
+	return user, ok
+}
+
+// RequireScope разрешает доступ по API-токену (Authorization: Bearer) к маршруту,
+// если у токена есть нужный scope. Запросы без заголовка проходят как раньше —
+// через сессию браузера. Маршруты без RequireScope токены не принимают вовсе.
+func (h *Handler) RequireScope(scope models.Scope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		plain, ok := bearerToken(r)
+		if !ok {
+			next(w, r)
+			return
+		}
+
+		token, err := h.apiTokens.Authenticate(plain)
+		if err != nil {
+			if errors.Is(err, services.ErrAPITokenInvalid) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, "Invalid API token", http.StatusUnauthorized)
+				return
+			}
+			slog.Error("Failed to authenticate api token", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if !token.HasScope(scope) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, scope))
+			http.Error(w, "Token lacks scope "+string(scope), http.StatusForbidden)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), apiTokenCtxKey, token)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+func apiTokenFromContext(ctx context.Context) (*models.APIToken, bool) {
+	token, ok := ctx.Value(apiTokenCtxKey).(*models.APIToken)
+	return token, ok
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// RateLimit ограничивает частоту запросов к маршруту с одного IP. name разделяет
+// счётчики маршрутов, чтобы лимит логина не расходовался, например, регистрацией.
+// Если Redis недоступен, запрос пропускается: лимитер не должен класть сайт.
+func (h *Handler) RateLimit(name string, limit services.RateLimit, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ip := services.ClientIP(r)
+		allowed, retryAfter, err := h.rateLimiter.Allow(name+":"+ip, limit)
+		if err != nil {
+			slog.Error("Rate limiter failed", "route", name, "error", err)
+			next(w, r)
+			return
+		}
+		if !allowed {
+			slog.Warn("Rate limit exceeded", "route", name, "ip", ip, "path", r.URL.Path)
+			services.CountRejection(name, "rate_limit")
+			setRetryAfter(w, retryAfter)
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func setRetryAfter(w http.ResponseWriter, d time.Duration) {
+	seconds := int(math.Ceil(d.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+}
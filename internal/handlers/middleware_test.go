@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/services"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type MockAPITokenManager struct {
+	Tokens map[string]*models.APIToken
+}
+
+func (m *MockAPITokenManager) CreateToken(userID int64, name string, scopes []models.Scope, ttl time.Duration) (string, *models.APIToken, error) {
+	return "", nil, nil
+}
+
+func (m *MockAPITokenManager) Authenticate(plain string) (*models.APIToken, error) {
+	token, ok := m.Tokens[plain]
+	if !ok {
+		return nil, services.ErrAPITokenInvalid
+	}
+	return token, nil
+}
+
+func (m *MockAPITokenManager) ListTokens(userID int64) ([]models.APIToken, error) { return nil, nil }
+func (m *MockAPITokenManager) RevokeToken(userID, tokenID int64) error            { return nil }
+
+func TestHandler_RequireScope(t *testing.T) {
+	h := &Handler{apiTokens: &MockAPITokenManager{Tokens: map[string]*models.APIToken{
+		"ca_reader": {ID: 1, UserID: 5, Scopes: []models.Scope{models.ScopeReadPosts}},
+	}}}
+
+	var gotUser int64
+	var gotOK bool
+	next := func(w http.ResponseWriter, r *http.Request) {
+		gotUser, gotOK = 0, false
+		if token, ok := apiTokenFromContext(r.Context()); ok {
+			gotUser, gotOK = token.UserID, true
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	tests := []struct {
+		name       string
+		header     string
+		scope      models.Scope
+		wantStatus int
+		wantToken  bool
+	}{
+		{name: "no header falls through to session", scope: models.ScopeReadPosts, wantStatus: http.StatusOK},
+		{name: "valid token with scope", header: "Bearer ca_reader", scope: models.ScopeReadPosts, wantStatus: http.StatusOK, wantToken: true},
+		{name: "scheme is case-insensitive", header: "bearer ca_reader", scope: models.ScopeReadPosts, wantStatus: http.StatusOK, wantToken: true},
+		{name: "missing scope", header: "Bearer ca_reader", scope: models.ScopeWritePosts, wantStatus: http.StatusForbidden},
+		{name: "unknown token", header: "Bearer ca_nope", scope: models.ScopeReadPosts, wantStatus: http.StatusUnauthorized},
+		{name: "basic auth ignored", header: "Basic dXNlcjpwdw==", scope: models.ScopeReadPosts, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/posts", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rr := httptest.NewRecorder()
+
+			h.RequireScope(tt.scope, next)(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+			if rr.Code == http.StatusOK && gotOK != tt.wantToken {
+				t.Fatalf("expected token in context=%v, got %v", tt.wantToken, gotOK)
+			}
+			if tt.wantToken && gotUser != 5 {
+				t.Errorf("expected user 5, got %d", gotUser)
+			}
+			if rr.Code == http.StatusUnauthorized && rr.Header().Get("WWW-Authenticate") == "" {
+				t.Error("missing WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+type MockRateLimiter struct {
+	Counts map[string]int
+	Err    error
+}
+
+func (m *MockRateLimiter) Allow(key string, limit services.RateLimit) (bool, time.Duration, error) {
+	if m.Err != nil {
+		return false, 0, m.Err
+	}
+	m.Counts[key]++
+	if m.Counts[key] > limit.Requests {
+		return false, 1500 * time.Millisecond, nil
+	}
+	return true, 0, nil
+}
+
+func TestHandler_RateLimit(t *testing.T) {
+	limiter := &MockRateLimiter{Counts: map[string]int{}}
+	h := &Handler{rateLimiter: limiter}
+	next := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	limited := h.RateLimit("login", services.RateLimit{Requests: 2, Window: time.Minute}, next)
+
+	send := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = ip + ":5555"
+		rr := httptest.NewRecorder()
+		limited(rr, req)
+		return rr
+	}
+
+	send("10.0.0.1")
+	send("10.0.0.1")
+	rr := send("10.0.0.1")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("expected Retry-After 2, got %q", got)
+	}
+	if rr := send("10.0.0.2"); rr.Code != http.StatusOK {
+		t.Errorf("other ip must not be limited, got %d", rr.Code)
+	}
+
+	// Недоступный Redis не должен блокировать вход
+	limiter.Err = errors.New("redis down")
+	if rr := send("10.0.0.1"); rr.Code != http.StatusOK {
+		t.Errorf("expected fail-open, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/services"
+	"crypto-analytics/internal/storage"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ModerateHandler скрывает или возвращает пост либо комментарий.
+// Маршрут закрыт RequireRole(moderator), пользователь берётся из контекста.
+func (h *Handler) ModerateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	moderator, ok := userFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		Target string `json:"target"`
+		ID     string `json:"id"`
+		Action string `json:"action"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	id, err := bson.ObjectIDFromHex(request.ID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.postsService.Moderate(
+		r.Context(),
+		moderator,
+		models.ModerationTarget(request.Target),
+		id,
+		models.ModerationAction(request.Action),
+		request.Reason,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidModeration):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrNotModerator):
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		case errors.Is(err, services.ErrPostNotFound),
+			errors.Is(err, services.ErrCommentNotFound):
+			http.Error(w, "Not found", http.StatusNotFound)
+		default:
+			slog.Error("Failed to moderate", "target", request.Target, "id", request.ID, "error", err)
+			http.Error(w, "Failed to moderate", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "Moderation action applied",
+	})
+}
+
+// ModerationLogHandler возвращает журнал модерации (?limit=, по умолчанию 100)
+func (h *Handler) ModerationLogHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	entries, err := h.postsService.ModerationLog(limit)
+	if err != nil {
+		slog.Error("Failed to get moderation log", "error", err)
+		http.Error(w, "Failed to get moderation log", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Data:    entries,
+	})
+}
+
+// ReportHandler принимает жалобу на пост или комментарий
+func (h *Handler) ReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	reporter, ok := h.currentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		Target  string `json:"target"`
+		ID      string `json:"id"`
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	id, err := bson.ObjectIDFromHex(request.ID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.moderation.Report(
+		r.Context(),
+		reporter,
+		models.ModerationTarget(request.Target),
+		id,
+		models.ReportReason(request.Reason),
+		request.Details,
+	)
+	if err != nil {
+		writePostError(w, err, "Failed to report")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "Report sent to moderators",
+	})
+}
+
+// ReportQueueHandler возвращает открытые жалобы по объектам (?limit=, по умолчанию 50)
+func (h *Handler) ReportQueueHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	items, err := h.moderation.ReportQueue(limit)
+	if err != nil {
+		slog.Error("Failed to get report queue", "error", err)
+		http.Error(w, "Failed to get report queue", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Data:    items,
+	})
+}
+
+// ResolveReportsHandler закрывает жалобы на объект: action remove скрывает его, dismiss — нет
+func (h *Handler) ResolveReportsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	moderator, ok := userFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		Target string `json:"target"`
+		ID     string `json:"id"`
+		Action string `json:"action"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	id, err := bson.ObjectIDFromHex(request.ID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.moderation.ResolveReports(
+		r.Context(),
+		moderator,
+		models.ModerationTarget(request.Target),
+		id,
+		models.ModerationAction(request.Action),
+		request.Reason,
+	)
+	if err != nil {
+		writeModerationError(w, err, "Failed to resolve reports")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "Reports resolved",
+	})
+}
+
+// BanUserHandler ограничивает пользователя: action ban (на hours часов), shadow_ban или unban
+func (h *Handler) BanUserHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	moderator, ok := userFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		UserID int64  `json:"userId"`
+		Action string `json:"action"`
+		Hours  int    `json:"hours"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.UserID <= 0 {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	switch models.ModerationAction(request.Action) {
+	case models.ModerationBan:
+		err = h.moderation.BanUser(moderator, request.UserID, time.Duration(request.Hours)*time.Hour, request.Reason)
+	case models.ModerationShadow:
+		err = h.moderation.ShadowBan(moderator, request.UserID, request.Reason)
+	case models.ModerationUnban:
+		err = h.moderation.Unban(moderator, request.UserID, request.Reason)
+	default:
+		http.Error(w, "Unknown action", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeModerationError(w, err, "Failed to update ban")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "User restrictions updated",
+	})
+}
+
+func writeModerationError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidModeration),
+		errors.Is(err, services.ErrInvalidReport):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrNotModerator),
+		errors.Is(err, services.ErrBanNotAllowed):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrPostNotFound),
+		errors.Is(err, services.ErrCommentNotFound),
+		errors.Is(err, services.ErrNoOpenReports),
+		errors.Is(err, storage.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		slog.Error(fallback, "error", err)
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
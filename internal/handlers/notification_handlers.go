@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/services"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// NotificationChannelsHandler — каналы уведомлений текущего администратора.
+// Пока каналов нет, уведомления приходят письмом на адрес аккаунта.
+func (h *Handler) NotificationChannelsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, ok := userFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+	channels, err := h.notifications.ListChannels(user.ID)
+	if err != nil {
+		writeNotificationError(w, err, "Failed to list notification channels")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Data:    channels,
+	})
+}
+
+// SaveNotificationChannelHandler создаёт или заменяет канал: channel, address,
+// events (пусто — все события) и enabled
+func (h *Handler) SaveNotificationChannelHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, ok := userFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+	var pref models.ChannelPreference
+	if err := json.NewDecoder(r.Body).Decode(&pref); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.notifications.SaveChannel(user, pref)
+	if err != nil {
+		writeNotificationError(w, err, "Failed to save notification channel")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "Notification channel saved",
+		Data:    saved,
+	})
+}
+
+// DeleteNotificationChannelHandler удаляет канал: channel
+func (h *Handler) DeleteNotificationChannelHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, ok := userFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+	var request struct {
+		Channel models.NotificationChannel `json:"channel"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.notifications.DeleteChannel(user, request.Channel); err != nil {
+		writeNotificationError(w, err, "Failed to delete notification channel")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "Notification channel deleted",
+	})
+}
+
+func writeNotificationError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrChannelNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrInvalidChannel):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		slog.Error(fallback, "error", err)
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
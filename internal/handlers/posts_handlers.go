@@ -2,10 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/services"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
@@ -13,11 +17,28 @@ import (
 func (h *Handler) CreatePostHandler(w http.ResponseWriter, r *http.Request) {
 	slog.Info("CreatePostHandler started")
 
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	author, ok := h.currentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
 	var request struct {
-		Person   string `json:"person"`
-		Heading  string `json:"heading"`
-		MainText string `json:"mainText"`
-		Date     string `json:"date"`
+		Heading  string   `json:"heading"`
+		MainText string   `json:"mainText"`
+		Tags     []string `json:"tags"`
+		// ID картинок, загруженных через /api/attachments/upload
+		Attachments []string `json:"attachments"`
+		// Снимок анализа: клиент выбирает только пару и таймфрейм
+		Analysis *struct {
+			Pair      string `json:"pair"`
+			Timeframe string `json:"timeframe"`
+		} `json:"analysis"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		slog.Error("Failed to decode request body", "error", err)
@@ -25,18 +46,27 @@ func (h *Handler) CreatePostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	post := models.Post{
-		Person:     request.Person,
-		Heading:    request.Heading,
-		MainText:   request.MainText,
-		Date:       request.Date,
-		CommentIDs: []bson.ObjectID{},
+	draft := models.PostDraft{
+		Heading:  request.Heading,
+		MainText: request.MainText,
+		Tags:     request.Tags,
+	}
+	if request.Analysis != nil {
+		draft.AnalysisPair = request.Analysis.Pair
+		draft.AnalysisTimeframe = request.Analysis.Timeframe
+	}
+	for _, hex := range request.Attachments {
+		id, err := bson.ObjectIDFromHex(hex)
+		if err != nil {
+			http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+			return
+		}
+		draft.Attachments = append(draft.Attachments, id)
 	}
 
-	postID, err := h.postsService.CreatePost(r.Context(), post)
+	postID, err := h.postsService.CreatePost(r.Context(), author, draft)
 	if err != nil {
-		slog.Error("Failed to create post", "error", err)
-		http.Error(w, "Failed to create post", http.StatusInternalServerError)
+		writePostError(w, err, "Failed to create post")
 		return
 	}
 
@@ -59,11 +89,21 @@ func (h *Handler) CreatePostHandler(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) CreateCommentHandler(w http.ResponseWriter, r *http.Request) {
 	slog.Info("CreateCommentHandler started")
 
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	author, ok := h.currentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
 	var request struct {
-		Person   string `json:"person"`
 		MainText string `json:"mainText"`
-		Date     string `json:"date"`
 		PostID   string `json:"postId"`
+		ParentID string `json:"parentId"` // пусто — комментарий верхнего уровня
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -79,22 +119,25 @@ func (h *Handler) CreateCommentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	comment := models.Comment{
-		Person:   request.Person,
-		MainText: request.MainText,
-		Date:     request.Date,
-		PostID:   postID,
+	var parentID bson.ObjectID
+	if request.ParentID != "" {
+		parentID, err = bson.ObjectIDFromHex(request.ParentID)
+		if err != nil {
+			http.Error(w, "Invalid parent comment ID", http.StatusBadRequest)
+			return
+		}
 	}
 
-	if err := h.postsService.CreateComment(r.Context(), comment); err != nil {
-		slog.Error("Failed to create comment", "error", err)
-		http.Error(w, "Failed to create comment", http.StatusInternalServerError)
+	commentID, err := h.postsService.CreateComment(r.Context(), author, postID, parentID, request.MainText)
+	if err != nil {
+		writePostError(w, err, "Failed to create comment")
 		return
 	}
 
 	response := map[string]interface{}{
-		"success": true,
-		"message": "Comment created successfully",
+		"success":   true,
+		"message":   "Comment created successfully",
+		"commentId": commentID.Hex(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -107,20 +150,48 @@ func (h *Handler) CreateCommentHandler(w http.ResponseWriter, r *http.Request) {
 	slog.Info("Comment created successfully", "postId", postID.Hex())
 }
 
-// GetPostsHandler возвращает список постов
+// GetPostsHandler возвращает страницу постов.
+// Параметры: sort (newest|comments|votes), author (ID автора), tag (через запятую — любой из), cursor, limit.
 func (h *Handler) GetPostsHandler(w http.ResponseWriter, r *http.Request) {
 	slog.Info("GetPostsHandler started")
 
-	posts, err := h.postsService.GetLastPosts(r.Context())
+	params := r.URL.Query()
+	query := models.PostQuery{
+		Sort:   models.PostSort(params.Get("sort")),
+		Cursor: params.Get("cursor"),
+	}
+	if tag := params.Get("tag"); tag != "" {
+		query.Tags = strings.Split(tag, ",")
+	}
+	if author := params.Get("author"); author != "" {
+		authorID, err := strconv.ParseInt(author, 10, 64)
+		if err != nil || authorID <= 0 {
+			http.Error(w, "Invalid author", http.StatusBadRequest)
+			return
+		}
+		query.AuthorID = authorID
+	}
+	if limit := params.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		query.Limit = n
+	}
+
+	query.ViewerID, _ = h.getCurrentUser(r)
+
+	page, err := h.postsService.ListPosts(r.Context(), query)
 	if err != nil {
-		slog.Error("Failed to get posts", "error", err)
-		http.Error(w, "Failed to get posts", http.StatusInternalServerError)
+		writePostError(w, err, "Failed to get posts")
 		return
 	}
 
 	response := map[string]interface{}{
-		"success": true,
-		"posts":   posts,
+		"success":    true,
+		"posts":      page.Posts,
+		"nextCursor": page.NextCursor,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -130,13 +201,109 @@ func (h *Handler) GetPostsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	slog.Info("Posts retrieved successfully", "count", len(posts))
+	slog.Info("Posts retrieved successfully", "count", len(page.Posts))
+}
+
+// CommentTreeHandler возвращает обсуждение поста деревом ответов
+func (h *Handler) CommentTreeHandler(w http.ResponseWriter, r *http.Request) {
+	postID, err := bson.ObjectIDFromHex(r.URL.Query().Get("postId"))
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	viewerID, _ := h.getCurrentUser(r)
+	tree, err := h.postsService.CommentTree(r.Context(), postID, viewerID)
+	if err != nil {
+		writePostError(w, err, "Failed to get comments")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"comments": tree,
+		"postId":   postID.Hex(),
+	}); err != nil {
+		slog.Error("Failed to encode comment tree response", "error", err)
+	}
+}
+
+// PostRevisionsHandler возвращает историю правок поста с отличиями между версиями
+func (h *Handler) PostRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	postID, err := bson.ObjectIDFromHex(r.URL.Query().Get("postId"))
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	viewerID, _ := h.getCurrentUser(r)
+	revisions, err := h.postsService.PostRevisions(r.Context(), postID, viewerID)
+	if err != nil {
+		writePostError(w, err, "Failed to get revisions")
+		return
+	}
+	writeRevisions(w, revisions)
 }
 
+// CommentRevisionsHandler возвращает историю правок комментария
+func (h *Handler) CommentRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	commentID, err := bson.ObjectIDFromHex(r.URL.Query().Get("commentId"))
+	if err != nil {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	viewerID, _ := h.getCurrentUser(r)
+	revisions, err := h.postsService.CommentRevisions(r.Context(), commentID, viewerID)
+	if err != nil {
+		writePostError(w, err, "Failed to get revisions")
+		return
+	}
+	writeRevisions(w, revisions)
+}
+
+func writeRevisions(w http.ResponseWriter, revisions []models.RevisionDiff) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"revisions": revisions,
+	}); err != nil {
+		slog.Error("Failed to encode revisions response", "error", err)
+	}
+}
+
+// RelatedPostsHandler возвращает свежие посты о паре для страницы анализа
+func (h *Handler) RelatedPostsHandler(w http.ResponseWriter, r *http.Request) {
+	pair := r.URL.Query().Get("pair")
+	if pair == "" {
+		http.Error(w, "Pair is required", http.StatusBadRequest)
+		return
+	}
+
+	posts, err := h.postsService.RelatedPosts(r.Context(), pair, 5)
+	if err != nil {
+		writePostError(w, err, "Failed to get related posts")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"posts":   posts,
+		"pair":    pair,
+	}); err != nil {
+		slog.Error("Failed to encode related posts response", "error", err)
+	}
+}
+
+// GetCommentsHandler возвращает страницу комментариев поста.
+// Параметры: postId, sort (newest|oldest|votes), cursor, limit.
 func (h *Handler) GetCommentsHandler(w http.ResponseWriter, r *http.Request) {
 	slog.Info("GetCommentsHandler started")
 
-	postID := r.URL.Query().Get("postId")
+	params := r.URL.Query()
+	postID := params.Get("postId")
 	if postID == "" {
 		slog.Error("Post ID is required")
 		http.Error(w, "Post ID is required", http.StatusBadRequest)
@@ -150,17 +317,33 @@ func (h *Handler) GetCommentsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	comments, err := h.postsService.GetLastCommentsByPost(r.Context(), objectID)
+	query := models.CommentQuery{
+		PostID: objectID,
+		Sort:   models.CommentSort(params.Get("sort")),
+		Cursor: params.Get("cursor"),
+	}
+	if limit := params.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		query.Limit = n
+	}
+
+	query.ViewerID, _ = h.getCurrentUser(r)
+
+	page, err := h.postsService.ListComments(r.Context(), query)
 	if err != nil {
-		slog.Error("Failed to get comments", "postId", postID, "error", err)
-		http.Error(w, "Failed to get comments", http.StatusInternalServerError)
+		writePostError(w, err, "Failed to get comments")
 		return
 	}
 
 	response := map[string]interface{}{
-		"success":  true,
-		"comments": comments,
-		"postId":   postID,
+		"success":    true,
+		"comments":   page.Comments,
+		"postId":     postID,
+		"nextCursor": page.NextCursor,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -170,14 +353,25 @@ func (h *Handler) GetCommentsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	slog.Info("Comments retrieved successfully", "postId", postID, "count", len(comments))
+	slog.Info("Comments retrieved successfully", "postId", postID, "count", len(page.Comments))
 }
+
 func (h *Handler) UpdatePostHandler(w http.ResponseWriter, r *http.Request) {
 	slog.Info("UpdatePostHandler started")
 
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	author, ok := h.currentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
 	var request struct {
 		PostID  string `json:"postId"`
-		Author  string `json:"author"`
 		Title   string `json:"title"`
 		Content string `json:"content"`
 	}
@@ -198,10 +392,9 @@ func (h *Handler) UpdatePostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.postsService.UpdatePost(r.Context(), postID, request.Author, request.Title, request.Content)
+	err = h.postsService.UpdatePost(r.Context(), postID, author, request.Title, request.Content)
 	if err != nil {
-		slog.Error("Failed to update post", "error", err)
-		http.Error(w, "Failed to update post", http.StatusInternalServerError)
+		writePostError(w, err, "Failed to update post")
 		return
 	}
 
@@ -224,9 +417,19 @@ func (h *Handler) UpdatePostHandler(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) DeletePostHandler(w http.ResponseWriter, r *http.Request) {
 	slog.Info("DeletePostHandler started")
 
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	authorID, ok := h.getCurrentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
 	var request struct {
 		PostID string `json:"postId"`
-		Author string `json:"author"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -242,10 +445,9 @@ func (h *Handler) DeletePostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.postsService.DeletePost(r.Context(), postID, request.Author)
+	err = h.postsService.DeletePost(r.Context(), postID, authorID)
 	if err != nil {
-		slog.Error("Failed to delete post", "error", err)
-		http.Error(w, "Failed to delete post", http.StatusInternalServerError)
+		writePostError(w, err, "Failed to delete post")
 		return
 	}
 
@@ -268,9 +470,19 @@ func (h *Handler) DeletePostHandler(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) UpdateCommentHandler(w http.ResponseWriter, r *http.Request) {
 	slog.Info("UpdateCommentHandler started")
 
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	author, ok := h.currentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
 	var request struct {
 		CommentID string `json:"commentId"`
-		Author    string `json:"author"`
 		Content   string `json:"content"`
 	}
 
@@ -287,10 +499,9 @@ func (h *Handler) UpdateCommentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.postsService.UpdateComment(r.Context(), commentID, request.Author, request.Content)
+	err = h.postsService.UpdateComment(r.Context(), commentID, author, request.Content)
 	if err != nil {
-		slog.Error("Failed to update comment", "error", err)
-		http.Error(w, "Failed to update comment", http.StatusInternalServerError)
+		writePostError(w, err, "Failed to update comment")
 		return
 	}
 
@@ -313,9 +524,19 @@ func (h *Handler) UpdateCommentHandler(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) DeleteCommentHandler(w http.ResponseWriter, r *http.Request) {
 	slog.Info("DeleteCommentHandler started")
 
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	authorID, ok := h.getCurrentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
 	var request struct {
 		CommentID string `json:"commentId"`
-		Author    string `json:"author"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -331,10 +552,9 @@ func (h *Handler) DeleteCommentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.postsService.DeleteComment(r.Context(), commentID, request.Author)
+	err = h.postsService.DeleteComment(r.Context(), commentID, authorID)
 	if err != nil {
-		slog.Error("Failed to delete comment", "error", err)
-		http.Error(w, "Failed to delete comment", http.StatusInternalServerError)
+		writePostError(w, err, "Failed to delete comment")
 		return
 	}
 
@@ -352,3 +572,52 @@ func (h *Handler) DeleteCommentHandler(w http.ResponseWriter, r *http.Request) {
 
 	slog.Info("Comment deleted successfully", "commentId", request.CommentID)
 }
+
+// writePostError переводит ошибки PostsService в HTTP-ответ
+func writePostError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrMissingAuthor):
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+	case errors.Is(err, services.ErrEmailNotVerified),
+		errors.Is(err, services.ErrSelfVote),
+		errors.Is(err, services.ErrUserBanned):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrPostingTooFast):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	case errors.Is(err, services.ErrPostNotFound),
+		errors.Is(err, services.ErrCommentNotFound),
+		errors.Is(err, services.ErrAttachmentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrEditConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrAttachmentTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, services.ErrEmptyPerson),
+		errors.Is(err, services.ErrEmptyHeading),
+		errors.Is(err, services.ErrEmptyMainText),
+		errors.Is(err, services.ErrInvalidPostID),
+		errors.Is(err, services.ErrPersonTooLong),
+		errors.Is(err, services.ErrHeadingTooLong),
+		errors.Is(err, services.ErrMainTextTooLong),
+		errors.Is(err, services.ErrCommentTooLong),
+		errors.Is(err, services.ErrParentOtherPost),
+		errors.Is(err, services.ErrInvalidSort),
+		errors.Is(err, services.ErrUnknownTag),
+		errors.Is(err, services.ErrTooManyTags),
+		errors.Is(err, services.ErrAnalysisUnavailable),
+		errors.Is(err, services.ErrInvalidVote),
+		errors.Is(err, services.ErrUnknownReaction),
+		errors.Is(err, services.ErrTooManyAttachments),
+		errors.Is(err, services.ErrUnsupportedImage),
+		errors.Is(err, services.ErrImageTooLarge),
+		errors.Is(err, services.ErrBannedContent),
+		errors.Is(err, services.ErrInvalidReport),
+		errors.Is(err, services.ErrSelfReport),
+		errors.Is(err, services.ErrInvalidSearch),
+		errors.Is(err, models.ErrInvalidCursor):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		slog.Error(fallback, "error", err)
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
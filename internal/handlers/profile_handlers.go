@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"crypto-analytics/internal/services"
+	"crypto-analytics/internal/storage"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// ProfileHandler возвращает профиль текущего пользователя
+func (h *Handler) ProfileHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, ok := h.currentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	profile := user.Profile()
+	reputation, err := h.postsService.Reputation(r.Context(), user.ID)
+	if err != nil {
+		// Профиль важнее репутации — отдаём его и без неё
+		slog.Error("Failed to get reputation", "user_id", user.ID, "error", err)
+	}
+	profile.Reputation = reputation
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Data:    profile,
+	})
+}
+
+// UpdateProfileHandler меняет отображаемое имя и email
+func (h *Handler) UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := h.getCurrentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		DisplayName string `json:"displayName"`
+		Email       string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.userService.UpdateProfile(userID, request.DisplayName, request.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrEmptyDisplayName),
+			errors.Is(err, services.ErrDisplayNameTooLong),
+			errors.Is(err, services.ErrInvalidEmail):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, storage.ErrEmailTaken):
+			http.Error(w, "Email is already in use", http.StatusConflict)
+		default:
+			slog.Error("Failed to update profile", "user_id", userID, "error", err)
+			http.Error(w, "Failed to update profile", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "Profile updated successfully",
+		Data:    user.Profile(),
+	})
+}
+
+// ChangePasswordHandler меняет пароль с подтверждением текущего
+func (h *Handler) ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := h.getCurrentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		CurrentPassword string `json:"currentPassword"`
+		NewPassword     string `json:"newPassword"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	err := h.userService.ChangePassword(userID, request.CurrentPassword, request.NewPassword)
+	if err != nil {
+		var verr *services.ValidationError
+		switch {
+		case errors.As(err, &verr):
+			writeFieldErrors(w, http.StatusUnprocessableEntity, map[string]string{"newPassword": verr.Fields["password"]})
+		case errors.Is(err, services.ErrWrongPassword):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, services.ErrEmptyPassword):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			slog.Error("Failed to change password", "user_id", userID, "error", err)
+			http.Error(w, "Failed to change password", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Остальные устройства должны войти заново уже с новым паролем
+	if _, err := h.storeSessions.RevokeAllSessions(userID, h.currentSessionID(r)); err != nil {
+		slog.Error("Failed to revoke other sessions", "user_id", userID, "error", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "Password changed successfully",
+	})
+}
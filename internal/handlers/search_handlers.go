@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"crypto-analytics/internal/models"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// SearchHandler ищет по постам и комментариям.
+// Параметры: q, type (post|comment), author, from и to (YYYY-MM-DD или RFC3339), limit.
+func (h *Handler) SearchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	query := models.SearchQuery{
+		Text: params.Get("q"),
+		Kind: models.SearchKind(params.Get("type")),
+	}
+	if author := params.Get("author"); author != "" {
+		authorID, err := strconv.ParseInt(author, 10, 64)
+		if err != nil || authorID <= 0 {
+			http.Error(w, "Invalid author", http.StatusBadRequest)
+			return
+		}
+		query.AuthorID = authorID
+	}
+	var err error
+	if query.From, err = parseSearchDate(params.Get("from"), false); err != nil {
+		http.Error(w, "Invalid from date", http.StatusBadRequest)
+		return
+	}
+	if query.To, err = parseSearchDate(params.Get("to"), true); err != nil {
+		http.Error(w, "Invalid to date", http.StatusBadRequest)
+		return
+	}
+	if limit := params.Get("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	query.ViewerID, _ = h.getCurrentUser(r)
+
+	hits, err := h.search.Search(r.Context(), query)
+	if err != nil {
+		writePostError(w, err, "Failed to search")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Data:    hits,
+	})
+}
+
+// parseSearchDate разбирает границу диапазона. Дата без времени в to
+// означает «по этот день включительно», поэтому сдвигается на сутки.
+func parseSearchDate(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"crypto-analytics/internal/storage"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type sessionInfo struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	LastSeen  time.Time `json:"lastSeen"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"userAgent"`
+	Current   bool      `json:"current"`
+}
+
+// ListSessionsHandler возвращает активные сессии текущего пользователя
+func (h *Handler) ListSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := h.getCurrentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	list, err := h.storeSessions.ListSessions(userID)
+	if err != nil {
+		slog.Error("Failed to list sessions", "user_id", userID, "error", err)
+		http.Error(w, "Failed to list sessions", http.StatusInternalServerError)
+		return
+	}
+
+	currentID := h.currentSessionID(r)
+	result := make([]sessionInfo, 0, len(list))
+	for _, s := range list {
+		result = append(result, sessionInfo{
+			ID:        s.PublicID,
+			CreatedAt: s.CreatedAt,
+			LastSeen:  s.LastSeen,
+			IP:        s.IP,
+			UserAgent: s.UserAgent,
+			Current:   s.ID == currentID,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Data:    result,
+	})
+}
+
+// RevokeSessionHandler завершает одну из сессий текущего пользователя
+func (h *Handler) RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := h.getCurrentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.ID == "" {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.storeSessions.RevokeSession(userID, request.ID); err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		slog.Error("Failed to revoke session", "user_id", userID, "error", err)
+		http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "Session revoked",
+	})
+}
+
+// RevokeAllSessionsHandler — «выйти на всех устройствах», включая текущее
+func (h *Handler) RevokeAllSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := h.getCurrentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	removed, err := h.storeSessions.RevokeAllSessions(userID, "")
+	if err != nil {
+		slog.Error("Failed to revoke sessions", "user_id", userID, "error", err)
+		http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+
+	session, _ := h.storeSessions.Get(r, "user-session")
+	session.Options.MaxAge = -1
+	if err := session.Save(r, w); err != nil {
+		slog.Error("Failed to delete session", "error", err)
+	}
+
+	slog.Info("All sessions revoked", "user_id", userID, "count", removed)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "Logged out on all devices",
+		Data:    map[string]int{"revoked": removed},
+	})
+}
+
+func (h *Handler) currentSessionID(r *http.Request) string {
+	session, err := h.storeSessions.Get(r, "user-session")
+	if err != nil {
+		return ""
+	}
+	return session.ID
+}
@@ -3,12 +3,9 @@ package handlers
 import (
 	"html/template"
 	"path/filepath"
-	"regexp"
 
 	"crypto-analytics/internal/services"
 	"crypto-analytics/internal/storage"
-
-	"github.com/gorilla/sessions"
 )
 
 type Handler struct {
@@ -17,32 +14,52 @@ type Handler struct {
 	cryptoSvc     services.GetAllPairsService
 	userService   services.UserLogService
 	tmpl          *template.Template
-	storeSessions *sessions.CookieStore
+	storeSessions services.SessionStore
 	newsStorage   services.NewsRssService
 	pairs         services.AIAnalysisService
 	Analysis      services.AnalysisGService
 	postsService  services.PostPService
+	feeds         services.FeedBuilder
+	apiTokens     services.APITokenManager
+	twoFactor     services.TwoFactorManager
+	rateLimiter   services.RateLimiter
+	loginGuard    services.LoginGuard
+	attachments   services.AttachmentManager
+	moderation    services.ModerationManager
+	search        services.Searcher
+	tickets       services.TicketManager
+	formGuard     services.FormChecker
+	notifications services.NotificationSettings
+	webhooks      services.WebhookManager
 }
 
 func NewHandler(storage storage.FormStorage,
 	notifier services.Notifier,
 	cryptoSvc services.GetAllPairsService,
 	userService services.UserLogService,
-	KeyUsersGorilla string,
+	sessionStore services.SessionStore,
 	newsStor services.NewsRssService,
 	pairss services.AIAnalysisService,
 	analys services.AnalysisGService,
-	post services.PostPService) (*Handler, error) {
+	post services.PostPService,
+	feeds services.FeedBuilder,
+	apiTokens services.APITokenManager,
+	twoFactor services.TwoFactorManager,
+	rateLimiter services.RateLimiter,
+	loginGuard services.LoginGuard,
+	attachments services.AttachmentManager,
+	moderation services.ModerationManager,
+	search services.Searcher,
+	tickets services.TicketManager,
+	formGuard services.FormChecker,
+	notifications services.NotificationSettings,
+	webhooks services.WebhookManager) (*Handler, error) {
 
 	tmpl := template.New("").Funcs(template.FuncMap{
 		"formatNumber": formatNumber,
 		"add":          add,
 		"formatMoney":  formatMoney,
 		"parseTime":    parseTime,
-		"stripHTML": func(html string) string {
-			re := regexp.MustCompile(`<[^>]*>`)
-			return re.ReplaceAllString(html, "")
-		},
 	})
 	tmpl, err := tmpl.ParseFiles(
 		filepath.Join("static", "answerForm.html"),
@@ -53,16 +70,27 @@ func NewHandler(storage storage.FormStorage,
 		return nil, err
 	}
 	return &Handler{
-		storage:     storage,
-		notifier:    notifier,
-		cryptoSvc:   cryptoSvc,
-		userService: userService,
-		tmpl:        tmpl,
-		storeSessions: sessions.NewCookieStore(
-			[]byte(KeyUsersGorilla)),
-		newsStorage:  newsStor,
-		pairs:        pairss,
-		Analysis:     analys,
-		postsService: post,
+		storage:       storage,
+		notifier:      notifier,
+		cryptoSvc:     cryptoSvc,
+		userService:   userService,
+		tmpl:          tmpl,
+		storeSessions: sessionStore,
+		newsStorage:   newsStor,
+		pairs:         pairss,
+		Analysis:      analys,
+		postsService:  post,
+		feeds:         feeds,
+		apiTokens:     apiTokens,
+		twoFactor:     twoFactor,
+		rateLimiter:   rateLimiter,
+		loginGuard:    loginGuard,
+		attachments:   attachments,
+		moderation:    moderation,
+		search:        search,
+		tickets:       tickets,
+		formGuard:     formGuard,
+		notifications: notifications,
+		webhooks:      webhooks,
 	}, nil
 }
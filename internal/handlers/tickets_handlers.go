@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/services"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// AdminTicketsHandler — список обращений. Фильтры: status, assignee (ID или none), email, limit, offset.
+func (h *Handler) AdminTicketsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	query := models.TicketQuery{
+		Status: models.TicketStatus(params.Get("status")),
+		Email:  params.Get("email"),
+	}
+	switch assignee := params.Get("assignee"); assignee {
+	case "":
+	case "none":
+		query.AssigneeID = -1
+	default:
+		id, err := strconv.ParseInt(assignee, 10, 64)
+		if err != nil || id <= 0 {
+			http.Error(w, "Invalid assignee", http.StatusBadRequest)
+			return
+		}
+		query.AssigneeID = id
+	}
+	query.Limit, _ = strconv.Atoi(params.Get("limit"))
+	query.Offset, _ = strconv.Atoi(params.Get("offset"))
+
+	tickets, err := h.tickets.ListTickets(query)
+	if err != nil {
+		writeTicketError(w, err, "Failed to list tickets")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Data:    tickets,
+	})
+}
+
+// AdminTicketHandler — одно обращение с заметками и ответами: id
+func (h *Handler) AdminTicketHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+	ticket, err := h.tickets.GetTicket(id)
+	if err != nil {
+		writeTicketError(w, err, "Failed to get ticket")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Data:    ticket,
+	})
+}
+
+// AdminUpdateTicketHandler меняет обращение. action: status (поле status),
+// assign (assigneeId, 0 — снять), note (body) или reply (body, уходит автору письмом).
+func (h *Handler) AdminUpdateTicketHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	admin, ok := userFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		ID         int64  `json:"id"`
+		Action     string `json:"action"`
+		Status     string `json:"status"`
+		AssigneeID int64  `json:"assigneeId"`
+		Body       string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.ID <= 0 {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	var data interface{}
+	var err error
+	switch request.Action {
+	case "status":
+		err = h.tickets.SetStatus(admin, request.ID, models.TicketStatus(request.Status))
+	case "assign":
+		err = h.tickets.Assign(admin, request.ID, request.AssigneeID)
+	case "note":
+		data, err = h.tickets.AddNote(admin, request.ID, request.Body)
+	case "reply":
+		data, err = h.tickets.Reply(r.Context(), admin, request.ID, request.Body)
+	default:
+		http.Error(w, "Unknown action", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeTicketError(w, err, "Failed to update ticket")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "Ticket updated",
+		Data:    data,
+	})
+}
+
+func writeTicketError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrTicketNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrInvalidTicket):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrReplyNotSent):
+		http.Error(w, err.Error(), http.StatusBadGateway)
+	default:
+		slog.Error(fallback, "error", err)
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/services"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Ключи сессии для входа, который ждёт второй фактор
+const (
+	pending2FAUserKey     = "pending2FAUserID"
+	pending2FAAtKey       = "pending2FAAt"
+	pending2FAAttemptsKey = "pending2FAAttempts"
+
+	pending2FATTL         = 5 * time.Minute
+	pending2FAMaxAttempts = 5
+)
+
+// LoginTwoFactorHandler — второй шаг входа: проверка кода из приложения или резервного кода
+func (h *Handler) LoginTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, _ := h.storeSessions.Get(r, "user-session")
+	userID, ok := session.Values[pending2FAUserKey].(int64)
+	startedAt, _ := session.Values[pending2FAAtKey].(int64)
+	if !ok || time.Since(time.Unix(startedAt, 0)) > pending2FATTL {
+		h.abortTwoFactorLogin(w, r, "2faExpired")
+		return
+	}
+
+	user, err := h.userService.GetUserByID(userID)
+	if err != nil {
+		slog.Error("Failed to load user for 2FA login", "user_id", userID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	// Код перебирают так же, как пароль: счётчик общий, по ID пользователя
+	ip := services.ClientIP(r)
+	if lockedFor, err := h.loginGuard.Check(user.Username, ip); err != nil {
+		slog.Error("Failed to check login lock", "error", err)
+	} else if lockedFor > 0 {
+		setRetryAfter(w, lockedFor)
+		h.abortTwoFactorLogin(w, r, "locked")
+		return
+	}
+
+	err = h.twoFactor.Verify(userID, r.FormValue("code"))
+	if err != nil {
+		if !errors.Is(err, services.ErrInvalidTwoFactorCode) {
+			slog.Error("Failed to verify 2FA code", "user_id", userID, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		lockedFor, guardErr := h.loginGuard.Failure(models.AuthEvent{
+			Login:     user.Username,
+			IP:        ip,
+			UserAgent: r.UserAgent(),
+		})
+		if guardErr != nil {
+			slog.Error("Failed to record 2FA failure", "error", guardErr)
+		}
+		if lockedFor > 0 {
+			setRetryAfter(w, lockedFor)
+			h.abortTwoFactorLogin(w, r, "locked")
+			return
+		}
+
+		attempts, _ := session.Values[pending2FAAttemptsKey].(int)
+		attempts++
+		slog.Warn("Invalid 2FA code", "user_id", userID, "attempt", attempts)
+		if attempts >= pending2FAMaxAttempts {
+			h.abortTwoFactorLogin(w, r, "2faExpired")
+			return
+		}
+		session.Values[pending2FAAttemptsKey] = attempts
+		if err := session.Save(r, w); err != nil {
+			slog.Error("Failed to save session", "error", err)
+		}
+		http.Redirect(w, r, "/static/TwoFactor.html?err=code", http.StatusSeeOther)
+		return
+	}
+	// Счётчики сбрасываем только после второго фактора: иначе повторный ввод пароля
+	// обнулял бы неудачные коды
+	if err := h.loginGuard.Success(user.Username); err != nil {
+		slog.Error("Failed to reset login failures", "error", err)
+	}
+
+	// Вход завершён — ещё раз меняем ID сессии
+	if err := h.storeSessions.Discard(session); err != nil {
+		slog.Error("Failed to discard pending session", "error", err)
+	}
+	h.completeLogin(w, r, session, userID)
+}
+
+// abortTwoFactorLogin сбрасывает незавершённый вход: начинать придётся с пароля
+func (h *Handler) abortTwoFactorLogin(w http.ResponseWriter, r *http.Request, reason string) {
+	session, _ := h.storeSessions.Get(r, "user-session")
+	session.Options.MaxAge = -1
+	if err := session.Save(r, w); err != nil {
+		slog.Error("Failed to delete session", "error", err)
+	}
+	http.Redirect(w, r, "/static/FormRegUser.html?err="+reason, http.StatusSeeOther)
+}
+
+// TwoFactorSetupHandler начинает подключение 2FA: выдаёт секрет и otpauth:// ссылку
+func (h *Handler) TwoFactorSetupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := h.getCurrentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	secret, uri, err := h.twoFactor.BeginEnrollment(userID)
+	if err != nil {
+		if errors.Is(err, services.ErrTwoFactorEnabled) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		slog.Error("Failed to start 2FA setup", "user_id", userID, "error", err)
+		http.Error(w, "Failed to start 2FA setup", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "Add the key to your authenticator app and confirm with a code",
+		Data: map[string]string{
+			"secret":          secret,
+			"provisioningUri": uri,
+		},
+	})
+}
+
+// TwoFactorConfirmHandler включает 2FA по первому коду и отдаёт резервные коды
+func (h *Handler) TwoFactorConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := h.getCurrentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.twoFactor.ConfirmEnrollment(userID, request.Code)
+	if err != nil {
+		h.writeTwoFactorError(w, userID, err)
+		return
+	}
+
+	writeRecoveryCodes(w, "Two-factor authentication enabled", codes)
+}
+
+// TwoFactorDisableHandler выключает 2FA (нужны пароль и код)
+func (h *Handler) TwoFactorDisableHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := h.getCurrentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.twoFactor.Disable(userID, request.Password, request.Code); err != nil {
+		h.writeTwoFactorError(w, userID, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "Two-factor authentication disabled",
+	})
+}
+
+// TwoFactorRecoveryCodesHandler выпускает новый набор резервных кодов взамен старых
+func (h *Handler) TwoFactorRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := h.getCurrentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.twoFactor.RegenerateRecoveryCodes(userID, request.Code)
+	if err != nil {
+		h.writeTwoFactorError(w, userID, err)
+		return
+	}
+
+	writeRecoveryCodes(w, "Recovery codes regenerated", codes)
+}
+
+func (h *Handler) writeTwoFactorError(w http.ResponseWriter, userID int64, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidTwoFactorCode),
+		errors.Is(err, services.ErrTwoFactorNotStarted):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrWrongPassword):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrTwoFactorEnabled),
+		errors.Is(err, services.ErrTwoFactorNotEnabled):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		slog.Error("2FA operation failed", "user_id", userID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func writeRecoveryCodes(w http.ResponseWriter, message string, codes []string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: message + ". Store the recovery codes, they will not be shown again",
+		Data:    map[string][]string{"recoveryCodes": codes},
+	})
+}
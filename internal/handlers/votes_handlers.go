@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"crypto-analytics/internal/models"
+	"encoding/json"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// VoteHandler ставит, меняет или снимает голос за пост либо комментарий
+func (h *Handler) VoteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	voter, ok := h.currentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		Target string `json:"target"`
+		ID     string `json:"id"`
+		Value  int    `json:"value"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	id, err := bson.ObjectIDFromHex(request.ID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	score, err := h.postsService.Vote(r.Context(), voter, models.VoteTarget(request.Target), id, request.Value)
+	if err != nil {
+		writePostError(w, err, "Failed to vote")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Data:    map[string]int{"score": score, "value": request.Value},
+	})
+}
+
+// ReactHandler ставит (on: true) или снимает эмодзи-реакцию
+func (h *Handler) ReactHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, ok := h.currentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		Target   string `json:"target"`
+		ID       string `json:"id"`
+		Reaction string `json:"reaction"`
+		On       bool   `json:"on"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	id, err := bson.ObjectIDFromHex(request.ID)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	counts, err := h.postsService.React(r.Context(), user, models.VoteTarget(request.Target), id, request.Reaction, request.On)
+	if err != nil {
+		writePostError(w, err, "Failed to react")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Data:    map[string]interface{}{"reactions": counts},
+	})
+}
+
+// ReactionsHandler отдаёт список допустимых реакций (имя → эмодзи)
+func (h *Handler) ReactionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Data:    models.Reactions,
+	})
+}
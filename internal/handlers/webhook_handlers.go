@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/services"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// webhookRequest — тело запросов на создание и изменение вебхука
+type webhookRequest struct {
+	ID          int64                 `json:"id"`
+	URL         string                `json:"url"`
+	Description string                `json:"description"`
+	Events      []models.WebhookEvent `json:"events"`
+	Enabled     bool                  `json:"enabled"`
+}
+
+func (req webhookRequest) endpoint() models.WebhookEndpoint {
+	return models.WebhookEndpoint{
+		URL:         req.URL,
+		Description: req.Description,
+		Events:      req.Events,
+		Enabled:     req.Enabled,
+	}
+}
+
+// ListWebhooksHandler — вебхуки текущего пользователя и события, на которые можно подписаться
+func (h *Handler) ListWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, ok := h.currentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+	webhooks, err := h.webhooks.ListWebhooks(user)
+	if err != nil {
+		writeWebhookError(w, err, "Failed to list webhooks")
+		return
+	}
+
+	var events []models.WebhookEvent
+	for _, e := range models.KnownWebhookEvents {
+		if user.Role.AtLeast(e.RequiredRole()) {
+			events = append(events, e)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"webhooks":        webhooks,
+			"availableEvents": events,
+		},
+	})
+}
+
+// CreateWebhookHandler регистрирует вебхук: url, description, events.
+// Секрет для проверки подписи показывается только в этом ответе.
+func (h *Handler) CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, ok := h.currentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+	var request webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	secret, webhook, err := h.webhooks.CreateWebhook(user, request.endpoint())
+	if err != nil {
+		writeWebhookError(w, err, "Failed to create webhook")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "Webhook created. Store the secret now, it will not be shown again",
+		Data: map[string]interface{}{
+			"webhook": webhook,
+			"secret":  secret,
+		},
+	})
+}
+
+// UpdateWebhookHandler меняет вебхук: id, url, description, events, enabled
+func (h *Handler) UpdateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, ok := h.currentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+	var request webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := h.webhooks.UpdateWebhook(user, request.ID, request.endpoint())
+	if err != nil {
+		writeWebhookError(w, err, "Failed to update webhook")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "Webhook updated",
+		Data:    webhook,
+	})
+}
+
+// DeleteWebhookHandler удаляет вебхук вместе с журналом доставок: id
+func (h *Handler) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	user, id, ok := h.webhookTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.webhooks.DeleteWebhook(user, id); err != nil {
+		writeWebhookError(w, err, "Failed to delete webhook")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "Webhook deleted",
+	})
+}
+
+// RotateWebhookSecretHandler выдаёт новый секрет: id. Старый перестаёт действовать сразу.
+func (h *Handler) RotateWebhookSecretHandler(w http.ResponseWriter, r *http.Request) {
+	user, id, ok := h.webhookTarget(w, r)
+	if !ok {
+		return
+	}
+
+	secret, err := h.webhooks.RotateSecret(user, id)
+	if err != nil {
+		writeWebhookError(w, err, "Failed to rotate webhook secret")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "Webhook secret rotated. Store the secret now, it will not be shown again",
+		Data:    map[string]string{"secret": secret},
+	})
+}
+
+// TestWebhookHandler сразу отправляет на вебхук событие ping: id.
+// Ответ — запись журнала с кодом ответа получателя или ошибкой.
+func (h *Handler) TestWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	user, id, ok := h.webhookTarget(w, r)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.webhooks.SendTestEvent(r.Context(), user, id)
+	if err != nil {
+		writeWebhookError(w, err, "Failed to send test event")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: delivery.Status == models.OutboxSent,
+		Data:    delivery,
+	})
+}
+
+// WebhookDeliveriesHandler — журнал доставок вебхука: ?id=...&limit=...
+func (h *Handler) WebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, ok := h.currentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid webhook id", http.StatusBadRequest)
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	deliveries, err := h.webhooks.Deliveries(user, id, limit)
+	if err != nil {
+		writeWebhookError(w, err, "Failed to list webhook deliveries")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Data:    deliveries,
+	})
+}
+
+// webhookTarget разбирает POST-запрос вида {"id": ...} от вошедшего пользователя
+func (h *Handler) webhookTarget(w http.ResponseWriter, r *http.Request) (*models.User, int64, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil, 0, false
+	}
+
+	user, ok := h.currentUser(r)
+	if !ok {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return nil, 0, false
+	}
+	var request struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return nil, 0, false
+	}
+	return user, request.ID, true
+}
+
+func writeWebhookError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrWebhookNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrInvalidWebhook):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrTooManyWebhooks):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		slog.Error(fallback, "error", err)
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+type Scope string
+
+const (
+	ScopeReadMarket     Scope = "read:market"
+	ScopeReadFavorites  Scope = "read:favorites"
+	ScopeWriteFavorites Scope = "write:favorites"
+	ScopeReadPosts      Scope = "read:posts"
+	ScopeWritePosts     Scope = "write:posts"
+)
+
+var KnownScopes = []Scope{
+	ScopeReadMarket,
+	ScopeReadFavorites,
+	ScopeWriteFavorites,
+	ScopeReadPosts,
+	ScopeWritePosts,
+}
+
+func (s Scope) Known() bool {
+	return slices.Contains(KnownScopes, s)
+}
+
+// APIToken — персональный токен для скриптов. Сам токен показывается один раз
+// при создании, в базе хранится только его хеш и короткий префикс для списка.
+type APIToken struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	TokenHash  string     `json:"-"`
+	Scopes     []Scope    `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+func (t *APIToken) HasScope(scope Scope) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+func (t *APIToken) Active(now time.Time) bool {
+	if t.RevokedAt != nil {
+		return false
+	}
+	return t.ExpiresAt == nil || now.Before(*t.ExpiresAt)
+}
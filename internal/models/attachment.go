@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Attachment — картинка, загруженная для поста. Файлы лежат в BlobStore под ключами
+// BlobKey и ThumbKey, в Mongo — только описание. Пока PostID пустой, вложение
+// ни к чему не привязано и через сутки удаляется.
+type Attachment struct {
+	ID               bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	OwnerID          int64         `bson:"ownerId" json:"ownerId"`
+	PostID           bson.ObjectID `bson:"postId,omitempty" json:"postId,omitempty"`
+	ContentType      string        `bson:"contentType" json:"contentType"`
+	Size             int64         `bson:"size" json:"size"`
+	Width            int           `bson:"width" json:"width"`
+	Height           int           `bson:"height" json:"height"`
+	BlobKey          string        `bson:"blobKey" json:"-"`
+	ThumbKey         string        `bson:"thumbKey" json:"-"`
+	ThumbContentType string        `bson:"thumbContentType" json:"-"`
+	ThumbWidth       int           `bson:"thumbWidth" json:"thumbWidth"`
+	ThumbHeight      int           `bson:"thumbHeight" json:"thumbHeight"`
+	CreatedAt        time.Time     `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type AuthEventType string
+
+const (
+	AuthEventLoginFailed AuthEventType = "login_failed"
+	AuthEventLockout     AuthEventType = "lockout"
+)
+
+// AuthEvent — запись журнала входов (auth_audit_log)
+type AuthEvent struct {
+	ID        int64         `json:"id"`
+	Event     AuthEventType `json:"event"`
+	Login     string        `json:"login"`
+	IP        string        `json:"ip"`
+	UserAgent string        `json:"userAgent"`
+	CreatedAt time.Time     `json:"createdAt"`
+}
@@ -1,7 +1,70 @@
 package models
 
+import "time"
+
 type ContactForm struct {
 	Name    string `json:"name"`
 	Email   string `json:"email"`
 	Message string `json:"message"`
+	// Откуда пришло обращение — чтобы разбирать спам
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+// TicketStatus — стадия обработки обращения из формы обратной связи
+type TicketStatus string
+
+const (
+	TicketNew        TicketStatus = "new"
+	TicketInProgress TicketStatus = "in_progress"
+	TicketResolved   TicketStatus = "resolved"
+)
+
+func (s TicketStatus) Valid() bool {
+	switch s {
+	case TicketNew, TicketInProgress, TicketResolved:
+		return true
+	}
+	return false
+}
+
+// Ticket — обращение из формы обратной связи вместе с тем, как его обрабатывают
+type Ticket struct {
+	ID         int64        `json:"id"`
+	Name       string       `json:"name"`
+	Email      string       `json:"email"`
+	Message    string       `json:"message"`
+	IP         string       `json:"ip"`
+	UserAgent  string       `json:"userAgent"`
+	Status     TicketStatus `json:"status"`
+	AssigneeID *int64       `json:"assigneeId"`
+	CreatedAt  time.Time    `json:"createdAt"`
+	UpdatedAt  *time.Time   `json:"updatedAt,omitempty"`
+	ResolvedAt *time.Time   `json:"resolvedAt,omitempty"`
+	Notes      []TicketNote `json:"notes,omitempty"` // только в выдаче одного обращения
+}
+
+type TicketNoteKind string
+
+const (
+	TicketNoteInternal TicketNoteKind = "note"  // видят только администраторы
+	TicketNoteReply    TicketNoteKind = "reply" // ушла автору обращения письмом
+)
+
+type TicketNote struct {
+	ID        int64          `json:"id"`
+	TicketID  int64          `json:"ticketId"`
+	AuthorID  int64          `json:"authorId"`
+	Kind      TicketNoteKind `json:"kind"`
+	Body      string         `json:"body"`
+	CreatedAt time.Time      `json:"createdAt"`
+}
+
+// TicketQuery — фильтры списка обращений
+type TicketQuery struct {
+	Status     TicketStatus // пустой — любые
+	AssigneeID int64        // 0 — любые, -1 — без исполнителя
+	Email      string
+	Limit      int
+	Offset     int
 }
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Feed — общее представление исходящей ленты, из которого собираются RSS, Atom и JSON Feed
+type Feed struct {
+	Title       string
+	Description string
+	HomeURL     string
+	FeedURL     string
+	Updated     time.Time
+	Items       []FeedItem
+}
+
+type FeedItem struct {
+	ID        string
+	Title     string
+	URL       string
+	Summary   string
+	Author    string
+	Published time.Time
+	Tags      []string
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+type ModerationAction string
+
+const (
+	ModerationRemove  ModerationAction = "remove"
+	ModerationRestore ModerationAction = "restore"
+	ModerationDismiss ModerationAction = "dismiss" // жалобы отклонены, объект остаётся
+	ModerationBan     ModerationAction = "ban"
+	ModerationShadow  ModerationAction = "shadow_ban"
+	ModerationUnban   ModerationAction = "unban"
+)
+
+type ModerationTarget string
+
+const (
+	ModerationTargetPost    ModerationTarget = "post"
+	ModerationTargetComment ModerationTarget = "comment"
+	ModerationTargetUser    ModerationTarget = "user"
+)
+
+// ModerationEntry — запись журнала модерации: кто, что и с каким объектом сделал
+type ModerationEntry struct {
+	ID        int64            `json:"id"`
+	ActorID   int64            `json:"actorId"`
+	Action    ModerationAction `json:"action"`
+	Target    ModerationTarget `json:"target"`
+	TargetID  string           `json:"targetId"`
+	Reason    string           `json:"reason,omitempty"`
+	CreatedAt time.Time        `json:"createdAt"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type NewsItem struct {
 	GUID        string `json:"guid"`
 	Title       string `json:"title"`
@@ -8,3 +10,14 @@ type NewsItem struct {
 	PublishedAt string `json:"published_at"`
 	Source      string `json:"source"`
 }
+
+// FeedState хранит состояние опроса одного RSS-фида между запусками
+type FeedState struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Failures     int       `json:"failures"`
+	LastError    string    `json:"last_error,omitempty"`
+	LastFetched  time.Time `json:"last_fetched"`
+	NextAttempt  time.Time `json:"next_attempt"`
+}
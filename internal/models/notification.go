@@ -0,0 +1,95 @@
+package models
+
+import "time"
+
+// NotificationChannel — куда доставляется уведомление
+type NotificationChannel string
+
+const (
+	ChannelEmail    NotificationChannel = "email"
+	ChannelTelegram NotificationChannel = "telegram"
+	ChannelWebhook  NotificationChannel = "webhook"
+)
+
+func (c NotificationChannel) Valid() bool {
+	switch c {
+	case ChannelEmail, ChannelTelegram, ChannelWebhook:
+		return true
+	}
+	return false
+}
+
+// NotificationEvent — что произошло
+type NotificationEvent string
+
+const (
+	EventNewUser       NotificationEvent = "new_user"
+	EventContactForm   NotificationEvent = "contact_form"
+	EventSecurityAlert NotificationEvent = "security_alert" // подозрительные входы и блокировки
+)
+
+func (e NotificationEvent) Valid() bool {
+	switch e {
+	case EventNewUser, EventContactForm, EventSecurityAlert:
+		return true
+	}
+	return false
+}
+
+// Notification — уведомление, не зависящее от канала: каждый адаптер сам
+// решает, как показать заголовок, текст и поля
+type Notification struct {
+	Event     NotificationEvent `json:"event"`
+	Subject   string            `json:"subject"`
+	Text      string            `json:"text"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// ChannelPreference — канал пользователя: адрес (email, chat_id, URL) и события,
+// которые он хочет туда получать. Пустой Events — все события.
+type ChannelPreference struct {
+	UserID  int64               `json:"-"`
+	Channel NotificationChannel `json:"channel"`
+	Address string              `json:"address"`
+	Events  []NotificationEvent `json:"events"`
+	Enabled bool                `json:"enabled"`
+}
+
+// Wants сообщает, подписан ли канал на событие
+func (p ChannelPreference) Wants(event NotificationEvent) bool {
+	if !p.Enabled {
+		return false
+	}
+	if len(p.Events) == 0 {
+		return true
+	}
+	for _, e := range p.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+type OutboxStatus string
+
+const (
+	OutboxPending OutboxStatus = "pending"
+	OutboxSent    OutboxStatus = "sent"
+	OutboxFailed  OutboxStatus = "failed" // попытки кончились
+)
+
+// OutboxItem — одна доставка уведомления в один канал
+type OutboxItem struct {
+	ID            int64
+	UserID        int64
+	Channel       NotificationChannel
+	Address       string
+	Message       Notification
+	Status        OutboxStatus
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+}
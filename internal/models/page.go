@@ -0,0 +1,98 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// PostSort — порядок выдачи постов
+type PostSort string
+
+const (
+	PostSortNewest   PostSort = "newest"
+	PostSortComments PostSort = "comments"
+	PostSortVotes    PostSort = "votes"
+	PostSortTop      PostSort = "top" // голоса с поправкой на возраст
+)
+
+// CommentSort — порядок выдачи комментариев к посту
+type CommentSort string
+
+const (
+	CommentSortNewest CommentSort = "newest"
+	CommentSortOldest CommentSort = "oldest"
+	CommentSortVotes  CommentSort = "votes"
+)
+
+// PageCursor — позиция в выдаче: значение ключа сортировки и _id последней
+// отданной записи. Клиент получает его непрозрачной строкой.
+type PageCursor struct {
+	Key float64
+	ID  bson.ObjectID
+}
+
+type pageCursorJSON struct {
+	Key float64 `json:"k,omitempty"`
+	ID  string  `json:"id"`
+}
+
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+func (c PageCursor) Encode() string {
+	data, _ := json.Marshal(pageCursorJSON{Key: c.Key, ID: c.ID.Hex()})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodePageCursor разбирает строку из Encode; пустая строка — первая страница
+func DecodePageCursor(s string) (*PageCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var raw pageCursorJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	id, err := bson.ObjectIDFromHex(raw.ID)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &PageCursor{Key: raw.Key, ID: id}, nil
+}
+
+// PostQuery — фильтры и страница для списка постов
+type PostQuery struct {
+	AuthorID int64
+	Tags     []string // пост подходит, если у него есть любой из тегов
+	Sort     PostSort
+	Cursor   string      // от клиента
+	After    *PageCursor // разобранный Cursor, его читает хранилище
+	Limit    int
+	ViewerID int64 // кто смотрит: свои скрытые теневым баном посты видны автору
+}
+
+// CommentQuery — страница комментариев одного поста
+type CommentQuery struct {
+	PostID   bson.ObjectID
+	Sort     CommentSort
+	Cursor   string
+	After    *PageCursor
+	Limit    int
+	ViewerID int64
+}
+
+type PostPage struct {
+	Posts      []Post
+	NextCursor string
+}
+
+type CommentPage struct {
+	Comments   []Comment
+	NextCursor string
+}
@@ -1,24 +1,93 @@
 package models
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 // Post структура для постов
 type Post struct {
-	ID         bson.ObjectID   `bson:"_id,omitempty"`
-	Person     string          `bson:"person"`
-	Heading    string          `bson:"heading"`
-	MainText   string          `bson:"mainText"`
-	Date       string          `bson:"date"`
-	CommentIDs []bson.ObjectID `bson:"commentIds,omitempty"`
+	ID           bson.ObjectID     `bson:"_id,omitempty"`
+	AuthorID     int64             `bson:"authorId"`
+	Person       string            `bson:"person"`
+	Heading      string            `bson:"heading"`
+	MainText     string            `bson:"mainText"`
+	MainTextHTML string            `bson:"-"`    // MainText, отрисованный из Markdown при выдаче
+	Date         string            `bson:"date"` // CreatedAt в RFC3339 — для старых документов и фронтенда
+	CreatedAt    time.Time         `bson:"createdAt"`
+	UpdatedAt    *time.Time        `bson:"updatedAt,omitempty"` // время последней правки
+	Edited       bool              `bson:"edited"`
+	Revision     int               `bson:"revision"` // номер текущей версии, см. models.Revision
+	DeletedAt    *time.Time        `bson:"deletedAt,omitempty"`
+	DeletedBy    int64             `bson:"deletedBy,omitempty"`
+	CommentIDs   []bson.ObjectID   `bson:"commentIds,omitempty"`
+	Tags         []string          `bson:"tags,omitempty"` // тикеры монет и пары, например BTC и BTCUSDT
+	Analysis     *AnalysisSnapshot `bson:"analysis,omitempty"`
+	Attachments  []bson.ObjectID   `bson:"attachments,omitempty"` // ID картинок, см. Attachment
+	// Shadowed — автор в теневом бане: пост виден только ему
+	Shadowed bool `bson:"shadowed,omitempty" json:"-"`
+	// Денормализованные счётчики для сортировки: без них не построить индекс
+	CommentCount int            `bson:"commentCount"`
+	VoteScore    int            `bson:"voteScore"`
+	HotScore     float64        `bson:"hotScore"` // см. HotScore
+	Reactions    map[string]int `bson:"reactions,omitempty"`
 }
 
 // Comment структура для комментариев
 type Comment struct {
-	ID       bson.ObjectID `bson:"_id,omitempty"`
-	Person   string        `bson:"person"`
-	MainText string        `bson:"mainText"`
-	Date     string        `bson:"date"`
-	PostID   bson.ObjectID `bson:"postId,omitempty"`
+	ID           bson.ObjectID  `bson:"_id,omitempty"`
+	AuthorID     int64          `bson:"authorId"`
+	Person       string         `bson:"person"`
+	MainText     string         `bson:"mainText"`
+	MainTextHTML string         `bson:"-"`
+	Date         string         `bson:"date"`
+	CreatedAt    time.Time      `bson:"createdAt"`
+	UpdatedAt    *time.Time     `bson:"updatedAt,omitempty"`
+	Edited       bool           `bson:"edited"`
+	Revision     int            `bson:"revision"`
+	DeletedAt    *time.Time     `bson:"deletedAt,omitempty"`
+	DeletedBy    int64          `bson:"deletedBy,omitempty"`
+	PostID       bson.ObjectID  `bson:"postId,omitempty"`
+	ParentID     bson.ObjectID  `bson:"parentId,omitempty"` // нулевой у комментария верхнего уровня
+	Depth        int            `bson:"depth"`
+	Mentions     []int64        `bson:"mentions,omitempty"` // ID упомянутых через @username
+	Shadowed     bool           `bson:"shadowed,omitempty" json:"-"`
+	VoteScore    int            `bson:"voteScore"`
+	Reactions    map[string]int `bson:"reactions,omitempty"`
+}
+
+// CommentNode — комментарий с ответами для вывода обсуждения деревом
+type CommentNode struct {
+	Comment
+	Replies []*CommentNode
+}
+
+// CommentNoticeReason — почему пользователь получает уведомление о комментарии
+type CommentNoticeReason string
+
+const (
+	NoticePostComment CommentNoticeReason = "post_comment" // комментарий к посту пользователя
+	NoticeReply       CommentNoticeReason = "reply"        // ответ на комментарий пользователя
+	NoticeMention     CommentNoticeReason = "mention"      // пользователя упомянули
+)
+
+// PostDraft — то, что автор присылает при создании поста
+type PostDraft struct {
+	Heading  string
+	MainText string
+	Tags     []string
+	// Пара и таймфрейм для снимка анализа; цены и индикаторы сервер берёт сам
+	AnalysisPair      string
+	AnalysisTimeframe string
+	Attachments       []bson.ObjectID // загруженные заранее через /api/attachments/upload
+}
+
+// AnalysisSnapshot — состояние пары на момент публикации поста
+type AnalysisSnapshot struct {
+	Pair       string              `bson:"pair"`
+	Timeframe  string              `bson:"timeframe"`
+	Price      float64             `bson:"price"`
+	Indicators TechnicalIndicators `bson:"indicators"`
+	TakenAt    time.Time           `bson:"takenAt"`
 }
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// ReportReason — категория жалобы
+type ReportReason string
+
+const (
+	ReportSpam  ReportReason = "spam"
+	ReportAbuse ReportReason = "abuse" // оскорбления и травля
+	ReportScam  ReportReason = "scam"  // мошенничество, «памп» монет
+	ReportOther ReportReason = "other"
+)
+
+func (r ReportReason) Valid() bool {
+	switch r {
+	case ReportSpam, ReportAbuse, ReportScam, ReportOther:
+		return true
+	}
+	return false
+}
+
+type ReportStatus string
+
+const (
+	ReportOpen      ReportStatus = "open"
+	ReportResolved  ReportStatus = "resolved"  // объект скрыт модератором
+	ReportDismissed ReportStatus = "dismissed" // нарушения нет
+)
+
+// Report — жалоба пользователя на пост или комментарий
+type Report struct {
+	ID         int64            `json:"id"`
+	ReporterID int64            `json:"reporterId"`
+	Target     ModerationTarget `json:"target"`
+	TargetID   string           `json:"targetId"`
+	Reason     ReportReason     `json:"reason"`
+	Details    string           `json:"details,omitempty"`
+	Status     ReportStatus     `json:"status"`
+	CreatedAt  time.Time        `json:"createdAt"`
+}
+
+// ReportQueueItem — открытые жалобы на один объект, собранные вместе для модератора
+type ReportQueueItem struct {
+	Target          ModerationTarget `json:"target"`
+	TargetID        string           `json:"targetId"`
+	Count           int              `json:"count"`
+	Reasons         []ReportReason   `json:"reasons"`
+	Details         []string         `json:"details"`
+	FirstReportedAt time.Time        `json:"firstReportedAt"`
+	LastReportedAt  time.Time        `json:"lastReportedAt"`
+}
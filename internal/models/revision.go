@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Revision — версия поста или комментария. Прежние версии хранятся в коллекции
+// revisions, текущая — в самом документе.
+type Revision struct {
+	ID        bson.ObjectID `bson:"_id,omitempty" json:"-"`
+	Target    VoteTarget    `bson:"target" json:"target"`
+	TargetID  bson.ObjectID `bson:"targetId" json:"targetId"`
+	Version   int           `bson:"version" json:"version"`
+	Heading   string        `bson:"heading,omitempty" json:"heading,omitempty"`
+	MainText  string        `bson:"mainText" json:"mainText"`
+	CreatedAt time.Time     `bson:"createdAt" json:"createdAt"` // когда версия стала текущей
+}
+
+// DiffOp — кусок текста, одинаковый в обеих версиях, добавленный или удалённый
+type DiffOp struct {
+	Op   string `json:"op"` // equal | insert | delete
+	Text string `json:"text"`
+}
+
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// RevisionDiff — версия и её отличия от предыдущей (у первой версии отличий нет)
+type RevisionDiff struct {
+	Revision
+	HeadingDiff []DiffOp `json:"headingDiff,omitempty"`
+	TextDiff    []DiffOp `json:"textDiff,omitempty"`
+}
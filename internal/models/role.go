@@ -0,0 +1,27 @@
+package models
+
+// Role — уровень доступа пользователя. Роли упорядочены: admin может всё, что moderator, и т.д.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+var roleRank = map[Role]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+func ParseRole(s string) (Role, bool) {
+	role := Role(s)
+	_, ok := roleRank[role]
+	return role, ok
+}
+
+// AtLeast сообщает, покрывает ли роль требуемый уровень
+func (r Role) AtLeast(required Role) bool {
+	return roleRank[r] >= roleRank[required] && roleRank[r] > 0
+}
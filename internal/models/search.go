@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// SearchKind — что искать: посты, комментарии или всё сразу (пустое значение)
+type SearchKind string
+
+const (
+	SearchPosts    SearchKind = "post"
+	SearchComments SearchKind = "comment"
+)
+
+// SearchQuery — запрос полнотекстового поиска
+type SearchQuery struct {
+	Text     string
+	Kind     SearchKind
+	AuthorID int64
+	From     *time.Time // включительно
+	To       *time.Time // не включительно
+	Limit    int
+	ViewerID int64 // как в PostQuery: автор видит своё скрытое теневым баном
+}
+
+// SearchHit — найденный пост или комментарий. Text и Heading движок отдаёт как есть,
+// HeadingHTML и Snippet с подсветкой совпадений заполняет сервис.
+type SearchHit struct {
+	Kind        SearchKind    `json:"kind"`
+	ID          bson.ObjectID `json:"id"`
+	PostID      bson.ObjectID `json:"postId"`
+	Heading     string        `json:"heading"` // у комментария — заголовок поста
+	HeadingHTML string        `json:"headingHtml"`
+	Text        string        `json:"-"`
+	Snippet     string        `json:"snippet"`
+	AuthorID    int64         `json:"authorId"`
+	Person      string        `json:"person"`
+	CreatedAt   time.Time     `json:"createdAt"`
+	Score       float64       `json:"score"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Session — серверная запись сессии; в куке лежит только подписанный ID
+type Session struct {
+	ID        string    `json:"id"`
+	PublicID  string    `json:"publicId"`
+	UserID    int64     `json:"userId"`
+	Data      []byte    `json:"data"`
+	CreatedAt time.Time `json:"createdAt"`
+	LastSeen  time.Time `json:"lastSeen"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"userAgent"`
+}
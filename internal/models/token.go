@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+type TokenPurpose string
+
+const (
+	TokenEmailVerification TokenPurpose = "email_verification"
+	TokenPasswordReset     TokenPurpose = "password_reset"
+)
+
+// UserToken — одноразовый токен из письма; в базе хранится только хеш
+type UserToken struct {
+	ID        int64
+	UserID    int64
+	Purpose   TokenPurpose
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -1,8 +1,64 @@
 package models
 
+import "time"
+
 type User struct {
-	Email         string   `json:"email"`
-	Password      string   `json:"password"`
-	Username      string   `json:"name"`
-	FavoriteCoins []string `json:"favoriteСoins"`
+	ID              int64      `json:"id"`
+	Email           string     `json:"email"`
+	Password        string     `json:"password"`
+	Username        string     `json:"name"`
+	DisplayName     string     `json:"displayName"`
+	FavoriteCoins   []string   `json:"favoriteСoins"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt,omitempty"`
+	Role            Role       `json:"role"`
+	TOTPEnabledAt   *time.Time `json:"totpEnabledAt,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	// BannedUntil — до какого момента пользователю нельзя писать
+	BannedUntil *time.Time `json:"bannedUntil,omitempty"`
+	// ShadowBanned — пользователь пишет как обычно, но его посты и комментарии видит только он сам
+	ShadowBanned bool `json:"-"`
+	// С какого адреса и браузера прошла регистрация; пишется только при создании
+	SignupIP        string `json:"-"`
+	SignupUserAgent string `json:"-"`
+}
+
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// BannedAt сообщает, действует ли временный бан в момент now
+func (u *User) BannedAt(now time.Time) bool {
+	return u.BannedUntil != nil && now.Before(*u.BannedUntil)
+}
+
+func (u *User) TwoFactorEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
+
+// Profile — то, что пользователь видит о себе через API (без хеша пароля)
+type Profile struct {
+	ID               int64      `json:"id"`
+	Username         string     `json:"username"`
+	DisplayName      string     `json:"displayName"`
+	Email            string     `json:"email"`
+	EmailVerified    bool       `json:"emailVerified"`
+	Role             Role       `json:"role"`
+	TwoFactorEnabled bool       `json:"twoFactorEnabled"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	Reputation       int        `json:"reputation"` // голоса за посты и комментарии, хранится в Mongo
+	BannedUntil      *time.Time `json:"bannedUntil,omitempty"`
+}
+
+func (u *User) Profile() Profile {
+	return Profile{
+		ID:               u.ID,
+		Username:         u.Username,
+		DisplayName:      u.DisplayName,
+		Email:            u.Email,
+		EmailVerified:    u.EmailVerified(),
+		Role:             u.Role,
+		TwoFactorEnabled: u.TwoFactorEnabled(),
+		CreatedAt:        u.CreatedAt,
+		BannedUntil:      u.BannedUntil,
+	}
 }
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// VoteTarget — за что голосуют и на что реагируют
+type VoteTarget string
+
+const (
+	VoteTargetPost    VoteTarget = "post"
+	VoteTargetComment VoteTarget = "comment"
+)
+
+// Vote — голос пользователя: +1 или -1, не больше одного на объект
+type Vote struct {
+	ID        bson.ObjectID `bson:"_id,omitempty"`
+	Target    VoteTarget    `bson:"target"`
+	TargetID  bson.ObjectID `bson:"targetId"`
+	UserID    int64         `bson:"userId"`
+	Value     int           `bson:"value"`
+	CreatedAt time.Time     `bson:"createdAt"`
+}
+
+// Reaction — эмодзи-реакция пользователя; одна реакция каждого вида на объект
+type Reaction struct {
+	ID        bson.ObjectID `bson:"_id,omitempty"`
+	Target    VoteTarget    `bson:"target"`
+	TargetID  bson.ObjectID `bson:"targetId"`
+	UserID    int64         `bson:"userId"`
+	Emoji     string        `bson:"emoji"`
+	CreatedAt time.Time     `bson:"createdAt"`
+}
+
+// Reactions — допустимые реакции: имя (ключ в счётчиках) → эмодзи
+var Reactions = map[string]string{
+	"like":   "👍",
+	"heart":  "❤️",
+	"laugh":  "😂",
+	"wow":    "😮",
+	"rocket": "🚀",
+	"bear":   "📉",
+}
+
+// Параметры ранжирования «top»: каждые HotDecay свежести весят столько же,
+// сколько десятикратный рост счёта голосов
+var (
+	HotEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	HotDecay = 12 * time.Hour
+)
+
+// HotScore — счёт для сортировки «top»: логарифм голосов плюс свежесть.
+// Хранилище пересчитывает его той же формулой при каждом голосе.
+func HotScore(votes int, createdAt time.Time) float64 {
+	sign := 0.0
+	switch {
+	case votes > 0:
+		sign = 1
+	case votes < 0:
+		sign = -1
+	}
+	order := math.Log10(math.Max(math.Abs(float64(votes)), 1))
+	return sign*order + float64(createdAt.Sub(HotEpoch).Milliseconds())/float64(HotDecay.Milliseconds())
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// WebhookEvent — тип события, на который подписывается вебхук
+type WebhookEvent string
+
+const (
+	WebhookPostCreated    WebhookEvent = "post.created"
+	WebhookCommentCreated WebhookEvent = "comment.created"
+	WebhookAlertTriggered WebhookEvent = "alert.triggered" // только для администраторов: блокировки входа
+	WebhookPairListed     WebhookEvent = "pair.listed"
+	WebhookNewsIngested   WebhookEvent = "news.ingested"
+	// WebhookPing уходит по кнопке «отправить тестовое событие», подписываться на него не нужно
+	WebhookPing WebhookEvent = "ping"
+)
+
+var KnownWebhookEvents = []WebhookEvent{
+	WebhookPostCreated,
+	WebhookCommentCreated,
+	WebhookAlertTriggered,
+	WebhookPairListed,
+	WebhookNewsIngested,
+}
+
+func (e WebhookEvent) Known() bool {
+	return slices.Contains(KnownWebhookEvents, e)
+}
+
+// RequiredRole — роль, с которой на событие можно подписаться
+func (e WebhookEvent) RequiredRole() Role {
+	if e == WebhookAlertTriggered {
+		return RoleAdmin
+	}
+	return RoleUser
+}
+
+// WebhookEndpoint — адрес, куда пользователь получает события. Secret хранится
+// зашифрованным и показывается только при создании и смене.
+type WebhookEndpoint struct {
+	ID          int64          `json:"id"`
+	UserID      int64          `json:"-"`
+	URL         string         `json:"url"`
+	Description string         `json:"description"`
+	Events      []WebhookEvent `json:"events"`
+	Enabled     bool           `json:"enabled"`
+	Secret      string         `json:"-"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   *time.Time     `json:"updatedAt,omitempty"`
+}
+
+// WebhookPayload — тело запроса: одно и то же для всех подписчиков события
+type WebhookPayload struct {
+	ID        string       `json:"id"`
+	Event     WebhookEvent `json:"event"`
+	CreatedAt time.Time    `json:"createdAt"`
+	Data      any          `json:"data"`
+}
+
+// WebhookDelivery — доставка события на один адрес и её запись в журнале
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	EndpointID     int64           `json:"endpointId"`
+	Event          WebhookEvent    `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         OutboxStatus    `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt,omitempty"`
+	ResponseStatus int             `json:"responseStatus,omitempty"` // код ответа последней попытки
+	LastError      string          `json:"lastError,omitempty"`
+	DurationMs     int64           `json:"durationMs"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty"`
+	// Endpoint заполняется при выборке из очереди: адрес и секрет для подписи
+	Endpoint *WebhookEndpoint `json:"-"`
+}
+
+// WebhookAttempt — результат одной попытки доставки
+type WebhookAttempt struct {
+	ResponseStatus int
+	Error          string
+	Duration       time.Duration
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/storage"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	apiTokenPrefix     = "ca_"
+	maxAPITokenTTL     = 365 * 24 * time.Hour
+	maxAPITokensByUser = 20
+)
+
+var (
+	ErrAPITokenName     = errors.New("token name must be 1-64 characters")
+	ErrAPITokenScopes   = errors.New("at least one known scope is required")
+	ErrAPITokenTTL      = errors.New("token lifetime must be between 1 and 365 days")
+	ErrTooManyAPITokens = errors.New("too many active tokens")
+	ErrAPITokenInvalid  = errors.New("api token is invalid, expired or revoked")
+)
+
+type APITokenService struct {
+	storage storage.APITokenStorage
+	now     func() time.Time
+}
+
+func NewAPITokenService(tokenStorage storage.APITokenStorage) *APITokenService {
+	return &APITokenService{storage: tokenStorage, now: time.Now}
+}
+
+// CreateToken выпускает новый токен. Открытое значение возвращается только здесь —
+// дальше его нельзя восстановить. ttl == 0 означает бессрочный токен.
+func (s *APITokenService) CreateToken(userID int64, name string, scopes []models.Scope, ttl time.Duration) (string, *models.APIToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > 64 {
+		return "", nil, ErrAPITokenName
+	}
+	if len(scopes) == 0 {
+		return "", nil, ErrAPITokenScopes
+	}
+	for _, scope := range scopes {
+		if !scope.Known() {
+			return "", nil, fmt.Errorf("%w: unknown scope %q", ErrAPITokenScopes, scope)
+		}
+	}
+	if ttl < 0 || ttl > maxAPITokenTTL {
+		return "", nil, ErrAPITokenTTL
+	}
+
+	existing, err := s.storage.ListAPITokens(userID)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(existing) >= maxAPITokensByUser {
+		return "", nil, ErrTooManyAPITokens
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", nil, fmt.Errorf("generate token: %w", err)
+	}
+	plain := apiTokenPrefix + base64.RawURLEncoding.EncodeToString(random)
+
+	token := &models.APIToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    plain[:len(apiTokenPrefix)+6],
+		TokenHash: HashToken(plain),
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(scopes))),
+	}
+	if ttl > 0 {
+		expiresAt := s.now().Add(ttl).UTC()
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := s.storage.CreateAPIToken(token); err != nil {
+		return "", nil, err
+	}
+	slog.Info("API token created", "user_id", userID, "token_id", token.ID, "scopes", token.Scopes)
+	return plain, token, nil
+}
+
+// Authenticate проверяет токен из заголовка Authorization и отмечает его использование
+func (s *APITokenService) Authenticate(plain string) (*models.APIToken, error) {
+	if !strings.HasPrefix(plain, apiTokenPrefix) {
+		return nil, ErrAPITokenInvalid
+	}
+
+	token, err := s.storage.GetAPITokenByHash(HashToken(plain))
+	if err != nil {
+		if errors.Is(err, storage.ErrAPITokenNotFound) {
+			return nil, ErrAPITokenInvalid
+		}
+		return nil, err
+	}
+	if !token.Active(s.now()) {
+		return nil, ErrAPITokenInvalid
+	}
+
+	if err := s.storage.TouchAPIToken(token.ID); err != nil {
+		slog.Warn("Failed to update api token last use", "token_id", token.ID, "error", err)
+	}
+	return token, nil
+}
+
+func (s *APITokenService) ListTokens(userID int64) ([]models.APIToken, error) {
+	return s.storage.ListAPITokens(userID)
+}
+
+func (s *APITokenService) RevokeToken(userID, tokenID int64) error {
+	return s.storage.RevokeAPIToken(userID, tokenID)
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/storage"
+	"errors"
+	"testing"
+	"time"
+)
+
+type MockAPITokenStorage struct {
+	Tokens map[int64]*models.APIToken
+	nextID int64
+}
+
+func NewMockAPITokenStorage() *MockAPITokenStorage {
+	return &MockAPITokenStorage{Tokens: map[int64]*models.APIToken{}}
+}
+
+func (m *MockAPITokenStorage) CreateAPIToken(token *models.APIToken) error {
+	m.nextID++
+	token.ID = m.nextID
+	token.CreatedAt = time.Now()
+	stored := *token
+	m.Tokens[token.ID] = &stored
+	return nil
+}
+
+func (m *MockAPITokenStorage) GetAPITokenByHash(tokenHash string) (*models.APIToken, error) {
+	for _, t := range m.Tokens {
+		if t.TokenHash == tokenHash {
+			copied := *t
+			return &copied, nil
+		}
+	}
+	return nil, storage.ErrAPITokenNotFound
+}
+
+func (m *MockAPITokenStorage) ListAPITokens(userID int64) ([]models.APIToken, error) {
+	var out []models.APIToken
+	for _, t := range m.Tokens {
+		if t.UserID == userID && t.RevokedAt == nil {
+			out = append(out, *t)
+		}
+	}
+	return out, nil
+}
+
+func (m *MockAPITokenStorage) RevokeAPIToken(userID, tokenID int64) error {
+	t, ok := m.Tokens[tokenID]
+	if !ok || t.UserID != userID || t.RevokedAt != nil {
+		return storage.ErrAPITokenNotFound
+	}
+	now := time.Now()
+	t.RevokedAt = &now
+	return nil
+}
+
+func (m *MockAPITokenStorage) TouchAPIToken(tokenID int64) error {
+	now := time.Now()
+	m.Tokens[tokenID].LastUsedAt = &now
+	return nil
+}
+
+func TestAPITokenService_CreateValidation(t *testing.T) {
+	s := NewAPITokenService(NewMockAPITokenStorage())
+
+	tests := []struct {
+		name    string
+		token   string
+		scopes  []models.Scope
+		ttl     time.Duration
+		wantErr error
+	}{
+		{name: "ok", token: "ci", scopes: []models.Scope{models.ScopeReadMarket}, ttl: 24 * time.Hour},
+		{name: "no expiry", token: "ci", scopes: []models.Scope{models.ScopeReadMarket}},
+		{name: "empty name", token: "  ", scopes: []models.Scope{models.ScopeReadMarket}, wantErr: ErrAPITokenName},
+		{name: "no scopes", token: "ci", wantErr: ErrAPITokenScopes},
+		{name: "unknown scope", token: "ci", scopes: []models.Scope{"admin:all"}, wantErr: ErrAPITokenScopes},
+		{name: "too long", token: "ci", scopes: []models.Scope{models.ScopeReadMarket}, ttl: 400 * 24 * time.Hour, wantErr: ErrAPITokenTTL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := s.CreateToken(1, tt.token, tt.scopes, tt.ttl)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestAPITokenService_Authenticate(t *testing.T) {
+	backend := NewMockAPITokenStorage()
+	s := NewAPITokenService(backend)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	plain, created, err := s.CreateToken(7, "script", []models.Scope{models.ScopeWritePosts, models.ScopeReadPosts, models.ScopeReadPosts}, time.Hour)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if len(created.Scopes) != 2 {
+		t.Errorf("scopes should be deduplicated: %v", created.Scopes)
+	}
+	if backend.Tokens[created.ID].TokenHash == plain {
+		t.Fatal("token must be stored hashed")
+	}
+
+	token, err := s.Authenticate(plain)
+	if err != nil || token.UserID != 7 || !token.HasScope(models.ScopeWritePosts) || token.HasScope(models.ScopeReadMarket) {
+		t.Fatalf("unexpected token %+v (%v)", token, err)
+	}
+	if backend.Tokens[created.ID].LastUsedAt == nil {
+		t.Error("last used time not recorded")
+	}
+
+	if _, err := s.Authenticate(plain + "x"); !errors.Is(err, ErrAPITokenInvalid) {
+		t.Errorf("expected ErrAPITokenInvalid for unknown token, got %v", err)
+	}
+
+	s.now = func() time.Time { return now.Add(2 * time.Hour) }
+	if _, err := s.Authenticate(plain); !errors.Is(err, ErrAPITokenInvalid) {
+		t.Errorf("expected ErrAPITokenInvalid for expired token, got %v", err)
+	}
+
+	s.now = func() time.Time { return now }
+	if err := s.RevokeToken(8, created.ID); !errors.Is(err, storage.ErrAPITokenNotFound) {
+		t.Errorf("must not revoke another user's token, got %v", err)
+	}
+	if err := s.RevokeToken(7, created.ID); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if _, err := s.Authenticate(plain); !errors.Is(err, ErrAPITokenInvalid) {
+		t.Errorf("expected ErrAPITokenInvalid for revoked token, got %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/storage"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	maxPostAttachments = 4
+	// orphanMaxAge — сколько ждать, пока загруженная картинка попадёт в пост
+	orphanMaxAge        = 24 * time.Hour
+	orphanCheckInterval = time.Hour
+	orphanBatch         = 100
+)
+
+// AttachmentService принимает картинки для постов: проверяет, чистит от метаданных,
+// делает превью и складывает файлы в BlobStore
+type AttachmentService struct {
+	store storage.AttachmentStorage
+	blobs storage.BlobStore
+	now   func() time.Time
+}
+
+func NewAttachmentService(store storage.AttachmentStorage, blobs storage.BlobStore) *AttachmentService {
+	return &AttachmentService{
+		store: store,
+		blobs: blobs,
+		now:   time.Now,
+	}
+}
+
+// Upload сохраняет картинку от имени owner. Привязка к посту — при его создании.
+func (s *AttachmentService) Upload(ctx context.Context, owner *models.User, r io.Reader) (*models.Attachment, error) {
+	if err := checkWriter(owner); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, maxUploadBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	img, err := processImage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	id := bson.NewObjectID()
+	a := models.Attachment{
+		ID:               id,
+		OwnerID:          owner.ID,
+		ContentType:      img.ContentType,
+		Size:             int64(len(img.Data)),
+		Width:            img.Width,
+		Height:           img.Height,
+		BlobKey:          "attachments/" + id.Hex() + "/original" + imageExt(img.ContentType),
+		ThumbKey:         "attachments/" + id.Hex() + "/thumb" + imageExt(img.ThumbContentType),
+		ThumbContentType: img.ThumbContentType,
+		ThumbWidth:       img.ThumbWidth,
+		ThumbHeight:      img.ThumbHeight,
+		CreatedAt:        s.now().UTC(),
+	}
+
+	if err := s.blobs.Put(ctx, a.BlobKey, img.Data, a.ContentType); err != nil {
+		return nil, fmt.Errorf("failed to store image: %w", err)
+	}
+	if err := s.blobs.Put(ctx, a.ThumbKey, img.Thumb, a.ThumbContentType); err != nil {
+		s.deleteBlobs(ctx, &a)
+		return nil, fmt.Errorf("failed to store thumbnail: %w", err)
+	}
+	if err := s.store.CreateAttachment(ctx, a); err != nil {
+		s.deleteBlobs(ctx, &a)
+		return nil, fmt.Errorf("failed to save attachment: %w", err)
+	}
+
+	slog.Info("Attachment uploaded",
+		"attachment_id", id.Hex(),
+		"user_id", owner.ID,
+		"type", a.ContentType,
+		"size", a.Size)
+	return &a, nil
+}
+
+// Open отдаёт описание вложения и содержимое оригинала или превью.
+// Читатель закрывает вызывающий.
+func (s *AttachmentService) Open(ctx context.Context, id bson.ObjectID, thumb bool) (*models.Attachment, io.ReadCloser, error) {
+	a, err := s.store.GetAttachment(ctx, id)
+	if err != nil {
+		return nil, nil, notFoundAs(err, ErrAttachmentNotFound)
+	}
+
+	key := a.BlobKey
+	if thumb {
+		key = a.ThumbKey
+	}
+	body, err := s.blobs.Get(ctx, key)
+	if errors.Is(err, storage.ErrBlobNotFound) {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, body, nil
+}
+
+// PurgeOrphans удаляет вложения, которые так и не попали в пост
+func (s *AttachmentService) PurgeOrphans(ctx context.Context) (int, error) {
+	orphans, err := s.store.ListOrphanAttachments(ctx, s.now().Add(-orphanMaxAge), orphanBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for i := range orphans {
+		if err := s.deleteBlobs(ctx, &orphans[i]); err != nil {
+			slog.Error("Failed to delete attachment files", "attachment_id", orphans[i].ID.Hex(), "error", err)
+			continue
+		}
+		if err := s.store.DeleteAttachment(ctx, orphans[i].ID); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// StartOrphanCleanup раз в час чистит брошенные загрузки
+func (s *AttachmentService) StartOrphanCleanup() {
+	ticker := time.NewTicker(orphanCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		n, err := s.PurgeOrphans(ctx)
+		cancel()
+		if err != nil {
+			slog.Error("Failed to purge orphan attachments", "error", err)
+		} else if n > 0 {
+			slog.Info("Purged orphan attachments", "count", n)
+		}
+	}
+}
+
+func (s *AttachmentService) deleteBlobs(ctx context.Context, a *models.Attachment) error {
+	return errors.Join(
+		s.blobs.Delete(ctx, a.BlobKey),
+		s.blobs.Delete(ctx, a.ThumbKey),
+	)
+}
+
+// claimAttachments привязывает загруженные автором картинки к новому посту.
+// Чужие, уже привязанные и несуществующие ID — ошибка, привязка тогда откатывается.
+func (s *PostsService) claimAttachments(
+	ctx context.Context,
+	ids []bson.ObjectID,
+	authorID int64,
+	postID bson.ObjectID,
+) error {
+	if len(ids) > maxPostAttachments {
+		return fmt.Errorf("%w: at most %d", ErrTooManyAttachments, maxPostAttachments)
+	}
+	if s.attachments == nil {
+		return fmt.Errorf("%w: uploads are disabled", ErrAttachmentNotFound)
+	}
+
+	claimed, err := s.attachments.ClaimAttachments(ctx, ids, authorID, postID)
+	if err == nil && claimed == len(ids) {
+		return nil
+	}
+	if releaseErr := s.attachments.ReleaseAttachments(ctx, postID); releaseErr != nil {
+		slog.Error("Failed to release attachments", "post_id", postID.Hex(), "error", releaseErr)
+	}
+	if err != nil {
+		return err
+	}
+	return ErrAttachmentNotFound
+}
+
+func imageExt(contentType string) string {
+	switch contentType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	}
+	return ""
+}
+
+var (
+	ErrAttachmentNotFound = errors.New("attachment not found")
+	ErrTooManyAttachments = errors.New("too many attachments")
+)
@@ -0,0 +1,377 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/storage"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type MockAttachmentStorage struct {
+	Items map[bson.ObjectID]*models.Attachment
+}
+
+func NewMockAttachmentStorage() *MockAttachmentStorage {
+	return &MockAttachmentStorage{Items: map[bson.ObjectID]*models.Attachment{}}
+}
+
+func (m *MockAttachmentStorage) CreateAttachment(ctx context.Context, a models.Attachment) error {
+	m.Items[a.ID] = &a
+	return nil
+}
+
+func (m *MockAttachmentStorage) GetAttachment(ctx context.Context, id bson.ObjectID) (*models.Attachment, error) {
+	if a, ok := m.Items[id]; ok {
+		return a, nil
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (m *MockAttachmentStorage) ClaimAttachments(ctx context.Context, ids []bson.ObjectID, ownerID int64, postID bson.ObjectID) (int, error) {
+	claimed := 0
+	for _, id := range ids {
+		if a, ok := m.Items[id]; ok && a.OwnerID == ownerID && a.PostID.IsZero() {
+			a.PostID = postID
+			claimed++
+		}
+	}
+	return claimed, nil
+}
+
+func (m *MockAttachmentStorage) ReleaseAttachments(ctx context.Context, postID bson.ObjectID) error {
+	for _, a := range m.Items {
+		if a.PostID == postID {
+			a.PostID = bson.ObjectID{}
+		}
+	}
+	return nil
+}
+
+func (m *MockAttachmentStorage) ListOrphanAttachments(ctx context.Context, before time.Time, limit int) ([]models.Attachment, error) {
+	var out []models.Attachment
+	for _, a := range m.Items {
+		if a.PostID.IsZero() && a.CreatedAt.Before(before) {
+			out = append(out, *a)
+		}
+	}
+	return out, nil
+}
+
+func (m *MockAttachmentStorage) DeleteAttachment(ctx context.Context, id bson.ObjectID) error {
+	delete(m.Items, id)
+	return nil
+}
+
+type MockBlobStore struct {
+	Blobs map[string][]byte
+}
+
+func (m *MockBlobStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	m.Blobs[key] = data
+	return nil
+}
+
+func (m *MockBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	data, ok := m.Blobs[key]
+	if !ok {
+		return nil, storage.ErrBlobNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *MockBlobStore) Delete(ctx context.Context, key string) error {
+	delete(m.Blobs, key)
+	return nil
+}
+
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+	return img
+}
+
+// jpegWithExif — JPEG с блоком EXIF: Orientation и строкой, похожей на геометку
+func jpegWithExif(t *testing.T, w, h int, orientation uint16) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(w, h), nil); err != nil {
+		t.Fatal(err)
+	}
+	tiff := []byte{'M', 'M', 0, 0x2a, 0, 0, 0, 8, 0, 1, 0x01, 0x12, 0, 3, 0, 0, 0, 1, byte(orientation >> 8), byte(orientation), 0, 0, 0, 0, 0, 0}
+	tiff = append(tiff, []byte("GPS 55.7558N 37.6173E")...)
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	size := len(payload) + 2
+	segment := append([]byte{0xFF, 0xE1, byte(size >> 8), byte(size)}, payload...)
+
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...)
+}
+
+func TestAttachmentService_Upload(t *testing.T) {
+	verifiedAt := time.Now()
+	owner := &models.User{ID: 1, EmailVerifiedAt: &verifiedAt}
+	store := NewMockAttachmentStorage()
+	blobs := &MockBlobStore{Blobs: map[string][]byte{}}
+	s := NewAttachmentService(store, blobs)
+	ctx := context.Background()
+
+	var pngData bytes.Buffer
+	png.Encode(&pngData, testImage(800, 400))
+	a, err := s.Upload(ctx, owner, &pngData)
+	if err != nil {
+		t.Fatalf("upload png: %v", err)
+	}
+	if a.ContentType != "image/png" || a.Width != 800 || a.Height != 400 {
+		t.Errorf("unexpected png attachment: %+v", a)
+	}
+	if a.ThumbWidth != thumbMaxSide || a.ThumbHeight != thumbMaxSide/2 {
+		t.Errorf("expected %dx%d thumbnail, got %dx%d", thumbMaxSide, thumbMaxSide/2, a.ThumbWidth, a.ThumbHeight)
+	}
+	_, body, err := s.Open(ctx, a.ID, true)
+	if err != nil {
+		t.Fatalf("open thumbnail: %v", err)
+	}
+	thumb, err := png.Decode(body)
+	if err != nil || thumb.Bounds().Dx() != a.ThumbWidth {
+		t.Errorf("stored thumbnail is not a %dpx png: %v", a.ThumbWidth, err)
+	}
+
+	// Ориентация 6 — камера повёрнута: картинку надо повернуть на 90° по часовой
+	a, err = s.Upload(ctx, owner, bytes.NewReader(jpegWithExif(t, 60, 20, 6)))
+	if err != nil {
+		t.Fatalf("upload jpeg: %v", err)
+	}
+	if a.ContentType != "image/jpeg" || a.Width != 20 || a.Height != 60 {
+		t.Errorf("expected rotated 20x60 jpeg, got %s %dx%d", a.ContentType, a.Width, a.Height)
+	}
+	stored := blobs.Blobs[a.BlobKey]
+	if bytes.Contains(stored, []byte("Exif")) || bytes.Contains(stored, []byte("GPS")) {
+		t.Error("EXIF metadata was not stripped")
+	}
+
+	tests := []struct {
+		name string
+		user *models.User
+		data []byte
+		want error
+	}{
+		{"unverified user", &models.User{ID: 2}, pngData.Bytes(), ErrEmailNotVerified},
+		{"html disguised as image", owner, []byte("<html><script>alert(1)</script></html>"), ErrUnsupportedImage},
+		{"truncated png", owner, []byte("\x89PNG\r\n\x1a\n"), ErrUnsupportedImage},
+		{"too many bytes", owner, bytes.Repeat([]byte{0}, maxUploadBytes+1), ErrAttachmentTooLarge},
+	}
+	var wide bytes.Buffer
+	png.Encode(&wide, image.NewGray(image.Rect(0, 0, maxImageSide+1, 1)))
+	tests = append(tests, struct {
+		name string
+		user *models.User
+		data []byte
+		want error
+	}{"too wide", owner, wide.Bytes(), ErrImageTooLarge})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Upload(ctx, tt.user, bytes.NewReader(tt.data)); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+	if len(store.Items) != 2 {
+		t.Errorf("rejected uploads must not be stored, got %d attachments", len(store.Items))
+	}
+}
+
+func TestPostsService_Attachments(t *testing.T) {
+	verifiedAt := time.Now()
+	author := &models.User{ID: 1, DisplayName: "op", EmailVerifiedAt: &verifiedAt}
+	other := &models.User{ID: 2, DisplayName: "other", EmailVerifiedAt: &verifiedAt}
+	attachments := NewMockAttachmentStorage()
+	uploads := NewAttachmentService(attachments, &MockBlobStore{Blobs: map[string][]byte{}})
+	posts := &MockPostStorage{}
+	s := NewPostService(posts, &MockModerationLogStorage{}, nil, nil, nil, nil, nil, attachments, nil, nil, nil)
+	ctx := context.Background()
+
+	upload := func(user *models.User) bson.ObjectID {
+		var buf bytes.Buffer
+		png.Encode(&buf, testImage(10, 10))
+		a, err := uploads.Upload(ctx, user, &buf)
+		if err != nil {
+			t.Fatalf("upload: %v", err)
+		}
+		return a.ID
+	}
+	mine, theirs := upload(author), upload(other)
+
+	draft := models.PostDraft{Heading: "Chart", MainText: "See the chart", Attachments: []bson.ObjectID{mine, theirs}}
+	if _, err := s.CreatePost(ctx, author, draft); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Errorf("expected ErrAttachmentNotFound for someone else's attachment, got %v", err)
+	}
+	if !attachments.Items[mine].PostID.IsZero() || len(posts.Posts) != 0 {
+		t.Error("failed post must release its attachments and not be stored")
+	}
+
+	draft.Attachments = []bson.ObjectID{mine, mine}
+	postID, err := s.CreatePost(ctx, author, draft)
+	if err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+	if got := posts.Posts[0].Attachments; len(got) != 1 || got[0] != mine {
+		t.Errorf("expected post to reference %v, got %v", mine, got)
+	}
+	if attachments.Items[mine].PostID != postID {
+		t.Error("attachment was not bound to the post")
+	}
+
+	// Привязанная картинка не переходит в другой пост
+	draft.Attachments = []bson.ObjectID{mine}
+	if _, err := s.CreatePost(ctx, author, draft); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Errorf("expected ErrAttachmentNotFound for a used attachment, got %v", err)
+	}
+
+	draft.Attachments = make([]bson.ObjectID, maxPostAttachments+1)
+	for i := range draft.Attachments {
+		draft.Attachments[i] = bson.NewObjectID()
+	}
+	if _, err := s.CreatePost(ctx, author, draft); !errors.Is(err, ErrTooManyAttachments) {
+		t.Errorf("expected ErrTooManyAttachments, got %v", err)
+	}
+
+	// Через сутки непривязанная загрузка удаляется вместе с файлами
+	uploads.now = func() time.Time { return time.Now().Add(orphanMaxAge + time.Hour) }
+	if n, err := uploads.PurgeOrphans(ctx); err != nil || n != 1 {
+		t.Fatalf("expected 1 orphan purged, got %d, %v", n, err)
+	}
+	if _, ok := attachments.Items[theirs]; ok {
+		t.Error("orphan attachment was not deleted")
+	}
+	if _, _, err := uploads.Open(ctx, mine, false); err != nil {
+		t.Errorf("attached image must survive cleanup: %v", err)
+	}
+	if _, _, err := uploads.Open(ctx, theirs, false); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Errorf("expected ErrAttachmentNotFound, got %v", err)
+	}
+	if strings.Contains(attachments.Items[mine].BlobKey, "..") {
+		t.Error("blob keys must be plain paths")
+	}
+}
+
+func TestGIFFrames(t *testing.T) {
+	palette := color.Palette{color.Black, color.White}
+	frame := func(r image.Rectangle) *image.Paletted { return image.NewPaletted(r, palette) }
+
+	var anim bytes.Buffer
+	g := &gif.GIF{Delay: []int{10, 10, 10}}
+	for i := 0; i < 3; i++ {
+		g.Image = append(g.Image, frame(image.Rect(0, 0, 40, 30)))
+	}
+	g.Image[1].Palette = color.Palette{color.White, color.Black, color.Gray{Y: 128}} // локальная палитра
+	if err := gif.EncodeAll(&anim, g); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := gifFrameCount(anim.Bytes()); err != nil || n != 3 {
+		t.Fatalf("expected 3 frames, got %d %v", n, err)
+	}
+	if _, err := gifFrameCount(anim.Bytes()[:anim.Len()/2]); err == nil {
+		t.Error("truncated gif must be rejected")
+	}
+	if _, err := processImage(anim.Bytes()); err != nil {
+		t.Fatalf("process animated gif: %v", err)
+	}
+
+	// Большой холст и крошечные кадры: файл маленький, а DecodeAll отвёл бы память на каждый кадр
+	bomb := &gif.GIF{
+		Image:  []*image.Paletted{frame(image.Rect(0, 0, 1, 1)), frame(image.Rect(0, 0, 1, 1))},
+		Delay:  []int{0, 0},
+		Config: image.Config{ColorModel: palette, Width: 6000, Height: 6000},
+	}
+	var bombData bytes.Buffer
+	if err := gif.EncodeAll(&bombData, bomb); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := processImage(bombData.Bytes()); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("expected ErrImageTooLarge for a multi-frame gif over the pixel budget, got %v", err)
+	}
+}
+
+func TestOrient(t *testing.T) {
+	const w, h = 6, 4
+	rgba := testImage(w, h)
+	ycbcr := func(ratio image.YCbCrSubsampleRatio) image.Image {
+		img := image.NewYCbCr(image.Rect(0, 0, w, h), ratio)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				img.Y[img.YOffset(x, y)] = uint8(x*40 + y)
+				img.Cb[img.COffset(x, y)] = uint8(x * 30)
+				img.Cr[img.COffset(x, y)] = uint8(y * 50)
+			}
+		}
+		return img
+	}
+	gray := image.NewGray(image.Rect(0, 0, w, h))
+	for i := range gray.Pix {
+		gray.Pix[i] = uint8(i * 9)
+	}
+	sources := map[string]image.Image{
+		"rgba":     rgba,
+		"ycbcr444": ycbcr(image.YCbCrSubsampleRatio444),
+		"ycbcr420": ycbcr(image.YCbCrSubsampleRatio420),
+		"ycbcr422": ycbcr(image.YCbCrSubsampleRatio422),
+		"gray":     gray,
+		"nrgba":    image.NewNRGBA(image.Rect(2, 3, 2+w, 3+h)),
+	}
+
+	// Куда попадает пиксель (x, y) исходника при каждом значении EXIF Orientation
+	target := func(o, x, y int) (int, int) {
+		switch o {
+		case 2:
+			return w - 1 - x, y
+		case 3:
+			return w - 1 - x, h - 1 - y
+		case 4:
+			return x, h - 1 - y
+		case 5:
+			return y, x
+		case 6:
+			return h - 1 - y, x
+		case 7:
+			return h - 1 - y, w - 1 - x
+		case 8:
+			return y, w - 1 - x
+		}
+		return x, y
+	}
+
+	for name, src := range sources {
+		for o := 1; o <= 8; o++ {
+			dst := orient(src, o)
+			b := src.Bounds()
+			for y := 0; y < h; y++ {
+				for x := 0; x < w; x++ {
+					dx, dy := target(o, x, y)
+					want := color.RGBAModel.Convert(src.At(b.Min.X+x, b.Min.Y+y))
+					got := color.RGBAModel.Convert(dst.At(dst.Bounds().Min.X+dx, dst.Bounds().Min.Y+dy))
+					if got != want {
+						t.Fatalf("%s orientation %d: pixel (%d,%d) -> (%d,%d) = %v, want %v", name, o, x, y, dx, dy, got, want)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// BreachChecker сообщает, встречался ли пароль в известных утечках
+type BreachChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+func passwordSHA1(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// RangeBreachChecker проверяет пароль через range API по модели k-anonymity
+// (как у Have I Been Pwned): наружу уходят только первые 5 символов SHA-1,
+// сравнение остальной части хеша происходит у нас
+type RangeBreachChecker struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewRangeBreachChecker(baseURL string) *RangeBreachChecker {
+	return &RangeBreachChecker{
+		baseURL: strings.TrimRight(baseURL, "/") + "/",
+		client:  &http.Client{Timeout: 3 * time.Second},
+	}
+}
+
+func (c *RangeBreachChecker) IsBreached(password string) (bool, error) {
+	hash := passwordSHA1(password)
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+prefix, nil)
+	if err != nil {
+		return false, err
+	}
+	// Ответы дополняются фиктивными суффиксами, чтобы по размеру нельзя было угадать префикс
+	req.Header.Set("Add-Padding", "true")
+	req.Header.Set("User-Agent", "crypto-analytics")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("breach range request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("breach range request: unexpected status %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		candidate, count, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if strings.EqualFold(candidate, suffix) {
+			// Фиктивные строки из Add-Padding приходят с нулевым счётчиком
+			return strings.TrimSpace(count) != "0", nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("breach range response: %w", err)
+	}
+	return false, nil
+}
+
+// HashListBreachChecker — офлайн-список SHA-1 хешей (по одному в строке, допускается
+// формат «HASH:COUNT»). Используется, когда API недоступно, и в тестах.
+type HashListBreachChecker struct {
+	hashes map[string]struct{}
+}
+
+func NewHashListBreachChecker(r io.Reader) (*HashListBreachChecker, error) {
+	hashes := make(map[string]struct{})
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hash, _, _ := strings.Cut(line, ":")
+		if len(hash) != sha1.Size*2 {
+			continue
+		}
+		hashes[strings.ToUpper(hash)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read breached hash list: %w", err)
+	}
+	return &HashListBreachChecker{hashes: hashes}, nil
+}
+
+func LoadHashListBreachChecker(path string) (*HashListBreachChecker, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open breached hash list: %w", err)
+	}
+	defer file.Close()
+	return NewHashListBreachChecker(file)
+}
+
+func (c *HashListBreachChecker) IsBreached(password string) (bool, error) {
+	_, found := c.hashes[passwordSHA1(password)]
+	return found, nil
+}
+
+// FallbackBreachChecker спрашивает primary, а при его ошибке — fallback.
+// Любой из них может быть nil.
+type FallbackBreachChecker struct {
+	primary  BreachChecker
+	fallback BreachChecker
+}
+
+func NewFallbackBreachChecker(primary, fallback BreachChecker) *FallbackBreachChecker {
+	return &FallbackBreachChecker{primary: primary, fallback: fallback}
+}
+
+func (c *FallbackBreachChecker) IsBreached(password string) (bool, error) {
+	if c.primary != nil {
+		breached, err := c.primary.IsBreached(password)
+		if err == nil || c.fallback == nil {
+			return breached, err
+		}
+		slog.Warn("Breach API failed, using offline list", "error", err)
+	}
+	if c.fallback != nil {
+		return c.fallback.IsBreached(password)
+	}
+	return false, nil
+}
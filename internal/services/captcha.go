@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CaptchaVerifier проверяет ответ CAPTCHA-виджета, присланный вместе с формой
+type CaptchaVerifier interface {
+	Verify(ctx context.Context, response, remoteIP string) (bool, error)
+}
+
+// CaptchaResponseFields — поля формы с ответом CAPTCHA: общее captcha и те,
+// что добавляют виджеты reCAPTCHA, hCaptcha и Turnstile
+var CaptchaResponseFields = []string{"captcha", "g-recaptcha-response", "h-captcha-response", "cf-turnstile-response"}
+
+// CaptchaWidget — что нужно странице, чтобы показать виджет: провайдер и публичный ключ сайта
+type CaptchaWidget struct {
+	Provider string `json:"provider"`
+	SiteKey  string `json:"siteKey"`
+}
+
+// CaptchaProvider определяет провайдера по siteverify-адресу; пусто — адрес незнакомый
+func CaptchaProvider(verifyURL string) string {
+	u, err := url.Parse(verifyURL)
+	if err != nil {
+		return ""
+	}
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case host == "challenges.cloudflare.com":
+		return "turnstile"
+	case host == "hcaptcha.com" || strings.HasSuffix(host, ".hcaptcha.com"):
+		return "hcaptcha"
+	case strings.Contains(u.Path, "recaptcha"):
+		return "recaptcha"
+	}
+	return ""
+}
+
+// NoopCaptchaVerifier принимает любой ответ — для разработки и тестов
+type NoopCaptchaVerifier struct{}
+
+func (NoopCaptchaVerifier) Verify(ctx context.Context, response, remoteIP string) (bool, error) {
+	return true, nil
+}
+
+// SiteVerifyCaptcha проверяет ответ через siteverify API. Формат запроса и ответа
+// один у reCAPTCHA, hCaptcha и Turnstile, меняется только адрес.
+type SiteVerifyCaptcha struct {
+	verifyURL string
+	secret    string
+	client    *http.Client
+}
+
+func NewSiteVerifyCaptcha(verifyURL, secret string) *SiteVerifyCaptcha {
+	return &SiteVerifyCaptcha{
+		verifyURL: verifyURL,
+		secret:    secret,
+		client:    &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (c *SiteVerifyCaptcha) Verify(ctx context.Context, response, remoteIP string) (bool, error) {
+	if response == "" {
+		return false, nil
+	}
+	form := url.Values{
+		"secret":   {c.secret},
+		"response": {response},
+		"remoteip": {remoteIP},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("captcha verify request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("captcha verify request: unexpected status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("captcha verify response: %w", err)
+	}
+	return result.Success, nil
+}
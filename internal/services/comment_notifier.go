@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"crypto-analytics/internal/models"
+)
+
+const commentExcerptRunes = 300
+
+// MailCommentNotifier сообщает о комментариях письмом. Письмо уходит в фоне:
+// создание комментария не ждёт SMTP.
+type MailCommentNotifier struct {
+	mailer  MailSender
+	baseURL string
+}
+
+func NewMailCommentNotifier(mailer MailSender, baseURL string) *MailCommentNotifier {
+	return &MailCommentNotifier{
+		mailer:  mailer,
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
+func (n *MailCommentNotifier) NotifyComment(
+	recipient *models.User,
+	reason models.CommentNoticeReason,
+	post *models.Post,
+	comment *models.Comment,
+) {
+	// Неподтверждённый адрес может быть чужим — не пишем на него
+	if recipient == nil || recipient.Email == "" || !recipient.EmailVerified() {
+		return
+	}
+
+	msg := commentMail(recipient, reason, post, comment, n.baseURL)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailSendTimeout)
+		defer cancel()
+		if err := n.mailer.Send(ctx, msg); err != nil {
+			slog.Error("Failed to send comment notification",
+				"user_id", recipient.ID,
+				"reason", reason,
+				"error", err)
+		}
+	}()
+}
+
+func commentMail(
+	recipient *models.User,
+	reason models.CommentNoticeReason,
+	post *models.Post,
+	comment *models.Comment,
+	baseURL string,
+) models.MailMessage {
+	var what string
+	switch reason {
+	case models.NoticeMention:
+		what = fmt.Sprintf("%s mentioned you in a comment on %q", comment.Person, post.Heading)
+	case models.NoticeReply:
+		what = fmt.Sprintf("%s replied to your comment on %q", comment.Person, post.Heading)
+	default:
+		what = fmt.Sprintf("%s commented on your post %q", comment.Person, post.Heading)
+	}
+
+	text := comment.MainText
+	if utf8.RuneCountInString(text) > commentExcerptRunes {
+		text = string([]rune(text)[:commentExcerptRunes]) + "…"
+	}
+
+	return models.MailMessage{
+		To:      recipient.Email,
+		Subject: what + " — Crypto Analytics",
+		Body: fmt.Sprintf("Hi %s,\n\n%s:\n\n%s\n\nOpen the discussion: %s/static/posts.html\n",
+			recipient.DisplayName, what, text, baseURL),
+	}
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+
+	"crypto-analytics/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	// maxCommentDepth — глубже ответы встают рядом с родителем, а не под него
+	maxCommentDepth = 5
+	// maxTreeComments — сколько комментариев поста собирается в дерево за раз
+	maxTreeComments = 500
+	maxMentions     = 10
+)
+
+// mentionPattern повторяет правила validateUsername: без пробелов и @
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([^\s@,;:!?()\[\]{}<>"']{1,50})`)
+
+// threadParent находит комментарий, под который встанет ответ. На максимальной
+// глубине ответ становится соседом родителя, чтобы ветка не уходила вправо бесконечно.
+func (s *PostsService) threadParent(ctx context.Context, postID, parentID bson.ObjectID, authorID int64) (*models.Comment, error) {
+	parent, err := s.postStorage.GetComment(ctx, parentID, authorID)
+	if err != nil {
+		return nil, notFoundAs(err, ErrCommentNotFound)
+	}
+	if parent.PostID != postID {
+		return nil, ErrParentOtherPost
+	}
+	if parent.Depth < maxCommentDepth || parent.ParentID.IsZero() {
+		return parent, nil
+	}
+
+	grandparent, err := s.postStorage.GetComment(ctx, parent.ParentID, authorID)
+	if err != nil {
+		// Предок удалён — отвечаем прямо ему же, глубина всё равно ограничена
+		return parent, nil
+	}
+	return grandparent, nil
+}
+
+// resolveMentions находит пользователей, упомянутых в тексте как @username.
+// Несуществующие имена и упоминание самого себя пропускаются.
+func (s *PostsService) resolveMentions(text string, authorID int64) []*models.User {
+	if s.users == nil {
+		return nil
+	}
+
+	var users []*models.User
+	seen := make(map[string]bool)
+	for _, m := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		name := m[1]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		user, err := s.users.GetUserByName(name)
+		if trimmed := trimMention(name); err != nil && trimmed != "" && trimmed != name {
+			user, err = s.users.GetUserByName(trimmed)
+		}
+		if err != nil || user.ID == authorID {
+			continue
+		}
+		users = append(users, user)
+		if len(users) == maxMentions {
+			break
+		}
+	}
+	return users
+}
+
+// trimMention отрезает знаки препинания, прилипшие к концу имени: «@satoshi.»
+func trimMention(name string) string {
+	for len(name) > 0 {
+		switch name[len(name)-1] {
+		case '.', '-':
+			name = name[:len(name)-1]
+		default:
+			return name
+		}
+	}
+	return name
+}
+
+// notifyComment уведомляет каждого адресата не больше одного раза:
+// упоминание важнее ответа, ответ важнее комментария к посту
+func (s *PostsService) notifyComment(
+	ctx context.Context,
+	comment *models.Comment,
+	parent *models.Comment,
+	mentioned []*models.User,
+) {
+	if s.notifier == nil {
+		return
+	}
+
+	post, err := s.postStorage.GetPost(ctx, comment.PostID, comment.AuthorID)
+	if err != nil {
+		slog.Error("Failed to load post for comment notifications", "post_id", comment.PostID.Hex(), "error", err)
+		return
+	}
+
+	notified := map[int64]bool{comment.AuthorID: true}
+	for _, user := range mentioned {
+		notified[user.ID] = true
+		s.notifier.NotifyComment(user, models.NoticeMention, post, comment)
+	}
+
+	notifyAuthor := func(authorID int64, reason models.CommentNoticeReason) {
+		if authorID == 0 || notified[authorID] || s.users == nil {
+			return
+		}
+		notified[authorID] = true
+		user, err := s.users.GetUserByID(authorID)
+		if err != nil {
+			slog.Warn("Comment notification recipient not found", "user_id", authorID, "error", err)
+			return
+		}
+		s.notifier.NotifyComment(user, reason, post, comment)
+	}
+	if parent != nil {
+		notifyAuthor(parent.AuthorID, models.NoticeReply)
+	}
+	notifyAuthor(post.AuthorID, models.NoticePostComment)
+}
+
+// CommentTree возвращает обсуждение поста деревом: корни и ответы по возрастанию времени.
+// Ответы на удалённые комментарии поднимаются на верхний уровень. Комментарии
+// пользователей в теневом бане видны только им самим (viewerID).
+func (s *PostsService) CommentTree(ctx context.Context, postID bson.ObjectID, viewerID int64) ([]*models.CommentNode, error) {
+	if postID.IsZero() {
+		return nil, ErrInvalidPostID
+	}
+	comments, err := s.postStorage.ListComments(ctx, models.CommentQuery{
+		PostID:   postID,
+		Sort:     models.CommentSortOldest,
+		Limit:    maxTreeComments,
+		ViewerID: viewerID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	renderComments(comments)
+	return buildCommentTree(comments), nil
+}
+
+func buildCommentTree(comments []models.Comment) []*models.CommentNode {
+	nodes := make(map[bson.ObjectID]*models.CommentNode, len(comments))
+	for _, c := range comments {
+		nodes[c.ID] = &models.CommentNode{Comment: c, Replies: []*models.CommentNode{}}
+	}
+
+	roots := []*models.CommentNode{}
+	for _, c := range comments {
+		node := nodes[c.ID]
+		if parent, ok := nodes[c.ParentID]; ok && !c.ParentID.IsZero() {
+			parent.Replies = append(parent.Replies, node)
+			continue
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
+
+var ErrParentOtherPost = errors.New("parent comment belongs to another post")
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ContentFilter отклоняет тексты с запрещёнными словами и шаблонами.
+// Нулевой (nil) фильтр пропускает всё.
+type ContentFilter struct {
+	rules []*regexp.Regexp
+}
+
+// NewContentFilter собирает фильтр из правил. Слово или фраза ищется целиком,
+// без учёта регистра; правило в косых чертах (/.../) — регулярное выражение.
+func NewContentFilter(rules []string) (*ContentFilter, error) {
+	f := &ContentFilter{}
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		var expr string
+		if len(rule) > 2 && strings.HasPrefix(rule, "/") && strings.HasSuffix(rule, "/") {
+			expr = "(?i)" + rule[1:len(rule)-1]
+		} else {
+			words := strings.Fields(regexp.QuoteMeta(rule))
+			// Граница слова вручную: \b в Go понимает только ASCII, а правила бывают на русском
+			expr = `(?i)(?:^|[^\p{L}\p{N}_])` + strings.Join(words, `\s+`) + `(?:$|[^\p{L}\p{N}_])`
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter rule %q: %w", rule, err)
+		}
+		f.rules = append(f.rules, re)
+	}
+	return f, nil
+}
+
+// LoadContentFilter читает правила из файла, по одному в строке; пустые строки
+// и строки с # пропускаются. Пустой путь — фильтр выключен.
+func LoadContentFilter(path string) (*ContentFilter, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open filter rules: %w", err)
+	}
+	defer file.Close()
+
+	var rules []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rules = append(rules, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read filter rules: %w", err)
+	}
+	return NewContentFilter(rules)
+}
+
+// invisibleChars — символы, которыми разбивают слово, чтобы обойти фильтр
+var invisibleChars = strings.NewReplacer(
+	"\u00ad", "", // мягкий перенос
+	"\u200b", "",
+	"\u200c", "",
+	"\u200d", "",
+	"\u2060", "",
+	"\ufeff", "",
+)
+
+// Check возвращает ErrBannedContent, если хоть один текст попадает под правило
+func (f *ContentFilter) Check(texts ...string) error {
+	if f == nil {
+		return nil
+	}
+	for _, text := range texts {
+		text = invisibleChars.Replace(text)
+		for _, re := range f.rules {
+			if re.MatchString(text) {
+				slog.Info("Content rejected by filter", "rule", re.String())
+				return ErrBannedContent
+			}
+		}
+	}
+	return nil
+}
+
+var ErrBannedContent = errors.New("text contains words or links that are not allowed")
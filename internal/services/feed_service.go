@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"crypto-analytics/internal/models"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	feedMaxItems       = 50
+	feedMaxSummaryRune = 500
+)
+
+type feedPostsSource interface {
+	GetLastPosts(ctx context.Context) ([]models.Post, error)
+}
+
+// FeedService собирает исходящие ленты из агрегированных новостей и постов сообщества
+type FeedService struct {
+	news    NewsRssService
+	coins   GetAllPairsService
+	posts   feedPostsSource
+	baseURL string
+}
+
+func NewFeedService(news NewsRssService, coins GetAllPairsService, posts feedPostsSource, baseURL string) *FeedService {
+	return &FeedService{
+		news:    news,
+		coins:   coins,
+		posts:   posts,
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// NewsFeed возвращает ленту новостей. Если coin не пустой, в ленту попадают
+// только новости, в которых упоминается монета (по тикеру или названию).
+func (s *FeedService) NewsFeed(coin string) (*models.Feed, error) {
+	news, err := s.news.GetNews()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get news: %w", err)
+	}
+
+	feed := &models.Feed{
+		Title:       "Crypto Analytics — news",
+		Description: "Aggregated cryptocurrency news",
+		HomeURL:     s.baseURL + "/news",
+		FeedURL:     s.baseURL + "/feeds/news",
+	}
+
+	var match func(models.NewsItem) bool
+	if coin != "" {
+		c, err := s.findCoin(coin)
+		if err != nil {
+			return nil, err
+		}
+		feed.Title = fmt.Sprintf("Crypto Analytics — %s news", c.Name)
+		feed.Description = fmt.Sprintf("News mentioning %s (%s)", c.Name, strings.ToUpper(c.Symbol))
+		feed.FeedURL = s.baseURL + "/feeds/coins/" + c.ID + "/news"
+		match = coinMatcher(c)
+	}
+
+	for _, item := range news {
+		if match != nil && !match(item) {
+			continue
+		}
+		published, _ := time.Parse(time.RFC3339, item.PublishedAt)
+		feed.Items = append(feed.Items, models.FeedItem{
+			ID:        newsItemID(item),
+			Title:     item.Title,
+			URL:       item.Link,
+			Summary:   item.Description,
+			Author:    item.Source,
+			Published: published,
+		})
+		if len(feed.Items) == feedMaxItems {
+			break
+		}
+	}
+
+	feed.Updated = latestPublished(feed.Items)
+	return feed, nil
+}
+
+// PostsFeed возвращает ленту последних постов сообщества
+func (s *FeedService) PostsFeed(ctx context.Context) (*models.Feed, error) {
+	posts, err := s.posts.GetLastPosts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get posts: %w", err)
+	}
+
+	feed := &models.Feed{
+		Title:       "Crypto Analytics — community posts",
+		Description: "Latest posts from the Crypto Analytics community",
+		HomeURL:     s.baseURL + "/static/posts.html",
+		FeedURL:     s.baseURL + "/feeds/posts",
+	}
+
+	for _, post := range posts {
+		published := post.CreatedAt
+		if published.IsZero() {
+			// Посты до появления createdAt: дата в строке от клиента
+			published, _ = time.Parse(time.RFC3339, post.Date)
+		}
+		feed.Items = append(feed.Items, models.FeedItem{
+			ID:        "post:" + post.ID.Hex(),
+			Title:     post.Heading,
+			URL:       s.baseURL + "/static/posts.html#post-" + post.ID.Hex(),
+			Summary:   truncateRunes(post.MainText, feedMaxSummaryRune),
+			Author:    post.Person,
+			Published: published,
+		})
+		if len(feed.Items) == feedMaxItems {
+			break
+		}
+	}
+
+	feed.Updated = latestPublished(feed.Items)
+	return feed, nil
+}
+
+func (s *FeedService) findCoin(coin string) (*models.Coin, error) {
+	coins, err := s.coins.GetTopCryptos(250)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get coins: %w", err)
+	}
+	for i := range coins {
+		if strings.EqualFold(coins[i].ID, coin) || strings.EqualFold(coins[i].Symbol, coin) {
+			return &coins[i], nil
+		}
+	}
+	return nil, ErrUnknownCoin
+}
+
+func coinMatcher(c *models.Coin) func(models.NewsItem) bool {
+	symbol := regexp.MustCompile(`\b` + regexp.QuoteMeta(strings.ToUpper(c.Symbol)) + `\b`)
+	name := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(c.Name) + `\b`)
+	return func(item models.NewsItem) bool {
+		text := item.Title + " " + item.Description
+		return symbol.MatchString(text) || name.MatchString(text)
+	}
+}
+
+func newsItemID(item models.NewsItem) string {
+	if item.GUID != "" {
+		return item.GUID
+	}
+	return item.Link
+}
+
+func latestPublished(items []models.FeedItem) time.Time {
+	var latest time.Time
+	for _, item := range items {
+		if item.Published.After(latest) {
+			latest = item.Published
+		}
+	}
+	return latest
+}
+
+var ErrUnknownCoin = errors.New("unknown coin")
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormKind — публичная форма, которую защищает FormGuard
+type FormKind string
+
+const (
+	FormContact  FormKind = "contact"
+	FormRegister FormKind = "register"
+)
+
+const (
+	// HoneypotField — скрытое поле формы: человек его не видит, бот заполняет
+	HoneypotField = "website"
+	// Быстрее человек форму не заполнит, а дольше токен не живёт
+	formMinFillTime = 3 * time.Second
+	formTokenTTL    = 2 * time.Hour
+)
+
+// formRejections — отклонённые отправки по "<форма>:<причина>", видны в /admin/api/metrics
+var formRejections = expvar.NewMap("rejected_submissions")
+
+// captchaDegraded — принятые без CAPTCHA из-за сбоя провайдера отправки по формам.
+// Отдельно от отказов: эти отправки прошли.
+var captchaDegraded = expvar.NewMap("captcha_degraded")
+
+// CountRejection учитывает отклонённый запрос в метриках
+func CountRejection(form, reason string) {
+	formRejections.Add(form+":"+reason, 1)
+}
+
+// FormSubmission — служебные поля отправленной формы
+type FormSubmission struct {
+	Honeypot string
+	Token    string // из IssueToken при открытии формы
+	Captcha  string
+	IP       string
+}
+
+// FormGuard отсеивает ботов на публичных формах: поле-ловушка, подписанный токен
+// с временем выдачи (слишком быстрая отправка — бот) и CAPTCHA
+type FormGuard struct {
+	secret  []byte
+	captcha CaptchaVerifier
+	widget  *CaptchaWidget
+	now     func() time.Time
+}
+
+// NewFormGuard: widget — виджет CAPTCHA для страниц, nil — CAPTCHA не настроена
+func NewFormGuard(secret string, captcha CaptchaVerifier, widget *CaptchaWidget) *FormGuard {
+	return &FormGuard{secret: []byte(secret), captcha: captcha, widget: widget, now: time.Now}
+}
+
+// Widget — виджет CAPTCHA, который страница должна показать вместе с формой
+func (g *FormGuard) Widget() *CaptchaWidget {
+	return g.widget
+}
+
+// IssueToken выдаёт токен вида <время выдачи в мс>.<hmac>
+func (g *FormGuard) IssueToken(form FormKind) string {
+	issued := strconv.FormatInt(g.now().UnixMilli(), 10)
+	return issued + "." + g.sign(form, issued)
+}
+
+// Check возвращает ErrFormRejected с причиной; каждая причина учитывается в метриках
+func (g *FormGuard) Check(ctx context.Context, form FormKind, sub FormSubmission) error {
+	reason := g.rejectReason(ctx, form, sub)
+	if reason == "" {
+		return nil
+	}
+	CountRejection(string(form), reason)
+	slog.Warn("Form submission rejected", "form", form, "reason", reason, "ip", sub.IP)
+	return fmt.Errorf("%w: %s", ErrFormRejected, reason)
+}
+
+func (g *FormGuard) rejectReason(ctx context.Context, form FormKind, sub FormSubmission) string {
+	if sub.Honeypot != "" {
+		return "honeypot"
+	}
+
+	issuedPart, sig, ok := strings.Cut(sub.Token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(g.sign(form, issuedPart))) {
+		return "invalid_token"
+	}
+	issued, err := strconv.ParseInt(issuedPart, 10, 64)
+	if err != nil {
+		return "invalid_token"
+	}
+	age := g.now().Sub(time.UnixMilli(issued))
+	if age < formMinFillTime {
+		return "too_fast"
+	}
+	if age > formTokenTTL {
+		return "expired_token"
+	}
+
+	passed, err := g.captcha.Verify(ctx, sub.Captcha, sub.IP)
+	if err != nil {
+		// Недоступный провайдер не должен закрывать регистрацию: остальные проверки уже пройдены,
+		// но пропущенные так отправки видны в метриках
+		slog.Error("Captcha verification failed", "form", form, "error", err)
+		captchaDegraded.Add(string(form), 1)
+		return ""
+	}
+	if !passed {
+		return "captcha"
+	}
+	return ""
+}
+
+func (g *FormGuard) sign(form FormKind, payload string) string {
+	mac := hmac.New(sha256.New, g.secret)
+	mac.Write([]byte("form:" + string(form)))
+	mac.Write([]byte{'.'})
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+var ErrFormRejected = errors.New("form submission rejected")
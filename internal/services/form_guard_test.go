@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type stubCaptcha struct {
+	pass bool
+	err  error
+}
+
+func (c stubCaptcha) Verify(ctx context.Context, response, remoteIP string) (bool, error) {
+	return c.pass, c.err
+}
+
+func rejections(key string) int64 {
+	return expvarCount(formRejections, key)
+}
+
+func expvarCount(m *expvar.Map, key string) int64 {
+	if v := m.Get(key); v != nil {
+		n, _ := strconv.ParseInt(v.String(), 10, 64)
+		return n
+	}
+	return 0
+}
+
+func TestFormGuard(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	g := NewFormGuard("secret", NoopCaptchaVerifier{}, nil)
+	g.now = func() time.Time { return now }
+
+	token := g.IssueToken(FormContact)
+	g.now = func() time.Time { return now.Add(10 * time.Second) }
+	if err := g.Check(ctx, FormContact, FormSubmission{Token: token}); err != nil {
+		t.Fatalf("valid submission rejected: %v", err)
+	}
+
+	forged := strconv.FormatInt(now.Add(-time.Minute).UnixMilli(), 10) + token[strings.Index(token, "."):]
+	tests := []struct {
+		name   string
+		guard  *FormGuard
+		form   FormKind
+		sub    FormSubmission
+		after  time.Duration
+		reason string
+	}{
+		{"honeypot filled", g, FormContact, FormSubmission{Token: token, Honeypot: "http://spam"}, 10 * time.Second, "honeypot"},
+		{"no token", g, FormContact, FormSubmission{}, 10 * time.Second, "invalid_token"},
+		{"issued time changed", g, FormContact, FormSubmission{Token: forged}, 10 * time.Second, "invalid_token"},
+		{"token of another form", g, FormRegister, FormSubmission{Token: token}, 10 * time.Second, "invalid_token"},
+		{"submitted too fast", g, FormContact, FormSubmission{Token: token}, time.Second, "too_fast"},
+		{"token expired", g, FormContact, FormSubmission{Token: token}, formTokenTTL + time.Minute, "expired_token"},
+		{"captcha failed", NewFormGuard("secret", stubCaptcha{pass: false}, nil), FormContact, FormSubmission{Token: token}, 10 * time.Second, "captcha"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.guard.now = func() time.Time { return now.Add(tt.after) }
+			key := string(tt.form) + ":" + tt.reason
+			before := rejections(key)
+
+			err := tt.guard.Check(ctx, tt.form, tt.sub)
+			if !errors.Is(err, ErrFormRejected) || !strings.HasSuffix(err.Error(), tt.reason) {
+				t.Errorf("expected rejection for %q, got %v", tt.reason, err)
+			}
+			if got := rejections(key); got != before+1 {
+				t.Errorf("rejection %q was not counted: %d -> %d", key, before, got)
+			}
+		})
+	}
+
+	// Недоступный провайдер CAPTCHA не блокирует форму, но попадает в метрики
+	down := NewFormGuard("secret", stubCaptcha{err: errors.New("timeout")}, nil)
+	down.now = func() time.Time { return now.Add(10 * time.Second) }
+	before := expvarCount(captchaDegraded, "contact")
+	if err := down.Check(ctx, FormContact, FormSubmission{Token: token}); err != nil {
+		t.Errorf("captcha outage must not reject the form: %v", err)
+	}
+	if got := expvarCount(captchaDegraded, "contact"); got != before+1 {
+		t.Errorf("captcha outage was not counted: %d -> %d", before, got)
+	}
+	if formRejections.Get("contact:captcha_unavailable") != nil {
+		t.Error("accepted submissions must not be counted as rejections")
+	}
+}
+
+func TestCaptchaProvider(t *testing.T) {
+	tests := map[string]string{
+		"https://www.google.com/recaptcha/api/siteverify":           "recaptcha",
+		"https://api.hcaptcha.com/siteverify":                       "hcaptcha",
+		"https://hcaptcha.com/siteverify":                           "hcaptcha",
+		"https://challenges.cloudflare.com/turnstile/v0/siteverify": "turnstile",
+		"https://captcha.example.com/siteverify":                    "",
+	}
+	for verifyURL, want := range tests {
+		if got := CaptchaProvider(verifyURL); got != want {
+			t.Errorf("CaptchaProvider(%q) = %q, want %q", verifyURL, got, want)
+		}
+	}
+}
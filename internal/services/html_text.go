@@ -0,0 +1,65 @@
+package services
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// htmlToText превращает HTML из фида в обычный текст: теги и их атрибуты
+// отбрасываются, содержимое script/style не попадает в результат,
+// HTML-сущности раскодируются, пробелы схлопываются.
+func htmlToText(src string, maxRunes int) string {
+	z := html.NewTokenizer(strings.NewReader(src))
+
+	var b strings.Builder
+	skipDepth := 0
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return truncateRunes(collapseSpaces(b.String()), maxRunes)
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if tag == "script" || tag == "style" {
+				if tt == html.StartTagToken {
+					skipDepth++
+				} else if tt == html.EndTagToken && skipDepth > 0 {
+					skipDepth--
+				}
+				continue
+			}
+			if isBlockTag(tag) {
+				b.WriteByte(' ')
+			}
+		case html.TextToken:
+			if skipDepth == 0 {
+				b.Write(z.Text())
+			}
+		}
+	}
+}
+
+func isBlockTag(tag string) bool {
+	switch tag {
+	case "p", "br", "div", "li", "ul", "ol", "h1", "h2", "h3", "h4", "h5", "h6",
+		"blockquote", "pre", "table", "tr", "td", "th", "hr", "figure", "figcaption":
+		return true
+	}
+	return false
+}
+
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func truncateRunes(s string, maxRunes int) string {
+	if maxRunes <= 0 || utf8.RuneCountInString(s) <= maxRunes {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:maxRunes])) + "…"
+}
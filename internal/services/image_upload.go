@@ -0,0 +1,391 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	maxUploadBytes = 8 << 20
+	maxImageSide   = 8000
+	// maxImagePixels защищает от «бомб»: маленький файл, который разжимается в гигабайты
+	maxImagePixels = 40_000_000
+	thumbMaxSide   = 320
+	jpegQuality    = 90
+)
+
+// processedImage — картинка после очистки: перекодированный оригинал и превью
+type processedImage struct {
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+
+	Thumb            []byte
+	ThumbContentType string
+	ThumbWidth       int
+	ThumbHeight      int
+}
+
+// processImage проверяет загрузку и перекодирует её. Тип определяется по содержимому,
+// а не по имени файла или заголовку клиента. Перекодирование выбрасывает EXIF
+// (в том числе геометку), комментарии и всё, что дописано после изображения.
+func processImage(data []byte) (*processedImage, error) {
+	if len(data) > maxUploadBytes {
+		return nil, fmt.Errorf("%w: at most %d MB", ErrAttachmentTooLarge, maxUploadBytes>>20)
+	}
+
+	contentType := http.DetectContentType(data)
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || "image/"+format != contentType {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedImage, contentType)
+	}
+	if cfg.Width > maxImageSide || cfg.Height > maxImageSide || cfg.Width*cfg.Height > maxImagePixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrImageTooLarge, cfg.Width, cfg.Height)
+	}
+
+	out := &processedImage{ContentType: contentType}
+	var img image.Image
+	var buf bytes.Buffer
+	switch contentType {
+	case "image/jpeg":
+		img, err = jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+		}
+		// Ориентацию камеры из EXIF применяем к пикселям, иначе без EXIF фото ляжет на бок
+		img = orient(img, jpegOrientation(data))
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+
+	case "image/png":
+		img, err = png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+		}
+		err = png.Encode(&buf, img)
+
+	case "image/gif":
+		// Кадры считаются до распаковки: DecodeAll выделяет память под каждый сразу
+		var frames int
+		frames, err = gifFrameCount(data)
+		if err != nil || frames == 0 {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+		}
+		if frames*cfg.Width*cfg.Height > maxImagePixels {
+			return nil, fmt.Errorf("%w: %d frames", ErrImageTooLarge, frames)
+		}
+		var g *gif.GIF
+		g, err = gif.DecodeAll(bytes.NewReader(data))
+		if err != nil || len(g.Image) == 0 {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+		}
+		img = g.Image[0]
+		err = gif.EncodeAll(&buf, g)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	bounds := img.Bounds()
+	out.Data = buf.Bytes()
+	out.Width, out.Height = bounds.Dx(), bounds.Dy()
+
+	thumb := thumbnail(img, thumbMaxSide)
+	var thumbBuf bytes.Buffer
+	if contentType == "image/jpeg" {
+		out.ThumbContentType = "image/jpeg"
+		err = jpeg.Encode(&thumbBuf, thumb, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		// У PNG и GIF бывает прозрачность — превью её сохраняет
+		out.ThumbContentType = "image/png"
+		err = png.Encode(&thumbBuf, thumb)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	out.Thumb = thumbBuf.Bytes()
+	out.ThumbWidth, out.ThumbHeight = thumb.Bounds().Dx(), thumb.Bounds().Dy()
+	return out, nil
+}
+
+// thumbnail уменьшает картинку так, чтобы большая сторона была не длиннее maxSide.
+// Каждый пиксель превью — среднее по своему прямоугольнику исходника. Исходник
+// переводится в RGBA полосами высотой в одну строку превью, а не копируется целиком.
+func thumbnail(img image.Image, maxSide int) *image.RGBA {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	tw, th := w, h
+	if w > maxSide || h > maxSide {
+		if w >= h {
+			tw, th = maxSide, max(1, h*maxSide/w)
+		} else {
+			tw, th = max(1, w*maxSide/h), maxSide
+		}
+	}
+	if tw == w && th == h {
+		dst := image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+		return dst
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	strip := image.NewRGBA(image.Rect(0, 0, w, (h+th-1)/th))
+	for ty := 0; ty < th; ty++ {
+		y0, y1 := ty*h/th, (ty+1)*h/th
+		rows := y1 - y0
+		draw.Draw(strip, image.Rect(0, 0, w, rows), img, image.Pt(b.Min.X, b.Min.Y+y0), draw.Src)
+		for tx := 0; tx < tw; tx++ {
+			x0, x1 := tx*w/tw, max((tx+1)*w/tw, tx*w/tw+1)
+			var sum [4]int
+			for y := 0; y < rows; y++ {
+				row := strip.Pix[y*strip.Stride+x0*4 : y*strip.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+			n := (x1 - x0) * rows
+			off := ty*dst.Stride + tx*4
+			for c := 0; c < 4; c++ {
+				dst.Pix[off+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+	return dst
+}
+
+// gifFrameCount считает кадры GIF по структуре блоков, не распаковывая их
+func gifFrameCount(data []byte) (int, error) {
+	errMalformed := errors.New("malformed gif")
+	if len(data) < 13 {
+		return 0, errMalformed
+	}
+	pos := 13
+	if flags := data[10]; flags&0x80 != 0 {
+		pos += 3 << (flags&7 + 1) // глобальная палитра
+	}
+	skipSubBlocks := func() bool {
+		for pos < len(data) {
+			n := int(data[pos])
+			pos += 1 + n
+			if n == 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	frames := 0
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21: // расширение: метка и подблоки
+			pos += 2
+			if !skipSubBlocks() {
+				return 0, errMalformed
+			}
+		case 0x2C: // кадр: дескриптор, локальная палитра, размер кода LZW и подблоки
+			if pos+10 > len(data) {
+				return 0, errMalformed
+			}
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&7 + 1)
+			}
+			pos++
+			if !skipSubBlocks() {
+				return 0, errMalformed
+			}
+			frames++
+		case 0x3B:
+			return frames, nil
+		default:
+			return 0, errMalformed
+		}
+	}
+	return 0, errMalformed
+}
+
+// jpegOrientation читает тег Orientation (0x0112) из EXIF-блока APP1.
+// Возвращает 1 (как есть), если тега нет или блок повреждён.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for pos := 2; pos+4 <= len(data); {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		size := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if marker == 0xDA || size < 2 || pos+2+size > len(data) {
+			return 1 // дальше идут сами данные изображения
+		}
+		segment := data[pos+4 : pos+2+size]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		pos += 2 + size
+	}
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if o := int(order.Uint16(tiff[entry+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// orient поворачивает и отражает картинку по значению EXIF Orientation.
+// Плоскости пикселей переставляются побайтно, без At/Set: YCbCr и Gray, которые
+// отдаёт декодер JPEG, остаются в своём компактном виде, а не раздуваются до RGBA.
+func orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	rect := image.Rect(0, 0, w, h)
+	if orientation >= 5 {
+		rect = image.Rect(0, 0, h, w)
+	}
+
+	switch src := img.(type) {
+	case *image.YCbCr:
+		ratio, ok := orientedRatio(src.SubsampleRatio, orientation)
+		if !ok || b.Min != (image.Point{}) {
+			break
+		}
+		cw, ch := chromaSize(src.SubsampleRatio, w, h)
+		dst := &image.YCbCr{SubsampleRatio: ratio, Rect: rect}
+		dst.Y, dst.YStride = orientPlane(src.Y, src.YStride, w, h, 1, orientation)
+		dst.Cb, dst.CStride = orientPlane(src.Cb, src.CStride, cw, ch, 1, orientation)
+		dst.Cr, _ = orientPlane(src.Cr, src.CStride, cw, ch, 1, orientation)
+		return dst
+	case *image.Gray:
+		if b.Min != (image.Point{}) {
+			break
+		}
+		pix, stride := orientPlane(src.Pix, src.Stride, w, h, 1, orientation)
+		return &image.Gray{Pix: pix, Stride: stride, Rect: rect}
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	pix, stride := orientPlane(rgba.Pix, rgba.Stride, w, h, 4, orientation)
+	return &image.RGBA{Pix: pix, Stride: stride, Rect: rect}
+}
+
+// orientPlane переставляет пиксели одной плоскости (bpp байт на пиксель) и
+// возвращает новую плоскость с её шагом строки
+func orientPlane(pix []byte, stride, w, h, bpp, orientation int) ([]byte, int) {
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dstStride := dw * bpp
+	dst := make([]byte, dstStride*dh)
+	for y := 0; y < h; y++ {
+		row := pix[y*stride : y*stride+w*bpp]
+		if orientation == 4 {
+			copy(dst[(h-1-y)*dstStride:], row)
+			continue
+		}
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			copy(dst[dy*dstStride+dx*bpp:dy*dstStride+dx*bpp+bpp], row[x*bpp:x*bpp+bpp])
+		}
+	}
+	return dst, dstStride
+}
+
+// orientedRatio — прореживание цветности после поворота: при повороте на 90°
+// горизонтальное и вертикальное меняются местами
+func orientedRatio(ratio image.YCbCrSubsampleRatio, orientation int) (image.YCbCrSubsampleRatio, bool) {
+	if orientation < 5 {
+		return ratio, true
+	}
+	switch ratio {
+	case image.YCbCrSubsampleRatio444, image.YCbCrSubsampleRatio420:
+		return ratio, true
+	case image.YCbCrSubsampleRatio422:
+		return image.YCbCrSubsampleRatio440, true
+	case image.YCbCrSubsampleRatio440:
+		return image.YCbCrSubsampleRatio422, true
+	}
+	return ratio, false
+}
+
+// chromaSize — размер плоскостей Cb и Cr для картинки w×h с началом в нуле
+func chromaSize(ratio image.YCbCrSubsampleRatio, w, h int) (int, int) {
+	switch ratio {
+	case image.YCbCrSubsampleRatio422:
+		return (w + 1) / 2, h
+	case image.YCbCrSubsampleRatio420:
+		return (w + 1) / 2, (h + 1) / 2
+	case image.YCbCrSubsampleRatio440:
+		return w, (h + 1) / 2
+	case image.YCbCrSubsampleRatio411:
+		return (w + 3) / 4, h
+	case image.YCbCrSubsampleRatio410:
+		return (w + 3) / 4, (h + 1) / 2
+	}
+	return w, h
+}
+
+var (
+	ErrAttachmentTooLarge = errors.New("file is too large")
+	ErrImageTooLarge      = errors.New("image dimensions are too large")
+	ErrUnsupportedImage   = errors.New("only JPEG, PNG and GIF images are supported")
+)
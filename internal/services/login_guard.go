@@ -0,0 +1,164 @@
+package services
+
+import (
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/storage"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Параметры защиты от перебора паролей
+const (
+	loginFailureWindow = 15 * time.Minute
+	// Неудачных попыток подряд до блокировки: по логину и по IP (с одного адреса
+	// могут входить несколько человек, поэтому порог выше)
+	loginMaxFailuresByLogin = 5
+	loginMaxFailuresByIP    = 20
+	// Блокировка растёт вдвое с каждым разом: 1, 2, 4 ... минут, но не дольше часа.
+	// Счётчик блокировок забывается через сутки без новых.
+	lockoutBase     = time.Minute
+	lockoutMax      = time.Hour
+	lockoutLevelTTL = 24 * time.Hour
+)
+
+type LoginGuardService struct {
+	limits   storage.RateLimitStorage
+	audit    storage.AuthAuditStorage
+	users    storage.UserStorage
+	notifier Notifier
+}
+
+func NewLoginGuardService(
+	limits storage.RateLimitStorage,
+	audit storage.AuthAuditStorage,
+	users storage.UserStorage,
+	notifier Notifier,
+) *LoginGuardService {
+	return &LoginGuardService{limits: limits, audit: audit, users: users, notifier: notifier}
+}
+
+type guardSubject struct {
+	key         string
+	maxFailures int64
+}
+
+// loginSubjects — счётчики попытки. Логин сначала сводится к пользователю: имя и email
+// одного аккаунта делят счётчик user:<id>. Строка как есть — только если такого пользователя нет.
+func (s *LoginGuardService) loginSubjects(login, ip string) []guardSubject {
+	subjects := make([]guardSubject, 0, 2)
+	if key := s.loginKey(login); key != "" {
+		subjects = append(subjects, guardSubject{key: key, maxFailures: loginMaxFailuresByLogin})
+	}
+	if ip != "" {
+		subjects = append(subjects, guardSubject{key: "ip:" + ip, maxFailures: loginMaxFailuresByIP})
+	}
+	return subjects
+}
+
+func (s *LoginGuardService) loginKey(login string) string {
+	if normalizeLogin(login) == "" {
+		return ""
+	}
+	// Ищем как при входе, без смены регистра: иначе не найдётся имя с заглавными
+	user, err := findUserByLogin(s.users, login)
+	if err == nil {
+		return "user:" + strconv.FormatInt(user.ID, 10)
+	}
+	if !errors.Is(err, storage.ErrUserNotFound) {
+		slog.Error("Failed to resolve login for login guard", "error", err)
+	}
+	return "login:" + normalizeLogin(login)
+}
+
+func normalizeLogin(login string) string {
+	return strings.ToLower(strings.TrimSpace(login))
+}
+
+// Check возвращает, сколько ещё длится блокировка логина или IP (0 — входить можно)
+func (s *LoginGuardService) Check(login, ip string) (time.Duration, error) {
+	var longest time.Duration
+	for _, subject := range s.loginSubjects(login, ip) {
+		ttl, err := s.limits.LockTTL("lock:" + subject.key)
+		if err != nil {
+			return 0, err
+		}
+		longest = max(longest, ttl)
+	}
+	return longest, nil
+}
+
+// Failure записывает неудачную попытку в журнал и при превышении порога блокирует
+// логин или IP. Возвращает длительность наложенной блокировки (0 — не блокировали).
+func (s *LoginGuardService) Failure(attempt models.AuthEvent) (time.Duration, error) {
+	// Ключ — по логину как ввели: поиск имени чувствителен к регистру.
+	// В журнал пишем нормализованную копию.
+	subjects := s.loginSubjects(attempt.Login, attempt.IP)
+	attempt.Login = normalizeLogin(attempt.Login)
+	attempt.Event = models.AuthEventLoginFailed
+	if err := s.audit.LogAuthEvent(&attempt); err != nil {
+		slog.Error("Failed to write auth audit log", "error", err)
+	}
+
+	var longest time.Duration
+	for _, subject := range subjects {
+		failures, _, err := s.limits.Hit("fail:"+subject.key, loginFailureWindow)
+		if err != nil {
+			return 0, err
+		}
+		if failures < subject.maxFailures {
+			continue
+		}
+
+		lockFor, err := s.lock(subject.key)
+		if err != nil {
+			return 0, err
+		}
+		longest = max(longest, lockFor)
+
+		lockout := attempt
+		lockout.Event = models.AuthEventLockout
+		if err := s.audit.LogAuthEvent(&lockout); err != nil {
+			slog.Error("Failed to write auth audit log", "error", err)
+		}
+		slog.Warn("Login locked after repeated failures",
+			"subject", subject.key,
+			"failures", failures,
+			"lock_for", lockFor)
+		s.notifier.NotifyAdmSuspiciousLogin(&lockout, failures, lockFor)
+	}
+	return longest, nil
+}
+
+func (s *LoginGuardService) lock(key string) (time.Duration, error) {
+	level, _, err := s.limits.Hit("level:"+key, lockoutLevelTTL)
+	if err != nil {
+		return 0, err
+	}
+	lockFor := lockoutBase
+	for i := int64(1); i < level && lockFor < lockoutMax; i++ {
+		lockFor *= 2
+	}
+	lockFor = min(lockFor, lockoutMax)
+
+	if err := s.limits.SetLock("lock:"+key, lockFor); err != nil {
+		return 0, err
+	}
+	// Новое окно: после блокировки снова даём maxFailures попыток
+	if err := s.limits.Reset("fail:" + key); err != nil {
+		return 0, err
+	}
+	return lockFor, nil
+}
+
+// Success сбрасывает счётчики логина после верного пароля. Счётчик IP не трогаем:
+// иначе перебор по многим логинам можно «разбавлять» входом в свой аккаунт.
+func (s *LoginGuardService) Success(login string) error {
+	key := s.loginKey(login)
+	if key == "" {
+		return nil
+	}
+	return s.limits.Reset("fail:"+key, "level:"+key)
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"crypto-analytics/internal/models"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// MockRateLimitStorage повторяет семантику Redis-хранилища на ручных часах
+type MockRateLimitStorage struct {
+	now     time.Time
+	values  map[string]int64
+	expires map[string]time.Time
+}
+
+func NewMockRateLimitStorage() *MockRateLimitStorage {
+	return &MockRateLimitStorage{
+		now:     time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		values:  map[string]int64{},
+		expires: map[string]time.Time{},
+	}
+}
+
+func (m *MockRateLimitStorage) expire() {
+	for key, at := range m.expires {
+		if !m.now.Before(at) {
+			delete(m.values, key)
+			delete(m.expires, key)
+		}
+	}
+}
+
+func (m *MockRateLimitStorage) Hit(key string, window time.Duration) (int64, time.Duration, error) {
+	m.expire()
+	if _, ok := m.values[key]; !ok {
+		m.expires[key] = m.now.Add(window)
+	}
+	m.values[key]++
+	return m.values[key], m.expires[key].Sub(m.now), nil
+}
+
+func (m *MockRateLimitStorage) SetLock(key string, ttl time.Duration) error {
+	m.values[key] = 1
+	m.expires[key] = m.now.Add(ttl)
+	return nil
+}
+
+func (m *MockRateLimitStorage) LockTTL(key string) (time.Duration, error) {
+	m.expire()
+	if _, ok := m.values[key]; !ok {
+		return 0, nil
+	}
+	return m.expires[key].Sub(m.now), nil
+}
+
+func (m *MockRateLimitStorage) Reset(keys ...string) error {
+	for _, key := range keys {
+		delete(m.values, key)
+		delete(m.expires, key)
+	}
+	return nil
+}
+
+type MockAuthAuditStorage struct {
+	Events []models.AuthEvent
+}
+
+func (m *MockAuthAuditStorage) LogAuthEvent(event *models.AuthEvent) error {
+	m.Events = append(m.Events, *event)
+	return nil
+}
+
+type MockNotifier struct {
+	Suspicious []models.AuthEvent
+}
+
+func (m *MockNotifier) NotifyAdmContForm(contact *models.ContactForm) {}
+func (m *MockNotifier) NotifyAdmNewUserForm(contact *models.User)     {}
+func (m *MockNotifier) NotifyAdmSuspiciousLogin(event *models.AuthEvent, failures int64, lockFor time.Duration) {
+	m.Suspicious = append(m.Suspicious, *event)
+}
+
+func TestLoginGuard_ExponentialLockout(t *testing.T) {
+	limits := NewMockRateLimitStorage()
+	audit := &MockAuthAuditStorage{}
+	notifier := &MockNotifier{}
+	guard := NewLoginGuardService(limits, audit, NewMockUserStorage(), notifier)
+
+	attempt := models.AuthEvent{Login: " Satoshi ", IP: "10.0.0.1"}
+	fail := func() time.Duration {
+		t.Helper()
+		lockFor, err := guard.Failure(attempt)
+		if err != nil {
+			t.Fatalf("failure: %v", err)
+		}
+		return lockFor
+	}
+
+	for _, wantLock := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute} {
+		for i := 1; i < loginMaxFailuresByLogin; i++ {
+			if lockFor := fail(); lockFor != 0 {
+				t.Fatalf("locked too early after %d failures", i)
+			}
+		}
+		if lockFor := fail(); lockFor != wantLock {
+			t.Fatalf("expected lock for %v, got %v", wantLock, lockFor)
+		}
+
+		// Регистр и пробелы в логине блокировку не обходят
+		if ttl, _ := guard.Check("satoshi", "10.0.0.2"); ttl != wantLock {
+			t.Fatalf("expected login locked for %v, got %v", wantLock, ttl)
+		}
+		limits.now = limits.now.Add(wantLock)
+		if ttl, _ := guard.Check("satoshi", "10.0.0.2"); ttl != 0 {
+			t.Fatalf("lock must expire, still %v", ttl)
+		}
+	}
+
+	if len(notifier.Suspicious) != 3 {
+		t.Errorf("expected 3 admin alerts, got %d", len(notifier.Suspicious))
+	}
+	if n := len(audit.Events); n != 3*loginMaxFailuresByLogin+3 {
+		t.Errorf("expected %d audit events, got %d", 3*loginMaxFailuresByLogin+3, n)
+	}
+
+	// Верный пароль сбрасывает эскалацию
+	if err := guard.Success("SATOSHI"); err != nil {
+		t.Fatalf("success: %v", err)
+	}
+	for i := 1; i < loginMaxFailuresByLogin; i++ {
+		fail()
+	}
+	if lockFor := fail(); lockFor != time.Minute {
+		t.Errorf("expected escalation reset to 1m, got %v", lockFor)
+	}
+}
+
+func TestLoginGuard_IPLockout(t *testing.T) {
+	limits := NewMockRateLimitStorage()
+	guard := NewLoginGuardService(limits, &MockAuthAuditStorage{}, NewMockUserStorage(), &MockNotifier{})
+
+	// Перебор разных логинов с одного адреса: каждый логин ниже порога, IP — нет
+	for i := 0; i < loginMaxFailuresByIP; i++ {
+		login := string(rune('a' + i))
+		if _, err := guard.Failure(models.AuthEvent{Login: login, IP: "10.0.0.1"}); err != nil {
+			t.Fatalf("failure: %v", err)
+		}
+	}
+
+	if ttl, _ := guard.Check("someone-else", "10.0.0.1"); ttl != time.Minute {
+		t.Errorf("expected ip locked for 1m, got %v", ttl)
+	}
+	if ttl, _ := guard.Check("someone-else", "10.0.0.2"); ttl != 0 {
+		t.Errorf("other ip must not be locked, got %v", ttl)
+	}
+}
+
+func TestLoginGuard_KeyedByUser(t *testing.T) {
+	limits := NewMockRateLimitStorage()
+	users := NewMockUserStorage()
+	user := &models.User{Username: "satoshi", Email: "satoshi@example.com"}
+	if err := users.CreateUser(user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	guard := NewLoginGuardService(limits, &MockAuthAuditStorage{}, users, &MockNotifier{})
+
+	// Имя и email одного аккаунта расходуют общий лимит
+	var lockFor time.Duration
+	for i := 0; i < loginMaxFailuresByLogin; i++ {
+		login := "satoshi"
+		if i%2 == 1 {
+			login = "satoshi@example.com"
+		}
+		var err error
+		if lockFor, err = guard.Failure(models.AuthEvent{Login: login, IP: "10.0.0." + strconv.Itoa(i)}); err != nil {
+			t.Fatalf("failure: %v", err)
+		}
+	}
+	if lockFor != time.Minute {
+		t.Fatalf("expected account locked for 1m, got %v", lockFor)
+	}
+	if ttl, _ := guard.Check("satoshi@example.com", "10.0.0.99"); ttl != time.Minute {
+		t.Errorf("expected email login locked, got %v", ttl)
+	}
+	if ttl, _ := guard.Check("nobody", "10.0.0.99"); ttl != 0 {
+		t.Errorf("unknown login must not be locked, got %v", ttl)
+	}
+	if _, ok := limits.values["lock:user:"+strconv.FormatInt(user.ID, 10)]; !ok {
+		t.Errorf("expected lock keyed by user id, got %v", limits.values)
+	}
+
+	// Имя с заглавными: поиск по имени чувствителен к регистру, ключ всё равно user:<id>
+	mixed := &models.User{Username: "Vitalik", Email: "vitalik@example.com"}
+	if err := users.CreateUser(mixed); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	for i := 0; i < loginMaxFailuresByLogin; i++ {
+		var err error
+		if lockFor, err = guard.Failure(models.AuthEvent{Login: " Vitalik ", IP: "10.0.1." + strconv.Itoa(i)}); err != nil {
+			t.Fatalf("failure: %v", err)
+		}
+	}
+	if lockFor != time.Minute {
+		t.Fatalf("expected mixed-case account locked for 1m, got %v", lockFor)
+	}
+	if ttl, _ := guard.Check("Vitalik", "10.0.1.99"); ttl != time.Minute {
+		t.Errorf("expected mixed-case login locked, got %v", ttl)
+	}
+	if _, ok := limits.values["lock:login:vitalik"]; ok {
+		t.Error("failures of an existing account must not be keyed by the raw login")
+	}
+	if err := guard.Success("Vitalik"); err != nil {
+		t.Fatalf("success: %v", err)
+	}
+	if _, ok := limits.values["level:user:"+strconv.FormatInt(mixed.ID, 10)]; ok {
+		t.Error("success must reset the mixed-case account's escalation")
+	}
+}
+
+func TestRedisRateLimiter_Allow(t *testing.T) {
+	limits := NewMockRateLimitStorage()
+	limiter := NewRedisRateLimiter(limits)
+	limit := RateLimit{Requests: 3, Window: time.Minute}
+
+	for i := 0; i < limit.Requests; i++ {
+		if ok, _, _ := limiter.Allow("login:1.2.3.4", limit); !ok {
+			t.Fatalf("request %d rejected", i+1)
+		}
+	}
+	ok, retryAfter, _ := limiter.Allow("login:1.2.3.4", limit)
+	if ok || retryAfter != time.Minute {
+		t.Fatalf("expected rejection with retry 1m, got ok=%v retry=%v", ok, retryAfter)
+	}
+
+	limits.now = limits.now.Add(time.Minute)
+	if ok, _, _ := limiter.Allow("login:1.2.3.4", limit); !ok {
+		t.Error("new window must allow requests")
+	}
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"crypto-analytics/internal/models"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type MailSender interface {
+	Send(ctx context.Context, msg models.MailMessage) error
+}
+
+// SMTPMailSender отправляет письма через SMTP-сервер (STARTTLS, если сервер его предлагает)
+type SMTPMailSender struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+}
+
+func NewSMTPMailSender(host, port, username, password, from string) *SMTPMailSender {
+	return &SMTPMailSender{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+func (s *SMTPMailSender) Send(ctx context.Context, msg models.MailMessage) error {
+	var auth smtp.Auth
+	if s.username != "" {
+		auth = smtp.PlainAuth("", s.username, s.password, s.host)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(net.JoinHostPort(s.host, s.port), auth, s.from, []string{msg.To}, buildMessage(s.from, msg))
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("smtp send: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// FileMailSender складывает письма в каталог в виде .eml — для локальной разработки
+type FileMailSender struct {
+	dir  string
+	from string
+}
+
+func NewFileMailSender(dir, from string) *FileMailSender {
+	return &FileMailSender{dir: dir, from: from}
+}
+
+func (s *FileMailSender) Send(ctx context.Context, msg models.MailMessage) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("create mail dir: %w", err)
+	}
+
+	name := fmt.Sprintf("%s_%s.eml",
+		time.Now().UTC().Format("20060102T150405.000000000"),
+		sanitizeFileName(msg.To))
+	path := filepath.Join(s.dir, name)
+	if err := os.WriteFile(path, buildMessage(s.from, msg), 0644); err != nil {
+		return fmt.Errorf("write mail: %w", err)
+	}
+
+	slog.Info("Mail written to file", "to", msg.To, "subject", msg.Subject, "path", path)
+	return nil
+}
+
+// MemoryMailSender держит письма в памяти — для тестов
+type MemoryMailSender struct {
+	mu       sync.Mutex
+	messages []models.MailMessage
+}
+
+func NewMemoryMailSender() *MemoryMailSender {
+	return &MemoryMailSender{}
+}
+
+func (s *MemoryMailSender) Send(ctx context.Context, msg models.MailMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+func (s *MemoryMailSender) Messages() []models.MailMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]models.MailMessage, len(s.messages))
+	copy(out, s.messages)
+	return out
+}
+
+func buildMessage(from string, msg models.MailMessage) []byte {
+	noCRLF := strings.NewReplacer("\r", "", "\n", "")
+
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + noCRLF.Replace(msg.To) + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().UTC().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+func sanitizeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, s)
+}
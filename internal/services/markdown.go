@@ -0,0 +1,401 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Поддерживаемое подмножество Markdown:
+//   - абзацы через пустую строку, перенос строки внутри абзаца — <br>;
+//   - списки «- », «* », «+ » и «1. »;
+//   - блоки кода между ``` (с необязательным языком) и `код` в строке;
+//   - **жирный**, *курсив* и _курсив_;
+//   - [текст](https://...) и голые http(s)-ссылки;
+//   - @username — упоминание.
+//
+// Сырой HTML из текста не проходит: всё, что не разметка, экранируется.
+// Переводов строк между тегами нет — текст на странице выводится с white-space: pre-wrap.
+// Результат дополнительно чистится sanitizeHTML по белому списку.
+
+const linkRel = "nofollow noopener"
+
+var (
+	listItemPattern  = regexp.MustCompile(`^ {0,3}([-*+]|\d{1,9}[.)])\s+(.*)$`)
+	codeLangPattern  = regexp.MustCompile(`^[A-Za-z0-9+#-]{1,20}$`)
+	codeClassPattern = regexp.MustCompile(`^language-[A-Za-z0-9+#-]{1,20}$`)
+	startPattern     = regexp.MustCompile(`^\d{1,9}$`)
+	mentionAtPattern = regexp.MustCompile(`^@([^\s@,;:!?()\[\]{}<>"']{1,50})`)
+)
+
+// renderMarkdown переводит текст поста или комментария в безопасный HTML
+func renderMarkdown(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+
+	var b strings.Builder
+	var para []string
+	var items []string
+	listTag, listStart := "", ""
+
+	flushPara := func() {
+		if len(para) == 0 {
+			return
+		}
+		b.WriteString("<p>")
+		renderLines(&b, para)
+		b.WriteString("</p>")
+		para = nil
+	}
+	flushList := func() {
+		if len(items) == 0 {
+			return
+		}
+		b.WriteString("<" + listTag + listStart + ">")
+		for _, item := range items {
+			b.WriteString("<li>")
+			renderLines(&b, strings.Split(item, "\n"))
+			b.WriteString("</li>")
+		}
+		b.WriteString("</" + listTag + ">")
+		items, listTag, listStart = nil, "", ""
+	}
+
+	lines := strings.Split(src, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if fence, ok := strings.CutPrefix(trimmed, "```"); ok {
+			flushPara()
+			flushList()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			// Незакрытый блок кода идёт до конца текста
+			b.WriteString("<pre><code")
+			if lang := strings.TrimSpace(fence); codeLangPattern.MatchString(lang) {
+				b.WriteString(` class="language-` + lang + `"`)
+			}
+			b.WriteString(">" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>")
+			continue
+		}
+
+		if trimmed == "" {
+			flushPara()
+			flushList()
+			continue
+		}
+
+		if m := listItemPattern.FindStringSubmatch(line); m != nil {
+			tag, start := "ul", ""
+			if marker := m[1]; marker[0] >= '0' && marker[0] <= '9' {
+				tag = "ol"
+				if n := strings.TrimLeft(marker[:len(marker)-1], "0"); n != "" && n != "1" {
+					start = ` start="` + n + `"`
+				}
+			}
+			flushPara()
+			if tag != listTag {
+				flushList()
+				listTag, listStart = tag, start
+			}
+			items = append(items, m[2])
+			continue
+		}
+
+		// Строка с отступом продолжает пункт списка, без отступа — начинает абзац
+		if len(items) > 0 && line != trimmed && line[0] == ' ' {
+			items[len(items)-1] += "\n" + trimmed
+			continue
+		}
+		flushList()
+		para = append(para, line)
+	}
+	flushPara()
+	flushList()
+
+	return sanitizeHTML(b.String())
+}
+
+func renderLines(b *strings.Builder, lines []string) {
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("<br>")
+		}
+		renderInline(b, line, false)
+	}
+}
+
+// renderInline разбирает строчную разметку. Внутри текста ссылки другие ссылки не создаются.
+func renderInline(b *strings.Builder, s string, inLink bool) {
+	plain := 0
+	flush := func(end int) {
+		b.WriteString(html.EscapeString(s[plain:end]))
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]):
+			flush(i)
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+			plain = i
+			continue
+
+		case c == '`':
+			if j := strings.IndexByte(s[i+1:], '`'); j > 0 {
+				flush(i)
+				b.WriteString("<code>" + html.EscapeString(s[i+1:i+1+j]) + "</code>")
+				i += j + 2
+				plain = i
+				continue
+			}
+
+		case c == '*' && strings.HasPrefix(s[i:], "**"):
+			if j := strings.Index(s[i+2:], "**"); j > 0 {
+				flush(i)
+				b.WriteString("<strong>")
+				renderInline(b, s[i+2:i+2+j], inLink)
+				b.WriteString("</strong>")
+				i += j + 4
+				plain = i
+				continue
+			}
+
+		case c == '*' || c == '_':
+			if j := emphasisEnd(s, i); j > 0 {
+				flush(i)
+				b.WriteString("<em>")
+				renderInline(b, s[i+1:j], inLink)
+				b.WriteString("</em>")
+				i = j + 1
+				plain = i
+				continue
+			}
+
+		case c == '[' && !inLink:
+			if label, href, n, ok := parseLink(s[i:]); ok {
+				flush(i)
+				b.WriteString(`<a href="` + html.EscapeString(href) + `" rel="` + linkRel + `">`)
+				renderInline(b, label, true)
+				b.WriteString("</a>")
+				i += n
+				plain = i
+				continue
+			}
+
+		case (c == 'h' || c == 'H') && !inLink && wordStart(s, i):
+			if n := autolinkLen(s[i:]); n > 0 {
+				flush(i)
+				href := s[i : i+n]
+				b.WriteString(`<a href="` + html.EscapeString(href) + `" rel="` + linkRel + `">` + html.EscapeString(href) + "</a>")
+				i += n
+				plain = i
+				continue
+			}
+
+		case c == '@' && (i == 0 || !isWordChar(s[i-1]) && s[i-1] != '@'):
+			if m := mentionAtPattern.FindStringSubmatch(s[i:]); m != nil {
+				if name := trimMention(m[1]); name != "" {
+					flush(i)
+					b.WriteString(`<span class="mention">@` + html.EscapeString(name) + "</span>")
+					i += 1 + len(name)
+					plain = i
+					continue
+				}
+			}
+		}
+		i++
+	}
+	flush(len(s))
+}
+
+// emphasisEnd ищет закрывающий * или _ для открывающего s[i]. Подчёркивание внутри
+// слова (snake_case) курсивом не считается.
+func emphasisEnd(s string, i int) int {
+	c := s[i]
+	if i+1 >= len(s) || s[i+1] == ' ' || s[i+1] == c {
+		return -1
+	}
+	if c == '_' && !wordStart(s, i) {
+		return -1
+	}
+	for j := i + 2; j < len(s); j++ {
+		if c == '*' && s[j] == '*' && j+1 < len(s) && s[j+1] == '*' {
+			j++ // **жирный** внутри курсива
+			continue
+		}
+		if s[j] != c || s[j-1] == ' ' {
+			continue
+		}
+		if c == '_' && j+1 < len(s) && isWordChar(s[j+1]) {
+			continue
+		}
+		return j
+	}
+	return -1
+}
+
+// parseLink разбирает [текст](адрес) в начале s. Ссылка с небезопасным адресом
+// остаётся обычным текстом.
+func parseLink(s string) (label, href string, n int, ok bool) {
+	end := strings.IndexByte(s, ']')
+	if end < 1 || end+1 >= len(s) || s[end+1] != '(' {
+		return "", "", 0, false
+	}
+	paren := strings.IndexByte(s[end+2:], ')')
+	if paren < 1 {
+		return "", "", 0, false
+	}
+	href = strings.TrimSpace(s[end+2 : end+2+paren])
+	if !safeURL(href) {
+		return "", "", 0, false
+	}
+	return s[1:end], href, end + 3 + paren, true
+}
+
+// autolinkLen — длина голой http(s)-ссылки в начале s без завершающей пунктуации
+func autolinkLen(s string) int {
+	lower := strings.ToLower(s[:min(len(s), 8)])
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+		return 0
+	}
+	n := strings.IndexFunc(s, func(r rune) bool {
+		return r <= ' ' || r == '<' || r == '>' || r == '"' || r == '`'
+	})
+	if n < 0 {
+		n = len(s)
+	}
+	n = len(strings.TrimRight(s[:n], ".,;:!?)'*_"))
+	if !safeURL(s[:n]) {
+		return 0
+	}
+	return n
+}
+
+// safeURL пропускает http(s), mailto и пути сайта; javascript:, data: и прочие схемы — нет
+func safeURL(raw string) bool {
+	if raw == "" || strings.ContainsFunc(raw, func(r rune) bool { return r <= ' ' || r == 0x7f }) {
+		return false
+	}
+	if strings.HasPrefix(raw, "/") {
+		return !strings.HasPrefix(raw, "//") && !strings.HasPrefix(raw, "/\\")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.Host != ""
+	case "mailto":
+		return u.Opaque != ""
+	}
+	return false
+}
+
+func wordStart(s string, i int) bool {
+	return i == 0 || !isWordChar(s[i-1])
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func isASCIIPunct(c byte) bool {
+	return c >= '!' && c <= '/' || c >= ':' && c <= '@' || c >= '[' && c <= '`' || c >= '{' && c <= '~'
+}
+
+// allowedTags — белый список тегов, которые может содержать отрисованный текст
+var allowedTags = map[string]bool{
+	"p": true, "br": true, "strong": true, "em": true, "code": true, "pre": true,
+	"ul": true, "ol": true, "li": true, "a": true, "span": true,
+}
+
+// dropContentTags удаляются вместе с содержимым: их текст не предназначен для чтения
+var dropContentTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true,
+	"template": true, "textarea": true, "title": true, "noscript": true, "noembed": true,
+	"noframes": true, "xmp": true, "svg": true, "math": true,
+}
+
+// sanitizeHTML оставляет только теги и атрибуты из белого списка. Неизвестные теги
+// выбрасываются с сохранением текста, комментарии и doctype — целиком.
+func sanitizeHTML(src string) string {
+	z := html.NewTokenizer(strings.NewReader(src))
+	var b strings.Builder
+	skip := 0
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return b.String()
+
+		case html.TextToken:
+			if skip == 0 {
+				b.WriteString(html.EscapeString(string(z.Text())))
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken, html.EndTagToken:
+			tok := z.Token()
+			if dropContentTags[tok.Data] {
+				switch {
+				case tt == html.StartTagToken:
+					skip++
+				case tt == html.EndTagToken && skip > 0:
+					skip--
+				}
+				continue
+			}
+			if skip > 0 || !allowedTags[tok.Data] {
+				continue
+			}
+			if tt == html.EndTagToken {
+				if tok.Data != "br" {
+					b.WriteString("</" + tok.Data + ">")
+				}
+				continue
+			}
+
+			b.WriteString("<" + tok.Data)
+			for _, attr := range allowedAttrs(tok) {
+				fmt.Fprintf(&b, ` %s="%s"`, attr.Key, html.EscapeString(attr.Val))
+			}
+			b.WriteString(">")
+		}
+	}
+}
+
+func allowedAttrs(tok html.Token) []html.Attribute {
+	var out []html.Attribute
+	for _, attr := range tok.Attr {
+		if attr.Namespace != "" {
+			continue
+		}
+		keep := false
+		switch tok.Data + "." + attr.Key {
+		case "a.href":
+			keep = safeURL(attr.Val)
+		case "code.class":
+			keep = codeClassPattern.MatchString(attr.Val)
+		case "span.class":
+			keep = attr.Val == "mention"
+		case "ol.start":
+			keep = startPattern.MatchString(attr.Val)
+		}
+		if keep {
+			out = append(out, attr)
+		}
+	}
+	// Ссылки из пользовательского текста не передают вес и доступ к window.opener
+	if tok.Data == "a" {
+		out = append(out, html.Attribute{Key: "rel", Val: linkRel})
+	}
+	return out
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "paragraphs and line breaks",
+			src:  "first line\nsecond line\n\nnext paragraph",
+			want: "<p>first line<br>second line</p><p>next paragraph</p>",
+		},
+		{
+			name: "emphasis",
+			src:  "**bold** and *italic* and _also_, but snake_case_name stays. *a **b** c*",
+			want: "<p><strong>bold</strong> and <em>italic</em> and <em>also</em>, but snake_case_name stays. <em>a <strong>b</strong> c</em></p>",
+		},
+		{
+			name: "inline code is escaped",
+			src:  "use `<b>` and `a*b*c`",
+			want: "<p>use <code>&lt;b&gt;</code> and <code>a*b*c</code></p>",
+		},
+		{
+			name: "code block",
+			src:  "```go\nif a < b {\n\treturn\n}\n```",
+			want: "<pre><code class=\"language-go\">if a &lt; b {\n\treturn\n}</code></pre>",
+		},
+		{
+			name: "lists",
+			src:  "- one\n- two\n  continued\n\n3. three\n4. four",
+			want: "<ul><li>one</li><li>two<br>continued</li></ul><ol start=\"3\"><li>three</li><li>four</li></ol>",
+		},
+		{
+			name: "links",
+			src:  "[docs](https://example.com/a?b=1&c=2) and https://binance.com.",
+			want: "<p><a href=\"https://example.com/a?b=1&amp;c=2\" rel=\"nofollow noopener\">docs</a> and " +
+				"<a href=\"https://binance.com\" rel=\"nofollow noopener\">https://binance.com</a>.</p>",
+		},
+		{
+			name: "site path link",
+			src:  "[BTC](/static/analysis.html?pair=BTCUSDT)",
+			want: "<p><a href=\"/static/analysis.html?pair=BTCUSDT\" rel=\"nofollow noopener\">BTC</a></p>",
+		},
+		{
+			name: "mention",
+			src:  "cc @satoshi.",
+			want: "<p>cc <span class=\"mention\">@satoshi</span>.</p>",
+		},
+		{
+			name: "escaped markup",
+			src:  `\*not italic\* 1 < 2 & "quotes"`,
+			want: "<p>*not italic* 1 &lt; 2 &amp; &#34;quotes&#34;</p>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderMarkdown(tt.src); got != tt.want {
+				t.Errorf("renderMarkdown(%q)\n got: %q\nwant: %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+// xssCorpus — приёмы из шпаргалок OWASP и типовые обходы фильтров
+var xssCorpus = []string{
+	`<script>alert(1)</script>`,
+	`<SCRIPT SRC=http://evil.example/xss.js></SCRIPT>`,
+	`<img src=x onerror=alert(1)>`,
+	`<svg/onload=alert(1)>`,
+	`<iframe src="javascript:alert(1)"></iframe>`,
+	`<a href="javascript:alert(1)">click</a>`,
+	`<body onload=alert(1)>`,
+	`<div style="background:url(javascript:alert(1))">x</div>`,
+	`"><script>alert(1)</script>`,
+	`<<script>alert(1);//<</script>`,
+	`<scr<script>ipt>alert(1)</script>`,
+	`<!--<script>alert(1)</script>-->`,
+	`[click](javascript:alert(1))`,
+	`[click](JaVaScRiPt:alert(1))`,
+	`[click](java	script:alert(1))`,
+	`[click](&#106;avascript:alert(1))`,
+	`[click](data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==)`,
+	`[click](vbscript:msgbox(1))`,
+	`[click](//evil.example/x)`,
+	`[click](https://ok.example/" onmouseover="alert(1))`,
+	`[<img src=x onerror=alert(1)>](https://ok.example)`,
+	"[x](https://ok.example)<script>alert(1)</script>",
+	"```\n</code></pre><script>alert(1)</script>\n```",
+	"```\"><script>alert(1)</script>\nx\n```",
+	"`<script>alert(1)</script>`",
+	"**<script>alert(1)</script>**",
+	"- <img src=x onerror=alert(1)>",
+	`https://ok.example/"onmouseover="alert(1)`,
+	`https://ok.example/<script>alert(1)</script>`,
+	`@"><script>alert(1)</script>`,
+	`<a href="https://ok.example" target="_blank" onclick="alert(1)">x</a>`,
+	`<math><mtext><table><mglyph><style><img src=x onerror=alert(1)>`,
+	`<form action="javascript:alert(1)"><button>x</button></form>`,
+	`<meta http-equiv="refresh" content="0;url=javascript:alert(1)">`,
+}
+
+func TestRenderMarkdown_XSS(t *testing.T) {
+	for _, src := range xssCorpus {
+		got := renderMarkdown(src)
+		if reason := unsafeHTML(got); reason != "" {
+			t.Errorf("renderMarkdown(%q) = %q: %s", src, got, reason)
+		}
+		// Уже очищенный HTML повторная очистка не меняет
+		if again := sanitizeHTML(got); again != got {
+			t.Errorf("sanitizeHTML is not idempotent for %q:\n%q\n%q", src, got, again)
+		}
+	}
+}
+
+func TestSanitizeHTML(t *testing.T) {
+	for _, src := range xssCorpus {
+		got := sanitizeHTML(src)
+		if reason := unsafeHTML(got); reason != "" {
+			t.Errorf("sanitizeHTML(%q) = %q: %s", src, got, reason)
+		}
+	}
+
+	got := sanitizeHTML(`<p onclick="x">a <a href="https://ok.example" rel="opener">b</a><script>c</script> d</p>`)
+	want := `<p>a <a href="https://ok.example" rel="nofollow noopener">b</a> d</p>`
+	if got != want {
+		t.Errorf("sanitizeHTML\n got: %q\nwant: %q", got, want)
+	}
+}
+
+// unsafeHTML разбирает результат так же, как браузер, и возвращает причину,
+// если в нём есть тег или атрибут вне белого списка
+func unsafeHTML(s string) string {
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.CommentToken, html.DoctypeToken:
+			return "comment or doctype survived"
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			if !allowedTags[tok.Data] {
+				return "tag <" + tok.Data + "> is not allowed"
+			}
+			rel := ""
+			for _, attr := range tok.Attr {
+				switch {
+				case tok.Data == "a" && attr.Key == "href":
+					if !safeURL(attr.Val) {
+						return "unsafe href " + attr.Val
+					}
+				case tok.Data == "a" && attr.Key == "rel":
+					rel = attr.Val
+				case tok.Data == "code" && attr.Key == "class",
+					tok.Data == "span" && attr.Key == "class",
+					tok.Data == "ol" && attr.Key == "start":
+				default:
+					return "attribute " + attr.Key + " on <" + tok.Data + "> is not allowed"
+				}
+			}
+			if tok.Data == "a" && rel != linkRel {
+				return "link without rel=" + linkRel
+			}
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/storage"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	maxReportDetails = 500
+	maxBanDuration   = 365 * 24 * time.Hour
+)
+
+// ModerationService — жалобы пользователей, очередь модератора и баны
+type ModerationService struct {
+	posts   *PostsService
+	reports storage.ReportStorage
+	users   storage.UserStorage
+	modLog  storage.ModerationLogStorage
+	now     func() time.Time
+}
+
+func NewModerationService(
+	posts *PostsService,
+	reports storage.ReportStorage,
+	users storage.UserStorage,
+	modLog storage.ModerationLogStorage,
+) *ModerationService {
+	return &ModerationService{
+		posts:   posts,
+		reports: reports,
+		users:   users,
+		modLog:  modLog,
+		now:     time.Now,
+	}
+}
+
+// Report ставит пост или комментарий в очередь модератора. Жаловаться на своё нельзя,
+// повторная жалоба того же пользователя на тот же объект ничего не меняет.
+func (s *ModerationService) Report(
+	ctx context.Context,
+	reporter *models.User,
+	target models.ModerationTarget,
+	id bson.ObjectID,
+	reason models.ReportReason,
+	details string,
+) error {
+	if err := checkWriter(reporter); err != nil {
+		return err
+	}
+	if !reason.Valid() {
+		return fmt.Errorf("%w: unknown reason %q", ErrInvalidReport, reason)
+	}
+	details = strings.TrimSpace(details)
+	if utf8.RuneCountInString(details) > maxReportDetails {
+		return fmt.Errorf("%w: details exceed %d characters", ErrInvalidReport, maxReportDetails)
+	}
+
+	var authorID int64
+	switch target {
+	case models.ModerationTargetPost:
+		post, err := s.posts.postStorage.GetPost(ctx, id, reporter.ID)
+		if err != nil {
+			return notFoundAs(err, ErrPostNotFound)
+		}
+		authorID = post.AuthorID
+	case models.ModerationTargetComment:
+		comment, err := s.posts.postStorage.GetComment(ctx, id, reporter.ID)
+		if err != nil {
+			return notFoundAs(err, ErrCommentNotFound)
+		}
+		authorID = comment.AuthorID
+	default:
+		return fmt.Errorf("%w: unknown target %q", ErrInvalidReport, target)
+	}
+	if authorID == reporter.ID {
+		return ErrSelfReport
+	}
+
+	report := &models.Report{
+		ReporterID: reporter.ID,
+		Target:     target,
+		TargetID:   id.Hex(),
+		Reason:     reason,
+		Details:    details,
+	}
+	if err := s.reports.CreateReport(report); err != nil {
+		return err
+	}
+	slog.Info("Content reported",
+		"reporter_id", reporter.ID,
+		"target", target,
+		"target_id", report.TargetID,
+		"reason", reason)
+	return nil
+}
+
+// ReportQueue — открытые жалобы, сгруппированные по объектам
+func (s *ModerationService) ReportQueue(limit int) ([]models.ReportQueueItem, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	return s.reports.ListOpenReports(limit)
+}
+
+// ResolveReports закрывает жалобы на объект: remove скрывает его (через Moderate,
+// с записью в журнал), dismiss оставляет как есть
+func (s *ModerationService) ResolveReports(
+	ctx context.Context,
+	actor *models.User,
+	target models.ModerationTarget,
+	id bson.ObjectID,
+	action models.ModerationAction,
+	note string,
+) error {
+	if actor == nil || !actor.Role.AtLeast(models.RoleModerator) {
+		return ErrNotModerator
+	}
+
+	switch action {
+	case models.ModerationRemove:
+		if err := s.posts.Moderate(ctx, actor, target, id, action, note); err != nil {
+			return err
+		}
+		_, err := s.reports.CloseReports(target, id.Hex(), models.ReportResolved, actor.ID)
+		return err
+
+	case models.ModerationDismiss:
+		note, err := moderationReason(note)
+		if err != nil {
+			return err
+		}
+		n, err := s.reports.CloseReports(target, id.Hex(), models.ReportDismissed, actor.ID)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrNoOpenReports
+		}
+		return s.logAction(actor, action, target, id.Hex(), note)
+
+	default:
+		return fmt.Errorf("%w: unknown action %q", ErrInvalidModeration, action)
+	}
+}
+
+// BanUser запрещает пользователю писать на duration. Теневой бан, если он есть, сохраняется.
+func (s *ModerationService) BanUser(actor *models.User, userID int64, duration time.Duration, reason string) error {
+	if duration <= 0 || duration > maxBanDuration {
+		return fmt.Errorf("%w: ban duration must be up to %d days", ErrInvalidModeration, int(maxBanDuration.Hours()/24))
+	}
+	user, reason, err := s.banTarget(actor, userID, reason)
+	if err != nil {
+		return err
+	}
+	until := s.now().UTC().Add(duration)
+	if err := s.users.SetBan(userID, &until, user.ShadowBanned); err != nil {
+		return err
+	}
+	slog.Info("User banned", "user_id", userID, "until", until)
+	return s.logAction(actor, models.ModerationBan, models.ModerationTargetUser, strconv.FormatInt(userID, 10), reason)
+}
+
+// ShadowBan скрывает всё, что пользователь напишет дальше, от всех, кроме него самого.
+// Уже опубликованное не трогается — для этого есть Moderate.
+func (s *ModerationService) ShadowBan(actor *models.User, userID int64, reason string) error {
+	user, reason, err := s.banTarget(actor, userID, reason)
+	if err != nil {
+		return err
+	}
+	if err := s.users.SetBan(userID, user.BannedUntil, true); err != nil {
+		return err
+	}
+	return s.logAction(actor, models.ModerationShadow, models.ModerationTargetUser, strconv.FormatInt(userID, 10), reason)
+}
+
+// Unban снимает и временный, и теневой бан
+func (s *ModerationService) Unban(actor *models.User, userID int64, reason string) error {
+	_, reason, err := s.banTarget(actor, userID, reason)
+	if err != nil {
+		return err
+	}
+	if err := s.users.SetBan(userID, nil, false); err != nil {
+		return err
+	}
+	return s.logAction(actor, models.ModerationUnban, models.ModerationTargetUser, strconv.FormatInt(userID, 10), reason)
+}
+
+// banTarget проверяет, что actor может ограничивать userID: модератор банит только
+// обычных пользователей, администратор — ещё и модераторов
+func (s *ModerationService) banTarget(actor *models.User, userID int64, reason string) (*models.User, string, error) {
+	if actor == nil || !actor.Role.AtLeast(models.RoleModerator) {
+		return nil, "", ErrNotModerator
+	}
+	reason, err := moderationReason(reason)
+	if err != nil {
+		return nil, "", err
+	}
+	if actor.ID == userID {
+		return nil, "", ErrBanNotAllowed
+	}
+	user, err := s.users.GetUserByID(userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if user.Role.AtLeast(actor.Role) {
+		return nil, "", ErrBanNotAllowed
+	}
+	return user, reason, nil
+}
+
+func moderationReason(reason string) (string, error) {
+	reason = strings.TrimSpace(reason)
+	if utf8.RuneCountInString(reason) > maxModerationReason {
+		return "", fmt.Errorf("%w: reason exceeds %d characters", ErrInvalidModeration, maxModerationReason)
+	}
+	return reason, nil
+}
+
+func (s *ModerationService) logAction(
+	actor *models.User,
+	action models.ModerationAction,
+	target models.ModerationTarget,
+	targetID string,
+	reason string,
+) error {
+	entry := &models.ModerationEntry{
+		ActorID:  actor.ID,
+		Action:   action,
+		Target:   target,
+		TargetID: targetID,
+		Reason:   reason,
+	}
+	if err := s.modLog.LogModeration(entry); err != nil {
+		return err
+	}
+	slog.Info("Moderation action",
+		"actor_id", actor.ID,
+		"action", action,
+		"target", target,
+		"target_id", targetID)
+	return nil
+}
+
+var (
+	ErrInvalidReport = errors.New("invalid report")
+	ErrSelfReport    = errors.New("you cannot report your own content")
+	ErrNoOpenReports = errors.New("no open reports for this item")
+	ErrBanNotAllowed = errors.New("you cannot ban this user")
+)
@@ -0,0 +1,378 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/storage"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type MockReportStorage struct {
+	Reports []models.Report
+}
+
+func (m *MockReportStorage) CreateReport(report *models.Report) error {
+	for _, r := range m.Reports {
+		if r.Status == models.ReportOpen && r.ReporterID == report.ReporterID &&
+			r.Target == report.Target && r.TargetID == report.TargetID {
+			return nil
+		}
+	}
+	report.ID = int64(len(m.Reports) + 1)
+	report.Status = models.ReportOpen
+	m.Reports = append(m.Reports, *report)
+	return nil
+}
+
+func (m *MockReportStorage) ListOpenReports(limit int) ([]models.ReportQueueItem, error) {
+	var items []models.ReportQueueItem
+	index := map[string]int{}
+	for _, r := range m.Reports {
+		if r.Status != models.ReportOpen {
+			continue
+		}
+		key := string(r.Target) + "/" + r.TargetID
+		i, ok := index[key]
+		if !ok {
+			i = len(items)
+			index[key] = i
+			items = append(items, models.ReportQueueItem{Target: r.Target, TargetID: r.TargetID})
+		}
+		items[i].Count++
+		items[i].Reasons = append(items[i].Reasons, r.Reason)
+	}
+	return items, nil
+}
+
+func (m *MockReportStorage) CloseReports(target models.ModerationTarget, targetID string, status models.ReportStatus, actorID int64) (int, error) {
+	n := 0
+	for i := range m.Reports {
+		r := &m.Reports[i]
+		if r.Status == models.ReportOpen && r.Target == target && r.TargetID == targetID {
+			r.Status = status
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TestContentFilter(t *testing.T) {
+	filter, err := NewContentFilter([]string{
+		"scam",
+		"free money",
+		"лохотрон",
+		`/t\.me\/\w+/`,
+	})
+	if err != nil {
+		t.Fatalf("NewContentFilter: %v", err)
+	}
+
+	tests := []struct {
+		text    string
+		blocked bool
+	}{
+		{"This coin is a SCAM!", true},
+		{"scam", true},
+		{"Get FREE\n money now", true},
+		{"Это лохотрон.", true},
+		{"Join t.me/pumpgroup for signals", true},
+		{"s\u200bcam hidden with a zero-width space", true},
+		{"Scampi and escaped text are fine", false},
+		{"Free of charge, money back", false},
+		{"Нелохотронный текст", false},
+		{"Read the docs at telegram.org", false},
+	}
+	for _, tt := range tests {
+		err := filter.Check(tt.text)
+		if blocked := errors.Is(err, ErrBannedContent); blocked != tt.blocked {
+			t.Errorf("Check(%q): blocked=%v, want %v", tt.text, blocked, tt.blocked)
+		}
+	}
+
+	var disabled *ContentFilter
+	if err := disabled.Check("scam"); err != nil {
+		t.Errorf("nil filter must allow everything, got %v", err)
+	}
+	if _, err := NewContentFilter([]string{"/(unclosed/"}); err == nil {
+		t.Error("expected error for invalid regexp rule")
+	}
+}
+
+func TestPostsService_ContentRules(t *testing.T) {
+	verifiedAt := time.Now()
+	users := NewMockUserStorage()
+	newUser := func(name string, role models.Role) *models.User {
+		u := &models.User{Username: name, DisplayName: name, Email: name + "@example.com", EmailVerifiedAt: &verifiedAt, Role: role}
+		if err := users.CreateUser(u); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		u.Role = role
+		users.SetRole(u.ID, role)
+		return u
+	}
+	author, reader := newUser("author", models.RoleUser), newUser("reader", models.RoleUser)
+	moderator := newUser("mod", models.RoleModerator)
+
+	filter, _ := NewContentFilter([]string{"scam"})
+	store := &MockPostStorage{}
+	notifier := &MockCommentNotifier{}
+	limiter := NewRedisRateLimiter(NewMockRateLimitStorage())
+	s := NewPostService(store, &MockModerationLogStorage{}, nil, nil, nil, users, notifier, nil, filter, limiter, nil)
+	ctx := context.Background()
+
+	if _, err := s.CreatePost(ctx, author, models.PostDraft{Heading: "Hot tip", MainText: "Not a scam, promise"}); !errors.Is(err, ErrBannedContent) {
+		t.Errorf("expected ErrBannedContent for post, got %v", err)
+	}
+	postID, err := s.CreatePost(ctx, author, models.PostDraft{Heading: "BTC", MainText: "Looks bullish"})
+	if err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+	if _, err := s.CreateComment(ctx, reader, postID, bson.ObjectID{}, "scam!"); !errors.Is(err, ErrBannedContent) {
+		t.Errorf("expected ErrBannedContent for comment, got %v", err)
+	}
+	if err := s.UpdatePost(ctx, postID, author, "BTC", "Actually a scam"); !errors.Is(err, ErrBannedContent) {
+		t.Errorf("expected ErrBannedContent for edit, got %v", err)
+	}
+
+	// Отклонённые фильтром попытки лимит не расходуют: до него ещё postRateLimit-1 публикаций
+	for i := 1; i < postRateLimit.Requests; i++ {
+		if _, err := s.CreatePost(ctx, author, models.PostDraft{Heading: "BTC", MainText: "More"}); err != nil {
+			t.Fatalf("post %d: %v", i, err)
+		}
+	}
+	if _, err := s.CreatePost(ctx, author, models.PostDraft{Heading: "BTC", MainText: "Too much"}); !errors.Is(err, ErrPostingTooFast) {
+		t.Errorf("expected ErrPostingTooFast, got %v", err)
+	}
+	for i := 0; i <= postRateLimit.Requests; i++ {
+		if _, err := s.CreatePost(ctx, moderator, models.PostDraft{Heading: "Rules", MainText: "Read them"}); err != nil {
+			t.Fatalf("moderators are not rate limited: %v", err)
+		}
+	}
+
+	bannedUntil := time.Now().Add(time.Hour)
+	reader.BannedUntil = &bannedUntil
+	if _, err := s.CreateComment(ctx, reader, postID, bson.ObjectID{}, "hello"); !errors.Is(err, ErrUserBanned) {
+		t.Errorf("expected ErrUserBanned, got %v", err)
+	}
+	if err := s.UpdatePost(ctx, postID, reader, "BTC", "Edited"); !errors.Is(err, ErrUserBanned) {
+		t.Errorf("expected ErrUserBanned for post edit, got %v", err)
+	}
+	if err := s.UpdateComment(ctx, bson.NewObjectID(), reader, "Edited"); !errors.Is(err, ErrUserBanned) {
+		t.Errorf("expected ErrUserBanned for comment edit, got %v", err)
+	}
+	expired := time.Now().Add(-time.Minute)
+	reader.BannedUntil = &expired
+	if _, err := s.CreateComment(ctx, reader, postID, bson.ObjectID{}, "hello"); err != nil {
+		t.Errorf("expired ban must not block: %v", err)
+	}
+
+	// Теневой бан: пишет как обычно, но видит написанное только сам
+	reader.ShadowBanned = true
+	notifier.Notices = nil
+	if _, err := s.CreateComment(ctx, reader, postID, bson.ObjectID{}, "buy my course"); err != nil {
+		t.Fatalf("shadow banned comment: %v", err)
+	}
+	if len(notifier.Notices) != 0 {
+		t.Errorf("shadowed comment must not notify anyone, got %v", notifier.Notices)
+	}
+	shadowPost, err := s.CreatePost(ctx, reader, models.PostDraft{Heading: "Course", MainText: "Buy it"})
+	if err != nil {
+		t.Fatalf("shadow banned post: %v", err)
+	}
+
+	visible := func(viewerID int64) (posts, comments int) {
+		page, err := s.ListPosts(ctx, models.PostQuery{Limit: maxPageSize, ViewerID: viewerID})
+		if err != nil {
+			t.Fatalf("list posts: %v", err)
+		}
+		for _, p := range page.Posts {
+			if p.ID == shadowPost {
+				posts++
+			}
+		}
+		tree, err := s.CommentTree(ctx, postID, viewerID)
+		if err != nil {
+			t.Fatalf("comment tree: %v", err)
+		}
+		return posts, len(tree)
+	}
+	if posts, comments := visible(reader.ID); posts != 1 || comments != 2 {
+		t.Errorf("author must see own shadowed content, got %d posts and %d comments", posts, comments)
+	}
+	for _, viewer := range []int64{0, author.ID} {
+		if posts, comments := visible(viewer); posts != 0 || comments != 1 {
+			t.Errorf("viewer %d must not see shadowed content, got %d posts and %d comments", viewer, posts, comments)
+		}
+	}
+}
+
+func TestModerationService_Reports(t *testing.T) {
+	verifiedAt := time.Now()
+	author := &models.User{ID: 1, DisplayName: "author", EmailVerifiedAt: &verifiedAt}
+	reporter := &models.User{ID: 2, DisplayName: "reporter", EmailVerifiedAt: &verifiedAt}
+	other := &models.User{ID: 3, DisplayName: "other", EmailVerifiedAt: &verifiedAt}
+	moderator := &models.User{ID: 4, DisplayName: "mod", Role: models.RoleModerator, EmailVerifiedAt: &verifiedAt}
+
+	store := &MockPostStorage{}
+	modLog := &MockModerationLogStorage{}
+	reports := &MockReportStorage{}
+	posts := NewPostService(store, modLog, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	s := NewModerationService(posts, reports, NewMockUserStorage(), modLog)
+	ctx := context.Background()
+
+	postID, err := posts.CreatePost(ctx, author, models.PostDraft{Heading: "Pump", MainText: "Buy now"})
+	if err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+	commentID, err := posts.CreateComment(ctx, author, postID, bson.ObjectID{}, "Seriously")
+	if err != nil {
+		t.Fatalf("create comment: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		user   *models.User
+		target models.ModerationTarget
+		id     bson.ObjectID
+		reason models.ReportReason
+		want   error
+	}{
+		{"report post", reporter, models.ModerationTargetPost, postID, models.ReportScam, nil},
+		{"duplicate is ignored", reporter, models.ModerationTargetPost, postID, models.ReportSpam, nil},
+		{"second reporter", other, models.ModerationTargetPost, postID, models.ReportSpam, nil},
+		{"report comment", reporter, models.ModerationTargetComment, commentID, models.ReportAbuse, nil},
+		{"own content", author, models.ModerationTargetPost, postID, models.ReportSpam, ErrSelfReport},
+		{"unknown reason", reporter, models.ModerationTargetPost, postID, "boring", ErrInvalidReport},
+		{"unknown target", reporter, "user", postID, models.ReportSpam, ErrInvalidReport},
+		{"missing post", reporter, models.ModerationTargetPost, bson.NewObjectID(), models.ReportSpam, ErrPostNotFound},
+		{"anonymous", nil, models.ModerationTargetPost, postID, models.ReportSpam, ErrMissingAuthor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.Report(ctx, tt.user, tt.target, tt.id, tt.reason, "details")
+			if !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	queue, err := s.ReportQueue(0)
+	if err != nil {
+		t.Fatalf("report queue: %v", err)
+	}
+	if len(queue) != 2 || queue[0].TargetID != postID.Hex() || queue[0].Count != 2 {
+		t.Fatalf("expected post with 2 reports and a comment in the queue, got %+v", queue)
+	}
+
+	if err := s.ResolveReports(ctx, reporter, models.ModerationTargetPost, postID, models.ModerationRemove, ""); !errors.Is(err, ErrNotModerator) {
+		t.Errorf("expected ErrNotModerator, got %v", err)
+	}
+	if err := s.ResolveReports(ctx, moderator, models.ModerationTargetPost, postID, models.ModerationRemove, "pump and dump"); err != nil {
+		t.Fatalf("resolve with remove: %v", err)
+	}
+	if _, err := store.GetPost(ctx, postID, 0); err == nil {
+		t.Error("reported post must be removed")
+	}
+	if err := s.ResolveReports(ctx, moderator, models.ModerationTargetComment, commentID, models.ModerationDismiss, "fine"); err != nil {
+		t.Fatalf("dismiss: %v", err)
+	}
+	if err := s.ResolveReports(ctx, moderator, models.ModerationTargetComment, commentID, models.ModerationDismiss, ""); !errors.Is(err, ErrNoOpenReports) {
+		t.Errorf("expected ErrNoOpenReports, got %v", err)
+	}
+	if queue, _ := s.ReportQueue(0); len(queue) != 0 {
+		t.Errorf("queue must be empty, got %+v", queue)
+	}
+
+	actions := []models.ModerationAction{}
+	for _, e := range modLog.Entries {
+		actions = append(actions, e.Action)
+	}
+	if len(actions) != 2 || actions[0] != models.ModerationRemove || actions[1] != models.ModerationDismiss {
+		t.Errorf("expected remove and dismiss in moderation log, got %v", actions)
+	}
+}
+
+func TestModerationService_Bans(t *testing.T) {
+	users := NewMockUserStorage()
+	newUser := func(name string, role models.Role) *models.User {
+		u := &models.User{Username: name, Email: name + "@example.com", Role: role}
+		if err := users.CreateUser(u); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		u.Role = role
+		users.SetRole(u.ID, role)
+		return u
+	}
+	user := newUser("user", models.RoleUser)
+	moderator := newUser("mod", models.RoleModerator)
+	otherModerator := newUser("mod2", models.RoleModerator)
+	admin := newUser("admin", models.RoleAdmin)
+
+	modLog := &MockModerationLogStorage{}
+	s := NewModerationService(nil, &MockReportStorage{}, users, modLog)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	tests := []struct {
+		name     string
+		actor    *models.User
+		userID   int64
+		duration time.Duration
+		want     error
+	}{
+		{"regular user cannot ban", user, otherModerator.ID, time.Hour, ErrNotModerator},
+		{"own account", moderator, moderator.ID, time.Hour, ErrBanNotAllowed},
+		{"moderator cannot ban moderator", moderator, otherModerator.ID, time.Hour, ErrBanNotAllowed},
+		{"zero duration", moderator, user.ID, 0, ErrInvalidModeration},
+		{"over a year", moderator, user.ID, maxBanDuration + time.Hour, ErrInvalidModeration},
+		{"unknown user", moderator, 999, time.Hour, storage.ErrUserNotFound},
+		{"admin bans moderator", admin, otherModerator.ID, time.Hour, nil},
+		{"moderator bans user", moderator, user.ID, 24 * time.Hour, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.BanUser(tt.actor, tt.userID, tt.duration, "spam")
+			if !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	stored := users.Users[user.ID]
+	if stored.BannedUntil == nil || !stored.BannedUntil.Equal(now.Add(24*time.Hour)) {
+		t.Fatalf("expected ban until %v, got %v", now.Add(24*time.Hour), stored.BannedUntil)
+	}
+	if err := s.BanUser(moderator, user.ID, time.Hour, strings.Repeat("x", maxModerationReason+1)); !errors.Is(err, ErrInvalidModeration) {
+		t.Errorf("expected ErrInvalidModeration for long reason, got %v", err)
+	}
+	if !stored.BannedUntil.Equal(now.Add(24 * time.Hour)) {
+		t.Error("rejected request must not change the ban")
+	}
+
+	if err := s.ShadowBan(moderator, user.ID, "bots"); err != nil {
+		t.Fatalf("shadow ban: %v", err)
+	}
+	if !stored.ShadowBanned || stored.BannedUntil == nil {
+		t.Error("shadow ban must keep the temporary ban")
+	}
+	if err := s.Unban(moderator, user.ID, "appeal"); err != nil {
+		t.Fatalf("unban: %v", err)
+	}
+	if stored.ShadowBanned || stored.BannedUntil != nil {
+		t.Errorf("unban must clear all restrictions, got %+v", stored)
+	}
+
+	if len(modLog.Entries) != 4 {
+		t.Fatalf("expected 4 moderation log entries, got %d", len(modLog.Entries))
+	}
+	last := modLog.Entries[3]
+	if last.Action != models.ModerationUnban || last.Target != models.ModerationTargetUser || last.TargetID != "1" {
+		t.Errorf("unexpected log entry: %+v", last)
+	}
+}
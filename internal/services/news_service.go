@@ -1,31 +1,54 @@
 package services
 
 import (
+	"context"
 	"crypto-analytics/internal/models"
 	"crypto-analytics/internal/storage"
+	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
 	"sort"
 	"time"
 
 	"github.com/mmcdole/gofeed"
 )
 
+const (
+	newsRefreshInterval = 3 * time.Hour
+	newsCheckInterval   = 5 * time.Minute
+	newsFetchTimeout    = 30 * time.Second
+	newsBackoffBase     = 5 * time.Minute
+	newsBackoffMax      = 24 * time.Hour
+	newsMaxFeedBytes    = 10 << 20
+	newsMaxDescRunes    = 500
+)
+
 type NewsService struct {
 	feeds        map[string]string
 	store        storage.NewsStorage
+	states       storage.FeedStateStorage
+	client       *http.Client
 	fetchEnabled bool
+	events       EventPublisher
+	now          func() time.Time
 }
 
-func NewNewsService(store storage.NewsStorage, fetchEnabled bool) *NewsService {
+func NewNewsService(store storage.NewsStorage, states storage.FeedStateStorage, fetchEnabled bool, events EventPublisher) *NewsService {
 	service := &NewsService{
 		feeds: map[string]string{
 			"https://cointelegraph.com/rss":                   "cointelegraph",
 			"https://www.coindesk.com/arc/outboundfeeds/rss/": "coindesk",
 		},
 		store:        store,
+		states:       states,
+		client:       &http.Client{Timeout: newsFetchTimeout},
 		fetchEnabled: fetchEnabled,
+		events:       events,
+		now:          time.Now,
 	}
 
+	service.normalizeStoredNews()
 	go service.startBackgroundUpdates()
 
 	return service
@@ -34,7 +57,9 @@ func NewNewsService(store storage.NewsStorage, fetchEnabled bool) *NewsService {
 func (n *NewsService) startBackgroundUpdates() {
 	n.updateNews()
 
-	ticker := time.NewTicker(3 * time.Hour)
+	// Тикер частый, но каждый фид сам решает, пора ли его опрашивать:
+	// успешные фиды обновляются раз в newsRefreshInterval, упавшие — по бэкоффу.
+	ticker := time.NewTicker(newsCheckInterval)
 	defer ticker.Stop()
 
 	for range ticker.C {
@@ -46,49 +71,208 @@ func (n *NewsService) updateNews() {
 	if !n.fetchEnabled {
 		return
 	}
-	slog.Info("Starting news update...")
 
-	newsItems, err := n.fetchNewsFromFeeds()
-	if err != nil {
-		slog.Error("Error fetching news", "error", err)
+	newsItems := n.fetchNewsFromFeeds()
+	if len(newsItems) == 0 {
 		return
 	}
 
-	if err := n.store.UpdateNews(newsItems); err != nil {
-
+	stored, err := n.store.GetAllNews()
+	if err != nil {
+		slog.Warn("Cannot load stored news", "error", err)
+	}
+	added, err := n.store.UpdateNews(newsItems)
+	if err != nil {
 		slog.Error("Error saving news", "error", err)
 		return
 	}
 
 	slog.Info("Successfully updated news items",
-		"amount", len(newsItems))
+		"amount", len(newsItems), "new", len(added))
+
+	n.publishIngested(len(stored) > 0, added)
 }
 
-func (n *NewsService) fetchNewsFromFeeds() ([]models.NewsItem, error) {
+// publishIngested сообщает подписчикам о новых новостях. На пустом кэше
+// (первый запуск, потерянный файл) новым оказался бы весь фид — молчим.
+func (n *NewsService) publishIngested(hadNews bool, added []models.NewsItem) {
+	if n.events == nil || !hadNews {
+		return
+	}
+	for _, item := range added {
+		n.events.PublishEvent(models.WebhookNewsIngested, map[string]any{
+			"guid":        item.GUID,
+			"title":       item.Title,
+			"link":        item.Link,
+			"source":      item.Source,
+			"publishedAt": item.PublishedAt,
+		})
+	}
+}
+
+func (n *NewsService) fetchNewsFromFeeds() []models.NewsItem {
 	var allNews []models.NewsItem
-	fp := gofeed.NewParser()
+
+	states, err := n.states.GetFeedStates()
+	if err != nil {
+		slog.Warn("Cannot load feed states, fetching unconditionally", "error", err)
+		states = map[string]models.FeedState{}
+	}
 
 	for url, source := range n.feeds {
-		feed, err := fp.ParseURL(url)
+		state, ok := states[url]
+		if !ok {
+			state = models.FeedState{URL: url}
+		}
+		if n.now().Before(state.NextAttempt) {
+			continue
+		}
+
+		items, err := n.fetchFeed(url, source, &state)
 		if err != nil {
-			slog.Warn("Warning: cannot parse feed from", "error", err)
-			continue // Продолжаем с другими фидами при ошибке
+			state.Failures++
+			state.LastError = err.Error()
+			state.NextAttempt = n.now().Add(feedBackoff(state.Failures))
+			slog.Warn("Cannot fetch feed",
+				"url", url,
+				"failures", state.Failures,
+				"next_attempt", state.NextAttempt.Format(time.RFC3339),
+				"error", err)
+		} else {
+			state.Failures = 0
+			state.LastError = ""
+			state.LastFetched = n.now()
+			state.NextAttempt = n.now().Add(newsRefreshInterval)
+			allNews = append(allNews, items...)
+		}
+
+		if err := n.states.SaveFeedState(state); err != nil {
+			slog.Warn("Cannot save feed state", "url", url, "error", err)
+		}
+	}
+
+	return allNews
+}
+
+// fetchFeed делает условный запрос к фиду. При 304 возвращает пустой список
+// без ошибки, ETag и Last-Modified из ответа сохраняются в state.
+func (n *NewsService) fetchFeed(url, source string, state *models.FeedState) ([]models.NewsItem, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), newsFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "crypto-analytics/1.0 (+news aggregator)")
+	if state.ETag != "" {
+		req.Header.Set("If-None-Match", state.ETag)
+	}
+	if state.LastModified != "" {
+		req.Header.Set("If-Modified-Since", state.LastModified)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		slog.Debug("Feed not modified", "url", url)
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	feed, err := gofeed.NewParser().Parse(io.LimitReader(resp.Body, newsMaxFeedBytes))
+	if err != nil {
+		return nil, fmt.Errorf("parse feed: %w", err)
+	}
+
+	state.ETag = resp.Header.Get("ETag")
+	state.LastModified = resp.Header.Get("Last-Modified")
+
+	fetchedAt := n.now()
+	items := make([]models.NewsItem, 0, len(feed.Items))
+	for _, item := range feed.Items {
+		items = append(items, models.NewsItem{
+			GUID:        item.GUID,
+			Title:       htmlToText(item.Title, 0),
+			Description: htmlToText(item.Description, newsMaxDescRunes),
+			Link:        item.Link,
+			PublishedAt: n.normalizePublished(item, fetchedAt),
+			Source:      source,
+		})
+	}
+
+	return items, nil
+}
+
+// normalizePublished приводит дату публикации к RFC3339 в UTC.
+// Если фид не прислал дату вовсе, используется время получения.
+func (n *NewsService) normalizePublished(item *gofeed.Item, fetchedAt time.Time) string {
+	switch {
+	case item.PublishedParsed != nil:
+		return item.PublishedParsed.UTC().Format(time.RFC3339)
+	case item.UpdatedParsed != nil:
+		return item.UpdatedParsed.UTC().Format(time.RFC3339)
+	}
+	if t := n.parseTimeWithFallback(item.Published); !t.IsZero() {
+		return t.UTC().Format(time.RFC3339)
+	}
+	return fetchedAt.UTC().Format(time.RFC3339)
+}
+
+// normalizeStoredNews один раз прогоняет уже сохранённые новости через
+// те же преобразования, что и новые, чтобы в кэше не оставалось сырого HTML.
+func (n *NewsService) normalizeStoredNews() {
+	news, err := n.store.GetAllNews()
+	if err != nil {
+		slog.Warn("Cannot load stored news for normalization", "error", err)
+		return
+	}
+
+	changed := false
+	for i := range news {
+		title := htmlToText(news[i].Title, 0)
+		desc := htmlToText(news[i].Description, newsMaxDescRunes)
+		published := news[i].PublishedAt
+		if t := n.parseTimeWithFallback(published); !t.IsZero() {
+			published = t.UTC().Format(time.RFC3339)
 		}
 
-		for _, item := range feed.Items {
-			newsItem := models.NewsItem{
-				GUID:        item.GUID,
-				Title:       item.Title,
-				Description: item.Description,
-				Link:        item.Link,
-				PublishedAt: item.Published,
-				Source:      source,
-			}
-			allNews = append(allNews, newsItem)
+		if title != news[i].Title || desc != news[i].Description || published != news[i].PublishedAt {
+			news[i].Title = title
+			news[i].Description = desc
+			news[i].PublishedAt = published
+			changed = true
 		}
 	}
 
-	return allNews, nil
+	if !changed {
+		return
+	}
+	if err := n.store.ReplaceNews(news); err != nil {
+		slog.Warn("Cannot save normalized news", "error", err)
+		return
+	}
+	slog.Info("Normalized stored news items", "amount", len(news))
+}
+
+func feedBackoff(failures int) time.Duration {
+	if failures < 1 {
+		return newsBackoffBase
+	}
+	delay := newsBackoffBase
+	for i := 1; i < failures; i++ {
+		delay *= 2
+		if delay >= newsBackoffMax {
+			return newsBackoffMax
+		}
+	}
+	return delay
 }
 
 func (n *NewsService) GetNews() ([]models.NewsItem, error) {
@@ -114,11 +298,11 @@ func (n *NewsService) parseTimeWithFallback(timeStr string) time.Time {
 	}
 
 	formats := []string{
+		time.RFC3339,
 		time.RFC1123,
 		time.RFC1123Z,
 		time.RFC822,
 		time.RFC822Z,
-		time.RFC3339,
 		"Mon, 2 Jan 2006 15:04:05 MST",
 		"Mon, 2 Jan 2006 15:04:05 -0700",
 		"02 Jan 2006 15:04:05 MST",
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"crypto-analytics/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type MockFeedStateStorage struct {
+	States map[string]models.FeedState
+}
+
+func (m *MockFeedStateStorage) GetFeedStates() (map[string]models.FeedState, error) {
+	out := make(map[string]models.FeedState, len(m.States))
+	for k, v := range m.States {
+		out[k] = v
+	}
+	return out, nil
+}
+
+func (m *MockFeedStateStorage) SaveFeedState(state models.FeedState) error {
+	m.States[state.URL] = state
+	return nil
+}
+
+const testRSS = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"><channel><title>t</title>
+<item>
+  <guid>id-1</guid>
+  <title>BTC &amp; ETH rally</title>
+  <description><![CDATA[<p style="x">Bitcoin <b>up</b> &amp; running</p><script>alert(1)</script><img src="x" onerror="y">]]></description>
+  <link>https://example.com/1</link>
+  <pubDate>Wed, 01 Oct 2025 16:59:12 +0100</pubDate>
+</item>
+</channel></rss>`
+
+func TestNewsService_FetchFeeds_ConditionalAndBackoff(t *testing.T) {
+	var requests []*http.Request
+	failing := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		if failing {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Wed, 01 Oct 2025 16:00:00 GMT")
+		w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+
+	now := time.Date(2025, 10, 2, 12, 0, 0, 0, time.UTC)
+	states := &MockFeedStateStorage{States: map[string]models.FeedState{}}
+	n := &NewsService{
+		feeds:        map[string]string{srv.URL: "test"},
+		states:       states,
+		client:       srv.Client(),
+		fetchEnabled: true,
+		now:          func() time.Time { return now },
+	}
+
+	items := n.fetchNewsFromFeeds()
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(items))
+	}
+	got := items[0]
+	if got.Title != "BTC & ETH rally" {
+		t.Errorf("unexpected title: %q", got.Title)
+	}
+	if got.Description != "Bitcoin up & running" {
+		t.Errorf("description was not sanitized: %q", got.Description)
+	}
+	if got.PublishedAt != "2025-10-01T15:59:12Z" {
+		t.Errorf("published_at not normalized: %q", got.PublishedAt)
+	}
+	if states.States[srv.URL].ETag != `"v1"` {
+		t.Errorf("etag not stored: %+v", states.States[srv.URL])
+	}
+
+	// Фид не должен запрашиваться раньше времени
+	if items := n.fetchNewsFromFeeds(); len(items) != 0 || len(requests) != 1 {
+		t.Fatalf("feed was refetched before refresh interval")
+	}
+
+	now = now.Add(newsRefreshInterval)
+	if items := n.fetchNewsFromFeeds(); len(items) != 0 {
+		t.Fatalf("expected no items on 304, got %d", len(items))
+	}
+	if h := requests[1].Header.Get("If-Modified-Since"); h != "Wed, 01 Oct 2025 16:00:00 GMT" {
+		t.Errorf("If-Modified-Since not sent: %q", h)
+	}
+
+	failing = true
+	now = now.Add(newsRefreshInterval)
+	n.fetchNewsFromFeeds()
+	state := states.States[srv.URL]
+	if state.Failures != 1 || !state.NextAttempt.Equal(now.Add(newsBackoffBase)) {
+		t.Errorf("unexpected state after failure: %+v", state)
+	}
+
+	now = now.Add(newsBackoffBase)
+	n.fetchNewsFromFeeds()
+	state = states.States[srv.URL]
+	if state.Failures != 2 || !state.NextAttempt.Equal(now.Add(2*newsBackoffBase)) {
+		t.Errorf("backoff did not grow: %+v", state)
+	}
+}
+
+func TestFeedBackoff_Capped(t *testing.T) {
+	if got := feedBackoff(50); got != newsBackoffMax {
+		t.Errorf("expected cap %v, got %v", newsBackoffMax, got)
+	}
+}
+
+func TestNewsService_PublishIngested(t *testing.T) {
+	events := &recordingPublisher{}
+	n := &NewsService{events: events}
+	added := []models.NewsItem{{GUID: "n1", Title: "BTC"}, {GUID: "n2", Title: "ETH"}}
+
+	// Пустой кэш до обновления: весь фид новый, событий нет
+	n.publishIngested(false, added)
+	if len(events.events) != 0 {
+		t.Fatalf("expected no events on an empty cache, got %v", events.events)
+	}
+
+	n.publishIngested(true, added)
+	if len(events.events) != 2 || events.events[0] != models.WebhookNewsIngested {
+		t.Fatalf("expected 2 news.ingested events, got %v", events.events)
+	}
+	if data := events.data[1].(map[string]any); data["guid"] != "n2" {
+		t.Errorf("unexpected payload: %v", data)
+	}
+}
@@ -1,33 +1,332 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
 	"log/slog"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/storage"
 )
 
-type NotifierStruct struct{}
+const (
+	deliveryInterval    = 10 * time.Second
+	deliveryBatch       = 50
+	deliveryLease       = 2 * time.Minute // дольше таймаута любого канала
+	deliveryTimeout     = 30 * time.Second
+	maxDeliveryAttempts = 8
+	retryBaseDelay      = 30 * time.Second
+	retryMaxDelay       = 6 * time.Hour
+	maxChannelAddress   = 2048
+)
+
+// Исход доставок по каналам: "<канал>:sent", "<канал>:retry", "<канал>:failed"
+var deliveryStats = expvar.NewMap("notification_deliveries")
+
+var telegramChatRe = regexp.MustCompile(`^(-?\d{1,20}|@[A-Za-z][A-Za-z0-9_]{4,31})$`)
+
+// NotificationService рассылает события администраторам по их каналам.
+// Уведомление не отправляется сразу, а кладётся в outbox в Postgres; фоновый
+// воркер доставляет его с повторами, так что недоступный Telegram или SMTP
+// не теряет событие и не тормозит запрос, который его вызвал.
+type NotificationService struct {
+	store    storage.NotificationStorage
+	users    storage.UserStorage
+	channels map[models.NotificationChannel]ChannelSender
+	events   EventPublisher
+}
+
+// NewNotificationService принимает адаптеры доступных каналов: канал без адаптера
+// нельзя выбрать в настройках
+func NewNotificationService(
+	store storage.NotificationStorage,
+	users storage.UserStorage,
+	channels map[models.NotificationChannel]ChannelSender,
+	events EventPublisher,
+) *NotificationService {
+	return &NotificationService{
+		store:    store,
+		users:    users,
+		channels: channels,
+		events:   events,
+	}
+}
+
+func (s *NotificationService) NotifyAdmContForm(contact *models.ContactForm) {
+	s.Publish(models.Notification{
+		Event:   models.EventContactForm,
+		Subject: "New contact form message",
+		Text:    contact.Message,
+		Fields: map[string]string{
+			"name":  contact.Name,
+			"email": contact.Email,
+			"ip":    contact.IP,
+		},
+	})
+}
 
-func NewNotifier() Notifier {
-	return &NotifierStruct{}
+func (s *NotificationService) NotifyAdmNewUserForm(user *models.User) {
+	s.Publish(models.Notification{
+		Event:   models.EventNewUser,
+		Subject: "New user registered",
+		Text:    fmt.Sprintf("User %s has signed up.", user.Username),
+		Fields: map[string]string{
+			"username": user.Username,
+			"email":    user.Email,
+			"ip":       user.SignupIP,
+		},
+	})
 }
 
+func (s *NotificationService) NotifyAdmSuspiciousLogin(event *models.AuthEvent, failures int64, lockFor time.Duration) {
+	s.Publish(models.Notification{
+		Event:   models.EventSecurityAlert,
+		Subject: "Login locked after repeated failures",
+		Text:    fmt.Sprintf("%d failed login attempts, login is locked for %s.", failures, lockFor),
+		Fields: map[string]string{
+			"login":      event.Login,
+			"ip":         event.IP,
+			"user_agent": event.UserAgent,
+		},
+	})
+	// Вебхуки администраторов получают то же событие для своих систем мониторинга
+	if s.events != nil {
+		s.events.PublishEvent(models.WebhookAlertTriggered, map[string]any{
+			"kind":      "login_locked",
+			"login":     event.Login,
+			"ip":        event.IP,
+			"userAgent": event.UserAgent,
+			"failures":  failures,
+			"lockedFor": lockFor.String(),
+		})
+	}
+}
+
+// Publish ставит уведомление в очередь каждому администратору во все каналы,
+// подписанные на событие. Администратор без настроек получает письмо на адрес аккаунта.
+func (s *NotificationService) Publish(n models.Notification) {
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now().UTC()
+	}
+	admins, err := s.users.ListUsersByRole(models.RoleAdmin)
+	if err != nil {
+		slog.Error("Failed to list notification recipients", "event", n.Event, "error", err)
+		return
+	}
+
+	var items []models.OutboxItem
+	for _, admin := range admins {
+		prefs, err := s.store.ListChannels(admin.ID)
+		if err != nil {
+			slog.Error("Failed to load notification channels", "user_id", admin.ID, "error", err)
+			continue
+		}
+		if len(prefs) == 0 && admin.Email != "" {
+			prefs = []models.ChannelPreference{{Channel: models.ChannelEmail, Address: admin.Email, Enabled: true}}
+		}
+		for _, p := range prefs {
+			if !p.Wants(n.Event) || s.channels[p.Channel] == nil {
+				continue
+			}
+			items = append(items, models.OutboxItem{
+				UserID:  admin.ID,
+				Channel: p.Channel,
+				Address: p.Address,
+				Message: n,
+			})
+		}
+	}
+	if len(items) == 0 {
+		slog.Warn("Notification has no recipients", "event", n.Event)
+		return
+	}
+	if err := s.store.Enqueue(items); err != nil {
+		slog.Error("Failed to enqueue notification", "event", n.Event, "error", err)
+	}
+}
+
+// StartDelivery разбирает outbox, пока работает приложение
+func (s *NotificationService) StartDelivery() {
+	ticker := time.NewTicker(deliveryInterval)
+	defer ticker.Stop()
 
-func (n *NotifierStruct) NotifyAdmContForm(contact *models.ContactForm) {
-	time.Sleep(100 * time.Millisecond)
-	slog.Info("<-> УВЕДОМЛЕНИЕ ДЛЯ АДМИНА <->",
-		"user_name", contact.Name,
-		"user_email", contact.Email,
-		"message", contact.Message,
-	)
+	for range ticker.C {
+		if _, err := s.DeliverDue(context.Background()); err != nil {
+			slog.Error("Failed to deliver notifications", "error", err)
+		}
+	}
 }
 
-func (n *NotifierStruct) NotifyAdmNewUserForm(contact *models.User) {
-	time.Sleep(100 * time.Millisecond) 
-	slog.Info("<-> УВЕДОМЛЕНИЕ ДЛЯ АДМИНА <->",
-		"username", contact.Username,
-		"email", contact.Email,
-		"event", "registration",
-	)
+// DeliverDue отправляет доставки, срок которых наступил, и возвращает число отправленных.
+// Неудачная доставка повторяется с экспоненциальной паузой, после maxDeliveryAttempts
+// или постоянной ошибки канала помечается failed.
+func (s *NotificationService) DeliverDue(ctx context.Context) (int, error) {
+	sent := 0
+	for {
+		items, err := s.store.ClaimDue(deliveryBatch, deliveryLease)
+		if err != nil {
+			return sent, err
+		}
+		for _, item := range items {
+			if s.deliver(ctx, item) {
+				sent++
+			}
+		}
+		if len(items) < deliveryBatch || ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+	}
+}
+
+func (s *NotificationService) deliver(ctx context.Context, item models.OutboxItem) bool {
+	sender := s.channels[item.Channel]
+	if sender == nil {
+		s.markFailed(item, fmt.Errorf("%w: channel %q is not configured", ErrUndeliverable, item.Channel))
+		return false
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	err := sender.Send(sendCtx, item.Address, item.Message)
+	cancel()
+	if err == nil {
+		if err := s.store.MarkSent(item.ID); err != nil {
+			slog.Error("Failed to mark notification sent", "id", item.ID, "error", err)
+		}
+		deliveryStats.Add(string(item.Channel)+":sent", 1)
+		return true
+	}
+
+	if errors.Is(err, ErrUndeliverable) || item.Attempts >= maxDeliveryAttempts {
+		s.markFailed(item, err)
+		return false
+	}
+	delay := retryDelay(item.Attempts)
+	slog.Warn("Notification delivery failed, will retry",
+		"id", item.ID, "channel", item.Channel, "attempt", item.Attempts, "retry_in", delay, "error", err)
+	if err := s.store.RetryLater(item.ID, err.Error(), delay); err != nil {
+		slog.Error("Failed to reschedule notification", "id", item.ID, "error", err)
+	}
+	deliveryStats.Add(string(item.Channel)+":retry", 1)
+	return false
 }
+
+func (s *NotificationService) markFailed(item models.OutboxItem, err error) {
+	slog.Error("Notification delivery failed",
+		"id", item.ID, "channel", item.Channel, "user_id", item.UserID, "attempts", item.Attempts, "error", err)
+	if err := s.store.MarkFailed(item.ID, err.Error()); err != nil {
+		slog.Error("Failed to mark notification failed", "id", item.ID, "error", err)
+	}
+	deliveryStats.Add(string(item.Channel)+":failed", 1)
+}
+
+// retryDelay — пауза перед следующей попыткой: 30 с, 1 мин, 2 мин… но не больше 6 ч
+func retryDelay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	if attempt > 16 {
+		return retryMaxDelay
+	}
+	return min(retryBaseDelay<<(attempt-1), retryMaxDelay)
+}
+
+func (s *NotificationService) ListChannels(userID int64) ([]models.ChannelPreference, error) {
+	return s.store.ListChannels(userID)
+}
+
+// SaveChannel проверяет и сохраняет канал пользователя. Из адреса убираются
+// пробелы по краям, у email — ещё и отображаемое имя.
+func (s *NotificationService) SaveChannel(user *models.User, pref models.ChannelPreference) (*models.ChannelPreference, error) {
+	if !pref.Channel.Valid() {
+		return nil, fmt.Errorf("%w: unknown channel %q", ErrInvalidChannel, pref.Channel)
+	}
+	if s.channels[pref.Channel] == nil {
+		return nil, fmt.Errorf("%w: channel %q is not available", ErrInvalidChannel, pref.Channel)
+	}
+	address, err := channelAddress(pref.Channel, pref.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]models.NotificationEvent, 0, len(pref.Events))
+	for _, e := range pref.Events {
+		if !e.Valid() {
+			return nil, fmt.Errorf("%w: unknown event %q", ErrInvalidChannel, e)
+		}
+		if !slices.Contains(events, e) {
+			events = append(events, e)
+		}
+	}
+
+	saved := &models.ChannelPreference{
+		UserID:  user.ID,
+		Channel: pref.Channel,
+		Address: address,
+		Events:  events,
+		Enabled: pref.Enabled,
+	}
+	if err := s.store.SaveChannel(saved); err != nil {
+		return nil, err
+	}
+	slog.Info("Notification channel saved", "user_id", user.ID, "channel", saved.Channel, "enabled", saved.Enabled)
+	return saved, nil
+}
+
+func (s *NotificationService) DeleteChannel(user *models.User, channel models.NotificationChannel) error {
+	err := s.store.DeleteChannel(user.ID, channel)
+	if errors.Is(err, storage.ErrChannelNotFound) {
+		return ErrChannelNotFound
+	}
+	return err
+}
+
+func channelAddress(channel models.NotificationChannel, address string) (string, error) {
+	address = strings.TrimSpace(address)
+	if address == "" {
+		return "", fmt.Errorf("%w: address is empty", ErrInvalidChannel)
+	}
+	if len(address) > maxChannelAddress {
+		return "", fmt.Errorf("%w: address is too long", ErrInvalidChannel)
+	}
+
+	switch channel {
+	case models.ChannelEmail:
+		addr, err := mail.ParseAddress(address)
+		if err != nil {
+			return "", fmt.Errorf("%w: %q is not an email address", ErrInvalidChannel, address)
+		}
+		return addr.Address, nil
+	case models.ChannelTelegram:
+		if !telegramChatRe.MatchString(address) {
+			return "", fmt.Errorf("%w: telegram address must be a chat ID or @channel", ErrInvalidChannel)
+		}
+		if _, err := strconv.ParseInt(address, 10, 64); err != nil && address[0] != '@' {
+			return "", fmt.Errorf("%w: telegram chat ID is out of range", ErrInvalidChannel)
+		}
+		return address, nil
+	case models.ChannelWebhook:
+		u, err := url.Parse(address)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "", fmt.Errorf("%w: webhook address must be an http(s) URL", ErrInvalidChannel)
+		}
+		if u.User != nil {
+			return "", fmt.Errorf("%w: webhook URL must not contain credentials", ErrInvalidChannel)
+		}
+		return u.String(), nil
+	}
+	return "", fmt.Errorf("%w: unknown channel %q", ErrInvalidChannel, channel)
+}
+
+var (
+	ErrInvalidChannel  = errors.New("invalid notification channel")
+	ErrChannelNotFound = errors.New("notification channel not found")
+)
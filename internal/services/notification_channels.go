@@ -0,0 +1,188 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"crypto-analytics/internal/models"
+)
+
+// ChannelSender доставляет уведомление по адресу одного канала. Ошибка, обёрнутая
+// в ErrUndeliverable, повтором не лечится (неверный адрес, бот заблокирован) —
+// такая доставка сразу снимается с очереди.
+type ChannelSender interface {
+	Send(ctx context.Context, address string, n models.Notification) error
+}
+
+var ErrUndeliverable = errors.New("notification cannot be delivered")
+
+const (
+	telegramMaxText = 4096
+	// Сколько тела ответа сохранять в ошибке доставки
+	channelErrorBody = 512
+)
+
+// EmailChannel отправляет уведомление письмом через MailSender
+type EmailChannel struct {
+	mailer MailSender
+}
+
+func NewEmailChannel(mailer MailSender) *EmailChannel {
+	return &EmailChannel{mailer: mailer}
+}
+
+func (c *EmailChannel) Send(ctx context.Context, address string, n models.Notification) error {
+	return c.mailer.Send(ctx, models.MailMessage{
+		To:      address,
+		Subject: n.Subject + " — Crypto Analytics",
+		Body:    notificationText(n),
+	})
+}
+
+// TelegramChannel пишет в чат через Bot API; адрес — chat_id или @username канала
+type TelegramChannel struct {
+	apiURL string
+	token  string
+	client *http.Client
+}
+
+func NewTelegramChannel(apiURL, token string) *TelegramChannel {
+	return &TelegramChannel{
+		apiURL: strings.TrimRight(apiURL, "/"),
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *TelegramChannel) Send(ctx context.Context, address string, n models.Notification) error {
+	text := n.Subject + "\n\n" + notificationText(n)
+	if utf8.RuneCountInString(text) > telegramMaxText {
+		text = string([]rune(text)[:telegramMaxText-1]) + "…"
+	}
+	payload, err := json.Marshal(map[string]any{
+		"chat_id":                  address,
+		"text":                     text,
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal telegram message: %w", err)
+	}
+
+	resp, err := postJSON(ctx, c.client, c.apiURL+"/bot"+c.token+"/sendMessage", payload, nil)
+	if err != nil {
+		// В URL запроса токен бота — в ошибку и логи его не пропускаем
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = c.apiURL + "/bot***/sendMessage"
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, channelErrorBody))
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	json.Unmarshal(body, &result)
+	if resp.StatusCode == http.StatusOK && result.OK {
+		return nil
+	}
+	err = fmt.Errorf("telegram API: status %d: %s", resp.StatusCode, result.Description)
+	if permanentStatus(resp.StatusCode) {
+		return fmt.Errorf("%w: %v", ErrUndeliverable, err)
+	}
+	return err
+}
+
+// WebhookChannel отправляет уведомление JSON-ом POST-запросом на адрес пользователя
+type WebhookChannel struct {
+	client *http.Client
+}
+
+func NewWebhookChannel() *WebhookChannel {
+	return &WebhookChannel{client: newWebhookHTTPClient(nil)}
+}
+
+// newWebhookHTTPClient — клиент для запросов на чужие адреса; transport nil — стандартный
+func newWebhookHTTPClient(transport http.RoundTripper) *http.Client {
+	return &http.Client{
+		Transport: transport,
+		Timeout:   10 * time.Second,
+		// Редирект — почти всегда ошибка в адресе; тело POST по нему всё равно не уйдёт
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func (c *WebhookChannel) Send(ctx context.Context, address string, n models.Notification) error {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("marshal webhook payload: %w", err)
+	}
+
+	resp, err := postJSON(ctx, c.client, address, payload, http.Header{
+		"User-Agent":     {"crypto-analytics-webhook/1"},
+		"X-Notification": {string(n.Event)},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, channelErrorBody))
+	err = fmt.Errorf("webhook: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	if permanentStatus(resp.StatusCode) {
+		return fmt.Errorf("%w: %v", ErrUndeliverable, err)
+	}
+	return err
+}
+
+func postJSON(ctx context.Context, client *http.Client, target string, payload []byte, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUndeliverable, err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return client.Do(req)
+}
+
+// permanentStatus — ответы 4xx, кроме таймаута и лимита запросов: повтор не поможет
+func permanentStatus(code int) bool {
+	return code >= 400 && code < 500 && code != http.StatusRequestTimeout && code != http.StatusTooManyRequests
+}
+
+// notificationText — текст уведомления и его поля строками "ключ: значение" в алфавитном порядке
+func notificationText(n models.Notification) string {
+	var b strings.Builder
+	b.WriteString(n.Text)
+	if len(n.Fields) > 0 {
+		keys := make([]string, 0, len(n.Fields))
+		for k := range n.Fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b.WriteString("\n")
+		for _, k := range keys {
+			fmt.Fprintf(&b, "\n%s: %s", k, n.Fields[k])
+		}
+	}
+	return b.String()
+}
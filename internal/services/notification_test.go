@@ -0,0 +1,480 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/storage"
+)
+
+type MockNotificationStorage struct {
+	Channels map[int64][]models.ChannelPreference
+	Outbox   []*models.OutboxItem
+}
+
+func NewMockNotificationStorage() *MockNotificationStorage {
+	return &MockNotificationStorage{Channels: map[int64][]models.ChannelPreference{}}
+}
+
+func (m *MockNotificationStorage) ListChannels(userID int64) ([]models.ChannelPreference, error) {
+	return m.Channels[userID], nil
+}
+
+func (m *MockNotificationStorage) SaveChannel(pref *models.ChannelPreference) error {
+	prefs := m.Channels[pref.UserID]
+	for i := range prefs {
+		if prefs[i].Channel == pref.Channel {
+			prefs[i] = *pref
+			return nil
+		}
+	}
+	m.Channels[pref.UserID] = append(prefs, *pref)
+	return nil
+}
+
+func (m *MockNotificationStorage) DeleteChannel(userID int64, channel models.NotificationChannel) error {
+	prefs := m.Channels[userID]
+	for i := range prefs {
+		if prefs[i].Channel == channel {
+			m.Channels[userID] = append(prefs[:i], prefs[i+1:]...)
+			return nil
+		}
+	}
+	return storage.ErrChannelNotFound
+}
+
+func (m *MockNotificationStorage) Enqueue(items []models.OutboxItem) error {
+	for _, item := range items {
+		item.ID = int64(len(m.Outbox) + 1)
+		item.Status = models.OutboxPending
+		item.CreatedAt = time.Now()
+		item.NextAttemptAt = item.CreatedAt
+		m.Outbox = append(m.Outbox, &item)
+	}
+	return nil
+}
+
+func (m *MockNotificationStorage) ClaimDue(limit int, lease time.Duration) ([]models.OutboxItem, error) {
+	var out []models.OutboxItem
+	now := time.Now()
+	for _, item := range m.Outbox {
+		if len(out) == limit {
+			break
+		}
+		if item.Status != models.OutboxPending || item.NextAttemptAt.After(now) {
+			continue
+		}
+		item.Attempts++
+		item.NextAttemptAt = now.Add(lease)
+		out = append(out, *item)
+	}
+	return out, nil
+}
+
+func (m *MockNotificationStorage) MarkSent(id int64) error {
+	m.Outbox[id-1].Status = models.OutboxSent
+	return nil
+}
+
+func (m *MockNotificationStorage) RetryLater(id int64, lastErr string, delay time.Duration) error {
+	m.Outbox[id-1].LastError = lastErr
+	m.Outbox[id-1].NextAttemptAt = time.Now().Add(delay)
+	return nil
+}
+
+func (m *MockNotificationStorage) MarkFailed(id int64, lastErr string) error {
+	m.Outbox[id-1].Status = models.OutboxFailed
+	m.Outbox[id-1].LastError = lastErr
+	return nil
+}
+
+// dueNow делает все отложенные доставки готовыми к отправке
+func (m *MockNotificationStorage) dueNow() {
+	for _, item := range m.Outbox {
+		item.NextAttemptAt = time.Now().Add(-time.Second)
+	}
+}
+
+// stubChannel отвечает ошибками из errs по очереди, потом — успехом
+type stubChannel struct {
+	errs []error
+	sent []string
+}
+
+func (c *stubChannel) Send(ctx context.Context, address string, n models.Notification) error {
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		return err
+	}
+	c.sent = append(c.sent, address)
+	return nil
+}
+
+func TestNotificationPublish(t *testing.T) {
+	users := NewMockUserStorage()
+	fallback := &models.User{Username: "fallback", Email: "fallback@example.com"}
+	oncall := &models.User{Username: "oncall", Email: "oncall@example.com"}
+	plain := &models.User{Username: "plain", Email: "plain@example.com"}
+	for _, u := range []*models.User{fallback, oncall, plain} {
+		users.CreateUser(u)
+	}
+	users.SetRole(fallback.ID, models.RoleAdmin)
+	users.SetRole(oncall.ID, models.RoleAdmin)
+
+	store := NewMockNotificationStorage()
+	store.Channels[oncall.ID] = []models.ChannelPreference{
+		{UserID: oncall.ID, Channel: models.ChannelTelegram, Address: "-100200", Events: []models.NotificationEvent{models.EventSecurityAlert}, Enabled: true},
+		{UserID: oncall.ID, Channel: models.ChannelWebhook, Address: "https://hooks.example.com/x", Enabled: false},
+	}
+	s := NewNotificationService(store, users, map[models.NotificationChannel]ChannelSender{
+		models.ChannelEmail:    &stubChannel{},
+		models.ChannelTelegram: &stubChannel{},
+		models.ChannelWebhook:  &stubChannel{},
+	}, nil)
+
+	s.NotifyAdmContForm(&models.ContactForm{Name: "Ann", Email: "ann@example.com", Message: "Hello"})
+	if len(store.Outbox) != 1 || store.Outbox[0].Address != fallback.Email || store.Outbox[0].Channel != models.ChannelEmail {
+		t.Fatalf("contact form must go only to the admin without settings by email, got %+v", store.Outbox)
+	}
+	if got := store.Outbox[0].Message; got.Event != models.EventContactForm || got.Fields["email"] != "ann@example.com" {
+		t.Errorf("unexpected message: %+v", got)
+	}
+
+	s.NotifyAdmSuspiciousLogin(&models.AuthEvent{Login: "bob", IP: "10.0.0.1"}, 10, 15*time.Minute)
+	if len(store.Outbox) != 3 {
+		t.Fatalf("security alert must reach both admins, got %d items", len(store.Outbox))
+	}
+	if tg := store.Outbox[2]; tg.UserID != oncall.ID || tg.Channel != models.ChannelTelegram || tg.Address != "-100200" {
+		t.Errorf("expected telegram delivery to the on-call admin, got %+v", tg)
+	}
+}
+
+func TestNotificationDelivery(t *testing.T) {
+	store := NewMockNotificationStorage()
+	flaky := &stubChannel{errs: []error{errors.New("timeout"), errors.New("timeout")}}
+	broken := &stubChannel{errs: []error{ErrUndeliverable}}
+	s := NewNotificationService(store, NewMockUserStorage(), map[models.NotificationChannel]ChannelSender{
+		models.ChannelEmail:    flaky,
+		models.ChannelTelegram: broken,
+	}, nil)
+	store.Enqueue([]models.OutboxItem{
+		{Channel: models.ChannelEmail, Address: "admin@example.com"},
+		{Channel: models.ChannelTelegram, Address: "@gone"},
+		{Channel: models.ChannelWebhook, Address: "https://example.com"},
+	})
+	ctx := context.Background()
+
+	if sent, err := s.DeliverDue(ctx); err != nil || sent != 0 {
+		t.Fatalf("first pass: sent %d, err %v", sent, err)
+	}
+	email, tg, hook := store.Outbox[0], store.Outbox[1], store.Outbox[2]
+	if email.Status != models.OutboxPending || email.LastError != "timeout" {
+		t.Errorf("transient error must be retried, got %+v", email)
+	}
+	if d := time.Until(email.NextAttemptAt); d < retryBaseDelay-time.Second || d > retryBaseDelay {
+		t.Errorf("first retry expected in %s, got %s", retryBaseDelay, d)
+	}
+	if tg.Status != models.OutboxFailed {
+		t.Errorf("undeliverable notification must fail at once, got %s", tg.Status)
+	}
+	if hook.Status != models.OutboxFailed {
+		t.Errorf("channel without adapter must fail, got %s", hook.Status)
+	}
+
+	// Пока пауза не прошла, повторной отправки нет
+	if sent, _ := s.DeliverDue(ctx); sent != 0 || email.Attempts != 1 {
+		t.Fatalf("retry must wait for the backoff, attempts %d", email.Attempts)
+	}
+	store.dueNow()
+	s.DeliverDue(ctx)
+	store.dueNow()
+	if sent, _ := s.DeliverDue(ctx); sent != 1 || email.Status != models.OutboxSent || email.Attempts != 3 {
+		t.Fatalf("expected delivery on the third attempt, got %+v", email)
+	}
+
+	// Попытки кончаются
+	down := &stubChannel{}
+	for range maxDeliveryAttempts {
+		down.errs = append(down.errs, errors.New("connection refused"))
+	}
+	s.channels[models.ChannelEmail] = down
+	store.Enqueue([]models.OutboxItem{{Channel: models.ChannelEmail, Address: "admin@example.com"}})
+	last := store.Outbox[3]
+	for range maxDeliveryAttempts {
+		store.dueNow()
+		s.DeliverDue(ctx)
+	}
+	if last.Status != models.OutboxFailed || last.Attempts != maxDeliveryAttempts {
+		t.Errorf("expected failure after %d attempts, got %+v", maxDeliveryAttempts, last)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{5, 8 * time.Minute},
+		{12, retryMaxDelay},
+		{100, retryMaxDelay},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempt); got != tt.want {
+			t.Errorf("retryDelay(%d) = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestSaveChannel(t *testing.T) {
+	store := NewMockNotificationStorage()
+	s := NewNotificationService(store, NewMockUserStorage(), map[models.NotificationChannel]ChannelSender{
+		models.ChannelEmail:   &stubChannel{},
+		models.ChannelWebhook: &stubChannel{},
+	}, nil)
+	admin := &models.User{ID: 7, Role: models.RoleAdmin}
+
+	saved, err := s.SaveChannel(admin, models.ChannelPreference{
+		Channel: models.ChannelEmail,
+		Address: " Ops <ops@example.com> ",
+		Events:  []models.NotificationEvent{models.EventNewUser, models.EventNewUser},
+		Enabled: true,
+	})
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if saved.Address != "ops@example.com" || len(saved.Events) != 1 || saved.UserID != admin.ID {
+		t.Errorf("unexpected saved channel: %+v", saved)
+	}
+
+	tests := []struct {
+		name string
+		pref models.ChannelPreference
+	}{
+		{"unknown channel", models.ChannelPreference{Channel: "sms", Address: "+100"}},
+		{"channel without adapter", models.ChannelPreference{Channel: models.ChannelTelegram, Address: "12345"}},
+		{"bad email", models.ChannelPreference{Channel: models.ChannelEmail, Address: "not an address"}},
+		{"empty address", models.ChannelPreference{Channel: models.ChannelEmail, Address: "  "}},
+		{"webhook not http", models.ChannelPreference{Channel: models.ChannelWebhook, Address: "ftp://example.com/hook"}},
+		{"webhook with credentials", models.ChannelPreference{Channel: models.ChannelWebhook, Address: "https://user:pw@example.com/hook"}},
+		{"unknown event", models.ChannelPreference{Channel: models.ChannelEmail, Address: "ops@example.com", Events: []models.NotificationEvent{"price"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.SaveChannel(admin, tt.pref); !errors.Is(err, ErrInvalidChannel) {
+				t.Errorf("expected ErrInvalidChannel, got %v", err)
+			}
+		})
+	}
+
+	if err := s.DeleteChannel(admin, models.ChannelWebhook); !errors.Is(err, ErrChannelNotFound) {
+		t.Errorf("expected ErrChannelNotFound, got %v", err)
+	}
+	if err := s.DeleteChannel(admin, models.ChannelEmail); err != nil || len(store.Channels[admin.ID]) != 0 {
+		t.Errorf("delete: %v", err)
+	}
+}
+
+func TestChannelAddressTelegram(t *testing.T) {
+	for _, addr := range []string{"123456789", "-1001234567890", "@crypto_alerts"} {
+		if _, err := channelAddress(models.ChannelTelegram, addr); err != nil {
+			t.Errorf("%q must be accepted: %v", addr, err)
+		}
+	}
+	for _, addr := range []string{"@ab", "chat", "99999999999999999999", "12 34"} {
+		if _, err := channelAddress(models.ChannelTelegram, addr); !errors.Is(err, ErrInvalidChannel) {
+			t.Errorf("%q must be rejected, got %v", addr, err)
+		}
+	}
+}
+
+var testNotification = models.Notification{
+	Event:   models.EventSecurityAlert,
+	Subject: "Login locked",
+	Text:    "10 failed attempts",
+	Fields:  map[string]string{"ip": "10.0.0.1", "login": "bob"},
+}
+
+func TestTelegramChannel(t *testing.T) {
+	var got map[string]any
+	status, reply := http.StatusOK, `{"ok":true}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/botTOKEN/sendMessage" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(status)
+		io.WriteString(w, reply)
+	}))
+	defer srv.Close()
+
+	c := NewTelegramChannel(srv.URL+"/", "TOKEN")
+	ctx := context.Background()
+	if err := c.Send(ctx, "-100200", testNotification); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if got["chat_id"] != "-100200" {
+		t.Errorf("unexpected chat_id %v", got["chat_id"])
+	}
+	if text, _ := got["text"].(string); !strings.HasPrefix(text, "Login locked\n\n10 failed attempts") || !strings.Contains(text, "login: bob") {
+		t.Errorf("unexpected text %q", text)
+	}
+
+	status, reply = http.StatusBadRequest, `{"ok":false,"description":"Bad Request: chat not found"}`
+	if err := c.Send(ctx, "1", testNotification); !errors.Is(err, ErrUndeliverable) || !strings.Contains(err.Error(), "chat not found") {
+		t.Errorf("unknown chat must be undeliverable, got %v", err)
+	}
+	status, reply = http.StatusTooManyRequests, `{"ok":false,"description":"Too Many Requests"}`
+	if err := c.Send(ctx, "1", testNotification); err == nil || errors.Is(err, ErrUndeliverable) {
+		t.Errorf("rate limit must be retried, got %v", err)
+	}
+
+	srv.Close()
+	if err := c.Send(ctx, "1", testNotification); err == nil || strings.Contains(err.Error(), "TOKEN") {
+		t.Errorf("network error must not leak the bot token, got %v", err)
+	}
+}
+
+func TestWebhookChannel(t *testing.T) {
+	var got models.Notification
+	var header http.Header
+	status := http.StatusNoContent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	c := NewWebhookChannel()
+	ctx := context.Background()
+	if err := c.Send(ctx, srv.URL+"/hook", testNotification); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if got.Event != models.EventSecurityAlert || got.Fields["login"] != "bob" {
+		t.Errorf("unexpected payload %+v", got)
+	}
+	if header.Get("Content-Type") != "application/json" || header.Get("X-Notification") != "security_alert" {
+		t.Errorf("unexpected headers %v", header)
+	}
+
+	status = http.StatusGone
+	if err := c.Send(ctx, srv.URL, testNotification); !errors.Is(err, ErrUndeliverable) {
+		t.Errorf("410 must be undeliverable, got %v", err)
+	}
+	status = http.StatusServiceUnavailable
+	if err := c.Send(ctx, srv.URL, testNotification); err == nil || errors.Is(err, ErrUndeliverable) {
+		t.Errorf("503 must be retried, got %v", err)
+	}
+}
+
+// fakeSMTP — минимальный SMTP-сервер: принимает одно письмо без STARTTLS и AUTH
+type fakeSMTP struct {
+	ln   net.Listener
+	mu   sync.Mutex
+	rcpt []string
+	data string
+}
+
+func startFakeSMTP(t *testing.T) *fakeSMTP {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeSMTP{ln: ln}
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *fakeSMTP) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.session(conn)
+	}
+}
+
+func (s *fakeSMTP) session(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250-localhost")
+			reply("250 8BITMIME")
+		case strings.HasPrefix(cmd, "MAIL FROM"):
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO"):
+			s.mu.Lock()
+			s.rcpt = append(s.rcpt, strings.TrimSpace(line[len("RCPT TO:"):]))
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var b strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				b.WriteString(l)
+			}
+			s.mu.Lock()
+			s.data = b.String()
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestEmailChannelSMTP(t *testing.T) {
+	srv := startFakeSMTP(t)
+	host, port, _ := net.SplitHostPort(srv.ln.Addr().String())
+	c := NewEmailChannel(NewSMTPMailSender(host, port, "", "", "alerts@example.com"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Send(ctx, "ops@example.com", testNotification); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.rcpt) != 1 || srv.rcpt[0] != "<ops@example.com>" {
+		t.Errorf("unexpected recipients %v", srv.rcpt)
+	}
+	if !strings.Contains(srv.data, "10 failed attempts") || !strings.Contains(srv.data, "ip: 10.0.0.1\r\nlogin: bob") {
+		t.Errorf("unexpected message:\n%s", srv.data)
+	}
+}
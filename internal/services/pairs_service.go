@@ -1,18 +1,26 @@
 package services
 
 import (
+	"crypto-analytics/internal/models"
 	"crypto-analytics/internal/storage"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 )
 
+// pairsRefreshInterval — как часто в проде перечитывается список пар с Binance
+const pairsRefreshInterval = 6 * time.Hour
+
 type CryptoPairsService struct {
 	store         storage.CacheStorage
+	events        EventPublisher
+	mu            sync.RWMutex
 	pairs         []string
 	isInitialized bool
 }
@@ -26,10 +34,11 @@ type PairsResponse struct {
 const BinanceAPIURL = "https://api.binance.com/api/v3/exchangeInfo"
 
 func NewCryptoPairsService(storePairs storage.CacheStorage,
-	downloadOnStart bool) *CryptoPairsService {
+	downloadOnStart bool, events EventPublisher) *CryptoPairsService {
 	service := &CryptoPairsService{
-		store: storePairs,
-		pairs: []string{},
+		store:  storePairs,
+		events: events,
+		pairs:  []string{},
 	}
 
 	if downloadOnStart {
@@ -40,7 +49,7 @@ func NewCryptoPairsService(storePairs storage.CacheStorage,
 	} else {
 		slog.Info("Loading crypto pairs from cache")
 		if strings, err := service.store.Load(); err == nil {
-			service.pairs = strings
+			service.setPairs(strings)
 		} else {
 			slog.Error("Cache load failed, downloading from API", "error", err)
 			if err := service.downloadAndCachePairs(); err != nil {
@@ -74,14 +83,63 @@ func (s *CryptoPairsService) downloadAndCachePairs() error {
 		return fmt.Errorf("failed to parse JSON: %v", err)
 	}
 
-	s.pairs = s.filterUSDTOairs(apiResponse)
+	pairs := s.filterUSDTOairs(apiResponse)
+	known := s.currentPairs()
+	if len(known) == 0 {
+		// При старте сравниваем с кэшем прошлого запуска
+		known, _ = s.store.Load()
+	}
+
+	s.setPairs(pairs)
+	s.publishListed(known, pairs)
 
-	data, err := json.Marshal(s.pairs)
+	data, err := json.Marshal(pairs)
 	if err != nil {
 		return err
 	}
-	return s.store.Save(data, len(s.pairs))
+	return s.store.Save(data, len(pairs))
+}
+
+// StartRefresh периодически обновляет список пар, чтобы замечать новые листинги
+func (s *CryptoPairsService) StartRefresh() {
+	ticker := time.NewTicker(pairsRefreshInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := s.downloadAndCachePairs(); err != nil {
+			slog.Error("Failed to refresh pairs", "error", err)
+		}
+	}
+}
+
+// publishListed сообщает подписчикам о парах, которых не было в known.
+// Без прежнего списка сравнивать не с чем — иначе все пары сошли бы за новые.
+func (s *CryptoPairsService) publishListed(known, pairs []string) {
+	if s.events == nil || len(known) == 0 {
+		return
+	}
+	for _, pair := range pairs {
+		if !slices.Contains(known, pair) {
+			slog.Info("New pair listed", "pair", pair)
+			s.events.PublishEvent(models.WebhookPairListed, map[string]any{
+				"pair":  pair,
+				"quote": "USDT",
+				"base":  strings.TrimSuffix(pair, "USDT"),
+			})
+		}
+	}
+}
+
+func (s *CryptoPairsService) setPairs(pairs []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pairs = pairs
+}
 
+func (s *CryptoPairsService) currentPairs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pairs
 }
 
 func (s *CryptoPairsService) filterUSDTOairs(response PairsResponse) []string {
@@ -99,9 +157,9 @@ func (s *CryptoPairsService) GetAllPairs() ([]string, error) {
 	if !s.isInitialized {
 		return nil, fmt.Errorf("service not initialized")
 	}
-	return s.pairs, nil
+	return s.currentPairs(), nil
 }
 
 func (s *CryptoPairsService) GetPairsCount() int {
-	return len(s.pairs)
+	return len(s.currentPairs())
 }
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"crypto-analytics/internal/models"
+	"log/slog"
+	"math"
+	"net/mail"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	minPasswordLength = 8
+	// bcrypt молча обрезает всё, что длиннее 72 байт
+	maxPasswordBytes   = 72
+	minPasswordEntropy = 40
+	maxUsernameLength  = 50
+	maxEmailLength     = 254
+)
+
+// ValidationError — ошибки по полям формы (поле → сообщение); handler отдаёт их клиенту как есть
+type ValidationError struct {
+	Fields map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for field := range e.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		parts[i] = field + ": " + e.Fields[field]
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+func (e *ValidationError) add(field, message string) {
+	if e.Fields == nil {
+		e.Fields = map[string]string{}
+	}
+	if _, exists := e.Fields[field]; !exists {
+		e.Fields[field] = message
+	}
+}
+
+func (e *ValidationError) orNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func validateUsername(username string) string {
+	switch {
+	case username == "":
+		return "username is required"
+	case utf8.RuneCountInString(username) > maxUsernameLength:
+		return "username must be at most 50 characters"
+	case strings.ContainsFunc(username, unicode.IsSpace), strings.Contains(username, "@"):
+		// По «@» вход отличает email от имени пользователя
+		return "username must not contain spaces or @"
+	}
+	return ""
+}
+
+// validateEmail проверяет синтаксис адреса (RFC 5322) без отображаемого имени
+// и требует домен с точкой: «user@localhost» для рассылки бесполезен
+func validateEmail(email string) error {
+	if email == "" || len(email) > maxEmailLength {
+		return ErrInvalidEmail
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return ErrInvalidEmail
+	}
+	domain := email[strings.LastIndex(email, "@")+1:]
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return ErrInvalidEmail
+	}
+	return nil
+}
+
+// passwordEntropy грубо оценивает энтропию в битах: размер алфавита по классам
+// символов, умноженный на длину без повторов и последовательностей («aaaa», «1234»)
+func passwordEntropy(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	effective := 0
+	prev := rune(-10)
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < utf8.RuneSelf:
+			symbol = true
+		default:
+			other = true
+		}
+		if d := r - prev; d < -1 || d > 1 {
+			effective++
+		}
+		prev = r
+	}
+
+	pool := 0
+	for _, class := range []struct {
+		present bool
+		size    int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.present {
+			pool += class.size
+		}
+	}
+	if pool == 0 {
+		return 0
+	}
+	return float64(effective) * math.Log2(float64(pool))
+}
+
+// checkPassword применяет политику паролей. Возвращает сообщение для поля password
+// или пустую строку, если пароль подходит.
+func (s *UserService) checkPassword(password, username, email string) string {
+	if utf8.RuneCountInString(password) < minPasswordLength {
+		return "password must be at least 8 characters"
+	}
+	if len(password) > maxPasswordBytes {
+		return "password must be at most 72 bytes"
+	}
+
+	lowered := strings.ToLower(password)
+	localPart, _, _ := strings.Cut(email, "@")
+	for _, personal := range []string{username, localPart} {
+		personal = strings.ToLower(strings.TrimSpace(personal))
+		if utf8.RuneCountInString(personal) >= 3 && strings.Contains(lowered, personal) {
+			return "password must not contain your username or email"
+		}
+	}
+
+	if passwordEntropy(password) < minPasswordEntropy {
+		return "password is too predictable: make it longer or mix letters, digits and symbols"
+	}
+
+	if s.breaches != nil {
+		breached, err := s.breaches.IsBreached(password)
+		if err != nil {
+			// Проверка по утечкам — дополнительная: без неё регистрация не должна вставать
+			slog.Warn("Breached password check unavailable", "error", err)
+		} else if breached {
+			return "this password has appeared in a data breach, choose another one"
+		}
+	}
+	return ""
+}
+
+// validateNewUser проверяет все поля регистрации сразу, чтобы форма показала все ошибки
+func (s *UserService) validateNewUser(user *models.User) error {
+	verr := &ValidationError{}
+	if msg := validateUsername(user.Username); msg != "" {
+		verr.add("username", msg)
+	}
+	if err := validateEmail(user.Email); err != nil {
+		verr.add("email", "enter a valid email address")
+	}
+	if msg := s.checkPassword(user.Password, user.Username, user.Email); msg != "" {
+		verr.add("password", msg)
+	}
+	return verr.orNil()
+}
+
+func (s *UserService) validateNewPassword(password, username, email string) error {
+	if msg := s.checkPassword(password, username, email); msg != "" {
+		return &ValidationError{Fields: map[string]string{"password": msg}}
+	}
+	return nil
+}
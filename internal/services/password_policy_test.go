@@ -0,0 +1,109 @@
+package services
+
+import (
+	"crypto-analytics/internal/models"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUserService_RegisterValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       models.User
+		wantFields []string
+	}{
+		{name: "valid", user: models.User{Username: "satoshi", Email: "s@example.com", Password: testPassword}},
+		{name: "everything empty", user: models.User{}, wantFields: []string{"username", "email", "password"}},
+		{name: "short password", user: models.User{Username: "satoshi", Email: "s@example.com", Password: "a1!"}, wantFields: []string{"password"}},
+		{name: "too long for bcrypt", user: models.User{Username: "satoshi", Email: "s@example.com", Password: strings.Repeat("ab1!", 19)}, wantFields: []string{"password"}},
+		{name: "low entropy", user: models.User{Username: "satoshi", Email: "s@example.com", Password: "abcdefghijkl"}, wantFields: []string{"password"}},
+		{name: "repeated characters", user: models.User{Username: "satoshi", Email: "s@example.com", Password: "aaaaaaaaaaaaaaaa"}, wantFields: []string{"password"}},
+		{name: "contains username", user: models.User{Username: "satoshi", Email: "s@example.com", Password: "my-Satoshi-2009!"}, wantFields: []string{"password"}},
+		{name: "contains email local part", user: models.User{Username: "hal", Email: "finney@example.com", Password: "Finney#rpow2004"}, wantFields: []string{"password"}},
+		{name: "breached", user: models.User{Username: "satoshi", Email: "s@example.com", Password: "Password123!"}, wantFields: []string{"password"}},
+		{name: "email with display name", user: models.User{Username: "satoshi", Email: "Satoshi <s@example.com>", Password: testPassword}, wantFields: []string{"email"}},
+		{name: "email without dot in domain", user: models.User{Username: "satoshi", Email: "s@localhost", Password: testPassword}, wantFields: []string{"email"}},
+		{name: "email without at", user: models.User{Username: "satoshi", Email: "example.com", Password: testPassword}, wantFields: []string{"email"}},
+		{name: "username with at", user: models.User{Username: "s@toshi", Email: "s@example.com", Password: testPassword}, wantFields: []string{"username"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestUserService(NewMockUserStorage(), NewMemoryMailSender())
+			user := tt.user
+			err := s.RegisterUser(&user)
+
+			if len(tt.wantFields) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("expected ValidationError, got %v", err)
+			}
+			if len(verr.Fields) != len(tt.wantFields) {
+				t.Fatalf("expected fields %v, got %v", tt.wantFields, verr.Fields)
+			}
+			for _, field := range tt.wantFields {
+				if verr.Fields[field] == "" {
+					t.Errorf("expected error for %s, got %v", field, verr.Fields)
+				}
+			}
+		})
+	}
+}
+
+func TestRangeBreachChecker(t *testing.T) {
+	breached := passwordSHA1("hunter2hunter2")
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+		fmt.Fprintf(w, "0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n%s:3861493\r\n%s:0\r\n",
+			breached[5:], passwordSHA1("padding only")[5:])
+	}))
+	defer server.Close()
+
+	checker := NewRangeBreachChecker(server.URL + "/range")
+
+	got, err := checker.IsBreached("hunter2hunter2")
+	if err != nil || !got {
+		t.Fatalf("expected breached, got %v (%v)", got, err)
+	}
+	// Наружу уходят только первые 5 символов хеша
+	if requested != "/range/"+breached[:5] {
+		t.Errorf("unexpected request path %s", requested)
+	}
+	if got, _ := checker.IsBreached("padding only"); got {
+		t.Error("padding entries must not count as breached")
+	}
+	if got, _ := checker.IsBreached(testPassword); got {
+		t.Error("unlisted password reported as breached")
+	}
+}
+
+func TestFallbackBreachChecker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	offline, err := NewHashListBreachChecker(strings.NewReader(
+		"# offline list\n" + strings.ToLower(passwordSHA1("hunter2hunter2")) + "\n"))
+	if err != nil {
+		t.Fatalf("load list: %v", err)
+	}
+	checker := NewFallbackBreachChecker(NewRangeBreachChecker(server.URL), offline)
+
+	if got, err := checker.IsBreached("hunter2hunter2"); err != nil || !got {
+		t.Errorf("expected offline list to report breach, got %v (%v)", got, err)
+	}
+	if got, err := checker.IsBreached(testPassword); err != nil || got {
+		t.Errorf("expected clean password, got %v (%v)", got, err)
+	}
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"crypto-analytics/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// PostRevisions возвращает историю правок поста от первой версии к текущей,
+// каждая версия — с отличиями от предыдущей. Пост в теневом бане видит только автор.
+func (s *PostsService) PostRevisions(ctx context.Context, postID bson.ObjectID, viewerID int64) ([]models.RevisionDiff, error) {
+	if postID.IsZero() {
+		return nil, ErrInvalidPostID
+	}
+	post, err := s.postStorage.GetPost(ctx, postID, viewerID)
+	if err != nil {
+		return nil, notFoundAs(err, ErrPostNotFound)
+	}
+
+	current := models.Revision{
+		Target:    models.VoteTargetPost,
+		TargetID:  post.ID,
+		Version:   post.Revision,
+		Heading:   post.Heading,
+		MainText:  post.MainText,
+		CreatedAt: editedAt(post.CreatedAt, post.UpdatedAt),
+	}
+	return s.revisionHistory(ctx, current)
+}
+
+// CommentRevisions — то же для комментария
+func (s *PostsService) CommentRevisions(ctx context.Context, commentID bson.ObjectID, viewerID int64) ([]models.RevisionDiff, error) {
+	if commentID.IsZero() {
+		return nil, ErrCommentNotFound
+	}
+	comment, err := s.postStorage.GetComment(ctx, commentID, viewerID)
+	if err != nil {
+		return nil, notFoundAs(err, ErrCommentNotFound)
+	}
+
+	current := models.Revision{
+		Target:    models.VoteTargetComment,
+		TargetID:  comment.ID,
+		Version:   comment.Revision,
+		MainText:  comment.MainText,
+		CreatedAt: editedAt(comment.CreatedAt, comment.UpdatedAt),
+	}
+	return s.revisionHistory(ctx, current)
+}
+
+func (s *PostsService) revisionHistory(ctx context.Context, current models.Revision) ([]models.RevisionDiff, error) {
+	if current.Version == 0 {
+		current.Version = 1 // запись старше счётчика версий и ни разу не правилась
+	}
+	past, err := s.postStorage.ListRevisions(ctx, current.Target, current.TargetID)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]models.Revision, 0, len(past)+1)
+	for _, r := range past {
+		// Версия сохранена, но правка не прошла — текущей остаётся та же версия
+		if r.Version < current.Version {
+			versions = append(versions, r)
+		}
+	}
+	versions = append(versions, current)
+
+	diffs := make([]models.RevisionDiff, len(versions))
+	for i, r := range versions {
+		diffs[i].Revision = r
+		if i == 0 {
+			continue
+		}
+		prev := versions[i-1]
+		if current.Target == models.VoteTargetPost {
+			diffs[i].HeadingDiff = diffWords(prev.Heading, r.Heading)
+		}
+		diffs[i].TextDiff = diffWords(prev.MainText, r.MainText)
+	}
+	return diffs, nil
+}
+
+// editedAt — когда текущая версия стала текущей
+func editedAt(createdAt time.Time, updatedAt *time.Time) time.Time {
+	if updatedAt != nil {
+		return *updatedAt
+	}
+	return createdAt
+}
+
+var ErrEditConflict = errors.New("the text was changed by another edit, reload and try again")
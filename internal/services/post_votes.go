@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"crypto-analytics/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Vote голосует за пост или комментарий: value +1, -1 или 0 (снять голос).
+// Возвращает новый счёт объекта. За свои записи голосовать нельзя — иначе
+// репутацию можно накрутить самому себе.
+func (s *PostsService) Vote(
+	ctx context.Context,
+	voter *models.User,
+	target models.VoteTarget,
+	id bson.ObjectID,
+	value int,
+) (int, error) {
+	if err := checkWriter(voter); err != nil {
+		return 0, err
+	}
+	if value < -1 || value > 1 {
+		return 0, fmt.Errorf("%w: value must be -1, 0 or 1", ErrInvalidVote)
+	}
+
+	authorID, err := s.targetAuthor(ctx, target, id, voter.ID)
+	if err != nil {
+		return 0, err
+	}
+	if authorID == voter.ID {
+		return 0, ErrSelfVote
+	}
+
+	return s.postStorage.Vote(ctx, target, id, authorID, voter.ID, value)
+}
+
+// React ставит или снимает эмодзи-реакцию и возвращает счётчики реакций объекта
+func (s *PostsService) React(
+	ctx context.Context,
+	user *models.User,
+	target models.VoteTarget,
+	id bson.ObjectID,
+	reaction string,
+	on bool,
+) (map[string]int, error) {
+	if err := checkWriter(user); err != nil {
+		return nil, err
+	}
+	if _, ok := models.Reactions[reaction]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownReaction, reaction)
+	}
+	if _, err := s.targetAuthor(ctx, target, id, user.ID); err != nil {
+		return nil, err
+	}
+
+	return s.postStorage.React(ctx, target, id, user.ID, reaction, on)
+}
+
+// Reputation — сумма голосов, полученных записями пользователя
+func (s *PostsService) Reputation(ctx context.Context, userID int64) (int, error) {
+	return s.postStorage.Reputation(ctx, userID)
+}
+
+func (s *PostsService) targetAuthor(ctx context.Context, target models.VoteTarget, id bson.ObjectID, viewerID int64) (int64, error) {
+	switch target {
+	case models.VoteTargetPost:
+		authorID, err := s.postStorage.TargetAuthor(ctx, target, id, viewerID)
+		return authorID, notFoundAs(err, ErrPostNotFound)
+	case models.VoteTargetComment:
+		authorID, err := s.postStorage.TargetAuthor(ctx, target, id, viewerID)
+		return authorID, notFoundAs(err, ErrCommentNotFound)
+	}
+	return 0, fmt.Errorf("%w: unknown target %q", ErrInvalidVote, target)
+}
+
+var (
+	ErrInvalidVote     = errors.New("invalid vote")
+	ErrSelfVote        = errors.New("you cannot vote for your own post or comment")
+	ErrUnknownReaction = errors.New("unknown reaction")
+)
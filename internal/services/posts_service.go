@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
-
+	"log/slog"
+	"strings"
+	"time"
 	"unicode/utf8"
 
 	"crypto-analytics/internal/models"
@@ -16,36 +18,464 @@ import (
 
 type PostsService struct {
 	postStorage storage.PostStorage
+	modLog      storage.ModerationLogStorage
+	coins       GetAllPairsService
+	pairs       AIAnalysisService
+	analysis    AnalysisGService
+	users       storage.UserStorage
+	notifier    CommentNotifier
+	attachments storage.AttachmentStorage
+	filter      *ContentFilter
+	limiter     RateLimiter
+	events      EventPublisher
+	now         func() time.Time
 }
 
-func NewPostService(ps storage.PostStorage) *PostsService {
+func NewPostService(
+	ps storage.PostStorage,
+	modLog storage.ModerationLogStorage,
+	coins GetAllPairsService,
+	pairs AIAnalysisService,
+	analysis AnalysisGService,
+	users storage.UserStorage,
+	notifier CommentNotifier,
+	attachments storage.AttachmentStorage,
+	filter *ContentFilter,
+	limiter RateLimiter,
+	events EventPublisher,
+) *PostsService {
 	return &PostsService{
 		postStorage: ps,
+		modLog:      modLog,
+		coins:       coins,
+		pairs:       pairs,
+		analysis:    analysis,
+		users:       users,
+		notifier:    notifier,
+		attachments: attachments,
+		filter:      filter,
+		limiter:     limiter,
+		events:      events,
+		now:         time.Now,
 	}
 }
 
-func (s *PostsService) CreatePost(ctx context.Context, post models.Post) (bson.ObjectID, error) {
+// CreatePost публикует пост от имени author — пользователя из сессии или API-токена.
+// Автор, время создания и снимок анализа задаются только здесь, из запроса они не берутся.
+func (s *PostsService) CreatePost(ctx context.Context, author *models.User, draft models.PostDraft) (bson.ObjectID, error) {
+	if err := checkWriter(author); err != nil {
+		return bson.ObjectID{}, err
+	}
+
+	createdAt := s.now().UTC()
+	post := models.Post{
+		AuthorID:   author.ID,
+		Person:     author.DisplayName,
+		Heading:    draft.Heading,
+		MainText:   draft.MainText,
+		Date:       createdAt.Format(time.RFC3339),
+		CreatedAt:  createdAt,
+		CommentIDs: []bson.ObjectID{},
+		HotScore:   models.HotScore(0, createdAt),
+		Revision:   1,
+		Shadowed:   author.ShadowBanned,
+	}
 	if err := s.validatePost(post); err != nil {
 		return bson.ObjectID{}, err
 	}
-	return s.postStorage.CreatePost(ctx, post)
+
+	tags := draft.Tags
+	if draft.AnalysisPair != "" {
+		snapshot, err := s.snapshot(draft.AnalysisPair, draft.AnalysisTimeframe)
+		if err != nil {
+			return bson.ObjectID{}, err
+		}
+		post.Analysis = snapshot
+		// Пост со снимком находится со страницы анализа этой пары
+		tags = append(tags, snapshot.Pair)
+	}
+	validTags, err := s.validateTags(tags)
+	if err != nil {
+		return bson.ObjectID{}, err
+	}
+	post.Tags = validTags
+	if err := s.allowPosting(author, "post", postRateLimit); err != nil {
+		return bson.ObjectID{}, err
+	}
+
+	id, err := s.insertPost(ctx, post, author.ID, uniqueIDs(draft.Attachments))
+	if err != nil {
+		return bson.ObjectID{}, err
+	}
+	if !post.Shadowed {
+		s.publish(models.WebhookPostCreated, map[string]any{
+			"id":        id.Hex(),
+			"heading":   post.Heading,
+			"authorId":  post.AuthorID,
+			"author":    post.Person,
+			"tags":      post.Tags,
+			"createdAt": post.CreatedAt,
+		})
+	}
+	return id, nil
 }
-func (s *PostsService) CreateComment(ctx context.Context, comment models.Comment) error {
+
+func (s *PostsService) insertPost(ctx context.Context, post models.Post, authorID int64, ids []bson.ObjectID) (bson.ObjectID, error) {
+	if len(ids) == 0 {
+		return s.postStorage.CreatePost(ctx, post)
+	}
+
+	// ID поста нужен до вставки: по нему вложения привязываются и, при сбое, отвязываются
+	post.ID = bson.NewObjectID()
+	if err := s.claimAttachments(ctx, ids, authorID, post.ID); err != nil {
+		return bson.ObjectID{}, err
+	}
+	post.Attachments = ids
+	id, err := s.postStorage.CreatePost(ctx, post)
+	if err != nil {
+		if releaseErr := s.attachments.ReleaseAttachments(ctx, post.ID); releaseErr != nil {
+			slog.Error("Failed to release attachments", "post_id", post.ID.Hex(), "error", releaseErr)
+		}
+		return bson.ObjectID{}, err
+	}
+	return id, nil
+}
+
+// publish отдаёт событие подписчикам вебхуков, если они подключены
+func (s *PostsService) publish(event models.WebhookEvent, data any) {
+	if s.events != nil {
+		s.events.PublishEvent(event, data)
+	}
+}
+
+func uniqueIDs(ids []bson.ObjectID) []bson.ObjectID {
+	var out []bson.ObjectID
+	seen := make(map[bson.ObjectID]bool)
+	for _, id := range ids {
+		if !id.IsZero() && !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+const maxPostTags = 5
+
+// validateTags приводит теги к верхнему регистру, убирает повторы и проверяет,
+// что каждый тег — тикер монеты из CryptoService или пара из CryptoPairsService
+func (s *PostsService) validateTags(tags []string) ([]string, error) {
+	var out []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	if len(out) > maxPostTags {
+		return nil, fmt.Errorf("%w: at most %d", ErrTooManyTags, maxPostTags)
+	}
+
+	known := make(map[string]bool)
+	coins, err := s.coins.GetTopCryptos(250)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get coins: %w", err)
+	}
+	for _, c := range coins {
+		known[strings.ToUpper(c.Symbol)] = true
+	}
+	pairs, err := s.pairs.GetAllPairs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pairs: %w", err)
+	}
+	for _, p := range pairs {
+		known[p] = true
+	}
+
+	for _, tag := range out {
+		if !known[tag] {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownTag, tag)
+		}
+	}
+	return out, nil
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToUpper(strings.TrimSpace(tag))
+}
+
+// snapshot фиксирует цену и индикаторы пары на текущий момент
+func (s *PostsService) snapshot(pair, timeframe string) (*models.AnalysisSnapshot, error) {
+	pair = normalizeTag(pair)
+	data, err := s.analysis.GetPairInfo(pair, timeframe)
+	if err != nil || len(data.Candles) == 0 {
+		return nil, fmt.Errorf("%w: %s %s", ErrAnalysisUnavailable, pair, timeframe)
+	}
+	return &models.AnalysisSnapshot{
+		Pair:       data.Pair,
+		Timeframe:  data.Timeframe,
+		Price:      data.Candles[len(data.Candles)-1].Close,
+		Indicators: data.Indicators,
+		TakenAt:    s.now().UTC(),
+	}, nil
+}
+
+// RelatedPosts — свежие посты о паре или её базовой монете (для BTCUSDT — ещё и о BTC)
+func (s *PostsService) RelatedPosts(ctx context.Context, pair string, limit int) ([]models.Post, error) {
+	pair = normalizeTag(pair)
+	if pair == "" {
+		return nil, fmt.Errorf("%w: pair is required", ErrUnknownTag)
+	}
+	tags := []string{pair}
+	if base, ok := strings.CutSuffix(pair, "USDT"); ok && base != "" {
+		tags = append(tags, base)
+	}
+
+	page, err := s.ListPosts(ctx, models.PostQuery{Tags: tags, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	return page.Posts, nil
+}
+
+// CreateComment добавляет комментарий к посту или, если parentID не нулевой, ответ
+// на другой комментарий того же поста. Автор поста, автор родительского комментария
+// и упомянутые через @username пользователи получают уведомления.
+func (s *PostsService) CreateComment(
+	ctx context.Context,
+	author *models.User,
+	postID bson.ObjectID,
+	parentID bson.ObjectID,
+	mainText string,
+) (bson.ObjectID, error) {
+	if err := checkWriter(author); err != nil {
+		return bson.ObjectID{}, err
+	}
+
+	createdAt := s.now().UTC()
+	comment := models.Comment{
+		AuthorID:  author.ID,
+		Person:    author.DisplayName,
+		MainText:  mainText,
+		Date:      createdAt.Format(time.RFC3339),
+		CreatedAt: createdAt,
+		PostID:    postID,
+		Revision:  1,
+		Shadowed:  author.ShadowBanned,
+	}
 	if err := s.validateComment(comment); err != nil {
-		return err
+		return bson.ObjectID{}, err
+	}
+	if err := s.allowPosting(author, "comment", commentRateLimit); err != nil {
+		return bson.ObjectID{}, err
+	}
+
+	var parent *models.Comment
+	if !parentID.IsZero() {
+		var err error
+		parent, err = s.threadParent(ctx, postID, parentID, author.ID)
+		if err != nil {
+			return bson.ObjectID{}, err
+		}
+		comment.ParentID = parent.ID
+		comment.Depth = parent.Depth + 1
+	}
+
+	mentioned := s.resolveMentions(mainText, author.ID)
+	for _, u := range mentioned {
+		comment.Mentions = append(comment.Mentions, u.ID)
 	}
-	return s.postStorage.CreateComment(ctx, comment)
+
+	id, err := s.postStorage.CreateComment(ctx, comment)
+	if err != nil {
+		return bson.ObjectID{}, notFoundAs(err, ErrPostNotFound)
+	}
+	comment.ID = id
+
+	// Теневой бан не должен выдавать себя уведомлениями и вебхуками
+	if !comment.Shadowed {
+		s.notifyComment(ctx, &comment, parent, mentioned)
+		data := map[string]any{
+			"id":        id.Hex(),
+			"postId":    postID.Hex(),
+			"authorId":  comment.AuthorID,
+			"author":    comment.Person,
+			"text":      comment.MainText,
+			"createdAt": comment.CreatedAt,
+		}
+		if parent != nil {
+			data["parentId"] = parent.ID.Hex()
+		}
+		s.publish(models.WebhookCommentCreated, data)
+	}
+	return id, nil
 }
 
-func (s *PostsService) GetLastPosts(ctx context.Context) ([]models.Post, error) {
-	return s.postStorage.GetLastPosts(ctx)
+// checkWriter: писать могут только вошедшие пользователи с подтверждённым email и без бана
+func checkWriter(author *models.User) error {
+	if author == nil || author.ID == 0 {
+		return ErrMissingAuthor
+	}
+	if !author.EmailVerified() {
+		return ErrEmailNotVerified
+	}
+	if author.BannedAt(time.Now()) {
+		return fmt.Errorf("%w until %s", ErrUserBanned, author.BannedUntil.UTC().Format(time.RFC3339))
+	}
+	return nil
 }
 
-func (s *PostsService) GetLastCommentsByPost(ctx context.Context, postID bson.ObjectID) ([]models.Comment, error) {
-	return s.postStorage.GetLastCommentsByPost(ctx, postID)
+var (
+	postRateLimit    = RateLimit{Requests: 5, Window: 10 * time.Minute}
+	commentRateLimit = RateLimit{Requests: 20, Window: 10 * time.Minute}
+)
+
+// allowPosting ограничивает частоту публикаций одного пользователя, модераторы без лимита.
+// Если хранилище лимитов недоступно, публикация проходит — как и в middleware RateLimit.
+func (s *PostsService) allowPosting(author *models.User, kind string, limit RateLimit) error {
+	if s.limiter == nil || author.Role.AtLeast(models.RoleModerator) {
+		return nil
+	}
+	allowed, retryAfter, err := s.limiter.Allow(fmt.Sprintf("%s:user:%d", kind, author.ID), limit)
+	if err != nil {
+		slog.Error("Posting rate limiter failed", "user_id", author.ID, "error", err)
+		return nil
+	}
+	if !allowed {
+		slog.Warn("Posting rate limit exceeded", "user_id", author.ID, "kind", kind)
+		return fmt.Errorf("%w: try again in %s", ErrPostingTooFast, retryAfter.Round(time.Second))
+	}
+	return nil
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// ListPosts отдаёт страницу постов и курсор следующей (пустой, если страница последняя)
+func (s *PostsService) ListPosts(ctx context.Context, q models.PostQuery) (*models.PostPage, error) {
+	switch q.Sort {
+	case "":
+		q.Sort = models.PostSortNewest
+	case models.PostSortNewest, models.PostSortComments, models.PostSortVotes, models.PostSortTop:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidSort, q.Sort)
+	}
+	for i := range q.Tags {
+		q.Tags[i] = normalizeTag(q.Tags[i])
+	}
+
+	after, err := models.DecodePageCursor(q.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	q.After = after
+
+	limit := pageSize(q.Limit)
+	q.Limit = limit + 1 // лишняя запись показывает, есть ли следующая страница
+	posts, err := s.postStorage.ListPosts(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	for i := range posts {
+		posts[i].MainTextHTML = renderMarkdown(posts[i].MainText)
+	}
+
+	page := &models.PostPage{Posts: posts}
+	if len(posts) > limit {
+		page.Posts = posts[:limit]
+		last := page.Posts[limit-1]
+		next := models.PageCursor{ID: last.ID}
+		switch q.Sort {
+		case models.PostSortComments:
+			next.Key = float64(last.CommentCount)
+		case models.PostSortVotes:
+			next.Key = float64(last.VoteScore)
+		case models.PostSortTop:
+			next.Key = last.HotScore
+		}
+		page.NextCursor = next.Encode()
+	}
+	return page, nil
+}
+
+// ListComments отдаёт страницу комментариев поста
+func (s *PostsService) ListComments(ctx context.Context, q models.CommentQuery) (*models.CommentPage, error) {
+	switch q.Sort {
+	case "":
+		q.Sort = models.CommentSortNewest
+	case models.CommentSortNewest, models.CommentSortOldest, models.CommentSortVotes:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidSort, q.Sort)
+	}
+	if q.PostID.IsZero() {
+		return nil, ErrInvalidPostID
+	}
+
+	after, err := models.DecodePageCursor(q.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	q.After = after
+
+	limit := pageSize(q.Limit)
+	q.Limit = limit + 1
+	comments, err := s.postStorage.ListComments(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	renderComments(comments)
+
+	page := &models.CommentPage{Comments: comments}
+	if len(comments) > limit {
+		page.Comments = comments[:limit]
+		last := page.Comments[limit-1]
+		next := models.PageCursor{ID: last.ID}
+		if q.Sort == models.CommentSortVotes {
+			next.Key = float64(last.VoteScore)
+		}
+		page.NextCursor = next.Encode()
+	}
+	return page, nil
+}
+
+// renderComments заполняет MainTextHTML: текст хранится как есть, HTML строится при выдаче
+func renderComments(comments []models.Comment) {
+	for i := range comments {
+		comments[i].MainTextHTML = renderMarkdown(comments[i].MainText)
+	}
+}
+
+func pageSize(limit int) int {
+	if limit <= 0 {
+		return defaultPageSize
+	}
+	if limit > maxPageSize {
+		return maxPageSize
+	}
+	return limit
+}
+
+// GetLastPosts — самые новые посты одной страницей (для RSS/Atom)
+func (s *PostsService) GetLastPosts(ctx context.Context) ([]models.Post, error) {
+	page, err := s.ListPosts(ctx, models.PostQuery{Limit: maxPageSize})
+	if err != nil {
+		return nil, err
+	}
+	return page.Posts, nil
 }
 
 func (s *PostsService) validatePost(post models.Post) error {
+	if post.AuthorID == 0 {
+		return ErrMissingAuthor
+	}
 	if post.Person == "" {
 		return ErrEmptyPerson
 	}
@@ -55,9 +485,6 @@ func (s *PostsService) validatePost(post models.Post) error {
 	if post.MainText == "" {
 		return ErrEmptyMainText
 	}
-	if post.Date == "" {
-		return ErrEmptyDate
-	}
 
 	if utf8.RuneCountInString(post.Person) > 100 {
 		return ErrPersonTooLong
@@ -69,20 +496,20 @@ func (s *PostsService) validatePost(post models.Post) error {
 		return ErrMainTextTooLong
 	}
 
-	return nil
+	return s.filter.Check(post.Heading, post.MainText)
 }
 
 // validateComment валидирует структуру комментария
 func (s *PostsService) validateComment(comment models.Comment) error {
+	if comment.AuthorID == 0 {
+		return ErrMissingAuthor
+	}
 	if comment.Person == "" {
 		return ErrEmptyPerson
 	}
 	if comment.MainText == "" {
 		return ErrEmptyMainText
 	}
-	if comment.Date == "" {
-		return ErrEmptyDate
-	}
 	if comment.PostID.IsZero() {
 		return ErrInvalidPostID
 	}
@@ -94,32 +521,34 @@ func (s *PostsService) validateComment(comment models.Comment) error {
 		return ErrCommentTooLong
 	}
 
-	return nil
+	return s.filter.Check(comment.MainText)
 }
 
-func (s *PostsService) DeletePost(ctx context.Context, postID bson.ObjectID, author string) error {
-	if author == "" {
-		return ErrEmptyPerson
+func (s *PostsService) DeletePost(ctx context.Context, postID bson.ObjectID, authorID int64) error {
+	if authorID == 0 {
+		return ErrMissingAuthor
 	}
-	return s.postStorage.DeletePost(ctx, postID, author)
+	return notFoundAs(s.postStorage.DeletePost(ctx, postID, authorID), ErrPostNotFound)
 }
 
-func (s *PostsService) DeleteComment(ctx context.Context, commentID bson.ObjectID, author string) error {
-	if author == "" {
-		return ErrEmptyPerson
+func (s *PostsService) DeleteComment(ctx context.Context, commentID bson.ObjectID, authorID int64) error {
+	if authorID == 0 {
+		return ErrMissingAuthor
 	}
-	return s.postStorage.DeleteComment(ctx, commentID, author)
+	return notFoundAs(s.postStorage.DeleteComment(ctx, commentID, authorID), ErrCommentNotFound)
 }
 
+// UpdatePost правит пост автора. Правила те же, что при публикации: забаненный
+// или неподтверждённый пользователь не может переписать и старые посты.
 func (s *PostsService) UpdatePost(
 	ctx context.Context,
 	postID bson.ObjectID,
-	author string,
+	author *models.User,
 	title string,
 	content string,
 ) error {
-	if author == "" {
-		return fmt.Errorf("%w: author is required", ErrEmptyPerson)
+	if err := checkWriter(author); err != nil {
+		return err
 	}
 	if title == "" {
 		return fmt.Errorf("%w: title is required", ErrEmptyHeading)
@@ -133,11 +562,17 @@ func (s *PostsService) UpdatePost(
 	if utf8.RuneCountInString(content) > 5000 {
 		return fmt.Errorf("%w: content exceeds 5000 characters", ErrMainTextTooLong)
 	}
+	if err := s.filter.Check(title, content); err != nil {
+		return err
+	}
 
-	err := s.postStorage.UpdatePost(ctx, postID, author, title, content)
+	err := s.postStorage.UpdatePost(ctx, postID, author.ID, title, content)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return fmt.Errorf("post not found or you don't have permission to edit it")
+			return ErrPostNotFound
+		}
+		if errors.Is(err, storage.ErrEditConflict) {
+			return ErrEditConflict
 		}
 		return fmt.Errorf("failed to update post: %w", err)
 	}
@@ -147,11 +582,11 @@ func (s *PostsService) UpdatePost(
 func (s *PostsService) UpdateComment(
 	ctx context.Context,
 	commentID bson.ObjectID,
-	author string,
+	author *models.User,
 	content string,
 ) error {
-	if author == "" {
-		return ErrEmptyPerson
+	if err := checkWriter(author); err != nil {
+		return err
 	}
 	if content == "" {
 		return ErrEmptyMainText
@@ -159,18 +594,118 @@ func (s *PostsService) UpdateComment(
 	if utf8.RuneCountInString(content) > 1000 {
 		return ErrCommentTooLong
 	}
+	if err := s.filter.Check(content); err != nil {
+		return err
+	}
+
+	err := s.postStorage.UpdateComment(ctx, commentID, author.ID, content)
+	if errors.Is(err, storage.ErrEditConflict) {
+		return ErrEditConflict
+	}
+	return notFoundAs(err, ErrCommentNotFound)
+}
+
+// notFoundAs превращает mongo.ErrNoDocuments (нет записи или она чужая) в ошибку сервиса
+func notFoundAs(err, notFound error) error {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return notFound
+	}
+	return err
+}
+
+const maxModerationReason = 500
+
+// Moderate скрывает (remove) или возвращает (restore) любой пост или комментарий
+// и записывает действие в журнал модерации. Доступно модераторам и администраторам.
+func (s *PostsService) Moderate(
+	ctx context.Context,
+	actor *models.User,
+	target models.ModerationTarget,
+	id bson.ObjectID,
+	action models.ModerationAction,
+	reason string,
+) error {
+	if actor == nil || !actor.Role.AtLeast(models.RoleModerator) {
+		return ErrNotModerator
+	}
+	if action != models.ModerationRemove && action != models.ModerationRestore {
+		return fmt.Errorf("%w: unknown action %q", ErrInvalidModeration, action)
+	}
+	reason = strings.TrimSpace(reason)
+	if utf8.RuneCountInString(reason) > maxModerationReason {
+		return fmt.Errorf("%w: reason exceeds %d characters", ErrInvalidModeration, maxModerationReason)
+	}
 
-	return s.postStorage.UpdateComment(ctx, commentID, author, content)
+	deleted := action == models.ModerationRemove
+	var err error
+	switch target {
+	case models.ModerationTargetPost:
+		err = notFoundAs(s.postStorage.SetPostDeleted(ctx, id, actor.ID, deleted), ErrPostNotFound)
+	case models.ModerationTargetComment:
+		err = notFoundAs(s.postStorage.SetCommentDeleted(ctx, id, actor.ID, deleted), ErrCommentNotFound)
+	default:
+		return fmt.Errorf("%w: unknown target %q", ErrInvalidModeration, target)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Повторное действие безопасно, поэтому при сбое журнала просим повторить его целиком
+	entry := &models.ModerationEntry{
+		ActorID:  actor.ID,
+		Action:   action,
+		Target:   target,
+		TargetID: id.Hex(),
+		Reason:   reason,
+	}
+	if err := s.modLog.LogModeration(entry); err != nil {
+		return err
+	}
+	slog.Info("Moderation action",
+		"actor_id", actor.ID,
+		"action", action,
+		"target", target,
+		"target_id", entry.TargetID)
+	return nil
+}
+
+func (s *PostsService) ModerationLog(limit int) ([]models.ModerationEntry, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	return s.modLog.ListModeration(limit)
+}
+
+// BackfillAuthors привязывает старые посты и комментарии к ID пользователей по имени
+func (s *PostsService) BackfillAuthors(ctx context.Context, users storage.UserStorage) (int, error) {
+	return s.postStorage.BackfillAuthorIDs(ctx, func(person string) (int64, bool) {
+		user, err := users.GetUserByName(person)
+		if err != nil {
+			return 0, false
+		}
+		return user.ID, true
+	})
 }
 
 var (
-	ErrEmptyPerson     = errors.New("person cannot be empty")
-	ErrEmptyHeading    = errors.New("heading cannot be empty")
-	ErrEmptyMainText   = errors.New("main text cannot be empty")
-	ErrEmptyDate       = errors.New("date cannot be empty")
-	ErrInvalidPostID   = errors.New("invalid post ID")
-	ErrPersonTooLong   = errors.New("person name too long (max 100 characters)")
-	ErrHeadingTooLong  = errors.New("heading too long (max 200 characters)")
-	ErrMainTextTooLong = errors.New("main text too long (max 5000 characters)")
-	ErrCommentTooLong  = errors.New("comment too long (max 1000 characters)")
+	ErrEmptyPerson         = errors.New("person cannot be empty")
+	ErrMissingAuthor       = errors.New("author id is required")
+	ErrEmptyHeading        = errors.New("heading cannot be empty")
+	ErrEmptyMainText       = errors.New("main text cannot be empty")
+	ErrInvalidPostID       = errors.New("invalid post ID")
+	ErrPersonTooLong       = errors.New("person name too long (max 100 characters)")
+	ErrHeadingTooLong      = errors.New("heading too long (max 200 characters)")
+	ErrMainTextTooLong     = errors.New("main text too long (max 5000 characters)")
+	ErrCommentTooLong      = errors.New("comment too long (max 1000 characters)")
+	ErrEmailNotVerified    = errors.New("please confirm your email before posting")
+	ErrPostNotFound        = errors.New("post not found or you don't have permission to change it")
+	ErrCommentNotFound     = errors.New("comment not found or you don't have permission to change it")
+	ErrNotModerator        = errors.New("moderator role required")
+	ErrInvalidModeration   = errors.New("invalid moderation request")
+	ErrInvalidSort         = errors.New("unknown sort mode")
+	ErrUnknownTag          = errors.New("tag is not a known coin or pair")
+	ErrTooManyTags         = errors.New("too many tags")
+	ErrAnalysisUnavailable = errors.New("analysis data is not available for this pair")
+	ErrUserBanned          = errors.New("you are banned from posting")
+	ErrPostingTooFast      = errors.New("you are posting too fast")
 )
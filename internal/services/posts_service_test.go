@@ -0,0 +1,806 @@
+package services
+
+import (
+	"context"
+	"crypto-analytics/internal/models"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type MockPostStorage struct {
+	Posts     []models.Post
+	Comments  []models.Comment
+	Votes     map[string]int // targetId/userId → голос
+	Reps      map[int64]int
+	Revisions []models.Revision
+}
+
+func (m *MockPostStorage) CreatePost(ctx context.Context, post models.Post) (bson.ObjectID, error) {
+	if post.ID.IsZero() {
+		post.ID = bson.NewObjectID()
+	}
+	m.Posts = append(m.Posts, post)
+	return post.ID, nil
+}
+
+func (m *MockPostStorage) CreateComment(ctx context.Context, comment models.Comment) (bson.ObjectID, error) {
+	comment.ID = bson.NewObjectID()
+	m.Comments = append(m.Comments, comment)
+	return comment.ID, nil
+}
+
+// visibleTo повторяет фильтр хранилища: теневые записи видит только автор
+func visibleTo(shadowed bool, authorID, viewerID int64) bool {
+	return !shadowed || authorID == viewerID
+}
+
+func (m *MockPostStorage) GetPost(ctx context.Context, postID bson.ObjectID, viewerID int64) (*models.Post, error) {
+	for i := range m.Posts {
+		p := &m.Posts[i]
+		if p.ID == postID && p.DeletedAt == nil && visibleTo(p.Shadowed, p.AuthorID, viewerID) {
+			return &m.Posts[i], nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (m *MockPostStorage) GetComment(ctx context.Context, commentID bson.ObjectID, viewerID int64) (*models.Comment, error) {
+	for i := range m.Comments {
+		c := &m.Comments[i]
+		if c.ID == commentID && c.DeletedAt == nil && visibleTo(c.Shadowed, c.AuthorID, viewerID) {
+			return &m.Comments[i], nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+// ListPosts повторяет порядок Mongo: ключ сортировки по убыванию, затем _id по убыванию
+func (m *MockPostStorage) ListPosts(ctx context.Context, q models.PostQuery) ([]models.Post, error) {
+	key := func(p models.Post) int64 {
+		switch q.Sort {
+		case models.PostSortComments:
+			return int64(p.CommentCount)
+		case models.PostSortVotes:
+			return int64(p.VoteScore)
+		}
+		return 0
+	}
+	before := func(a, b models.Post) bool {
+		if key(a) != key(b) {
+			return key(a) > key(b)
+		}
+		return a.ID.Hex() > b.ID.Hex()
+	}
+
+	var posts []models.Post
+	for _, p := range m.Posts {
+		if p.DeletedAt != nil || (q.AuthorID != 0 && p.AuthorID != q.AuthorID) {
+			continue
+		}
+		if !visibleTo(p.Shadowed, p.AuthorID, q.ViewerID) {
+			continue
+		}
+		if len(q.Tags) > 0 && !slices.ContainsFunc(p.Tags, func(tag string) bool { return slices.Contains(q.Tags, tag) }) {
+			continue
+		}
+		if q.After != nil && !before(models.Post{ID: q.After.ID, CommentCount: int(q.After.Key), VoteScore: int(q.After.Key)}, p) {
+			continue
+		}
+		posts = append(posts, p)
+	}
+	sort.Slice(posts, func(i, j int) bool { return before(posts[i], posts[j]) })
+	if len(posts) > q.Limit {
+		posts = posts[:q.Limit]
+	}
+	return posts, nil
+}
+
+func (m *MockPostStorage) ListComments(ctx context.Context, q models.CommentQuery) ([]models.Comment, error) {
+	var comments []models.Comment
+	for _, c := range m.Comments {
+		if c.PostID == q.PostID && visibleTo(c.Shadowed, c.AuthorID, q.ViewerID) {
+			comments = append(comments, c)
+		}
+	}
+	return comments, nil
+}
+
+func (m *MockPostStorage) DeletePost(ctx context.Context, postID bson.ObjectID, authorID int64) error {
+	for i := range m.Posts {
+		if m.Posts[i].ID == postID && m.Posts[i].AuthorID == authorID && m.Posts[i].DeletedAt == nil {
+			return m.SetPostDeleted(ctx, postID, authorID, true)
+		}
+	}
+	return mongo.ErrNoDocuments
+}
+
+func (m *MockPostStorage) DeleteComment(ctx context.Context, commentID bson.ObjectID, authorID int64) error {
+	return mongo.ErrNoDocuments
+}
+
+func (m *MockPostStorage) SetPostDeleted(ctx context.Context, postID bson.ObjectID, actorID int64, deleted bool) error {
+	for i := range m.Posts {
+		if m.Posts[i].ID == postID {
+			m.Posts[i].DeletedAt, m.Posts[i].DeletedBy = nil, 0
+			if deleted {
+				now := time.Now()
+				m.Posts[i].DeletedAt, m.Posts[i].DeletedBy = &now, actorID
+			}
+			return nil
+		}
+	}
+	return mongo.ErrNoDocuments
+}
+
+func (m *MockPostStorage) SetCommentDeleted(ctx context.Context, commentID bson.ObjectID, actorID int64, deleted bool) error {
+	return mongo.ErrNoDocuments
+}
+
+func (m *MockPostStorage) UpdatePost(ctx context.Context, postID bson.ObjectID, authorID int64, title, content string) error {
+	for i := range m.Posts {
+		p := &m.Posts[i]
+		if p.ID == postID && p.AuthorID == authorID && p.DeletedAt == nil {
+			m.Revisions = append(m.Revisions, models.Revision{
+				Target:   models.VoteTargetPost,
+				TargetID: p.ID,
+				Version:  p.Revision,
+				Heading:  p.Heading,
+				MainText: p.MainText,
+			})
+			now := time.Now()
+			p.Heading, p.MainText = title, content
+			p.UpdatedAt, p.Edited = &now, true
+			p.Revision++
+			return nil
+		}
+	}
+	return mongo.ErrNoDocuments
+}
+
+func (m *MockPostStorage) ListRevisions(ctx context.Context, target models.VoteTarget, targetID bson.ObjectID) ([]models.Revision, error) {
+	var out []models.Revision
+	for _, r := range m.Revisions {
+		if r.Target == target && r.TargetID == targetID {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (m *MockPostStorage) UpdateComment(ctx context.Context, commentID bson.ObjectID, authorID int64, content string) error {
+	return mongo.ErrNoDocuments
+}
+
+func (m *MockPostStorage) BackfillAuthorIDs(ctx context.Context, resolve func(person string) (int64, bool)) (int, error) {
+	return 0, nil
+}
+
+func (m *MockPostStorage) TargetAuthor(ctx context.Context, target models.VoteTarget, id bson.ObjectID, viewerID int64) (int64, error) {
+	for _, p := range m.Posts {
+		if p.ID == id && p.DeletedAt == nil && visibleTo(p.Shadowed, p.AuthorID, viewerID) {
+			return p.AuthorID, nil
+		}
+	}
+	return 0, mongo.ErrNoDocuments
+}
+
+func (m *MockPostStorage) Vote(ctx context.Context, target models.VoteTarget, targetID bson.ObjectID, authorID, userID int64, value int) (int, error) {
+	if m.Votes == nil {
+		m.Votes, m.Reps = map[string]int{}, map[int64]int{}
+	}
+	key := fmt.Sprintf("%s/%d", targetID.Hex(), userID)
+	delta := value - m.Votes[key]
+	m.Votes[key] = value
+	m.Reps[authorID] += delta
+	for i := range m.Posts {
+		if m.Posts[i].ID == targetID {
+			m.Posts[i].VoteScore += delta
+			return m.Posts[i].VoteScore, nil
+		}
+	}
+	return 0, mongo.ErrNoDocuments
+}
+
+func (m *MockPostStorage) React(ctx context.Context, target models.VoteTarget, targetID bson.ObjectID, userID int64, emoji string, on bool) (map[string]int, error) {
+	return nil, nil
+}
+
+func (m *MockPostStorage) Reputation(ctx context.Context, userID int64) (int, error) {
+	return m.Reps[userID], nil
+}
+
+func (m *MockPostStorage) Close() {}
+
+type MockModerationLogStorage struct {
+	Entries []models.ModerationEntry
+}
+
+func (m *MockModerationLogStorage) LogModeration(entry *models.ModerationEntry) error {
+	m.Entries = append(m.Entries, *entry)
+	return nil
+}
+
+func (m *MockModerationLogStorage) ListModeration(limit int) ([]models.ModerationEntry, error) {
+	return m.Entries, nil
+}
+
+type MockCoins struct {
+	Coins []models.Coin
+}
+
+func (m *MockCoins) GetTopCryptos(limit int) ([]models.Coin, error) {
+	return m.Coins, nil
+}
+
+func (m *MockCoins) GetCacheInfo() (int, time.Time) {
+	return len(m.Coins), time.Time{}
+}
+
+type MockPairs struct {
+	Pairs []string
+}
+
+func (m *MockPairs) GetAllPairs() ([]string, error) {
+	return m.Pairs, nil
+}
+
+func (m *MockPairs) GetPairsCount() int {
+	return len(m.Pairs)
+}
+
+type MockPairAnalysis struct {
+	Data map[string]*models.AnalysisData
+}
+
+func (m *MockPairAnalysis) GetPairInfo(pair, timeframe string) (*models.AnalysisData, error) {
+	if data, ok := m.Data[pair+"/"+timeframe]; ok {
+		return data, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func TestPostsService_AuthorFromSession(t *testing.T) {
+	store := &MockPostStorage{}
+	s := NewPostService(store, &MockModerationLogStorage{}, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("MSK", 3*3600))
+	s.now = func() time.Time { return now }
+
+	verifiedAt := now
+	author := &models.User{ID: 7, Username: "satoshi", DisplayName: "Satoshi", EmailVerifiedAt: &verifiedAt}
+	unverified := &models.User{ID: 8, Username: "hal", DisplayName: "Hal"}
+
+	if _, err := s.CreatePost(context.Background(), nil, models.PostDraft{Heading: "Title", MainText: "Text"}); !errors.Is(err, ErrMissingAuthor) {
+		t.Errorf("expected ErrMissingAuthor for anonymous post, got %v", err)
+	}
+	if _, err := s.CreatePost(context.Background(), unverified, models.PostDraft{Heading: "Title", MainText: "Text"}); !errors.Is(err, ErrEmailNotVerified) {
+		t.Errorf("expected ErrEmailNotVerified, got %v", err)
+	}
+
+	postID, err := s.CreatePost(context.Background(), author, models.PostDraft{Heading: "Title", MainText: "Text"})
+	if err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+	post := store.Posts[0]
+	if post.AuthorID != 7 || post.Person != "Satoshi" {
+		t.Errorf("author must come from the user, got %d %q", post.AuthorID, post.Person)
+	}
+	if !post.CreatedAt.Equal(now) || post.CreatedAt.Location() != time.UTC || post.Date != "2024-05-01T09:00:00Z" {
+		t.Errorf("unexpected timestamps: %v %q", post.CreatedAt, post.Date)
+	}
+
+	if _, err := s.CreateComment(context.Background(), author, postID, bson.ObjectID{}, "Nice"); err != nil {
+		t.Fatalf("create comment: %v", err)
+	}
+	if c := store.Comments[0]; c.AuthorID != 7 || c.Date != post.Date {
+		t.Errorf("unexpected comment: %+v", c)
+	}
+
+	if err := s.UpdatePost(context.Background(), postID, unverified, "Hijack", "Text"); !errors.Is(err, ErrEmailNotVerified) {
+		t.Errorf("expected ErrEmailNotVerified for edit, got %v", err)
+	}
+	stranger := &models.User{ID: 9, DisplayName: "Hal", EmailVerifiedAt: &verifiedAt}
+	if err := s.UpdatePost(context.Background(), postID, stranger, "Hijack", "Text"); !errors.Is(err, ErrPostNotFound) {
+		t.Errorf("expected ErrPostNotFound for foreign post, got %v", err)
+	}
+}
+
+func TestPostsService_Moderate(t *testing.T) {
+	store := &MockPostStorage{}
+	modLog := &MockModerationLogStorage{}
+	s := NewPostService(store, modLog, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	verifiedAt := time.Now()
+	author := &models.User{ID: 1, DisplayName: "Author", Role: models.RoleUser, EmailVerifiedAt: &verifiedAt}
+	moderator := &models.User{ID: 2, Role: models.RoleModerator}
+	postID, err := s.CreatePost(context.Background(), author, models.PostDraft{Heading: "Title", MainText: "Text"})
+	if err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		actor   *models.User
+		target  models.ModerationTarget
+		action  models.ModerationAction
+		wantErr error
+	}{
+		{name: "regular user", actor: author, target: models.ModerationTargetPost, action: models.ModerationRemove, wantErr: ErrNotModerator},
+		{name: "unknown action", actor: moderator, target: models.ModerationTargetPost, action: "ban", wantErr: ErrInvalidModeration},
+		{name: "unknown target", actor: moderator, target: "user", action: models.ModerationRemove, wantErr: ErrInvalidModeration},
+		{name: "remove", actor: moderator, target: models.ModerationTargetPost, action: models.ModerationRemove},
+		{name: "remove again is idempotent", actor: moderator, target: models.ModerationTargetPost, action: models.ModerationRemove},
+		{name: "restore", actor: moderator, target: models.ModerationTargetPost, action: models.ModerationRestore},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.Moderate(context.Background(), tt.actor, tt.target, postID, tt.action, "spam")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if len(modLog.Entries) != 3 {
+		t.Fatalf("expected 3 log entries, got %d", len(modLog.Entries))
+	}
+	if e := modLog.Entries[0]; e.ActorID != 2 || e.TargetID != postID.Hex() || e.Reason != "spam" {
+		t.Errorf("unexpected log entry: %+v", e)
+	}
+	if store.Posts[0].DeletedAt != nil {
+		t.Error("post must be restored")
+	}
+
+	// Автор удаляет свой пост мягко; повторно удалить или изменить его уже нельзя
+	if err := s.DeletePost(context.Background(), postID, author.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if store.Posts[0].DeletedAt == nil || store.Posts[0].DeletedBy != author.ID {
+		t.Errorf("expected soft delete by author, got %+v", store.Posts[0])
+	}
+	if err := s.DeletePost(context.Background(), postID, author.ID); !errors.Is(err, ErrPostNotFound) {
+		t.Errorf("expected ErrPostNotFound on second delete, got %v", err)
+	}
+}
+
+func TestPostsService_ListPosts(t *testing.T) {
+	store := &MockPostStorage{}
+	s := NewPostService(store, &MockModerationLogStorage{}, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	// Одинаковые счётчики у соседних постов: курсор обязан различать их по _id
+	for i, count := range []int{3, 1, 3, 0, 3, 2, 1} {
+		store.Posts = append(store.Posts, models.Post{
+			ID:           bson.NewObjectID(),
+			AuthorID:     int64(i%2 + 1),
+			Heading:      "Post",
+			CommentCount: count,
+		})
+	}
+
+	tests := []struct {
+		name  string
+		query models.PostQuery
+		want  []int // CommentCount по порядку выдачи
+	}{
+		{name: "most commented", query: models.PostQuery{Sort: models.PostSortComments, Limit: 2}, want: []int{3, 3, 3, 2, 1, 1, 0}},
+		{name: "newest", query: models.PostQuery{Limit: 3}, want: []int{1, 2, 3, 0, 3, 1, 3}},
+		{name: "by author", query: models.PostQuery{AuthorID: 2, Limit: 2}, want: []int{2, 0, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			seen := map[bson.ObjectID]bool{}
+			q := tt.query
+			for pages := 0; ; pages++ {
+				if pages > len(store.Posts) {
+					t.Fatal("pagination does not terminate")
+				}
+				page, err := s.ListPosts(context.Background(), q)
+				if err != nil {
+					t.Fatalf("list: %v", err)
+				}
+				for _, p := range page.Posts {
+					if seen[p.ID] {
+						t.Fatalf("post %s returned twice", p.ID.Hex())
+					}
+					seen[p.ID] = true
+					got = append(got, p.CommentCount)
+				}
+				if page.NextCursor == "" {
+					break
+				}
+				q.Cursor = page.NextCursor
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("expected %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+
+	if _, err := s.ListPosts(context.Background(), models.PostQuery{Sort: "random"}); !errors.Is(err, ErrInvalidSort) {
+		t.Errorf("expected ErrInvalidSort, got %v", err)
+	}
+	if _, err := s.ListPosts(context.Background(), models.PostQuery{Cursor: "not a cursor"}); !errors.Is(err, models.ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestPostsService_TagsAndSnapshot(t *testing.T) {
+	store := &MockPostStorage{}
+	s := NewPostService(
+		store,
+		&MockModerationLogStorage{},
+		&MockCoins{Coins: []models.Coin{{ID: "bitcoin", Symbol: "btc"}, {ID: "ethereum", Symbol: "eth"}}},
+		&MockPairs{Pairs: []string{"BTCUSDT", "ETHUSDT"}},
+		&MockPairAnalysis{Data: map[string]*models.AnalysisData{
+			"BTCUSDT/1h": {
+				Pair:       "BTCUSDT",
+				Timeframe:  "1h",
+				Candles:    []models.Candle{{Close: 60000}, {Close: 61000}},
+				Indicators: models.TechnicalIndicators{RSI: 55},
+			},
+		}},
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+	)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	verifiedAt := now
+	author := &models.User{ID: 1, DisplayName: "Author", EmailVerifiedAt: &verifiedAt}
+	draft := func(tags []string, pair, timeframe string) models.PostDraft {
+		return models.PostDraft{Heading: "Title", MainText: "Text", Tags: tags, AnalysisPair: pair, AnalysisTimeframe: timeframe}
+	}
+
+	tests := []struct {
+		name     string
+		draft    models.PostDraft
+		wantErr  error
+		wantTags []string
+	}{
+		{name: "normalized and deduplicated", draft: draft([]string{" btc", "BTC", "ethusdt", ""}, "", ""), wantTags: []string{"BTC", "ETHUSDT"}},
+		{name: "unknown coin", draft: draft([]string{"DOGE"}, "", ""), wantErr: ErrUnknownTag},
+		{name: "too many", draft: draft([]string{"BTC", "ETH", "BTCUSDT", "ETHUSDT", "SOL", "XRP"}, "", ""), wantErr: ErrTooManyTags},
+		{name: "no analysis data", draft: draft(nil, "ETHUSDT", "5m"), wantErr: ErrAnalysisUnavailable},
+		{name: "snapshot tags its pair", draft: draft([]string{"BTC"}, "btcusdt", "1h"), wantTags: []string{"BTC", "BTCUSDT"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store.Posts = nil
+			_, err := s.CreatePost(context.Background(), author, tt.draft)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if got := store.Posts[0].Tags; !slices.Equal(got, tt.wantTags) {
+				t.Errorf("expected tags %v, got %v", tt.wantTags, got)
+			}
+		})
+	}
+
+	// Последний пост — со снимком: цена с последней свечи, время — серверное
+	snap := store.Posts[0].Analysis
+	if snap == nil || snap.Price != 61000 || snap.Indicators.RSI != 55 || !snap.TakenAt.Equal(now) {
+		t.Fatalf("unexpected snapshot: %+v", snap)
+	}
+
+	related, err := s.RelatedPosts(context.Background(), "btcusdt", 5)
+	if err != nil || len(related) != 1 {
+		t.Fatalf("expected the snapshot post to be related to BTCUSDT, got %v %v", related, err)
+	}
+}
+
+func TestPostsService_Vote(t *testing.T) {
+	store := &MockPostStorage{}
+	s := NewPostService(store, &MockModerationLogStorage{}, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	verifiedAt := time.Now()
+	author := &models.User{ID: 1, DisplayName: "Author", EmailVerifiedAt: &verifiedAt}
+	alice := &models.User{ID: 2, DisplayName: "Alice", EmailVerifiedAt: &verifiedAt}
+	bob := &models.User{ID: 3, DisplayName: "Bob", EmailVerifiedAt: &verifiedAt}
+	postID, err := s.CreatePost(context.Background(), author, models.PostDraft{Heading: "Title", MainText: "Text"})
+	if err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		voter     *models.User
+		target    models.VoteTarget
+		value     int
+		wantErr   error
+		wantScore int
+	}{
+		{name: "upvote", voter: alice, target: models.VoteTargetPost, value: 1, wantScore: 1},
+		{name: "repeated upvote counts once", voter: alice, target: models.VoteTargetPost, value: 1, wantScore: 1},
+		{name: "second user", voter: bob, target: models.VoteTargetPost, value: 1, wantScore: 2},
+		{name: "change to downvote", voter: alice, target: models.VoteTargetPost, value: -1, wantScore: 0},
+		{name: "retract", voter: bob, target: models.VoteTargetPost, value: 0, wantScore: -1},
+		{name: "own post", voter: author, target: models.VoteTargetPost, value: 1, wantErr: ErrSelfVote},
+		{name: "bad value", voter: bob, target: models.VoteTargetPost, value: 5, wantErr: ErrInvalidVote},
+		{name: "bad target", voter: bob, target: "user", value: 1, wantErr: ErrInvalidVote},
+		{name: "anonymous", voter: nil, target: models.VoteTargetPost, value: 1, wantErr: ErrMissingAuthor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, err := s.Vote(context.Background(), tt.voter, tt.target, postID, tt.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && score != tt.wantScore {
+				t.Errorf("expected score %d, got %d", tt.wantScore, score)
+			}
+		})
+	}
+
+	if rep, _ := s.Reputation(context.Background(), author.ID); rep != -1 {
+		t.Errorf("expected author reputation -1, got %d", rep)
+	}
+	if _, err := s.React(context.Background(), alice, models.VoteTargetPost, postID, "clown", true); !errors.Is(err, ErrUnknownReaction) {
+		t.Errorf("expected ErrUnknownReaction, got %v", err)
+	}
+}
+
+func TestHotScore(t *testing.T) {
+	created := models.HotEpoch.Add(48 * time.Hour)
+
+	if got := models.HotScore(0, created); got != 4 {
+		t.Errorf("expected 4 decay periods after two days, got %v", got)
+	}
+	// Десятикратный рост голосов стоит ровно одного периода свежести
+	if a, b := models.HotScore(100, created), models.HotScore(10, created.Add(models.HotDecay)); a != b {
+		t.Errorf("expected equal scores, got %v and %v", a, b)
+	}
+	if models.HotScore(-10, created) >= models.HotScore(0, created) {
+		t.Error("downvoted post must rank below a neutral one")
+	}
+}
+
+type commentNotice struct {
+	UserID int64
+	Reason models.CommentNoticeReason
+}
+
+type MockCommentNotifier struct {
+	Notices []commentNotice
+}
+
+func (m *MockCommentNotifier) NotifyComment(recipient *models.User, reason models.CommentNoticeReason, post *models.Post, comment *models.Comment) {
+	m.Notices = append(m.Notices, commentNotice{UserID: recipient.ID, Reason: reason})
+}
+
+func TestPostsService_Threads(t *testing.T) {
+	users := NewMockUserStorage()
+	verifiedAt := time.Now()
+	newUser := func(name string) *models.User {
+		u := &models.User{Username: name, DisplayName: name, Email: name + "@example.com", EmailVerifiedAt: &verifiedAt}
+		if err := users.CreateUser(u); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		return u
+	}
+	op, alice, bob := newUser("op"), newUser("alice"), newUser("bob.eth")
+
+	store := &MockPostStorage{}
+	notifier := &MockCommentNotifier{}
+	s := NewPostService(store, &MockModerationLogStorage{}, nil, nil, nil, users, notifier, nil, nil, nil, nil)
+	ctx := context.Background()
+
+	postID, err := s.CreatePost(ctx, op, models.PostDraft{Heading: "Title", MainText: "Text"})
+	if err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+	otherPostID, _ := s.CreatePost(ctx, op, models.PostDraft{Heading: "Other", MainText: "Text"})
+
+	// Алиса комментирует пост: уведомлён только автор поста
+	rootID, err := s.CreateComment(ctx, alice, postID, bson.ObjectID{}, "First!")
+	if err != nil {
+		t.Fatalf("create comment: %v", err)
+	}
+	// Боб отвечает Алисе и упоминает её и автора поста: каждый получает одно уведомление
+	replyID, err := s.CreateComment(ctx, bob, postID, rootID, "@alice agreed, cc @op. @nobody")
+	if err != nil {
+		t.Fatalf("create reply: %v", err)
+	}
+
+	want := []commentNotice{
+		{UserID: op.ID, Reason: models.NoticePostComment},
+		{UserID: alice.ID, Reason: models.NoticeMention},
+		{UserID: op.ID, Reason: models.NoticeMention},
+	}
+	if !slices.Equal(notifier.Notices, want) {
+		t.Errorf("expected notices %v, got %v", want, notifier.Notices)
+	}
+	reply := store.Comments[1]
+	if reply.ParentID != rootID || reply.Depth != 1 || !slices.Equal(reply.Mentions, []int64{alice.ID, op.ID}) {
+		t.Errorf("unexpected reply: parent %v depth %d mentions %v", reply.ParentID, reply.Depth, reply.Mentions)
+	}
+
+	// Упоминание с точкой в имени и точкой в конце предложения
+	if _, err := s.CreateComment(ctx, alice, postID, replyID, "thanks @bob.eth."); err != nil {
+		t.Fatalf("create comment: %v", err)
+	}
+	if m := store.Comments[2].Mentions; !slices.Equal(m, []int64{bob.ID}) {
+		t.Errorf("expected bob.eth to be mentioned, got %v", m)
+	}
+
+	if _, err := s.CreateComment(ctx, alice, otherPostID, rootID, "wrong thread"); !errors.Is(err, ErrParentOtherPost) {
+		t.Errorf("expected ErrParentOtherPost, got %v", err)
+	}
+
+	// На максимальной глубине ответ встаёт рядом с родителем
+	parentID := replyID
+	for i := 0; i < maxCommentDepth+2; i++ {
+		parentID, err = s.CreateComment(ctx, alice, postID, parentID, "deeper")
+		if err != nil {
+			t.Fatalf("create nested comment: %v", err)
+		}
+	}
+	for _, c := range store.Comments {
+		if c.Depth > maxCommentDepth {
+			t.Fatalf("comment depth %d exceeds the limit", c.Depth)
+		}
+	}
+
+	tree, err := s.CommentTree(ctx, postID, 0)
+	if err != nil {
+		t.Fatalf("tree: %v", err)
+	}
+	if len(tree) != 1 || tree[0].ID != rootID || len(tree[0].Replies) != 1 || tree[0].Replies[0].ID != replyID {
+		t.Fatalf("unexpected tree shape: %+v", tree)
+	}
+}
+
+func TestPostsService_Revisions(t *testing.T) {
+	verifiedAt := time.Now()
+	author := &models.User{ID: 1, DisplayName: "op", EmailVerifiedAt: &verifiedAt}
+	store := &MockPostStorage{}
+	s := NewPostService(store, &MockModerationLogStorage{}, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	ctx := context.Background()
+
+	postID, err := s.CreatePost(ctx, author, models.PostDraft{Heading: "BTC outlook", MainText: "BTC will go up soon"})
+	if err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+	if err := s.UpdatePost(ctx, postID, author, "BTC outlook", "BTC will go down soon"); err != nil {
+		t.Fatalf("update post: %v", err)
+	}
+	if err := s.UpdatePost(ctx, postID, author, "BTC weekly outlook", "BTC will go down soon"); err != nil {
+		t.Fatalf("update post: %v", err)
+	}
+	if post := store.Posts[0]; !post.Edited || post.Revision != 3 {
+		t.Errorf("expected edited post at revision 3, got edited=%v revision=%d", post.Edited, post.Revision)
+	}
+
+	revisions, err := s.PostRevisions(ctx, postID, 0)
+	if err != nil {
+		t.Fatalf("revisions: %v", err)
+	}
+	if len(revisions) != 3 {
+		t.Fatalf("expected 3 versions, got %d", len(revisions))
+	}
+	for i, r := range revisions {
+		if r.Version != i+1 {
+			t.Errorf("version %d: got number %d", i, r.Version)
+		}
+	}
+	if revisions[0].TextDiff != nil {
+		t.Errorf("first version has no diff, got %v", revisions[0].TextDiff)
+	}
+	wantText := []models.DiffOp{
+		{Op: models.DiffEqual, Text: "BTC will go "},
+		{Op: models.DiffDelete, Text: "up"},
+		{Op: models.DiffInsert, Text: "down"},
+		{Op: models.DiffEqual, Text: " soon"},
+	}
+	if !slices.Equal(revisions[1].TextDiff, wantText) {
+		t.Errorf("expected text diff %v, got %v", wantText, revisions[1].TextDiff)
+	}
+	wantHeading := []models.DiffOp{
+		{Op: models.DiffEqual, Text: "BTC "},
+		{Op: models.DiffInsert, Text: "weekly "},
+		{Op: models.DiffEqual, Text: "outlook"},
+	}
+	if !slices.Equal(revisions[2].HeadingDiff, wantHeading) {
+		t.Errorf("expected heading diff %v, got %v", wantHeading, revisions[2].HeadingDiff)
+	}
+
+	if _, err := s.PostRevisions(ctx, bson.NewObjectID(), 0); !errors.Is(err, ErrPostNotFound) {
+		t.Errorf("expected ErrPostNotFound, got %v", err)
+	}
+}
+
+func TestDiffWords(t *testing.T) {
+	tests := []struct {
+		before, after string
+	}{
+		{"", ""},
+		{"", "new text"},
+		{"old text", ""},
+		{"a b c d", "a x c y"},
+		{"same", "same"},
+		{"line one\nline two", "line one\nline 2\nline three"},
+	}
+	for _, tt := range tests {
+		var before, after string
+		for _, op := range diffWords(tt.before, tt.after) {
+			if op.Op != models.DiffInsert {
+				before += op.Text
+			}
+			if op.Op != models.DiffDelete {
+				after += op.Text
+			}
+		}
+		if before != tt.before || after != tt.after {
+			t.Errorf("diff of %q → %q restores %q → %q", tt.before, tt.after, before, after)
+		}
+	}
+}
+
+func TestPostsService_ShadowedSingleReads(t *testing.T) {
+	store := &MockPostStorage{}
+	s := NewPostService(store, &MockModerationLogStorage{}, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	ctx := context.Background()
+
+	verifiedAt := time.Now()
+	shadowed := &models.User{ID: 1, DisplayName: "Shadow", EmailVerifiedAt: &verifiedAt, ShadowBanned: true}
+	reader := &models.User{ID: 2, DisplayName: "Reader", EmailVerifiedAt: &verifiedAt}
+
+	hiddenPost, err := s.CreatePost(ctx, shadowed, models.PostDraft{Heading: "Buy", MainText: "my course"})
+	if err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+	publicPost, err := s.CreatePost(ctx, reader, models.PostDraft{Heading: "BTC", MainText: "Looks bullish"})
+	if err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+	hiddenComment, err := s.CreateComment(ctx, shadowed, publicPost, bson.ObjectID{}, "my course")
+	if err != nil {
+		t.Fatalf("create comment: %v", err)
+	}
+
+	// Для остальных теневой пост не существует: обработчики отвечают на это 404
+	for _, viewerID := range []int64{0, reader.ID} {
+		if _, err := s.PostRevisions(ctx, hiddenPost, viewerID); !errors.Is(err, ErrPostNotFound) {
+			t.Errorf("viewer %d: expected ErrPostNotFound for revisions, got %v", viewerID, err)
+		}
+		if _, err := s.CommentRevisions(ctx, hiddenComment, viewerID); !errors.Is(err, ErrCommentNotFound) {
+			t.Errorf("viewer %d: expected ErrCommentNotFound for revisions, got %v", viewerID, err)
+		}
+	}
+	if _, err := s.Vote(ctx, reader, models.VoteTargetPost, hiddenPost, 1); !errors.Is(err, ErrPostNotFound) {
+		t.Errorf("expected ErrPostNotFound for vote, got %v", err)
+	}
+	if _, err := s.CreateComment(ctx, reader, publicPost, hiddenComment, "reply"); !errors.Is(err, ErrCommentNotFound) {
+		t.Errorf("expected ErrCommentNotFound for reply, got %v", err)
+	}
+
+	// Автор видит своё как обычно
+	if _, err := s.PostRevisions(ctx, hiddenPost, shadowed.ID); err != nil {
+		t.Errorf("author must see own post revisions: %v", err)
+	}
+	if _, err := s.CreateComment(ctx, shadowed, publicPost, hiddenComment, "reply"); err != nil {
+		t.Errorf("author must be able to reply to own comment: %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"crypto-analytics/internal/storage"
+	"time"
+)
+
+// RateLimit — не больше Requests запросов за Window
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+type RedisRateLimiter struct {
+	storage storage.RateLimitStorage
+}
+
+func NewRedisRateLimiter(limitStorage storage.RateLimitStorage) *RedisRateLimiter {
+	return &RedisRateLimiter{storage: limitStorage}
+}
+
+// Allow учитывает запрос по ключу key. При превышении лимита возвращает false
+// и время до начала следующего окна.
+func (l *RedisRateLimiter) Allow(key string, limit RateLimit) (bool, time.Duration, error) {
+	count, ttl, err := l.storage.Hit("req:"+key, limit.Window)
+	if err != nil {
+		return false, 0, err
+	}
+	if count > int64(limit.Requests) {
+		return false, ttl, nil
+	}
+	return true, 0, nil
+}
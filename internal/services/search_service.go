@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/storage"
+)
+
+const (
+	minSearchQuery     = 2
+	maxSearchQuery     = 200
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+	snippetRunes       = 200
+	snippetLead        = 60 // сколько текста оставить перед первым совпадением
+)
+
+// SearchService проверяет запрос, передаёт его движку и строит сниппеты с подсветкой
+type SearchService struct {
+	engine storage.SearchEngine
+}
+
+func NewSearchService(engine storage.SearchEngine) *SearchService {
+	return &SearchService{engine: engine}
+}
+
+// Search находит посты и комментарии. Синтаксис запроса — как у $text в Mongo:
+// слова через пробел (любое из них), "точная фраза", -исключённое слово.
+func (s *SearchService) Search(ctx context.Context, q models.SearchQuery) ([]models.SearchHit, error) {
+	q.Text = strings.TrimSpace(q.Text)
+	if n := utf8.RuneCountInString(q.Text); n < minSearchQuery || n > maxSearchQuery {
+		return nil, fmt.Errorf("%w: query must be %d to %d characters", ErrInvalidSearch, minSearchQuery, maxSearchQuery)
+	}
+	terms := searchTerms(q.Text)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("%w: query has no words to look for", ErrInvalidSearch)
+	}
+	switch q.Kind {
+	case "", models.SearchPosts, models.SearchComments:
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidSearch, q.Kind)
+	}
+	if q.From != nil && q.To != nil && !q.From.Before(*q.To) {
+		return nil, fmt.Errorf("%w: empty date range", ErrInvalidSearch)
+	}
+	if q.Limit <= 0 || q.Limit > maxSearchLimit {
+		q.Limit = defaultSearchLimit
+	}
+
+	hits, err := s.engine.Search(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	for i := range hits {
+		hits[i].HeadingHTML = highlightTerms(hits[i].Heading, terms, 0)
+		// Сниппет строится по тексту без разметки Markdown
+		plain := htmlToText(renderMarkdown(hits[i].Text), 0)
+		hits[i].Snippet = highlightTerms(plain, terms, snippetRunes)
+	}
+	if hits == nil {
+		hits = []models.SearchHit{}
+	}
+	return hits, nil
+}
+
+// searchTerms — слова запроса в нижнем регистре, которые надо подсветить.
+// Слова из фраз учитываются, исключённые через минус — нет.
+func searchTerms(query string) map[string]bool {
+	terms := map[string]bool{}
+	inPhrase := false
+	for i, part := range strings.Split(query, `"`) {
+		if i > 0 {
+			inPhrase = !inPhrase
+		}
+		for _, field := range strings.Fields(part) {
+			if !inPhrase && strings.HasPrefix(field, "-") {
+				continue
+			}
+			for _, word := range strings.FieldsFunc(field, isNotWordRune) {
+				terms[strings.ToLower(word)] = true
+			}
+		}
+	}
+	return terms
+}
+
+func isNotWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// highlightTerms экранирует текст и оборачивает совпавшие слова в <mark>.
+// При maxRunes > 0 оставляет окно вокруг первого совпадения, не разрезая слов.
+func highlightTerms(text string, terms map[string]bool, maxRunes int) string {
+	runes := []rune(text)
+
+	type word struct{ start, end int }
+	var words []word
+	first := -1
+	for i := 0; i < len(runes); {
+		if isNotWordRune(runes[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && !isNotWordRune(runes[j]) {
+			j++
+		}
+		if terms[strings.ToLower(string(runes[i:j]))] {
+			words = append(words, word{i, j})
+			if first < 0 {
+				first = i
+			}
+		}
+		i = j
+	}
+
+	start, end := 0, len(runes)
+	if maxRunes > 0 && len(runes) > maxRunes {
+		start = max(first-snippetLead, 0)
+		end = min(start+maxRunes, len(runes))
+		start = max(end-maxRunes, 0)
+		for start > 0 && start < len(runes) && !unicode.IsSpace(runes[start-1]) && (first < 0 || start < first) {
+			start++
+		}
+		for cut := end; cut > start && cut < len(runes); cut-- {
+			if unicode.IsSpace(runes[cut]) {
+				end = cut
+				break
+			}
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, w := range words {
+		if w.start < start || w.end > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(runes[pos:w.start])))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(string(runes[w.start:w.end])))
+		b.WriteString("</mark>")
+		pos = w.end
+	}
+	b.WriteString(html.EscapeString(strings.TrimRightFunc(string(runes[pos:end]), unicode.IsSpace)))
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+var ErrInvalidSearch = errors.New("invalid search query")
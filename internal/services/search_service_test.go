@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"crypto-analytics/internal/models"
+)
+
+type MockSearchEngine struct {
+	Hits  []models.SearchHit
+	Query models.SearchQuery
+	Calls int
+}
+
+func (m *MockSearchEngine) Search(ctx context.Context, q models.SearchQuery) ([]models.SearchHit, error) {
+	m.Query = q
+	m.Calls++
+	return append([]models.SearchHit(nil), m.Hits...), nil
+}
+
+func TestSearchTerms(t *testing.T) {
+	got := searchTerms(`BTC "halving cycle" -scam ETH/USDT`)
+	want := []string{"btc", "halving", "cycle", "eth", "usdt"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for _, w := range want {
+		if !got[w] {
+			t.Errorf("term %q is missing in %v", w, got)
+		}
+	}
+	if got["scam"] {
+		t.Error("excluded words must not be highlighted")
+	}
+}
+
+func TestHighlightTerms(t *testing.T) {
+	terms := map[string]bool{"btc": true, "рост": true}
+
+	tests := []struct {
+		name     string
+		text     string
+		maxRunes int
+		want     string
+	}{
+		{"case-insensitive whole words", "Btc and BTCUSDT", 0, "<mark>Btc</mark> and BTCUSDT"},
+		{"cyrillic", "Ждём рост, а не падение", 0, "Ждём <mark>рост</mark>, а не падение"},
+		{"markup is escaped", `<script>alert("btc")</script>`, 0, "&lt;script&gt;alert(&#34;<mark>btc</mark>&#34;)&lt;/script&gt;"},
+		{"no match", "nothing here", 0, "nothing here"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlightTerms(tt.text, terms, tt.maxRunes); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	long := strings.Repeat("filler words ", 40) + "then btc broke out " + strings.Repeat("more text ", 40)
+	snippet := highlightTerms(long, terms, 100)
+	if !strings.HasPrefix(snippet, "…") || !strings.HasSuffix(snippet, "…") {
+		t.Errorf("expected ellipses around a window, got %q", snippet)
+	}
+	if !strings.Contains(snippet, "<mark>btc</mark>") {
+		t.Errorf("window must contain the first match, got %q", snippet)
+	}
+	body := strings.Trim(snippet, "…")
+	if strings.HasPrefix(body, "iller") || strings.HasPrefix(body, "ords") || strings.HasSuffix(body, "tex") {
+		t.Errorf("window must not cut words, got %q", snippet)
+	}
+	if n := len([]rune(strings.NewReplacer("<mark>", "", "</mark>", "").Replace(body))); n > 100 {
+		t.Errorf("snippet is %d runes, want at most 100", n)
+	}
+}
+
+func TestSearchService_Search(t *testing.T) {
+	engine := &MockSearchEngine{Hits: []models.SearchHit{
+		{Kind: models.SearchPosts, Heading: "BTC weekly", Text: "**BTC** looks <b>strong</b>", Score: 2},
+		{Kind: models.SearchComments, Heading: "Altseason", Text: "no btc talk here", Score: 1},
+	}}
+	s := NewSearchService(engine)
+	ctx := context.Background()
+
+	hits, err := s.Search(ctx, models.SearchQuery{Text: "  btc  ", Limit: 500})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if engine.Query.Text != "btc" || engine.Query.Limit != defaultSearchLimit {
+		t.Errorf("query was not normalized: %+v", engine.Query)
+	}
+	if hits[0].HeadingHTML != "<mark>BTC</mark> weekly" {
+		t.Errorf("unexpected heading: %q", hits[0].HeadingHTML)
+	}
+	// Сниппет — текст без Markdown и HTML из поста
+	if hits[0].Snippet != "<mark>BTC</mark> looks &lt;b&gt;strong&lt;/b&gt;" {
+		t.Errorf("unexpected snippet: %q", hits[0].Snippet)
+	}
+	if hits[1].Snippet != "no <mark>btc</mark> talk here" {
+		t.Errorf("unexpected comment snippet: %q", hits[1].Snippet)
+	}
+
+	from := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, -1)
+	tests := []struct {
+		name  string
+		query models.SearchQuery
+	}{
+		{"too short", models.SearchQuery{Text: "b"}},
+		{"too long", models.SearchQuery{Text: strings.Repeat("b", maxSearchQuery+1)}},
+		{"only exclusions", models.SearchQuery{Text: "-scam -rug"}},
+		{"unknown type", models.SearchQuery{Text: "btc", Kind: "user"}},
+		{"reversed dates", models.SearchQuery{Text: "btc", From: &from, To: &to}},
+	}
+	calls := engine.Calls
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Search(ctx, tt.query); !errors.Is(err, ErrInvalidSearch) {
+				t.Errorf("expected ErrInvalidSearch, got %v", err)
+			}
+		})
+	}
+	if engine.Calls != calls {
+		t.Error("invalid queries must not reach the engine")
+	}
+}
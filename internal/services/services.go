@@ -3,11 +3,37 @@ package services
 import (
 	"context"
 	"crypto-analytics/internal/models"
+	"io"
 	"time"
 
+	"github.com/gorilla/sessions"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+type SessionStore interface {
+	sessions.Store
+	Discard(session *sessions.Session) error
+	ListSessions(userID int64) ([]models.Session, error)
+	RevokeSession(userID int64, publicID string) error
+	RevokeAllSessions(userID int64, exceptID string) (int, error)
+}
+
+type APITokenManager interface {
+	CreateToken(userID int64, name string, scopes []models.Scope, ttl time.Duration) (string, *models.APIToken, error)
+	Authenticate(plain string) (*models.APIToken, error)
+	ListTokens(userID int64) ([]models.APIToken, error)
+	RevokeToken(userID, tokenID int64) error
+}
+
+type TwoFactorManager interface {
+	BeginEnrollment(userID int64) (secret, uri string, err error)
+	ConfirmEnrollment(userID int64, code string) ([]string, error)
+	Verify(userID int64, code string) error
+	Disable(userID int64, password, code string) error
+	RegenerateRecoveryCodes(userID int64, code string) ([]string, error)
+	RecoveryCodesLeft(userID int64) (int, error)
+}
+
 type AnalysisGService interface {
 	GetPairInfo(pair, timeframe string) (*models.AnalysisData, error)
 }
@@ -22,9 +48,52 @@ type NewsRssService interface {
 	GetNewsCount() (int, error)
 }
 
+type FeedBuilder interface {
+	NewsFeed(coin string) (*models.Feed, error)
+	PostsFeed(ctx context.Context) (*models.Feed, error)
+}
+
 type Notifier interface {
 	NotifyAdmContForm(contact *models.ContactForm)
 	NotifyAdmNewUserForm(contact *models.User)
+	NotifyAdmSuspiciousLogin(event *models.AuthEvent, failures int64, lockFor time.Duration)
+}
+
+// NotificationSettings — каналы, в которые пользователь получает уведомления
+type NotificationSettings interface {
+	ListChannels(userID int64) ([]models.ChannelPreference, error)
+	SaveChannel(user *models.User, pref models.ChannelPreference) (*models.ChannelPreference, error)
+	DeleteChannel(user *models.User, channel models.NotificationChannel) error
+}
+
+// EventPublisher рассылает события подписчикам исходящих вебхуков
+type EventPublisher interface {
+	PublishEvent(event models.WebhookEvent, data any)
+}
+
+// WebhookManager — вебхуки пользователя: регистрация, смена секрета, журнал и тестовое событие
+type WebhookManager interface {
+	ListWebhooks(user *models.User) ([]models.WebhookEndpoint, error)
+	CreateWebhook(user *models.User, draft models.WebhookEndpoint) (string, *models.WebhookEndpoint, error)
+	UpdateWebhook(user *models.User, id int64, draft models.WebhookEndpoint) (*models.WebhookEndpoint, error)
+	DeleteWebhook(user *models.User, id int64) error
+	RotateSecret(user *models.User, id int64) (string, error)
+	SendTestEvent(ctx context.Context, user *models.User, id int64) (*models.WebhookDelivery, error)
+	Deliveries(user *models.User, id int64, limit int) ([]models.WebhookDelivery, error)
+}
+
+type CommentNotifier interface {
+	NotifyComment(recipient *models.User, reason models.CommentNoticeReason, post *models.Post, comment *models.Comment)
+}
+
+type RateLimiter interface {
+	Allow(key string, limit RateLimit) (bool, time.Duration, error)
+}
+
+type LoginGuard interface {
+	Check(login, ip string) (time.Duration, error)
+	Failure(attempt models.AuthEvent) (time.Duration, error)
+	Success(login string) error
 }
 
 type AIAnalysisService interface {
@@ -33,32 +102,118 @@ type AIAnalysisService interface {
 }
 
 type PostPService interface {
-	CreatePost(ctx context.Context, post models.Post) (bson.ObjectID, error)
-	CreateComment(ctx context.Context, comment models.Comment) error
-	GetLastPosts(ctx context.Context) ([]models.Post, error)
-	GetLastCommentsByPost(ctx context.Context, postID bson.ObjectID) ([]models.Comment, error)
-	DeletePost(ctx context.Context, postID bson.ObjectID, author string) error
-	DeleteComment(ctx context.Context, commentID bson.ObjectID, author string) error
+	CreatePost(ctx context.Context, author *models.User, draft models.PostDraft) (bson.ObjectID, error)
+	CreateComment(
+		ctx context.Context,
+		author *models.User,
+		postID bson.ObjectID,
+		parentID bson.ObjectID,
+		mainText string,
+	) (bson.ObjectID, error)
+	CommentTree(ctx context.Context, postID bson.ObjectID, viewerID int64) ([]*models.CommentNode, error)
+	ListPosts(ctx context.Context, q models.PostQuery) (*models.PostPage, error)
+	ListComments(ctx context.Context, q models.CommentQuery) (*models.CommentPage, error)
+	RelatedPosts(ctx context.Context, pair string, limit int) ([]models.Post, error)
+	Vote(ctx context.Context, voter *models.User, target models.VoteTarget, id bson.ObjectID, value int) (int, error)
+	React(
+		ctx context.Context,
+		user *models.User,
+		target models.VoteTarget,
+		id bson.ObjectID,
+		reaction string,
+		on bool,
+	) (map[string]int, error)
+	Reputation(ctx context.Context, userID int64) (int, error)
+	DeletePost(ctx context.Context, postID bson.ObjectID, authorID int64) error
+	DeleteComment(ctx context.Context, commentID bson.ObjectID, authorID int64) error
+	Moderate(
+		ctx context.Context,
+		actor *models.User,
+		target models.ModerationTarget,
+		id bson.ObjectID,
+		action models.ModerationAction,
+		reason string,
+	) error
+	ModerationLog(limit int) ([]models.ModerationEntry, error)
 	UpdatePost(
 		ctx context.Context,
 		postID bson.ObjectID,
-		author string,
+		author *models.User,
 		title string,
 		content string,
 	) error
 	UpdateComment(
 		ctx context.Context,
 		commentID bson.ObjectID,
-		author string,
+		author *models.User,
 		content string,
 	) error
+	PostRevisions(ctx context.Context, postID bson.ObjectID, viewerID int64) ([]models.RevisionDiff, error)
+	CommentRevisions(ctx context.Context, commentID bson.ObjectID, viewerID int64) ([]models.RevisionDiff, error)
 }
+type AttachmentManager interface {
+	Upload(ctx context.Context, owner *models.User, r io.Reader) (*models.Attachment, error)
+	Open(ctx context.Context, id bson.ObjectID, thumb bool) (*models.Attachment, io.ReadCloser, error)
+}
+
+type ModerationManager interface {
+	Report(
+		ctx context.Context,
+		reporter *models.User,
+		target models.ModerationTarget,
+		id bson.ObjectID,
+		reason models.ReportReason,
+		details string,
+	) error
+	ReportQueue(limit int) ([]models.ReportQueueItem, error)
+	ResolveReports(
+		ctx context.Context,
+		actor *models.User,
+		target models.ModerationTarget,
+		id bson.ObjectID,
+		action models.ModerationAction,
+		note string,
+	) error
+	BanUser(actor *models.User, userID int64, duration time.Duration, reason string) error
+	ShadowBan(actor *models.User, userID int64, reason string) error
+	Unban(actor *models.User, userID int64, reason string) error
+}
+
+type TicketManager interface {
+	ListTickets(q models.TicketQuery) ([]models.Ticket, error)
+	GetTicket(id int64) (*models.Ticket, error)
+	SetStatus(actor *models.User, id int64, status models.TicketStatus) error
+	Assign(actor *models.User, id int64, assigneeID int64) error
+	AddNote(actor *models.User, id int64, body string) (*models.TicketNote, error)
+	Reply(ctx context.Context, actor *models.User, id int64, body string) (*models.TicketNote, error)
+}
+
+type FormChecker interface {
+	IssueToken(form FormKind) string
+	Check(ctx context.Context, form FormKind, sub FormSubmission) error
+	Widget() *CaptchaWidget
+}
+
+type Searcher interface {
+	Search(ctx context.Context, q models.SearchQuery) ([]models.SearchHit, error)
+}
+
 type UserLogService interface {
 	RegisterUser(user *models.User) error
-	LoginUser(username, password string) error
+	LoginUser(login, password string) (*models.User, error)
+	GetUserByID(id int64) (*models.User, error)
+	GetUserByName(username string) (*models.User, error)
+	UpdateProfile(userID int64, displayName, email string) (*models.User, error)
+	ChangePassword(userID int64, currentPassword, newPassword string) error
+	SendVerificationEmail(userID int64) error
+	VerifyEmail(token string) (*models.User, error)
+	RequestPasswordReset(email string) error
+	ResetPassword(token, newPassword string) (int64, error)
 	HashPassword(password string) (string, error)
-	AddFavorite(username, CoinID string) error
-	RemoveFavorite(username, CoinID string) error
-	GetFavorites(username string) ([]string, error)
+	AddFavorite(userID int64, CoinID string) error
+	RemoveFavorite(userID int64, CoinID string) error
+	GetFavorites(userID int64) ([]string, error)
+	SetRole(actorID, userID int64, role models.Role) error
+	ExportUsers(w io.Writer) (int, error)
 	PrintJsonAllUsers(fileName string) error
 }
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/storage"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+const (
+	sessionMaxAge = 86400
+	// Чаще этого last seen не обновляется, чтобы не писать в Redis на каждый запрос
+	sessionTouchInterval = time.Minute
+)
+
+// RedisSessionStore — реализация sessions.Store, которая хранит значения сессии на сервере,
+// а в куке держит только подписанный ID. Ключи подписи передаются от нового к старому:
+// новые куки подписываются первым ключом, остальные принимаются, пока идёт ротация.
+type RedisSessionStore struct {
+	storage storage.SessionStorage
+	codecs  []securecookie.Codec
+	Options *sessions.Options
+	now     func() time.Time
+}
+
+func NewRedisSessionStore(sessionStorage storage.SessionStorage, secure bool, keys ...string) *RedisSessionStore {
+	pairs := make([][]byte, 0, len(keys)*2)
+	for _, key := range keys {
+		// Куки содержит только случайный ID, поэтому шифровать нечего — только подпись
+		pairs = append(pairs, []byte(key), nil)
+	}
+
+	return &RedisSessionStore{
+		storage: sessionStorage,
+		codecs:  securecookie.CodecsFromPairs(pairs...),
+		Options: &sessions.Options{
+			Path:     "/",
+			MaxAge:   sessionMaxAge,
+			HttpOnly: true,
+			Secure:   secure,
+			SameSite: http.SameSiteLaxMode,
+		},
+		now: time.Now,
+	}
+}
+
+func (s *RedisSessionStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+func (s *RedisSessionStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.Options
+	session.Options = &opts
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	var id string
+	if err := securecookie.DecodeMulti(name, cookie.Value, &id, s.codecs...); err != nil {
+		// Подделанная кука или подписанная выведенным из ротации ключом
+		return session, nil
+	}
+
+	record, err := s.storage.GetSession(id)
+	if err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			return session, nil
+		}
+		return session, err
+	}
+
+	if len(record.Data) > 0 {
+		if err := (securecookie.GobEncoder{}).Deserialize(record.Data, &session.Values); err != nil {
+			return session, fmt.Errorf("decode session values: %w", err)
+		}
+	}
+	session.ID = id
+	session.IsNew = false
+
+	s.touch(r, record)
+	return session, nil
+}
+
+func (s *RedisSessionStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := s.storage.DeleteSession(session.ID); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	now := s.now().UTC()
+	record := &models.Session{ID: session.ID, CreatedAt: now}
+	if session.ID == "" {
+		id, err := newSessionID()
+		if err != nil {
+			return err
+		}
+		session.ID = id
+		record.ID = id
+	} else if existing, err := s.storage.GetSession(session.ID); err == nil {
+		record.CreatedAt = existing.CreatedAt
+	}
+
+	data, err := (securecookie.GobEncoder{}).Serialize(session.Values)
+	if err != nil {
+		return fmt.Errorf("encode session values: %w", err)
+	}
+
+	record.PublicID = publicSessionID(record.ID)
+	record.UserID = sessionUserID(session)
+	record.Data = data
+	record.LastSeen = now
+	record.IP = ClientIP(r)
+	record.UserAgent = r.UserAgent()
+
+	ttl := time.Duration(session.Options.MaxAge) * time.Second
+	if ttl == 0 {
+		ttl = sessionMaxAge * time.Second
+	}
+	if err := s.storage.SaveSession(record, ttl); err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.codecs...)
+	if err != nil {
+		return fmt.Errorf("encode session cookie: %w", err)
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// Discard удаляет серверную запись и сбрасывает ID — при следующем Save выпустится новый.
+// Вызывается при входе, чтобы ID, известный до логина, не стал авторизованным.
+func (s *RedisSessionStore) Discard(session *sessions.Session) error {
+	if session.ID != "" {
+		if err := s.storage.DeleteSession(session.ID); err != nil {
+			return err
+		}
+	}
+	session.ID = ""
+	session.IsNew = true
+	session.Values = make(map[interface{}]interface{})
+	return nil
+}
+
+// ListSessions возвращает сессии пользователя, самые активные первыми
+func (s *RedisSessionStore) ListSessions(userID int64) ([]models.Session, error) {
+	list, err := s.storage.ListUserSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].LastSeen.After(list[j].LastSeen)
+	})
+	return list, nil
+}
+
+// RevokeSession завершает одну сессию пользователя по её публичному ID
+func (s *RedisSessionStore) RevokeSession(userID int64, publicID string) error {
+	list, err := s.storage.ListUserSessions(userID)
+	if err != nil {
+		return err
+	}
+	for _, session := range list {
+		if session.PublicID == publicID {
+			return s.storage.DeleteSession(session.ID)
+		}
+	}
+	return storage.ErrSessionNotFound
+}
+
+// RevokeAllSessions завершает все сессии пользователя, кроме exceptID (пусто — все)
+func (s *RedisSessionStore) RevokeAllSessions(userID int64, exceptID string) (int, error) {
+	return s.storage.DeleteUserSessions(userID, exceptID)
+}
+
+func (s *RedisSessionStore) touch(r *http.Request, record *models.Session) {
+	now := s.now().UTC()
+	if now.Sub(record.LastSeen) < sessionTouchInterval {
+		return
+	}
+
+	record.LastSeen = now
+	record.IP = ClientIP(r)
+	record.UserAgent = r.UserAgent()
+	if err := s.storage.SaveSession(record, 0); err != nil {
+		// Не критично: сессия валидна, просто last seen останется старым
+		slog.Debug("Failed to update session last seen", "error", err)
+	}
+}
+
+func sessionUserID(session *sessions.Session) int64 {
+	if loggedIn, _ := session.Values["loggedIn"].(bool); !loggedIn {
+		return 0
+	}
+	userID, _ := session.Values["userID"].(int64)
+	return userID
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate session id: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// publicSessionID — идентификатор для API: по нему можно отозвать сессию, но нельзя её угнать
+func publicSessionID(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ClientIP берёт последний адрес из X-Forwarded-For — его дописал наш прокси (Caddy),
+// предыдущие клиент может подставить сам
+func ClientIP(r *http.Request) string {
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+		parts := strings.Split(fwd, ",")
+		if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
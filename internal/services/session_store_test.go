@@ -0,0 +1,164 @@
+package services
+
+import (
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/storage"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type MockSessionStorage struct {
+	Sessions map[string]*models.Session
+}
+
+func NewMockSessionStorage() *MockSessionStorage {
+	return &MockSessionStorage{Sessions: map[string]*models.Session{}}
+}
+
+func (m *MockSessionStorage) SaveSession(session *models.Session, ttl time.Duration) error {
+	stored := *session
+	m.Sessions[session.ID] = &stored
+	return nil
+}
+
+func (m *MockSessionStorage) GetSession(id string) (*models.Session, error) {
+	s, ok := m.Sessions[id]
+	if !ok {
+		return nil, storage.ErrSessionNotFound
+	}
+	copied := *s
+	return &copied, nil
+}
+
+func (m *MockSessionStorage) DeleteSession(id string) error {
+	delete(m.Sessions, id)
+	return nil
+}
+
+func (m *MockSessionStorage) ListUserSessions(userID int64) ([]models.Session, error) {
+	var out []models.Session
+	for _, s := range m.Sessions {
+		if s.UserID == userID {
+			out = append(out, *s)
+		}
+	}
+	return out, nil
+}
+
+func (m *MockSessionStorage) DeleteUserSessions(userID int64, exceptID string) (int, error) {
+	removed := 0
+	for id, s := range m.Sessions {
+		if s.UserID == userID && id != exceptID {
+			delete(m.Sessions, id)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// login сохраняет авторизованную сессию и возвращает выданную куку
+func login(t *testing.T, store *RedisSessionStore, userID int64) *http.Cookie {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/login", nil)
+	w := httptest.NewRecorder()
+
+	session, err := store.New(r, "user-session")
+	if err != nil {
+		t.Fatalf("new session: %v", err)
+	}
+	session.Values["loggedIn"] = true
+	session.Values["userID"] = userID
+	if err := store.Save(r, w, session); err != nil {
+		t.Fatalf("save session: %v", err)
+	}
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("expected one cookie, got %d", len(cookies))
+	}
+	return cookies[0]
+}
+
+func loadSession(t *testing.T, store *RedisSessionStore, cookie *http.Cookie) (int64, bool) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cookie)
+	session, err := store.New(r, "user-session")
+	if err != nil {
+		t.Fatalf("load session: %v", err)
+	}
+	userID, ok := session.Values["userID"].(int64)
+	return userID, ok && !session.IsNew
+}
+
+func TestRedisSessionStore_KeyRotation(t *testing.T) {
+	backend := NewMockSessionStorage()
+	oldStore := NewRedisSessionStore(backend, true, "old-key")
+	cookie := login(t, oldStore, 42)
+
+	if !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("insecure cookie flags: %+v", cookie)
+	}
+
+	tests := []struct {
+		name   string
+		keys   []string
+		wantOK bool
+	}{
+		{name: "same key", keys: []string{"old-key"}, wantOK: true},
+		{name: "rotated, old key still accepted", keys: []string{"new-key", "old-key"}, wantOK: true},
+		{name: "old key retired", keys: []string{"new-key"}, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewRedisSessionStore(backend, true, tt.keys...)
+			userID, ok := loadSession(t, store, cookie)
+			if ok != tt.wantOK || (ok && userID != 42) {
+				t.Fatalf("expected ok=%v user 42, got ok=%v user %d", tt.wantOK, ok, userID)
+			}
+		})
+	}
+}
+
+func TestRedisSessionStore_Revoke(t *testing.T) {
+	backend := NewMockSessionStorage()
+	store := NewRedisSessionStore(backend, false, "key")
+
+	laptop := login(t, store, 1)
+	phone := login(t, store, 1)
+	other := login(t, store, 2)
+
+	list, err := store.ListSessions(1)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("expected 2 sessions, got %d (%v)", len(list), err)
+	}
+	for _, s := range list {
+		if s.PublicID == "" || s.PublicID == s.ID {
+			t.Fatalf("public id must differ from session id: %+v", s)
+		}
+	}
+
+	if err := store.RevokeSession(2, list[0].PublicID); !errors.Is(err, storage.ErrSessionNotFound) {
+		t.Errorf("must not revoke another user's session, got %v", err)
+	}
+	if err := store.RevokeSession(1, list[0].PublicID); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+
+	_, laptopOK := loadSession(t, store, laptop)
+	_, phoneOK := loadSession(t, store, phone)
+	if laptopOK == phoneOK {
+		t.Fatalf("exactly one session should survive, laptop=%v phone=%v", laptopOK, phoneOK)
+	}
+
+	if n, err := store.RevokeAllSessions(1, ""); err != nil || n != 1 {
+		t.Fatalf("expected 1 revoked, got %d (%v)", n, err)
+	}
+	if _, ok := loadSession(t, store, other); !ok {
+		t.Error("other user's session must survive")
+	}
+}
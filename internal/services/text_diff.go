@@ -0,0 +1,91 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+
+	"crypto-analytics/internal/models"
+)
+
+// maxDiffCells ограничивает таблицу LCS: на больших правках память растёт квадратично,
+// и дешевле показать изменённый кусок целиком как удалённый и добавленный
+const maxDiffCells = 1_000_000
+
+var diffTokenPattern = regexp.MustCompile(`\s+|\S+`)
+
+// diffWords сравнивает две версии текста по словам (пробелы — отдельные токены,
+// поэтому склейка кусков даёт исходные строки)
+func diffWords(before, after string) []models.DiffOp {
+	a := diffTokenPattern.FindAllString(before, -1)
+	b := diffTokenPattern.FindAllString(after, -1)
+
+	// Общие начало и конец не участвуют в LCS — обычно правка затрагивает малую часть текста
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []models.DiffOp
+	add := func(op string, tokens []string) {
+		if len(tokens) == 0 {
+			return
+		}
+		text := strings.Join(tokens, "")
+		if n := len(ops); n > 0 && ops[n-1].Op == op {
+			ops[n-1].Text += text
+			return
+		}
+		ops = append(ops, models.DiffOp{Op: op, Text: text})
+	}
+
+	add(models.DiffEqual, a[:prefix])
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(midA)*len(midB) > maxDiffCells {
+		add(models.DiffDelete, midA)
+		add(models.DiffInsert, midB)
+	} else {
+		lcsDiff(midA, midB, add)
+	}
+	add(models.DiffEqual, a[len(a)-suffix:])
+	return ops
+}
+
+// lcsDiff раскладывает различия a и b по наибольшей общей подпоследовательности
+func lcsDiff(a, b []string, add func(op string, tokens []string)) {
+	// lcs[i][j] — длина LCS для a[i:] и b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			add(models.DiffEqual, a[i:i+1])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			add(models.DiffDelete, a[i:i+1])
+			i++
+		default:
+			add(models.DiffInsert, b[j:j+1])
+			j++
+		}
+	}
+	add(models.DiffDelete, a[i:])
+	add(models.DiffInsert, b[j:])
+}
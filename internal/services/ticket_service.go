@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/storage"
+)
+
+const (
+	maxTicketNote      = 5000
+	defaultTicketLimit = 50
+	maxTicketLimit     = 200
+)
+
+// TicketService ведёт обращения из формы обратной связи: статус, исполнитель,
+// внутренние заметки и ответы автору письмом. Работают с ним администраторы.
+type TicketService struct {
+	tickets storage.TicketStorage
+	users   storage.UserStorage
+	mailer  MailSender
+}
+
+func NewTicketService(tickets storage.TicketStorage, users storage.UserStorage, mailer MailSender) *TicketService {
+	return &TicketService{
+		tickets: tickets,
+		users:   users,
+		mailer:  mailer,
+	}
+}
+
+func (s *TicketService) ListTickets(q models.TicketQuery) ([]models.Ticket, error) {
+	if q.Status != "" && !q.Status.Valid() {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidTicket, q.Status)
+	}
+	if q.Limit <= 0 || q.Limit > maxTicketLimit {
+		q.Limit = defaultTicketLimit
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	q.Email = strings.TrimSpace(q.Email)
+	return s.tickets.ListTickets(q)
+}
+
+func (s *TicketService) GetTicket(id int64) (*models.Ticket, error) {
+	ticket, err := s.tickets.GetTicket(id)
+	if errors.Is(err, storage.ErrTicketNotFound) {
+		return nil, ErrTicketNotFound
+	}
+	return ticket, err
+}
+
+func (s *TicketService) SetStatus(actor *models.User, id int64, status models.TicketStatus) error {
+	if !status.Valid() {
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidTicket, status)
+	}
+	if err := s.ticketErr(s.tickets.SetTicketStatus(id, status)); err != nil {
+		return err
+	}
+	slog.Info("Ticket status changed", "ticket_id", id, "status", status, "actor_id", actor.ID)
+	return nil
+}
+
+// Assign назначает обращение администратору; assigneeID = 0 снимает назначение
+func (s *TicketService) Assign(actor *models.User, id int64, assigneeID int64) error {
+	var assignee *int64
+	if assigneeID != 0 {
+		user, err := s.users.GetUserByID(assigneeID)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return fmt.Errorf("%w: assignee not found", ErrInvalidTicket)
+		}
+		if err != nil {
+			return err
+		}
+		if !user.Role.AtLeast(models.RoleAdmin) {
+			return fmt.Errorf("%w: tickets can only be assigned to admins", ErrInvalidTicket)
+		}
+		assignee = &assigneeID
+	}
+	if err := s.ticketErr(s.tickets.AssignTicket(id, assignee)); err != nil {
+		return err
+	}
+	slog.Info("Ticket assigned", "ticket_id", id, "assignee_id", assigneeID, "actor_id", actor.ID)
+	return nil
+}
+
+// AddNote сохраняет внутреннюю заметку; автор обращения её не видит
+func (s *TicketService) AddNote(actor *models.User, id int64, body string) (*models.TicketNote, error) {
+	body, err := ticketNoteBody(body)
+	if err != nil {
+		return nil, err
+	}
+	note := &models.TicketNote{TicketID: id, AuthorID: actor.ID, Kind: models.TicketNoteInternal, Body: body}
+	if err := s.ticketErr(s.tickets.AddTicketNote(note)); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// Reply отправляет ответ автору обращения и сохраняет его в истории.
+// Письмо уходит до записи: неотправленный ответ не должен выглядеть отправленным.
+// Новое обращение после ответа переходит в работу.
+func (s *TicketService) Reply(ctx context.Context, actor *models.User, id int64, body string) (*models.TicketNote, error) {
+	body, err := ticketNoteBody(body)
+	if err != nil {
+		return nil, err
+	}
+	ticket, err := s.GetTicket(id)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := mail.ParseAddress(ticket.Email)
+	if err != nil {
+		return nil, fmt.Errorf("%w: sender address %q is not valid", ErrInvalidTicket, ticket.Email)
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, mailSendTimeout)
+	defer cancel()
+	if err := s.mailer.Send(sendCtx, ticketReplyMail(ticket, addr.Address, body)); err != nil {
+		slog.Error("Failed to send ticket reply", "ticket_id", id, "error", err)
+		return nil, fmt.Errorf("%w: %v", ErrReplyNotSent, err)
+	}
+
+	note := &models.TicketNote{TicketID: id, AuthorID: actor.ID, Kind: models.TicketNoteReply, Body: body}
+	if err := s.tickets.AddTicketNote(note); err != nil {
+		slog.Error("Ticket reply was sent but not saved", "ticket_id", id, "error", err)
+		return nil, err
+	}
+	if ticket.Status == models.TicketNew {
+		if err := s.tickets.SetTicketStatus(id, models.TicketInProgress); err != nil {
+			slog.Error("Failed to move ticket in progress", "ticket_id", id, "error", err)
+		}
+	}
+	slog.Info("Ticket reply sent", "ticket_id", id, "actor_id", actor.ID)
+	return note, nil
+}
+
+func (s *TicketService) ticketErr(err error) error {
+	if errors.Is(err, storage.ErrTicketNotFound) {
+		return ErrTicketNotFound
+	}
+	return err
+}
+
+func ticketNoteBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", fmt.Errorf("%w: text is empty", ErrInvalidTicket)
+	}
+	if utf8.RuneCountInString(body) > maxTicketNote {
+		return "", fmt.Errorf("%w: text exceeds %d characters", ErrInvalidTicket, maxTicketNote)
+	}
+	return body, nil
+}
+
+func ticketReplyMail(ticket *models.Ticket, to string, body string) models.MailMessage {
+	quoted := "> " + strings.ReplaceAll(strings.TrimSpace(ticket.Message), "\n", "\n> ")
+	return models.MailMessage{
+		To:      to,
+		Subject: fmt.Sprintf("Re: your message #%d — Crypto Analytics", ticket.ID),
+		Body: fmt.Sprintf("Hi %s,\n\n%s\n\nYou wrote on %s:\n\n%s\n",
+			ticket.Name, body, ticket.CreatedAt.Format("2006-01-02"), quoted),
+	}
+}
+
+var (
+	ErrTicketNotFound = errors.New("ticket not found")
+	ErrInvalidTicket  = errors.New("invalid ticket update")
+	ErrReplyNotSent   = errors.New("reply could not be sent")
+)
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/storage"
+)
+
+type MockTicketStorage struct {
+	Tickets map[int64]*models.Ticket
+	nextID  int64
+}
+
+func (m *MockTicketStorage) ListTickets(q models.TicketQuery) ([]models.Ticket, error) {
+	var out []models.Ticket
+	for _, t := range m.Tickets {
+		if q.Status != "" && t.Status != q.Status {
+			continue
+		}
+		out = append(out, *t)
+	}
+	return out, nil
+}
+
+func (m *MockTicketStorage) GetTicket(id int64) (*models.Ticket, error) {
+	t, ok := m.Tickets[id]
+	if !ok {
+		return nil, storage.ErrTicketNotFound
+	}
+	ticket := *t
+	return &ticket, nil
+}
+
+func (m *MockTicketStorage) SetTicketStatus(id int64, status models.TicketStatus) error {
+	t, ok := m.Tickets[id]
+	if !ok {
+		return storage.ErrTicketNotFound
+	}
+	t.Status = status
+	return nil
+}
+
+func (m *MockTicketStorage) AssignTicket(id int64, assigneeID *int64) error {
+	t, ok := m.Tickets[id]
+	if !ok {
+		return storage.ErrTicketNotFound
+	}
+	t.AssigneeID = assigneeID
+	return nil
+}
+
+func (m *MockTicketStorage) AddTicketNote(note *models.TicketNote) error {
+	t, ok := m.Tickets[note.TicketID]
+	if !ok {
+		return storage.ErrTicketNotFound
+	}
+	m.nextID++
+	note.ID = m.nextID
+	t.Notes = append(t.Notes, *note)
+	return nil
+}
+
+type failingMailSender struct{}
+
+func (failingMailSender) Send(ctx context.Context, msg models.MailMessage) error {
+	return errors.New("smtp is down")
+}
+
+func TestTicketService(t *testing.T) {
+	users := NewMockUserStorage()
+	admin := &models.User{Username: "admin", Email: "admin@example.com"}
+	plain := &models.User{Username: "plain", Email: "plain@example.com"}
+	users.CreateUser(admin)
+	users.CreateUser(plain)
+	users.SetRole(admin.ID, models.RoleAdmin)
+	admin.Role = models.RoleAdmin
+
+	tickets := &MockTicketStorage{Tickets: map[int64]*models.Ticket{
+		1: {ID: 1, Name: "Ann", Email: "ann@example.com", Message: "Chart is broken\nPlease fix", Status: models.TicketNew, CreatedAt: time.Now()},
+		2: {ID: 2, Name: "Bob", Email: "not an address", Message: "Hi", Status: models.TicketNew},
+	}}
+	mailer := NewMemoryMailSender()
+	s := NewTicketService(tickets, users, mailer)
+	ctx := context.Background()
+
+	if err := s.Assign(admin, 1, admin.ID); err != nil {
+		t.Fatalf("assign: %v", err)
+	}
+	if got := tickets.Tickets[1].AssigneeID; got == nil || *got != admin.ID {
+		t.Errorf("expected ticket assigned to %d, got %v", admin.ID, got)
+	}
+	if err := s.Assign(admin, 1, 0); err != nil || tickets.Tickets[1].AssigneeID != nil {
+		t.Errorf("assignee 0 must unassign, got %v, %v", tickets.Tickets[1].AssigneeID, err)
+	}
+
+	if _, err := s.AddNote(admin, 1, "  called the user  "); err != nil {
+		t.Fatalf("add note: %v", err)
+	}
+	if len(mailer.Messages()) != 0 {
+		t.Error("internal notes must not be emailed")
+	}
+
+	note, err := s.Reply(ctx, admin, 1, "Fixed, thanks!")
+	if err != nil {
+		t.Fatalf("reply: %v", err)
+	}
+	if note.Kind != models.TicketNoteReply {
+		t.Errorf("expected reply note, got %q", note.Kind)
+	}
+	msgs := mailer.Messages()
+	if len(msgs) != 1 || msgs[0].To != "ann@example.com" {
+		t.Fatalf("expected one reply to the sender, got %+v", msgs)
+	}
+	if !strings.Contains(msgs[0].Body, "Fixed, thanks!") || !strings.Contains(msgs[0].Body, "> Please fix") {
+		t.Errorf("reply must contain the answer and quote the message:\n%s", msgs[0].Body)
+	}
+	if tickets.Tickets[1].Status != models.TicketInProgress {
+		t.Errorf("replied ticket must move in progress, got %q", tickets.Tickets[1].Status)
+	}
+	if got := tickets.Tickets[1].Notes; len(got) != 2 || got[0].Body != "called the user" {
+		t.Errorf("unexpected notes: %+v", got)
+	}
+
+	if err := s.SetStatus(admin, 1, models.TicketResolved); err != nil || tickets.Tickets[1].Status != models.TicketResolved {
+		t.Errorf("resolve: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		run  func() error
+		want error
+	}{
+		{"unknown status", func() error { return s.SetStatus(admin, 1, "closed") }, ErrInvalidTicket},
+		{"missing ticket", func() error { return s.SetStatus(admin, 42, models.TicketResolved) }, ErrTicketNotFound},
+		{"assign to non-admin", func() error { return s.Assign(admin, 1, plain.ID) }, ErrInvalidTicket},
+		{"assign to unknown user", func() error { return s.Assign(admin, 1, 999) }, ErrInvalidTicket},
+		{"empty note", func() error { _, err := s.AddNote(admin, 1, "   "); return err }, ErrInvalidTicket},
+		{"note too long", func() error { _, err := s.AddNote(admin, 1, strings.Repeat("a", maxTicketNote+1)); return err }, ErrInvalidTicket},
+		{"reply to bad address", func() error { _, err := s.Reply(ctx, admin, 2, "hello"); return err }, ErrInvalidTicket},
+		{"list unknown status", func() error { _, err := s.ListTickets(models.TicketQuery{Status: "open"}); return err }, ErrInvalidTicket},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	// Неотправленный ответ не сохраняется
+	broken := NewTicketService(tickets, users, failingMailSender{})
+	before := len(tickets.Tickets[1].Notes)
+	if _, err := broken.Reply(ctx, admin, 1, "hello again"); !errors.Is(err, ErrReplyNotSent) {
+		t.Errorf("expected ErrReplyNotSent, got %v", err)
+	}
+	if len(tickets.Tickets[1].Notes) != before {
+		t.Error("failed reply must not be stored")
+	}
+}
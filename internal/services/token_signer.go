@@ -0,0 +1,76 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"crypto-analytics/internal/models"
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// TokenSigner выпускает подписанные токены вида <random>.<expires>.<hmac>.
+// Подпись позволяет отбросить подделку и просроченный токен без похода в базу,
+// а одноразовость обеспечивает хранилище (там лежит только хеш токена).
+type TokenSigner struct {
+	secret []byte
+	now    func() time.Time
+}
+
+func NewTokenSigner(secret string) *TokenSigner {
+	return &TokenSigner{secret: []byte(secret), now: time.Now}
+}
+
+func (t *TokenSigner) Issue(purpose models.TokenPurpose, ttl time.Duration) (token, hash string, expiresAt time.Time, err error) {
+	random := make([]byte, 24)
+	if _, err := rand.Read(random); err != nil {
+		return "", "", time.Time{}, fmt.Errorf("generate token: %w", err)
+	}
+
+	expiresAt = t.now().Add(ttl).UTC().Truncate(time.Second)
+	payload := base64.RawURLEncoding.EncodeToString(random) + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+	token = payload + "." + t.sign(purpose, payload)
+
+	return token, HashToken(token), expiresAt, nil
+}
+
+// Verify проверяет подпись и срок действия и возвращает хеш для поиска в хранилище
+func (t *TokenSigner) Verify(purpose models.TokenPurpose, token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", ErrInvalidToken
+	}
+
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(t.sign(purpose, payload))) {
+		return "", ErrInvalidToken
+	}
+
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || t.now().Unix() >= expires {
+		return "", ErrInvalidToken
+	}
+
+	return HashToken(token), nil
+}
+
+func (t *TokenSigner) sign(purpose models.TokenPurpose, payload string) string {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(purpose))
+	mac.Write([]byte{'.'})
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
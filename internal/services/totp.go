@@ -0,0 +1,139 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Параметры TOTP (RFC 6238) — те, что понимают все приложения-аутентификаторы
+const (
+	totpPeriod = 30 * time.Second
+	totpDigits = 6
+	// Допускаем расхождение часов телефона и сервера на один шаг в каждую сторону
+	totpSkew = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// generateTOTPSecret возвращает 160-битный секрет в base32, как рекомендует RFC 4226
+func generateTOTPSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("generate totp secret: %w", err)
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// hotp — RFC 4226: HMAC-SHA1 от счётчика с динамическим усечением
+func hotp(secret []byte, counter uint64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, code%mod)
+}
+
+func totpCounter(at time.Time) uint64 {
+	return uint64(at.Unix()) / uint64(totpPeriod/time.Second)
+}
+
+// validateTOTP проверяет код в окне ±totpSkew шагов и возвращает совпавший счётчик
+func validateTOTP(secretB32, code string, at time.Time) (uint64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	secret, err := totpEncoding.DecodeString(strings.ToUpper(secretB32))
+	if err != nil {
+		return 0, false
+	}
+
+	current := totpCounter(at)
+	for delta := -totpSkew; delta <= totpSkew; delta++ {
+		counter := current + uint64(delta)
+		if subtle.ConstantTimeCompare([]byte(hotp(secret, counter, totpDigits)), []byte(code)) == 1 {
+			return counter, true
+		}
+	}
+	return 0, false
+}
+
+// totpProvisioningURI — ссылка otpauth:// для QR-кода или ручного ввода в приложение
+func totpProvisioningURI(issuer, account, secretB32 string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	q := url.Values{}
+	q.Set("secret", secretB32)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(int(totpPeriod/time.Second)))
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+// secretBox шифрует секреты перед записью в базу (AES-256-GCM): утечка дампа
+// не должна давать генерировать TOTP-коды или подделывать подписи вебхуков
+type secretBox struct {
+	aead cipher.AEAD
+}
+
+var errSecretBoxOpen = errors.New("cannot decrypt secret")
+
+// newSecretBox выводит ключ из key и назначения: у каждого вида секретов свой ключ
+func newSecretBox(purpose, key string) *secretBox {
+	sum := sha256.Sum256([]byte(purpose + ":" + key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		// Ключ всегда 32 байта — ошибки здесь быть не может
+		panic(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return &secretBox{aead: aead}
+}
+
+func (b *secretBox) seal(plain string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plain), nil)
+	return base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+func (b *secretBox) open(encoded string) (string, error) {
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < b.aead.NonceSize() {
+		return "", errSecretBoxOpen
+	}
+	nonce, ciphertext := sealed[:b.aead.NonceSize()], sealed[b.aead.NonceSize():]
+	plain, err := b.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errSecretBoxOpen
+	}
+	return string(plain), nil
+}
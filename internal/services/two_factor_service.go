@@ -0,0 +1,210 @@
+package services
+
+import (
+	"crypto-analytics/internal/storage"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	totpIssuer        = "Crypto Analytics"
+	recoveryCodeCount = 10
+)
+
+var (
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled  = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotStarted  = errors.New("two-factor setup has not been started")
+	ErrInvalidTwoFactorCode = errors.New("invalid authentication code")
+)
+
+// TwoFactorService — TOTP (RFC 6238) и одноразовые резервные коды
+type TwoFactorService struct {
+	users storage.UserStorage
+	store storage.TwoFactorStorage
+	box   *secretBox
+	now   func() time.Time
+}
+
+func NewTwoFactorService(users storage.UserStorage, store storage.TwoFactorStorage, encryptionKey string) *TwoFactorService {
+	return &TwoFactorService{
+		users: users,
+		store: store,
+		box:   newSecretBox("totp-secret", encryptionKey),
+		now:   time.Now,
+	}
+}
+
+// BeginEnrollment создаёт новый секрет и возвращает его вместе с otpauth:// ссылкой.
+// 2FA включится только после ConfirmEnrollment с кодом из приложения.
+func (s *TwoFactorService) BeginEnrollment(userID int64) (secret, uri string, err error) {
+	user, err := s.users.GetUserByID(userID)
+	if err != nil {
+		return "", "", err
+	}
+	if user.TwoFactorEnabled() {
+		return "", "", ErrTwoFactorEnabled
+	}
+
+	secret, err = generateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+	sealed, err := s.box.seal(secret)
+	if err != nil {
+		return "", "", fmt.Errorf("encrypt totp secret: %w", err)
+	}
+	if err := s.store.SetPendingTOTPSecret(userID, sealed); err != nil {
+		return "", "", err
+	}
+
+	return secret, totpProvisioningURI(totpIssuer, user.Email, secret), nil
+}
+
+// ConfirmEnrollment включает 2FA по первому коду и возвращает резервные коды (показываются один раз)
+func (s *TwoFactorService) ConfirmEnrollment(userID int64, code string) ([]string, error) {
+	user, err := s.users.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := s.secret(userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTOTPNotConfigured) {
+			return nil, ErrTwoFactorNotStarted
+		}
+		return nil, err
+	}
+
+	counter, ok := validateTOTP(secret, code, s.now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.EnableTOTP(userID, int64(counter), hashes); err != nil {
+		return nil, err
+	}
+
+	slog.Info("Two-factor authentication enabled", "user_id", userID)
+	return codes, nil
+}
+
+// Verify принимает код из приложения или резервный код. Каждый код срабатывает один раз.
+func (s *TwoFactorService) Verify(userID int64, code string) error {
+	user, err := s.users.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TwoFactorEnabled() {
+		return ErrTwoFactorNotEnabled
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if len(normalized) != totpDigits {
+		err := s.store.ConsumeRecoveryCode(userID, HashToken(normalized))
+		if errors.Is(err, storage.ErrRecoveryCodeInvalid) {
+			return ErrInvalidTwoFactorCode
+		}
+		if err == nil {
+			slog.Info("Recovery code used", "user_id", userID)
+		}
+		return err
+	}
+
+	secret, err := s.secret(userID)
+	if err != nil {
+		return err
+	}
+	counter, ok := validateTOTP(secret, code, s.now())
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+	if err := s.store.ConsumeTOTPCounter(userID, int64(counter)); err != nil {
+		if errors.Is(err, storage.ErrTOTPCounterReused) {
+			return ErrInvalidTwoFactorCode
+		}
+		return err
+	}
+	return nil
+}
+
+// Disable выключает 2FA; нужны и пароль, и действующий код
+func (s *TwoFactorService) Disable(userID int64, password, code string) error {
+	user, err := s.users.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return ErrWrongPassword
+	}
+	if err := s.Verify(userID, code); err != nil {
+		return err
+	}
+	if err := s.store.DisableTOTP(userID); err != nil {
+		return err
+	}
+	slog.Info("Two-factor authentication disabled", "user_id", userID)
+	return nil
+}
+
+// RegenerateRecoveryCodes заменяет все резервные коды новыми
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID int64, code string) ([]string, error) {
+	if err := s.Verify(userID, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *TwoFactorService) RecoveryCodesLeft(userID int64) (int, error) {
+	return s.store.CountRecoveryCodes(userID)
+}
+
+func (s *TwoFactorService) secret(userID int64) (string, error) {
+	sealed, err := s.store.GetTOTPSecret(userID)
+	if err != nil {
+		return "", err
+	}
+	return s.box.open(sealed)
+}
+
+// newRecoveryCodes генерирует коды вида abcde-fghij (50 бит) и их хеши для хранения
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	codes = make([]string, recoveryCodeCount)
+	hashes = make([]string, recoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, fmt.Errorf("generate recovery code: %w", err)
+		}
+		encoded := strings.ToLower(totpEncoding.EncodeToString(raw))[:10]
+		codes[i] = encoded[:5] + "-" + encoded[5:]
+		hashes[i] = HashToken(encoded)
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
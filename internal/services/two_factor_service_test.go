@@ -0,0 +1,191 @@
+package services
+
+import (
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/storage"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Векторы из RFC 6238, приложение B (SHA1, 8 цифр)
+func TestHOTP_RFC6238Vectors(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, tt := range tests {
+		got := hotp(secret, totpCounter(time.Unix(tt.unix, 0)), 8)
+		if got != tt.want {
+			t.Errorf("T=%d: expected %s, got %s", tt.unix, tt.want, got)
+		}
+	}
+}
+
+type MockTwoFactorStorage struct {
+	users         *MockUserStorage
+	secrets       map[int64]string
+	counters      map[int64]int64
+	recoveryCodes map[int64]map[string]bool
+}
+
+func NewMockTwoFactorStorage(users *MockUserStorage) *MockTwoFactorStorage {
+	return &MockTwoFactorStorage{
+		users:         users,
+		secrets:       map[int64]string{},
+		counters:      map[int64]int64{},
+		recoveryCodes: map[int64]map[string]bool{},
+	}
+}
+
+func (m *MockTwoFactorStorage) GetTOTPSecret(userID int64) (string, error) {
+	secret, ok := m.secrets[userID]
+	if !ok {
+		return "", storage.ErrTOTPNotConfigured
+	}
+	return secret, nil
+}
+
+func (m *MockTwoFactorStorage) SetPendingTOTPSecret(userID int64, secret string) error {
+	m.secrets[userID] = secret
+	return nil
+}
+
+func (m *MockTwoFactorStorage) EnableTOTP(userID int64, counter int64, recoveryHashes []string) error {
+	now := time.Now()
+	m.users.Users[userID].TOTPEnabledAt = &now
+	m.counters[userID] = counter
+	return m.ReplaceRecoveryCodes(userID, recoveryHashes)
+}
+
+func (m *MockTwoFactorStorage) DisableTOTP(userID int64) error {
+	m.users.Users[userID].TOTPEnabledAt = nil
+	delete(m.secrets, userID)
+	delete(m.recoveryCodes, userID)
+	return nil
+}
+
+func (m *MockTwoFactorStorage) ConsumeTOTPCounter(userID int64, counter int64) error {
+	if counter <= m.counters[userID] {
+		return storage.ErrTOTPCounterReused
+	}
+	m.counters[userID] = counter
+	return nil
+}
+
+func (m *MockTwoFactorStorage) ReplaceRecoveryCodes(userID int64, hashes []string) error {
+	m.recoveryCodes[userID] = map[string]bool{}
+	for _, h := range hashes {
+		m.recoveryCodes[userID][h] = true
+	}
+	return nil
+}
+
+func (m *MockTwoFactorStorage) ConsumeRecoveryCode(userID int64, hash string) error {
+	if !m.recoveryCodes[userID][hash] {
+		return storage.ErrRecoveryCodeInvalid
+	}
+	delete(m.recoveryCodes[userID], hash)
+	return nil
+}
+
+func (m *MockTwoFactorStorage) CountRecoveryCodes(userID int64) (int, error) {
+	return len(m.recoveryCodes[userID]), nil
+}
+
+func codeAt(t *testing.T, secretB32 string, at time.Time) string {
+	t.Helper()
+	secret, err := totpEncoding.DecodeString(secretB32)
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+	return hotp(secret, totpCounter(at), totpDigits)
+}
+
+func TestTwoFactorService_Flow(t *testing.T) {
+	users := NewMockUserStorage()
+	userService := newTestUserService(users, NewMemoryMailSender())
+	user := &models.User{Username: "satoshi", Email: "s@example.com", Password: testPassword}
+	if err := userService.RegisterUser(user); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	store := NewMockTwoFactorStorage(users)
+	s := NewTwoFactorService(users, store, "test-key")
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	if _, err := s.ConfirmEnrollment(user.ID, "123456"); !errors.Is(err, ErrTwoFactorNotStarted) {
+		t.Fatalf("expected ErrTwoFactorNotStarted, got %v", err)
+	}
+
+	secret, uri, err := s.BeginEnrollment(user.ID)
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	if !strings.HasPrefix(uri, "otpauth://totp/") || !strings.Contains(uri, "secret="+secret) {
+		t.Errorf("unexpected provisioning uri: %s", uri)
+	}
+	if store.secrets[user.ID] == secret {
+		t.Error("secret must be stored encrypted")
+	}
+
+	if _, err := s.ConfirmEnrollment(user.ID, "000000"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Fatalf("expected ErrInvalidTwoFactorCode, got %v", err)
+	}
+	recovery, err := s.ConfirmEnrollment(user.ID, codeAt(t, secret, now))
+	if err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+	if len(recovery) != recoveryCodeCount {
+		t.Fatalf("expected %d recovery codes, got %d", recoveryCodeCount, len(recovery))
+	}
+
+	// Код, которым подтвердили подключение, повторно не принимается
+	if err := s.Verify(user.ID, codeAt(t, secret, now)); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("replayed code accepted: %v", err)
+	}
+
+	now = now.Add(totpPeriod)
+	if err := s.Verify(user.ID, codeAt(t, secret, now)); err != nil {
+		t.Errorf("valid code rejected: %v", err)
+	}
+	// Часы телефона отстают на шаг — код ещё действителен
+	now = now.Add(2 * totpPeriod)
+	if err := s.Verify(user.ID, codeAt(t, secret, now.Add(-totpPeriod))); err != nil {
+		t.Errorf("code within skew rejected: %v", err)
+	}
+	if err := s.Verify(user.ID, codeAt(t, secret, now.Add(-5*totpPeriod))); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("stale code accepted: %v", err)
+	}
+
+	if err := s.Verify(user.ID, strings.ToUpper(recovery[0])); err != nil {
+		t.Errorf("recovery code rejected: %v", err)
+	}
+	if err := s.Verify(user.ID, recovery[0]); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("recovery code reused: %v", err)
+	}
+	if left, _ := s.RecoveryCodesLeft(user.ID); left != recoveryCodeCount-1 {
+		t.Errorf("expected %d recovery codes left, got %d", recoveryCodeCount-1, left)
+	}
+
+	if err := s.Disable(user.ID, "wrong", recovery[1]); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("expected ErrWrongPassword, got %v", err)
+	}
+	if err := s.Disable(user.ID, testPassword, recovery[1]); err != nil {
+		t.Fatalf("disable: %v", err)
+	}
+	if err := s.Verify(user.ID, recovery[2]); !errors.Is(err, ErrTwoFactorNotEnabled) {
+		t.Errorf("expected ErrTwoFactorNotEnabled, got %v", err)
+	}
+}
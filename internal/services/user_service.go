@@ -3,21 +3,66 @@ package services
 import (
 	"crypto-analytics/internal/models"
 	"crypto-analytics/internal/storage"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrInvalidCredentials = errors.New("invalid login or password")
+	ErrWrongPassword      = errors.New("current password is incorrect")
+	ErrEmptyDisplayName   = errors.New("display name cannot be empty")
+	ErrDisplayNameTooLong = errors.New("display name too long (max 100 characters)")
+	ErrInvalidEmail       = errors.New("invalid email")
+	ErrEmptyPassword      = errors.New("password cannot be empty")
+	ErrInvalidRole        = errors.New("invalid role")
+	ErrOwnRoleChange      = errors.New("cannot change your own role")
+)
+
 type UserService struct {
 	userStorage storage.UserStorage
+	tokens      storage.TokenStorage
+	mailer      MailSender
+	signer      *TokenSigner
+	baseURL     string
+	breaches    BreachChecker
 }
 
-func NewUserService(userStorage storage.UserStorage) *UserService {
-	return &UserService{userStorage: userStorage}
+func NewUserService(
+	userStorage storage.UserStorage,
+	tokens storage.TokenStorage,
+	mailer MailSender,
+	signer *TokenSigner,
+	baseURL string,
+	breaches BreachChecker,
+) *UserService {
+	return &UserService{
+		userStorage: userStorage,
+		tokens:      tokens,
+		mailer:      mailer,
+		signer:      signer,
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		breaches:    breaches,
+	}
 }
 
+// RegisterUser проверяет поля (ошибки приходят как *ValidationError) и создаёт пользователя
 func (s *UserService) RegisterUser(user *models.User) error {
+	user.Username = strings.TrimSpace(user.Username)
+	user.Email = strings.TrimSpace(user.Email)
+	if err := s.validateNewUser(user); err != nil {
+		return err
+	}
+
 	var err error
+	if user.DisplayName == "" {
+		user.DisplayName = user.Username
+	}
 	user.Password, err = s.HashPassword(user.Password)
 	if err != nil {
 		return fmt.Errorf("failed Hashing: %w", err)
@@ -27,17 +72,106 @@ func (s *UserService) RegisterUser(user *models.User) error {
 
 		return err
 	}
+
+	// Письмо не должно ломать регистрацию: его всегда можно запросить повторно
+	if err := s.SendVerificationEmail(user.ID); err != nil {
+		slog.Warn("Failed to send verification email", "user_id", user.ID, "error", err)
+	}
 	return nil
 }
 
-func (s *UserService) LoginUser(username, password string) error {
-	User, err := s.userStorage.GetUserByName(username)
+// LoginUser проверяет пароль; login может быть как именем пользователя, так и email
+func (s *UserService) LoginUser(login, password string) (*models.User, error) {
+	user, err := s.findByLogin(login)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+func (s *UserService) findByLogin(login string) (*models.User, error) {
+	return findUserByLogin(s.userStorage, login)
+}
+
+// findUserByLogin ищет пользователя по логину так же, как вход: email или имя
+func findUserByLogin(users storage.UserStorage, login string) (*models.User, error) {
+	login = strings.TrimSpace(login)
+	if strings.Contains(login, "@") {
+		user, err := users.GetUserByEmail(login)
+		if err == nil {
+			return user, nil
+		}
+		if !errors.Is(err, storage.ErrUserNotFound) {
+			return nil, err
+		}
+	}
+	return users.GetUserByName(login)
+}
+
+func (s *UserService) GetUserByID(id int64) (*models.User, error) {
+	return s.userStorage.GetUserByID(id)
+}
+
+func (s *UserService) GetUserByName(username string) (*models.User, error) {
+	return s.userStorage.GetUserByName(username)
+}
+
+func (s *UserService) UpdateProfile(userID int64, displayName, email string) (*models.User, error) {
+	displayName = strings.TrimSpace(displayName)
+	email = strings.TrimSpace(email)
+
+	if displayName == "" {
+		return nil, ErrEmptyDisplayName
+	}
+	if utf8.RuneCountInString(displayName) > 100 {
+		return nil, ErrDisplayNameTooLong
+	}
+	if err := validateEmail(email); err != nil {
+		return nil, err
+	}
+
+	if err := s.userStorage.UpdateProfile(userID, displayName, email); err != nil {
+		return nil, err
+	}
+	user, err := s.userStorage.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.EmailVerified() {
+		if err := s.SendVerificationEmail(user.ID); err != nil {
+			slog.Warn("Failed to send verification email", "user_id", user.ID, "error", err)
+		}
+	}
+	return user, nil
+}
+
+// ChangePassword меняет пароль только после проверки текущего
+func (s *UserService) ChangePassword(userID int64, currentPassword, newPassword string) error {
+	if newPassword == "" {
+		return ErrEmptyPassword
+	}
+
+	user, err := s.userStorage.GetUserByID(userID)
 	if err != nil {
-		return fmt.Errorf("we have not this acc")
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(currentPassword)); err != nil {
+		return ErrWrongPassword
+	}
+	if err := s.validateNewPassword(newPassword, user.Username, user.Email); err != nil {
+		return err
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(User.Password), []byte(password))
-	return err
+	hashed, err := s.HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed Hashing: %w", err)
+	}
+	return s.userStorage.UpdatePassword(userID, hashed)
 }
 
 func (s *UserService) HashPassword(password string) (string, error) {
@@ -48,29 +182,80 @@ func (s *UserService) HashPassword(password string) (string, error) {
 	return string(hashed), nil
 }
 
-func (s *UserService) AddFavorite(username, CoinID string) error {
-	err := s.userStorage.NewFavoriteCoin(username, CoinID)
+func (s *UserService) AddFavorite(userID int64, CoinID string) error {
+	err := s.userStorage.NewFavoriteCoin(userID, CoinID)
 	if err != nil {
 		return fmt.Errorf("in AddFavorite: %w", err)
 	}
 	return nil
 }
-func (s *UserService) RemoveFavorite(username, CoinID string) error {
-	err := s.userStorage.RemoveFavoriteCoin(username, CoinID)
+func (s *UserService) RemoveFavorite(userID int64, CoinID string) error {
+	err := s.userStorage.RemoveFavoriteCoin(userID, CoinID)
 	if err != nil {
 		return fmt.Errorf("in RemoveFavorite: %w", err)
 	}
 	return nil
 }
 
-func (s *UserService) GetFavorites(username string) ([]string, error) {
-	allFavC, err := s.userStorage.GetAllFavoriteCoins(username)
+func (s *UserService) GetFavorites(userID int64) ([]string, error) {
+	allFavC, err := s.userStorage.GetAllFavoriteCoins(userID)
 	if err != nil {
 		return nil, fmt.Errorf("in RemoveFavorite: %w", err)
 	}
 	return allFavC, nil
 }
 
+// SetRole меняет роль пользователя. Свою роль менять нельзя — так последний
+// администратор не лишит себя доступа случайно.
+func (s *UserService) SetRole(actorID, userID int64, role models.Role) error {
+	if _, ok := models.ParseRole(string(role)); !ok {
+		return ErrInvalidRole
+	}
+	if actorID == userID {
+		return ErrOwnRoleChange
+	}
+	if err := s.userStorage.SetRole(userID, role); err != nil {
+		return err
+	}
+	slog.Info("User role changed", "actor_id", actorID, "user_id", userID, "role", role)
+	return nil
+}
+
+// EnsureAdmins выдаёт роль admin пользователям из конфигурации (ADMIN_EMAILS).
+// Отсутствующие адреса пропускаются: аккаунт может быть зарегистрирован позже.
+// Неподтверждённые тоже: иначе адрес администратора мог бы занять кто угодно.
+func (s *UserService) EnsureAdmins(emails []string) error {
+	for _, email := range emails {
+		email = strings.TrimSpace(email)
+		if email == "" {
+			continue
+		}
+		user, err := s.userStorage.GetUserByEmail(email)
+		if err != nil {
+			if errors.Is(err, storage.ErrUserNotFound) {
+				continue
+			}
+			return err
+		}
+		if user.Role == models.RoleAdmin {
+			continue
+		}
+		if !user.EmailVerified() {
+			slog.Warn("Configured admin email is not verified, skipping promotion", "user_id", user.ID)
+			continue
+		}
+		if err := s.userStorage.SetRole(user.ID, models.RoleAdmin); err != nil {
+			return err
+		}
+		slog.Info("Promoted configured admin", "user_id", user.ID)
+	}
+	return nil
+}
+
+func (s *UserService) ExportUsers(w io.Writer) (int, error) {
+	return s.userStorage.ExportUsers(w)
+}
+
 func (s *UserService) PrintJsonAllUsers(fileName string) error {
 	err := s.userStorage.ExportUsersToJSON(fileName)
 	if err != nil {
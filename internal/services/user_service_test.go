@@ -0,0 +1,265 @@
+package services
+
+import (
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/storage"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type MockUserStorage struct {
+	Users  map[int64]*models.User
+	nextID int64
+}
+
+func NewMockUserStorage() *MockUserStorage {
+	return &MockUserStorage{Users: map[int64]*models.User{}}
+}
+
+func (m *MockUserStorage) CreateUser(user *models.User) error {
+	for _, u := range m.Users {
+		if strings.EqualFold(u.Email, user.Email) {
+			return storage.ErrEmailTaken
+		}
+		if u.Username == user.Username {
+			return storage.ErrUsernameTaken
+		}
+	}
+	m.nextID++
+	user.ID = m.nextID
+	user.Role = models.RoleUser
+	stored := *user
+	m.Users[user.ID] = &stored
+	return nil
+}
+
+func (m *MockUserStorage) GetUserByID(id int64) (*models.User, error) {
+	u, ok := m.Users[id]
+	if !ok {
+		return nil, storage.ErrUserNotFound
+	}
+	copied := *u
+	return &copied, nil
+}
+
+func (m *MockUserStorage) GetUserByName(name string) (*models.User, error) {
+	for _, u := range m.Users {
+		if u.Username == name {
+			copied := *u
+			return &copied, nil
+		}
+	}
+	return nil, storage.ErrUserNotFound
+}
+
+func (m *MockUserStorage) GetUserByEmail(email string) (*models.User, error) {
+	for _, u := range m.Users {
+		if strings.EqualFold(u.Email, email) {
+			copied := *u
+			return &copied, nil
+		}
+	}
+	return nil, storage.ErrUserNotFound
+}
+
+func (m *MockUserStorage) ListUsersByRole(role models.Role) ([]models.User, error) {
+	var out []models.User
+	for id := int64(1); id <= m.nextID; id++ {
+		if u, ok := m.Users[id]; ok && u.Role == role {
+			out = append(out, *u)
+		}
+	}
+	return out, nil
+}
+
+func (m *MockUserStorage) UpdateProfile(userID int64, displayName, email string) error {
+	u, ok := m.Users[userID]
+	if !ok {
+		return storage.ErrUserNotFound
+	}
+	u.DisplayName, u.Email = displayName, email
+	return nil
+}
+
+func (m *MockUserStorage) UpdatePassword(userID int64, passwordHash string) error {
+	u, ok := m.Users[userID]
+	if !ok {
+		return storage.ErrUserNotFound
+	}
+	u.Password = passwordHash
+	return nil
+}
+
+func (m *MockUserStorage) MarkEmailVerified(userID int64) error {
+	u, ok := m.Users[userID]
+	if !ok {
+		return storage.ErrUserNotFound
+	}
+	now := time.Now()
+	u.EmailVerifiedAt = &now
+	return nil
+}
+
+func (m *MockUserStorage) SetRole(userID int64, role models.Role) error {
+	u, ok := m.Users[userID]
+	if !ok {
+		return storage.ErrUserNotFound
+	}
+	u.Role = role
+	return nil
+}
+
+func (m *MockUserStorage) SetBan(userID int64, until *time.Time, shadow bool) error {
+	u, ok := m.Users[userID]
+	if !ok {
+		return storage.ErrUserNotFound
+	}
+	u.BannedUntil = until
+	u.ShadowBanned = shadow
+	return nil
+}
+
+func (m *MockUserStorage) GetAllFavoriteCoins(userID int64) ([]string, error)  { return nil, nil }
+func (m *MockUserStorage) NewFavoriteCoin(userID int64, nameCoin string) error { return nil }
+func (m *MockUserStorage) RemoveFavoriteCoin(userID int64, nameCoin string) error {
+	return nil
+}
+func (m *MockUserStorage) ExportUsersToJSON(filename string) error { return nil }
+func (m *MockUserStorage) ExportUsers(w io.Writer) (int, error)    { return len(m.Users), nil }
+func (m *MockUserStorage) Close()                                  {}
+
+// testPassword проходит политику паролей и не входит в тестовый список утечек
+const testPassword = "correct horse battery"
+
+func newTestUserService(users storage.UserStorage, mailer MailSender) *UserService {
+	breaches, _ := NewHashListBreachChecker(strings.NewReader(passwordSHA1("Password123!") + ":42\n"))
+	return NewUserService(users, NewMockTokenStorage(), mailer, NewTokenSigner("test-secret"), "http://example.test/", breaches)
+}
+
+func TestUserService_LoginByUsernameOrEmail(t *testing.T) {
+	users := NewMockUserStorage()
+	s := newTestUserService(users, NewMemoryMailSender())
+
+	user := &models.User{Username: "satoshi", Email: "Satoshi@Example.com", Password: "correct horse"}
+	if err := s.RegisterUser(user); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if user.ID == 0 || user.DisplayName != "satoshi" {
+		t.Fatalf("unexpected registered user: %+v", user)
+	}
+
+	tests := []struct {
+		name     string
+		login    string
+		password string
+		wantErr  error
+	}{
+		{name: "by username", login: "satoshi", password: "correct horse"},
+		{name: "by email, case-insensitive", login: "satoshi@example.com", password: "correct horse"},
+		{name: "wrong password", login: "satoshi", password: "nope", wantErr: ErrInvalidCredentials},
+		{name: "unknown user", login: "hal", password: "correct horse", wantErr: ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.LoginUser(tt.login, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr == nil && got.ID != user.ID {
+				t.Errorf("logged in as wrong user: %+v", got)
+			}
+		})
+	}
+}
+
+func TestUserService_ChangePassword(t *testing.T) {
+	users := NewMockUserStorage()
+	s := newTestUserService(users, NewMemoryMailSender())
+	user := &models.User{Username: "satoshi", Email: "s@example.com", Password: "old password"}
+	if err := s.RegisterUser(user); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	if err := s.ChangePassword(user.ID, "wrong", "new password"); !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("expected ErrWrongPassword, got %v", err)
+	}
+	if err := s.ChangePassword(user.ID, "old password", "new password"); err != nil {
+		t.Fatalf("change password: %v", err)
+	}
+	if _, err := s.LoginUser("satoshi", "new password"); err != nil {
+		t.Errorf("cannot login with new password: %v", err)
+	}
+}
+
+func TestUserService_UpdateProfile(t *testing.T) {
+	users := NewMockUserStorage()
+	s := newTestUserService(users, NewMemoryMailSender())
+	user := &models.User{Username: "satoshi", Email: "s@example.com", Password: testPassword}
+	if err := s.RegisterUser(user); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	if _, err := s.UpdateProfile(user.ID, "  ", "s@example.com"); !errors.Is(err, ErrEmptyDisplayName) {
+		t.Errorf("expected ErrEmptyDisplayName, got %v", err)
+	}
+	updated, err := s.UpdateProfile(user.ID, " Satoshi N. ", "new@example.com")
+	if err != nil {
+		t.Fatalf("update profile: %v", err)
+	}
+	if updated.DisplayName != "Satoshi N." || updated.Email != "new@example.com" || updated.Username != "satoshi" {
+		t.Errorf("unexpected profile: %+v", updated)
+	}
+}
+
+func TestUserService_SetRole(t *testing.T) {
+	users := NewMockUserStorage()
+	s := newTestUserService(users, NewMemoryMailSender())
+	admin := &models.User{Username: "admin", Email: "admin@example.com", Password: testPassword}
+	user := &models.User{Username: "satoshi", Email: "s@example.com", Password: testPassword}
+	squatter := &models.User{Username: "squatter", Email: "ops@example.com", Password: testPassword}
+	for _, u := range []*models.User{admin, user, squatter} {
+		if err := s.RegisterUser(u); err != nil {
+			t.Fatalf("register: %v", err)
+		}
+	}
+	users.MarkEmailVerified(admin.ID)
+
+	if err := s.EnsureAdmins([]string{"ADMIN@example.com", "ops@example.com", "missing@example.com"}); err != nil {
+		t.Fatalf("ensure admins: %v", err)
+	}
+	if got, _ := users.GetUserByID(admin.ID); got.Role != models.RoleAdmin {
+		t.Fatalf("configured admin not promoted: %s", got.Role)
+	}
+	if got, _ := users.GetUserByID(squatter.ID); got.Role != models.RoleUser {
+		t.Fatalf("unverified account with a configured email must stay user, got %s", got.Role)
+	}
+
+	tests := []struct {
+		name    string
+		target  int64
+		role    models.Role
+		wantErr error
+	}{
+		{name: "promote to moderator", target: user.ID, role: models.RoleModerator},
+		{name: "unknown role", target: user.ID, role: "root", wantErr: ErrInvalidRole},
+		{name: "own role", target: admin.ID, role: models.RoleUser, wantErr: ErrOwnRoleChange},
+		{name: "unknown user", target: 999, role: models.RoleUser, wantErr: storage.ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.SetRole(admin.ID, tt.target, tt.role)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if got, _ := users.GetUserByID(user.ID); !got.Role.AtLeast(models.RoleModerator) || got.Role.AtLeast(models.RoleAdmin) {
+		t.Errorf("unexpected role after promotion: %s", got.Role)
+	}
+}
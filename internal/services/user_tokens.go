@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/storage"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	emailVerificationTTL = 24 * time.Hour
+	passwordResetTTL     = time.Hour
+	mailSendTimeout      = 15 * time.Second
+)
+
+var ErrAlreadyVerified = errors.New("email is already verified")
+
+// SendVerificationEmail выпускает новый токен подтверждения (старые гасятся) и отправляет письмо
+func (s *UserService) SendVerificationEmail(userID int64) error {
+	user, err := s.userStorage.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerified() {
+		return ErrAlreadyVerified
+	}
+
+	token, err := s.issueToken(user.ID, models.TokenEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.baseURL + "/verify-email?token=" + url.QueryEscape(token)
+	return s.sendMail(models.MailMessage{
+		To:      user.Email,
+		Subject: "Confirm your email — Crypto Analytics",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\n"+
+				"The link is valid for 24 hours. If you did not create an account, ignore this email.\n",
+			user.DisplayName, link),
+	})
+}
+
+// VerifyEmail подтверждает email по токену из письма
+func (s *UserService) VerifyEmail(token string) (*models.User, error) {
+	stored, err := s.consumeToken(token, models.TokenEmailVerification)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userStorage.MarkEmailVerified(stored.UserID); err != nil {
+		return nil, err
+	}
+	return s.userStorage.GetUserByID(stored.UserID)
+}
+
+// RequestPasswordReset отправляет ссылку для сброса пароля. Если адрес
+// не найден, ошибки нет — чтобы форму нельзя было использовать для перебора email.
+func (s *UserService) RequestPasswordReset(email string) error {
+	user, err := s.userStorage.GetUserByEmail(email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, err := s.issueToken(user.ID, models.TokenPasswordReset, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	link := s.baseURL + "/static/PasswordReset.html?token=" + url.QueryEscape(token)
+	return s.sendMail(models.MailMessage{
+		To:      user.Email,
+		Subject: "Password reset — Crypto Analytics",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nSomeone requested a password reset for your account. To choose a new password open:\n\n%s\n\n"+
+				"The link is valid for 1 hour and can be used once. If it wasn't you, ignore this email.\n",
+			user.DisplayName, link),
+	})
+}
+
+// ResetPassword устанавливает новый пароль по токену сброса и возвращает ID
+// пользователя, чтобы вызывающий мог завершить его сессии
+func (s *UserService) ResetPassword(token, newPassword string) (int64, error) {
+	if newPassword == "" {
+		return 0, ErrEmptyPassword
+	}
+	// Проверяем до погашения токена: слабый пароль не должен сжигать ссылку.
+	// Владелец токена ещё неизвестен, поэтому без проверки на имя пользователя.
+	if err := s.validateNewPassword(newPassword, "", ""); err != nil {
+		return 0, err
+	}
+
+	stored, err := s.consumeToken(token, models.TokenPasswordReset)
+	if err != nil {
+		return 0, err
+	}
+
+	hashed, err := s.HashPassword(newPassword)
+	if err != nil {
+		return 0, fmt.Errorf("failed Hashing: %w", err)
+	}
+	if err := s.userStorage.UpdatePassword(stored.UserID, hashed); err != nil {
+		return 0, err
+	}
+
+	// Письмо со ссылкой пришло на этот адрес, значит адрес рабочий
+	if err := s.userStorage.MarkEmailVerified(stored.UserID); err != nil {
+		return 0, err
+	}
+	if err := s.tokens.InvalidateTokens(stored.UserID, models.TokenPasswordReset); err != nil {
+		return 0, err
+	}
+	return stored.UserID, nil
+}
+
+func (s *UserService) issueToken(userID int64, purpose models.TokenPurpose, ttl time.Duration) (string, error) {
+	if err := s.tokens.InvalidateTokens(userID, purpose); err != nil {
+		return "", err
+	}
+
+	token, hash, expiresAt, err := s.signer.Issue(purpose, ttl)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.tokens.CreateToken(&models.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hash,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *UserService) consumeToken(token string, purpose models.TokenPurpose) (*models.UserToken, error) {
+	hash, err := s.signer.Verify(purpose, token)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.tokens.ConsumeToken(hash, purpose)
+	if err != nil {
+		if errors.Is(err, storage.ErrTokenInvalid) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	return stored, nil
+}
+
+func (s *UserService) sendMail(msg models.MailMessage) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mailSendTimeout)
+	defer cancel()
+	return s.mailer.Send(ctx, msg)
+}
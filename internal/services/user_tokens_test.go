@@ -0,0 +1,179 @@
+package services
+
+import (
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/storage"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+)
+
+type MockTokenStorage struct {
+	Tokens map[string]*models.UserToken
+}
+
+func NewMockTokenStorage() *MockTokenStorage {
+	return &MockTokenStorage{Tokens: map[string]*models.UserToken{}}
+}
+
+func (m *MockTokenStorage) CreateToken(token *models.UserToken) error {
+	stored := *token
+	m.Tokens[token.TokenHash] = &stored
+	return nil
+}
+
+func (m *MockTokenStorage) ConsumeToken(tokenHash string, purpose models.TokenPurpose) (*models.UserToken, error) {
+	t, ok := m.Tokens[tokenHash]
+	if !ok || t.Purpose != purpose || t.UsedAt != nil || time.Now().After(t.ExpiresAt) {
+		return nil, storage.ErrTokenInvalid
+	}
+	now := time.Now()
+	t.UsedAt = &now
+	copied := *t
+	return &copied, nil
+}
+
+func (m *MockTokenStorage) InvalidateTokens(userID int64, purpose models.TokenPurpose) error {
+	now := time.Now()
+	for _, t := range m.Tokens {
+		if t.UserID == userID && t.Purpose == purpose && t.UsedAt == nil {
+			t.UsedAt = &now
+		}
+	}
+	return nil
+}
+
+var tokenInMail = regexp.MustCompile(`token=(\S+)`)
+
+func lastMailToken(t *testing.T, mailer *MemoryMailSender) string {
+	t.Helper()
+	msgs := mailer.Messages()
+	if len(msgs) == 0 {
+		t.Fatal("no mail sent")
+	}
+	m := tokenInMail.FindStringSubmatch(msgs[len(msgs)-1].Body)
+	if m == nil {
+		t.Fatalf("no token in mail body: %q", msgs[len(msgs)-1].Body)
+	}
+	token, err := url.QueryUnescape(m[1])
+	if err != nil {
+		t.Fatalf("unescape token: %v", err)
+	}
+	return token
+}
+
+func TestTokenSigner_Verify(t *testing.T) {
+	signer := NewTokenSigner("secret")
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	signer.now = func() time.Time { return now }
+
+	token, hash, _, err := signer.Issue(models.TokenPasswordReset, time.Hour)
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		purpose models.TokenPurpose
+		token   string
+		at      time.Time
+		wantErr bool
+	}{
+		{name: "valid", purpose: models.TokenPasswordReset, token: token, at: now.Add(30 * time.Minute)},
+		{name: "expired", purpose: models.TokenPasswordReset, token: token, at: now.Add(time.Hour), wantErr: true},
+		{name: "other purpose", purpose: models.TokenEmailVerification, token: token, at: now, wantErr: true},
+		{name: "tampered", purpose: models.TokenPasswordReset, token: token + "x", at: now, wantErr: true},
+		{name: "garbage", purpose: models.TokenPasswordReset, token: "abc", at: now, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer.now = func() time.Time { return tt.at }
+			got, err := signer.Verify(tt.purpose, tt.token)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Fatalf("expected ErrInvalidToken, got %v", err)
+				}
+				return
+			}
+			if err != nil || got != hash {
+				t.Fatalf("expected hash %s, got %s (%v)", hash, got, err)
+			}
+		})
+	}
+}
+
+func TestUserService_VerifyEmail(t *testing.T) {
+	users := NewMockUserStorage()
+	mailer := NewMemoryMailSender()
+	s := newTestUserService(users, mailer)
+
+	user := &models.User{Username: "satoshi", Email: "s@example.com", Password: testPassword}
+	if err := s.RegisterUser(user); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if stored, _ := users.GetUserByID(user.ID); stored.EmailVerified() {
+		t.Fatal("new user must not be verified")
+	}
+
+	token := lastMailToken(t, mailer)
+	if _, err := s.VerifyEmail(token); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if stored, _ := users.GetUserByID(user.ID); !stored.EmailVerified() {
+		t.Error("user should be verified")
+	}
+	if _, err := s.VerifyEmail(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("token must be single-use, got %v", err)
+	}
+	if err := s.SendVerificationEmail(user.ID); !errors.Is(err, ErrAlreadyVerified) {
+		t.Errorf("expected ErrAlreadyVerified, got %v", err)
+	}
+}
+
+func TestUserService_ResetPassword(t *testing.T) {
+	users := NewMockUserStorage()
+	mailer := NewMemoryMailSender()
+	s := newTestUserService(users, mailer)
+
+	user := &models.User{Username: "satoshi", Email: "s@example.com", Password: "old password"}
+	if err := s.RegisterUser(user); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	sent := len(mailer.Messages())
+	if err := s.RequestPasswordReset("nobody@example.com"); err != nil {
+		t.Fatalf("unknown email must not fail: %v", err)
+	}
+	if len(mailer.Messages()) != sent {
+		t.Fatal("no mail expected for unknown email")
+	}
+
+	if err := s.RequestPasswordReset("s@example.com"); err != nil {
+		t.Fatalf("request reset: %v", err)
+	}
+	first := lastMailToken(t, mailer)
+	if err := s.RequestPasswordReset("s@example.com"); err != nil {
+		t.Fatalf("request reset: %v", err)
+	}
+	second := lastMailToken(t, mailer)
+
+	if _, err := s.ResetPassword(first, "new password"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("older token should be invalidated, got %v", err)
+	}
+	userID, err := s.ResetPassword(second, "new password")
+	if err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if userID != user.ID {
+		t.Errorf("expected user %d, got %d", user.ID, userID)
+	}
+	if _, err := s.LoginUser("satoshi", "new password"); err != nil {
+		t.Errorf("cannot login with new password: %v", err)
+	}
+	if _, err := s.ResetPassword(second, "another password"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("token must be single-use, got %v", err)
+	}
+}
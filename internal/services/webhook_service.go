@@ -0,0 +1,529 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/storage"
+)
+
+const (
+	webhookSecretPrefix   = "whsec_"
+	maxWebhooksPerUser    = 10
+	maxWebhookDescription = 200
+	defaultWebhookLog     = 50
+	maxWebhookLog         = 200
+	webhookLogRetention   = 30 * 24 * time.Hour
+	webhookPurgeInterval  = 24 * time.Hour
+
+	// WebhookSignatureHeader — заголовок с подписью запроса, см. SignWebhook
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	// WebhookSignatureTolerance — насколько время подписи может расходиться с часами получателя
+	WebhookSignatureTolerance = 5 * time.Minute
+)
+
+// Исход доставок по событиям: "<событие>:sent", "<событие>:retry", "<событие>:failed"
+var webhookStats = expvar.NewMap("webhook_deliveries")
+
+var errWebhookAddressBlocked = errors.New("webhook address is not public")
+
+// WebhookService ведёт исходящие вебхуки пользователей: подписки на события,
+// доставку подписанных JSON-запросов через очередь в Postgres с повторами и журнал доставок.
+// Очередь и паузы между попытками те же, что у NotificationService.
+type WebhookService struct {
+	store  storage.WebhookStorage
+	users  storage.UserStorage
+	box    *secretBox
+	client *http.Client
+	// allowPrivate разрешает адреса во внутренней сети и http — для разработки и тестов
+	allowPrivate bool
+	now          func() time.Time
+}
+
+func NewWebhookService(store storage.WebhookStorage, users storage.UserStorage, encryptionKey string, allowPrivate bool) *WebhookService {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !allowPrivate {
+		// Адрес проверяется после разрешения имени: подмена DNS не пустит запрос во внутреннюю сеть
+		dialer := &net.Dialer{Timeout: 5 * time.Second, Control: publicAddressOnly}
+		transport.DialContext = dialer.DialContext
+		transport.Proxy = nil
+	}
+	return &WebhookService{
+		store:        store,
+		users:        users,
+		box:          newSecretBox("webhook-secret", encryptionKey),
+		client:       newWebhookHTTPClient(transport),
+		allowPrivate: allowPrivate,
+		now:          time.Now,
+	}
+}
+
+// PublishEvent ставит событие в очередь всем включённым вебхукам, подписанным на него.
+// data — тело события, попадает в поле data как есть.
+func (s *WebhookService) PublishEvent(event models.WebhookEvent, data any) {
+	endpoints, err := s.store.ListSubscribers(event)
+	if err != nil {
+		slog.Error("Failed to list webhook subscribers", "event", event, "error", err)
+		return
+	}
+	if len(endpoints) == 0 {
+		return
+	}
+
+	payload, err := s.payload(event, data)
+	if err != nil {
+		slog.Error("Failed to build webhook payload", "event", event, "error", err)
+		return
+	}
+	deliveries := make([]models.WebhookDelivery, 0, len(endpoints))
+	for _, ep := range endpoints {
+		// Роль могли понизить после подписки
+		if !s.ownerMayReceive(ep.UserID, event) {
+			continue
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{EndpointID: ep.ID, Event: event, Payload: payload})
+	}
+	if err := s.store.EnqueueDeliveries(deliveries); err != nil {
+		slog.Error("Failed to enqueue webhook deliveries", "event", event, "error", err)
+	}
+}
+
+func (s *WebhookService) ownerMayReceive(userID int64, event models.WebhookEvent) bool {
+	if event.RequiredRole() == models.RoleUser {
+		return true
+	}
+	owner, err := s.users.GetUserByID(userID)
+	if err != nil {
+		slog.Error("Failed to load webhook owner", "user_id", userID, "error", err)
+		return false
+	}
+	return owner.Role.AtLeast(event.RequiredRole())
+}
+
+func (s *WebhookService) payload(event models.WebhookEvent, data any) (json.RawMessage, error) {
+	random := make([]byte, 12)
+	if _, err := rand.Read(random); err != nil {
+		return nil, fmt.Errorf("generate event id: %w", err)
+	}
+	return json.Marshal(models.WebhookPayload{
+		ID:        "evt_" + hex.EncodeToString(random),
+		Event:     event,
+		CreatedAt: s.now().UTC(),
+		Data:      data,
+	})
+}
+
+// StartDelivery разбирает очередь доставок и раз в сутки чистит старый журнал
+func (s *WebhookService) StartDelivery() {
+	ticker := time.NewTicker(deliveryInterval)
+	defer ticker.Stop()
+	purge := time.NewTicker(webhookPurgeInterval)
+	defer purge.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := s.DeliverDue(context.Background()); err != nil {
+				slog.Error("Failed to deliver webhooks", "error", err)
+			}
+		case <-purge.C:
+			if n, err := s.store.PurgeDeliveries(webhookLogRetention); err != nil {
+				slog.Error("Failed to purge webhook deliveries", "error", err)
+			} else if n > 0 {
+				slog.Info("Purged webhook deliveries", "count", n)
+			}
+		}
+	}
+}
+
+// DeliverDue отправляет доставки, срок которых наступил, и возвращает число успешных
+func (s *WebhookService) DeliverDue(ctx context.Context) (int, error) {
+	sent := 0
+	for {
+		deliveries, err := s.store.ClaimDueDeliveries(deliveryBatch, deliveryLease)
+		if err != nil {
+			return sent, err
+		}
+		for i := range deliveries {
+			if s.deliver(ctx, &deliveries[i]) {
+				sent++
+			}
+		}
+		if len(deliveries) < deliveryBatch || ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+	}
+}
+
+func (s *WebhookService) deliver(ctx context.Context, d *models.WebhookDelivery) bool {
+	var attempt models.WebhookAttempt
+	var err error
+	if d.Endpoint == nil || !d.Endpoint.Enabled {
+		attempt.Error = "webhook is disabled"
+		err = fmt.Errorf("%w: %s", ErrUndeliverable, attempt.Error)
+	} else {
+		attempt, err = s.attempt(ctx, d)
+	}
+
+	switch {
+	case err == nil:
+		if err := s.store.CompleteDelivery(d.ID, attempt); err != nil {
+			slog.Error("Failed to mark webhook delivered", "id", d.ID, "error", err)
+		}
+		webhookStats.Add(string(d.Event)+":sent", 1)
+		return true
+	case errors.Is(err, ErrUndeliverable) || d.Attempts >= maxDeliveryAttempts:
+		slog.Warn("Webhook delivery failed",
+			"id", d.ID, "endpoint_id", d.EndpointID, "event", d.Event, "attempts", d.Attempts, "error", err)
+		if err := s.store.FailDelivery(d.ID, attempt); err != nil {
+			slog.Error("Failed to mark webhook failed", "id", d.ID, "error", err)
+		}
+		webhookStats.Add(string(d.Event)+":failed", 1)
+	default:
+		if err := s.store.RetryDelivery(d.ID, attempt, retryDelay(d.Attempts)); err != nil {
+			slog.Error("Failed to reschedule webhook", "id", d.ID, "error", err)
+		}
+		webhookStats.Add(string(d.Event)+":retry", 1)
+	}
+	return false
+}
+
+// attempt выполняет один подписанный запрос. Ошибка, обёрнутая в ErrUndeliverable,
+// означает, что повтор не поможет.
+func (s *WebhookService) attempt(ctx context.Context, d *models.WebhookDelivery) (models.WebhookAttempt, error) {
+	var attempt models.WebhookAttempt
+	// В журнал попадает исходная ошибка; permanent помечает её как не требующую повторов
+	fail := func(err error, permanent bool) (models.WebhookAttempt, error) {
+		attempt.Error = err.Error()
+		if permanent {
+			return attempt, fmt.Errorf("%w: %v", ErrUndeliverable, err)
+		}
+		return attempt, err
+	}
+
+	secret, err := s.box.open(d.Endpoint.Secret)
+	if err != nil {
+		return fail(err, true)
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+	started := s.now()
+	resp, err := postJSON(sendCtx, s.client, d.Endpoint.URL, d.Payload, http.Header{
+		"User-Agent":           {"crypto-analytics-webhook/1"},
+		"X-Webhook-Event":      {string(d.Event)},
+		"X-Webhook-Delivery":   {strconv.FormatInt(d.ID, 10)},
+		WebhookSignatureHeader: {SignWebhook(secret, started, d.Payload)},
+	})
+	attempt.Duration = s.now().Sub(started)
+	if err != nil {
+		return fail(err, errors.Is(err, errWebhookAddressBlocked))
+	}
+	defer resp.Body.Close()
+	attempt.ResponseStatus = resp.StatusCode
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return attempt, nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, channelErrorBody))
+	err = fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	return fail(err, permanentStatus(resp.StatusCode))
+}
+
+// SignWebhook подписывает тело запроса: "t=<unix>,v1=<hex>", где v1 — HMAC-SHA256
+// от "<unix>.<тело>" на секрете вебхука. Время в подписи не даёт повторить
+// перехваченный запрос позже.
+func SignWebhook(secret string, at time.Time, body []byte) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(webhookMAC(secret, ts, body))
+}
+
+func webhookMAC(secret, ts string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// VerifyWebhookSignature проверяет подпись так, как это должен делать получатель
+func VerifyWebhookSignature(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			sig = value
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sig == "" {
+		return ErrWebhookSignature
+	}
+	at := time.Unix(unix, 0)
+	if now.Sub(at) > tolerance || at.Sub(now) > tolerance {
+		return fmt.Errorf("%w: timestamp is outside the tolerance", ErrWebhookSignature)
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, webhookMAC(secret, ts, body)) {
+		return ErrWebhookSignature
+	}
+	return nil
+}
+
+// publicAddressOnly не даёт соединиться с внутренними адресами: loopback, частные
+// сети, link-local (в том числе метаданные облака) и CGNAT
+func publicAddressOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("%w: %s", errWebhookAddressBlocked, host)
+	}
+	return nil
+}
+
+var cgnat = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || cgnat.Contains(ip))
+}
+
+func (s *WebhookService) ListWebhooks(user *models.User) ([]models.WebhookEndpoint, error) {
+	return s.store.ListEndpoints(user.ID)
+}
+
+// CreateWebhook регистрирует вебхук и возвращает его секрет. Открытое значение
+// секрета показывается только здесь и при смене.
+func (s *WebhookService) CreateWebhook(user *models.User, draft models.WebhookEndpoint) (string, *models.WebhookEndpoint, error) {
+	ep, err := s.validate(user, draft)
+	if err != nil {
+		return "", nil, err
+	}
+	existing, err := s.store.ListEndpoints(user.ID)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(existing) >= maxWebhooksPerUser {
+		return "", nil, fmt.Errorf("%w: at most %d", ErrTooManyWebhooks, maxWebhooksPerUser)
+	}
+
+	secret, sealed, err := s.newSecret()
+	if err != nil {
+		return "", nil, err
+	}
+	ep.Secret = sealed
+	ep.Enabled = true
+	if err := s.store.CreateEndpoint(ep); err != nil {
+		return "", nil, err
+	}
+	slog.Info("Webhook created", "user_id", user.ID, "webhook_id", ep.ID, "events", ep.Events)
+	return secret, ep, nil
+}
+
+// UpdateWebhook меняет адрес, описание, подписки и включённость; секрет остаётся прежним
+func (s *WebhookService) UpdateWebhook(user *models.User, id int64, draft models.WebhookEndpoint) (*models.WebhookEndpoint, error) {
+	ep, err := s.validate(user, draft)
+	if err != nil {
+		return nil, err
+	}
+	ep.ID = id
+	ep.Enabled = draft.Enabled
+	if err := webhookErr(s.store.UpdateEndpoint(ep)); err != nil {
+		return nil, err
+	}
+	return s.endpoint(user, id)
+}
+
+func (s *WebhookService) DeleteWebhook(user *models.User, id int64) error {
+	if err := webhookErr(s.store.DeleteEndpoint(user.ID, id)); err != nil {
+		return err
+	}
+	slog.Info("Webhook deleted", "user_id", user.ID, "webhook_id", id)
+	return nil
+}
+
+// RotateSecret выдаёт вебхуку новый секрет; старый перестаёт действовать сразу,
+// в том числе для доставок, которые ещё в очереди
+func (s *WebhookService) RotateSecret(user *models.User, id int64) (string, error) {
+	secret, sealed, err := s.newSecret()
+	if err != nil {
+		return "", err
+	}
+	if err := webhookErr(s.store.SetEndpointSecret(user.ID, id, sealed)); err != nil {
+		return "", err
+	}
+	slog.Info("Webhook secret rotated", "user_id", user.ID, "webhook_id", id)
+	return secret, nil
+}
+
+// SendTestEvent сразу отправляет на вебхук событие ping, без повторов, и
+// возвращает результат попытки; доставка попадает в журнал
+func (s *WebhookService) SendTestEvent(ctx context.Context, user *models.User, id int64) (*models.WebhookDelivery, error) {
+	ep, err := s.endpoint(user, id)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := s.payload(models.WebhookPing, map[string]any{
+		"webhookId": ep.ID,
+		"message":   "Test event from Crypto Analytics",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Доставка записывается до отправки, чтобы её ID ушёл в заголовке; в очередь она не попадает
+	d := &models.WebhookDelivery{
+		EndpointID: ep.ID,
+		Event:      models.WebhookPing,
+		Payload:    payload,
+		Status:     models.OutboxPending,
+		Attempts:   1,
+		Endpoint:   ep,
+	}
+	if err := s.store.RecordDelivery(d); err != nil {
+		return nil, err
+	}
+
+	attempt, sendErr := s.attempt(ctx, d)
+	d.ResponseStatus = attempt.ResponseStatus
+	d.LastError = attempt.Error
+	d.DurationMs = attempt.Duration.Milliseconds()
+	if sendErr == nil {
+		d.Status = models.OutboxSent
+		now := s.now().UTC()
+		d.DeliveredAt = &now
+		err = s.store.CompleteDelivery(d.ID, attempt)
+	} else {
+		d.Status = models.OutboxFailed
+		err = s.store.FailDelivery(d.ID, attempt)
+	}
+	if err != nil {
+		slog.Error("Failed to record test webhook result", "id", d.ID, "error", err)
+	}
+	return d, nil
+}
+
+// Deliveries — журнал доставок вебхука пользователя, новые сверху
+func (s *WebhookService) Deliveries(user *models.User, id int64, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := s.endpoint(user, id); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > maxWebhookLog {
+		limit = defaultWebhookLog
+	}
+	return s.store.ListDeliveries(id, limit)
+}
+
+func (s *WebhookService) validate(user *models.User, draft models.WebhookEndpoint) (*models.WebhookEndpoint, error) {
+	address, err := s.webhookURL(draft.URL)
+	if err != nil {
+		return nil, err
+	}
+	description := strings.TrimSpace(draft.Description)
+	if utf8.RuneCountInString(description) > maxWebhookDescription {
+		return nil, fmt.Errorf("%w: description exceeds %d characters", ErrInvalidWebhook, maxWebhookDescription)
+	}
+	if len(draft.Events) == 0 {
+		return nil, fmt.Errorf("%w: subscribe to at least one event", ErrInvalidWebhook)
+	}
+	for _, e := range draft.Events {
+		if !e.Known() {
+			return nil, fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, e)
+		}
+		if !user.Role.AtLeast(e.RequiredRole()) {
+			return nil, fmt.Errorf("%w: event %q requires role %s", ErrInvalidWebhook, e, e.RequiredRole())
+		}
+	}
+	return &models.WebhookEndpoint{
+		UserID:      user.ID,
+		URL:         address,
+		Description: description,
+		Events:      slices.Compact(slices.Sorted(slices.Values(draft.Events))),
+	}, nil
+}
+
+func (s *WebhookService) webhookURL(address string) (string, error) {
+	address = strings.TrimSpace(address)
+	if address == "" || len(address) > maxChannelAddress {
+		return "", fmt.Errorf("%w: url is required and must be at most %d characters", ErrInvalidWebhook, maxChannelAddress)
+	}
+	u, err := url.Parse(address)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return "", fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalidWebhook)
+	}
+	if u.User != nil {
+		return "", fmt.Errorf("%w: url must not contain credentials", ErrInvalidWebhook)
+	}
+	if s.allowPrivate {
+		return u.String(), nil
+	}
+	if u.Scheme != "https" {
+		return "", fmt.Errorf("%w: url must use https", ErrInvalidWebhook)
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); (ip != nil && !isPublicIP(ip)) || strings.EqualFold(host, "localhost") {
+		return "", fmt.Errorf("%w: url must point to a public address", ErrInvalidWebhook)
+	}
+	return u.String(), nil
+}
+
+// newSecret возвращает открытый секрет и его зашифрованный вид для базы
+func (s *WebhookService) newSecret() (plain, sealed string, err error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", "", fmt.Errorf("generate webhook secret: %w", err)
+	}
+	plain = webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(random)
+	sealed, err = s.box.seal(plain)
+	if err != nil {
+		return "", "", fmt.Errorf("encrypt webhook secret: %w", err)
+	}
+	return plain, sealed, nil
+}
+
+func (s *WebhookService) endpoint(user *models.User, id int64) (*models.WebhookEndpoint, error) {
+	ep, err := s.store.GetEndpoint(user.ID, id)
+	if err != nil {
+		return nil, webhookErr(err)
+	}
+	return ep, nil
+}
+
+func webhookErr(err error) error {
+	if errors.Is(err, storage.ErrWebhookNotFound) {
+		return ErrWebhookNotFound
+	}
+	return err
+}
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrInvalidWebhook   = errors.New("invalid webhook")
+	ErrTooManyWebhooks  = errors.New("too many webhooks")
+	ErrWebhookSignature = errors.New("webhook signature is invalid")
+)
@@ -0,0 +1,617 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"crypto-analytics/internal/models"
+	"crypto-analytics/internal/storage"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type MockWebhookStorage struct {
+	Endpoints  []*models.WebhookEndpoint
+	Deliveries []*models.WebhookDelivery
+}
+
+func (m *MockWebhookStorage) CreateEndpoint(ep *models.WebhookEndpoint) error {
+	ep.ID = int64(len(m.Endpoints) + 1)
+	ep.CreatedAt = time.Now()
+	stored := *ep
+	m.Endpoints = append(m.Endpoints, &stored)
+	return nil
+}
+
+func (m *MockWebhookStorage) ListEndpoints(userID int64) ([]models.WebhookEndpoint, error) {
+	var out []models.WebhookEndpoint
+	for _, ep := range m.Endpoints {
+		if ep != nil && ep.UserID == userID {
+			out = append(out, *ep)
+		}
+	}
+	return out, nil
+}
+
+func (m *MockWebhookStorage) ListSubscribers(event models.WebhookEvent) ([]models.WebhookEndpoint, error) {
+	var out []models.WebhookEndpoint
+	for _, ep := range m.Endpoints {
+		if ep != nil && ep.Enabled && slices.Contains(ep.Events, event) {
+			out = append(out, *ep)
+		}
+	}
+	return out, nil
+}
+
+func (m *MockWebhookStorage) find(userID, id int64) (*models.WebhookEndpoint, error) {
+	if id < 1 || int(id) > len(m.Endpoints) || m.Endpoints[id-1] == nil || m.Endpoints[id-1].UserID != userID {
+		return nil, storage.ErrWebhookNotFound
+	}
+	return m.Endpoints[id-1], nil
+}
+
+func (m *MockWebhookStorage) GetEndpoint(userID, id int64) (*models.WebhookEndpoint, error) {
+	ep, err := m.find(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	out := *ep
+	return &out, nil
+}
+
+func (m *MockWebhookStorage) UpdateEndpoint(ep *models.WebhookEndpoint) error {
+	stored, err := m.find(ep.UserID, ep.ID)
+	if err != nil {
+		return err
+	}
+	stored.URL, stored.Description, stored.Events, stored.Enabled = ep.URL, ep.Description, ep.Events, ep.Enabled
+	return nil
+}
+
+func (m *MockWebhookStorage) SetEndpointSecret(userID, id int64, secret string) error {
+	stored, err := m.find(userID, id)
+	if err != nil {
+		return err
+	}
+	stored.Secret = secret
+	return nil
+}
+
+func (m *MockWebhookStorage) DeleteEndpoint(userID, id int64) error {
+	if _, err := m.find(userID, id); err != nil {
+		return err
+	}
+	m.Endpoints[id-1] = nil
+	return nil
+}
+
+func (m *MockWebhookStorage) EnqueueDeliveries(deliveries []models.WebhookDelivery) error {
+	for _, d := range deliveries {
+		now := time.Now()
+		d.ID = int64(len(m.Deliveries) + 1)
+		d.Status = models.OutboxPending
+		d.CreatedAt = now
+		d.NextAttemptAt = &now
+		m.Deliveries = append(m.Deliveries, &d)
+	}
+	return nil
+}
+
+func (m *MockWebhookStorage) RecordDelivery(d *models.WebhookDelivery) error {
+	d.ID = int64(len(m.Deliveries) + 1)
+	d.CreatedAt = time.Now()
+	stored := *d
+	stored.NextAttemptAt = nil
+	m.Deliveries = append(m.Deliveries, &stored)
+	return nil
+}
+
+func (m *MockWebhookStorage) ClaimDueDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	var out []models.WebhookDelivery
+	now := time.Now()
+	for _, d := range m.Deliveries {
+		if len(out) == limit {
+			break
+		}
+		if d.Status != models.OutboxPending || d.NextAttemptAt == nil || d.NextAttemptAt.After(now) {
+			continue
+		}
+		d.Attempts++
+		next := now.Add(lease)
+		d.NextAttemptAt = &next
+		claimed := *d
+		if ep := m.Endpoints[d.EndpointID-1]; ep != nil {
+			endpoint := *ep
+			claimed.Endpoint = &endpoint
+		}
+		out = append(out, claimed)
+	}
+	return out, nil
+}
+
+func (m *MockWebhookStorage) record(id int64, attempt models.WebhookAttempt) *models.WebhookDelivery {
+	d := m.Deliveries[id-1]
+	d.ResponseStatus = attempt.ResponseStatus
+	d.LastError = attempt.Error
+	d.DurationMs = attempt.Duration.Milliseconds()
+	return d
+}
+
+func (m *MockWebhookStorage) CompleteDelivery(id int64, attempt models.WebhookAttempt) error {
+	d := m.record(id, attempt)
+	now := time.Now()
+	d.Status = models.OutboxSent
+	d.DeliveredAt = &now
+	d.NextAttemptAt = nil
+	return nil
+}
+
+func (m *MockWebhookStorage) RetryDelivery(id int64, attempt models.WebhookAttempt, delay time.Duration) error {
+	next := time.Now().Add(delay)
+	m.record(id, attempt).NextAttemptAt = &next
+	return nil
+}
+
+func (m *MockWebhookStorage) FailDelivery(id int64, attempt models.WebhookAttempt) error {
+	d := m.record(id, attempt)
+	d.Status = models.OutboxFailed
+	d.NextAttemptAt = nil
+	return nil
+}
+
+func (m *MockWebhookStorage) ListDeliveries(endpointID int64, limit int) ([]models.WebhookDelivery, error) {
+	var out []models.WebhookDelivery
+	for i := len(m.Deliveries) - 1; i >= 0 && len(out) < limit; i-- {
+		if m.Deliveries[i].EndpointID == endpointID {
+			out = append(out, *m.Deliveries[i])
+		}
+	}
+	return out, nil
+}
+
+func (m *MockWebhookStorage) PurgeDeliveries(olderThan time.Duration) (int, error) {
+	return 0, nil
+}
+
+// dueNow делает все отложенные доставки готовыми к отправке
+func (m *MockWebhookStorage) dueNow() {
+	past := time.Now().Add(-time.Second)
+	for _, d := range m.Deliveries {
+		if d.NextAttemptAt != nil {
+			d.NextAttemptAt = &past
+		}
+	}
+}
+
+// webhookReceiver — получатель, который проверяет подпись и отвечает кодами из statuses по очереди
+type webhookReceiver struct {
+	mu       sync.Mutex
+	secret   string
+	statuses []int
+	events   []models.WebhookPayload
+	errs     []error
+}
+
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	err := VerifyWebhookSignature(rcv.secret, r.Header.Get(WebhookSignatureHeader), body, time.Now(), WebhookSignatureTolerance)
+	if err != nil {
+		rcv.errs = append(rcv.errs, err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var payload models.WebhookPayload
+	json.Unmarshal(body, &payload)
+	if r.Header.Get("X-Webhook-Event") != string(payload.Event) || r.Header.Get("X-Webhook-Delivery") == "" {
+		rcv.errs = append(rcv.errs, errors.New("missing event headers"))
+	}
+	rcv.events = append(rcv.events, payload)
+
+	status := http.StatusNoContent
+	if len(rcv.statuses) > 0 {
+		status = rcv.statuses[0]
+		rcv.statuses = rcv.statuses[1:]
+	}
+	w.WriteHeader(status)
+	io.WriteString(w, "ack")
+}
+
+func TestWebhookSignature(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	body := []byte(`{"event":"post.created"}`)
+	header := SignWebhook("whsec_test", at, body)
+	if !strings.HasPrefix(header, "t=1700000000,v1=") {
+		t.Fatalf("unexpected header %q", header)
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+		now    time.Time
+		ok     bool
+	}{
+		{"valid", "whsec_test", header, body, at.Add(time.Minute), true},
+		{"wrong secret", "whsec_other", header, body, at, false},
+		{"tampered body", "whsec_test", header, []byte(`{"event":"ping"}`), at, false},
+		{"replayed later", "whsec_test", header, body, at.Add(10 * time.Minute), false},
+		{"from the future", "whsec_test", header, body, at.Add(-10 * time.Minute), false},
+		{"no timestamp", "whsec_test", header[strings.Index(header, ",")+1:], body, at, false},
+		{"garbage", "whsec_test", "t=1700000000,v1=zz", body, at, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyWebhookSignature(tt.secret, tt.header, tt.body, tt.now, WebhookSignatureTolerance)
+			if (err == nil) != tt.ok {
+				t.Errorf("ok = %v, got err %v", tt.ok, err)
+			}
+			if err != nil && !errors.Is(err, ErrWebhookSignature) {
+				t.Errorf("expected ErrWebhookSignature, got %v", err)
+			}
+		})
+	}
+}
+
+func TestWebhookService_Validation(t *testing.T) {
+	s := NewWebhookService(&MockWebhookStorage{}, NewMockUserStorage(), "key", false)
+	user := &models.User{ID: 1, Role: models.RoleUser}
+	admin := &models.User{ID: 2, Role: models.RoleAdmin}
+	posts := []models.WebhookEvent{models.WebhookPostCreated}
+
+	tests := []struct {
+		name  string
+		user  *models.User
+		draft models.WebhookEndpoint
+		ok    bool
+	}{
+		{"https", user, models.WebhookEndpoint{URL: "https://hooks.example.com/in", Events: posts}, true},
+		{"plain http", user, models.WebhookEndpoint{URL: "http://hooks.example.com/in", Events: posts}, false},
+		{"localhost", user, models.WebhookEndpoint{URL: "https://localhost/in", Events: posts}, false},
+		{"private ip", user, models.WebhookEndpoint{URL: "https://10.0.0.5/in", Events: posts}, false},
+		{"metadata ip", user, models.WebhookEndpoint{URL: "https://169.254.169.254/latest", Events: posts}, false},
+		{"credentials", user, models.WebhookEndpoint{URL: "https://u:p@hooks.example.com/in", Events: posts}, false},
+		{"relative", user, models.WebhookEndpoint{URL: "/in", Events: posts}, false},
+		{"no events", user, models.WebhookEndpoint{URL: "https://hooks.example.com/in"}, false},
+		{"unknown event", user, models.WebhookEndpoint{URL: "https://hooks.example.com/in", Events: []models.WebhookEvent{"user.deleted"}}, false},
+		{"ping is not subscribable", user, models.WebhookEndpoint{URL: "https://hooks.example.com/in", Events: []models.WebhookEvent{models.WebhookPing}}, false},
+		{"alerts for user", user, models.WebhookEndpoint{URL: "https://hooks.example.com/in", Events: []models.WebhookEvent{models.WebhookAlertTriggered}}, false},
+		{"alerts for admin", admin, models.WebhookEndpoint{URL: "https://hooks.example.com/in", Events: []models.WebhookEvent{models.WebhookAlertTriggered}}, true},
+		{"long description", user, models.WebhookEndpoint{URL: "https://hooks.example.com/in", Events: posts, Description: strings.Repeat("x", maxWebhookDescription+1)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.validate(tt.user, tt.draft)
+			if (err == nil) != tt.ok {
+				t.Errorf("ok = %v, got err %v", tt.ok, err)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidWebhook) {
+				t.Errorf("expected ErrInvalidWebhook, got %v", err)
+			}
+		})
+	}
+
+	ep, err := s.validate(user, models.WebhookEndpoint{
+		URL:    " https://hooks.example.com/in ",
+		Events: []models.WebhookEvent{models.WebhookPostCreated, models.WebhookCommentCreated, models.WebhookPostCreated},
+	})
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if ep.URL != "https://hooks.example.com/in" || len(ep.Events) != 2 {
+		t.Errorf("expected trimmed url and deduplicated events, got %q %v", ep.URL, ep.Events)
+	}
+}
+
+func TestWebhookService_Manage(t *testing.T) {
+	store := &MockWebhookStorage{}
+	s := NewWebhookService(store, NewMockUserStorage(), "key", false)
+	owner := &models.User{ID: 1, Role: models.RoleUser}
+	stranger := &models.User{ID: 2, Role: models.RoleUser}
+	draft := models.WebhookEndpoint{URL: "https://hooks.example.com/in", Events: []models.WebhookEvent{models.WebhookPostCreated}}
+
+	secret, ep, err := s.CreateWebhook(owner, draft)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if !strings.HasPrefix(secret, webhookSecretPrefix) || !ep.Enabled {
+		t.Errorf("unexpected webhook %+v with secret %q", ep, secret)
+	}
+	if stored := store.Endpoints[0].Secret; stored == secret || strings.Contains(stored, secret) {
+		t.Error("secret must be stored encrypted")
+	}
+	if opened, err := s.box.open(store.Endpoints[0].Secret); err != nil || opened != secret {
+		t.Errorf("stored secret does not decrypt: %q %v", opened, err)
+	}
+
+	if _, err := s.UpdateWebhook(stranger, ep.ID, draft); !errors.Is(err, ErrWebhookNotFound) {
+		t.Errorf("expected ErrWebhookNotFound for a foreign webhook, got %v", err)
+	}
+	if _, err := s.RotateSecret(stranger, ep.ID); !errors.Is(err, ErrWebhookNotFound) {
+		t.Errorf("expected ErrWebhookNotFound on foreign rotate, got %v", err)
+	}
+	if _, err := s.Deliveries(stranger, ep.ID, 0); !errors.Is(err, ErrWebhookNotFound) {
+		t.Errorf("expected ErrWebhookNotFound for foreign deliveries, got %v", err)
+	}
+
+	rotated, err := s.RotateSecret(owner, ep.ID)
+	if err != nil || rotated == secret {
+		t.Fatalf("rotate: %q %v", rotated, err)
+	}
+
+	updated, err := s.UpdateWebhook(owner, ep.ID, models.WebhookEndpoint{
+		URL:    "https://hooks.example.com/v2",
+		Events: []models.WebhookEvent{models.WebhookNewsIngested},
+	})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if updated.URL != "https://hooks.example.com/v2" || updated.Enabled {
+		t.Errorf("unexpected update result %+v", updated)
+	}
+
+	for i := 1; i < maxWebhooksPerUser; i++ {
+		if _, _, err := s.CreateWebhook(owner, draft); err != nil {
+			t.Fatalf("create %d: %v", i, err)
+		}
+	}
+	if _, _, err := s.CreateWebhook(owner, draft); !errors.Is(err, ErrTooManyWebhooks) {
+		t.Errorf("expected ErrTooManyWebhooks, got %v", err)
+	}
+
+	if err := s.DeleteWebhook(owner, ep.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := s.DeleteWebhook(owner, ep.ID); !errors.Is(err, ErrWebhookNotFound) {
+		t.Errorf("expected ErrWebhookNotFound after delete, got %v", err)
+	}
+}
+
+func TestWebhookService_Delivery(t *testing.T) {
+	users := NewMockUserStorage()
+	owner := &models.User{Username: "owner", Email: "owner@example.com"}
+	users.CreateUser(owner)
+	users.SetRole(owner.ID, models.RoleAdmin)
+	owner.Role = models.RoleAdmin
+
+	store := &MockWebhookStorage{}
+	// allowPrivate: httptest слушает 127.0.0.1
+	s := NewWebhookService(store, users, "key", true)
+
+	flaky := &webhookReceiver{statuses: []int{http.StatusServiceUnavailable, http.StatusOK}}
+	flakySrv := httptest.NewServer(flaky)
+	defer flakySrv.Close()
+	gone := &webhookReceiver{statuses: []int{http.StatusGone}}
+	goneSrv := httptest.NewServer(gone)
+	defer goneSrv.Close()
+
+	var err error
+	flaky.secret, _, err = s.CreateWebhook(owner, models.WebhookEndpoint{
+		URL:    flakySrv.URL,
+		Events: []models.WebhookEvent{models.WebhookPostCreated, models.WebhookAlertTriggered},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	gone.secret, _, err = s.CreateWebhook(owner, models.WebhookEndpoint{
+		URL:    goneSrv.URL,
+		Events: []models.WebhookEvent{models.WebhookPostCreated},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	s.PublishEvent(models.WebhookPostCreated, map[string]any{"id": "p1"})
+	s.PublishEvent(models.WebhookNewsIngested, map[string]any{"guid": "n1"})
+	if len(store.Deliveries) != 2 {
+		t.Fatalf("expected 2 deliveries for post.created only, got %d", len(store.Deliveries))
+	}
+	if string(store.Deliveries[0].Payload) != string(store.Deliveries[1].Payload) {
+		t.Error("all subscribers must get the same payload")
+	}
+
+	ctx := context.Background()
+	if sent, err := s.DeliverDue(ctx); err != nil || sent != 0 {
+		t.Fatalf("first round: sent %d, err %v", sent, err)
+	}
+	retried, failed := store.Deliveries[0], store.Deliveries[1]
+	if retried.Status != models.OutboxPending || retried.ResponseStatus != http.StatusServiceUnavailable ||
+		retried.NextAttemptAt.Before(time.Now().Add(retryBaseDelay-time.Second)) {
+		t.Errorf("503 must be retried with backoff, got %+v", retried)
+	}
+	if failed.Status != models.OutboxFailed || failed.ResponseStatus != http.StatusGone || !strings.Contains(failed.LastError, "ack") {
+		t.Errorf("410 must fail without retries, got %+v", failed)
+	}
+
+	// Повтор не приходит раньше срока
+	if sent, _ := s.DeliverDue(ctx); sent != 0 {
+		t.Errorf("retry must wait for backoff, sent %d", sent)
+	}
+	store.dueNow()
+	if sent, err := s.DeliverDue(ctx); err != nil || sent != 1 {
+		t.Fatalf("second round: sent %d, err %v", sent, err)
+	}
+	if retried.Status != models.OutboxSent || retried.Attempts != 2 || retried.DeliveredAt == nil {
+		t.Errorf("expected delivery on the second attempt, got %+v", retried)
+	}
+	if len(flaky.errs) > 0 || len(gone.errs) > 0 {
+		t.Fatalf("receiver rejected the request: %v %v", flaky.errs, gone.errs)
+	}
+	if got := flaky.events[1]; got.Event != models.WebhookPostCreated || !strings.HasPrefix(got.ID, "evt_") || got.ID != flaky.events[0].ID {
+		t.Errorf("retry must resend the same event, got %+v", got)
+	}
+
+	// alert.triggered уходит, только пока владелец остаётся администратором
+	s.PublishEvent(models.WebhookAlertTriggered, map[string]any{"kind": "login_locked"})
+	users.SetRole(owner.ID, models.RoleUser)
+	s.PublishEvent(models.WebhookAlertTriggered, map[string]any{"kind": "login_locked"})
+	if len(store.Deliveries) != 3 {
+		t.Errorf("alerts must not reach demoted owners, got %d deliveries", len(store.Deliveries))
+	}
+}
+
+func TestWebhookService_GivesUp(t *testing.T) {
+	store := &MockWebhookStorage{}
+	s := NewWebhookService(store, NewMockUserStorage(), "key", true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	user := &models.User{ID: 1, Role: models.RoleUser}
+	if _, _, err := s.CreateWebhook(user, models.WebhookEndpoint{URL: srv.URL, Events: []models.WebhookEvent{models.WebhookPairListed}}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	s.PublishEvent(models.WebhookPairListed, map[string]any{"pair": "NEWUSDT"})
+
+	for i := 0; i < maxDeliveryAttempts; i++ {
+		store.dueNow()
+		s.DeliverDue(context.Background())
+	}
+	if d := store.Deliveries[0]; d.Status != models.OutboxFailed || d.Attempts != maxDeliveryAttempts {
+		t.Errorf("expected failure after %d attempts, got %+v", maxDeliveryAttempts, d)
+	}
+
+	// Отключённый вебхук не получает то, что осталось в очереди
+	s.PublishEvent(models.WebhookPairListed, map[string]any{"pair": "NEXTUSDT"})
+	store.Endpoints[0].Enabled = false
+	s.DeliverDue(context.Background())
+	if d := store.Deliveries[1]; d.Status != models.OutboxFailed || d.Attempts != 1 {
+		t.Errorf("disabled webhook must fail at once, got %+v", d)
+	}
+}
+
+func TestWebhookService_BlocksPrivateAddresses(t *testing.T) {
+	hit := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+	}))
+	defer srv.Close()
+
+	store := &MockWebhookStorage{}
+	s := NewWebhookService(store, NewMockUserStorage(), "key", false)
+	// Адрес мог пройти проверку при регистрации, а потом начать указывать во внутреннюю сеть
+	_, sealed, err := s.newSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.CreateEndpoint(&models.WebhookEndpoint{
+		UserID:  1,
+		URL:     srv.URL,
+		Events:  []models.WebhookEvent{models.WebhookNewsIngested},
+		Enabled: true,
+		Secret:  sealed,
+	})
+
+	s.PublishEvent(models.WebhookNewsIngested, map[string]any{"guid": "n1"})
+	s.DeliverDue(context.Background())
+	if hit {
+		t.Fatal("request reached a loopback address")
+	}
+	if d := store.Deliveries[0]; d.Status != models.OutboxFailed || !strings.Contains(d.LastError, "not public") {
+		t.Errorf("blocked address must fail without retries, got %+v", d)
+	}
+}
+
+func TestWebhookService_SendTestEvent(t *testing.T) {
+	store := &MockWebhookStorage{}
+	s := NewWebhookService(store, NewMockUserStorage(), "key", true)
+	rcv := &webhookReceiver{statuses: []int{http.StatusOK, http.StatusBadRequest}}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	user := &models.User{ID: 1, Role: models.RoleUser}
+	var ep *models.WebhookEndpoint
+	var err error
+	rcv.secret, ep, err = s.CreateWebhook(user, models.WebhookEndpoint{URL: srv.URL, Events: []models.WebhookEvent{models.WebhookPostCreated}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	d, err := s.SendTestEvent(context.Background(), user, ep.ID)
+	if err != nil {
+		t.Fatalf("send test event: %v", err)
+	}
+	if d.Status != models.OutboxSent || d.ResponseStatus != http.StatusOK || d.Event != models.WebhookPing {
+		t.Errorf("unexpected test delivery %+v", d)
+	}
+	if len(rcv.events) != 1 || rcv.events[0].Event != models.WebhookPing || len(rcv.errs) > 0 {
+		t.Fatalf("receiver got %+v, errors %v", rcv.events, rcv.errs)
+	}
+
+	d, err = s.SendTestEvent(context.Background(), user, ep.ID)
+	if err != nil {
+		t.Fatalf("send test event: %v", err)
+	}
+	if d.Status != models.OutboxFailed || d.ResponseStatus != http.StatusBadRequest || d.LastError == "" {
+		t.Errorf("failed test event must report the response, got %+v", d)
+	}
+
+	// Тестовые события видны в журнале, но воркер их не подбирает
+	log, err := s.Deliveries(user, ep.ID, 0)
+	if err != nil || len(log) != 2 || log[0].Status != models.OutboxFailed || log[1].Status != models.OutboxSent {
+		t.Fatalf("unexpected delivery log %+v, %v", log, err)
+	}
+	if sent, _ := s.DeliverDue(context.Background()); sent != 0 || len(rcv.events) != 2 {
+		t.Errorf("test events must not be redelivered")
+	}
+
+	if _, err := s.SendTestEvent(context.Background(), &models.User{ID: 2}, ep.ID); !errors.Is(err, ErrWebhookNotFound) {
+		t.Errorf("expected ErrWebhookNotFound for a foreign webhook, got %v", err)
+	}
+}
+
+// recordingPublisher запоминает опубликованные события
+type recordingPublisher struct {
+	events []models.WebhookEvent
+	data   []any
+}
+
+func (p *recordingPublisher) PublishEvent(event models.WebhookEvent, data any) {
+	p.events = append(p.events, event)
+	p.data = append(p.data, data)
+}
+
+func TestPostsService_WebhookEvents(t *testing.T) {
+	events := &recordingPublisher{}
+	store := &MockPostStorage{}
+	s := NewPostService(store, &MockModerationLogStorage{}, nil, nil, nil, nil, nil, nil, nil, nil, events)
+	ctx := context.Background()
+
+	verifiedAt := time.Now()
+	author := &models.User{ID: 1, DisplayName: "Author", EmailVerifiedAt: &verifiedAt}
+	shadowed := &models.User{ID: 2, DisplayName: "Shadow", EmailVerifiedAt: &verifiedAt, ShadowBanned: true}
+
+	postID, err := s.CreatePost(ctx, author, models.PostDraft{Heading: "Title", MainText: "Text"})
+	if err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+	if _, err := s.CreateComment(ctx, author, postID, bson.ObjectID{}, "Nice"); err != nil {
+		t.Fatalf("create comment: %v", err)
+	}
+	if _, err := s.CreatePost(ctx, shadowed, models.PostDraft{Heading: "Hidden", MainText: "Text"}); err != nil {
+		t.Fatalf("create shadowed post: %v", err)
+	}
+	if _, err := s.CreateComment(ctx, shadowed, postID, bson.ObjectID{}, "Hidden"); err != nil {
+		t.Fatalf("create shadowed comment: %v", err)
+	}
+
+	want := []models.WebhookEvent{models.WebhookPostCreated, models.WebhookCommentCreated}
+	if !slices.Equal(events.events, want) {
+		t.Fatalf("expected %v (shadowed content is not published), got %v", want, events.events)
+	}
+	if data := events.data[1].(map[string]any); data["postId"] != postID.Hex() || data["text"] != "Nice" {
+		t.Errorf("unexpected comment event data %v", data)
+	}
+}
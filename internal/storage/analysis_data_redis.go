@@ -81,6 +81,17 @@ func (a *AnalysisTempRStorage) GetStats() string {
 		return fmt.Sprintf("Error: %v", err)
 	}
 
+	// В том же Redis живут сессии и счётчики лимитов — в статистику анализа они не входят
+	analysisKeys := keys[:0]
+	for _, key := range keys {
+		if !strings.HasPrefix(key, sessionKeyPrefix) &&
+			!strings.HasPrefix(key, userSessionKeyPrefix) &&
+			!strings.HasPrefix(key, rateLimitKeyPrefix) {
+			analysisKeys = append(analysisKeys, key)
+		}
+	}
+	keys = analysisKeys
+
 	if len(keys) == 0 {
 		return "Redis is empty"
 	}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"crypto-analytics/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrAPITokenNotFound = errors.New("api token not found")
+
+const apiTokenColumns = `id, user_id, name, token_prefix, token_hash, scopes, expires_at, last_used_at, revoked_at, created_at`
+
+type APITokenPostgresStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewAPITokenPostgresStorage(pool *pgxpool.Pool) *APITokenPostgresStorage {
+	return &APITokenPostgresStorage{pool: pool}
+}
+
+func (s *APITokenPostgresStorage) CreateAPIToken(token *models.APIToken) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO api_tokens (user_id, name, token_prefix, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		token.UserID, token.Name, token.Prefix, token.TokenHash, scopeStrings(token.Scopes), token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create api token: %w", err)
+	}
+	return nil
+}
+
+// GetAPITokenByHash ищет токен по хешу; отозванные и просроченные тоже возвращаются —
+// решение о доступе принимает сервис
+func (s *APITokenPostgresStorage) GetAPITokenByHash(tokenHash string) (*models.APIToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	row := s.pool.QueryRow(ctx, `SELECT `+apiTokenColumns+` FROM api_tokens WHERE token_hash = $1`, tokenHash)
+	token, err := scanAPIToken(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAPITokenNotFound
+		}
+		return nil, fmt.Errorf("failed to get api token: %w", err)
+	}
+	return token, nil
+}
+
+func (s *APITokenPostgresStorage) ListAPITokens(userID int64) ([]models.APIToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+apiTokenColumns+`
+		FROM api_tokens
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api token: %w", err)
+		}
+		tokens = append(tokens, *token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return tokens, nil
+}
+
+func (s *APITokenPostgresStorage) RevokeAPIToken(userID, tokenID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := s.pool.Exec(ctx, `
+		UPDATE api_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`, tokenID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api token: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// TouchAPIToken обновляет last_used_at не чаще раза в минуту, чтобы не писать на каждый запрос
+func (s *APITokenPostgresStorage) TouchAPIToken(tokenID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := s.pool.Exec(ctx, `
+		UPDATE api_tokens
+		SET last_used_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+			AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute')`, tokenID)
+	if err != nil {
+		return fmt.Errorf("failed to touch api token: %w", err)
+	}
+	return nil
+}
+
+func scanAPIToken(row pgx.Row) (*models.APIToken, error) {
+	token := &models.APIToken{}
+	var scopes []string
+	err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Name,
+		&token.Prefix,
+		&token.TokenHash,
+		&scopes,
+		&token.ExpiresAt,
+		&token.LastUsedAt,
+		&token.RevokedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	token.Scopes = make([]models.Scope, len(scopes))
+	for i, s := range scopes {
+		token.Scopes[i] = models.Scope(s)
+	}
+	return token, nil
+}
+
+func scopeStrings(scopes []models.Scope) []string {
+	out := make([]string, len(scopes))
+	for i, s := range scopes {
+		out[i] = string(s)
+	}
+	return out
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"crypto-analytics/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const AttachmentsCollName = "attachments"
+
+type AttachmentMongoStorage struct {
+	coll *mongo.Collection
+}
+
+func NewAttachmentMongoStorage(client *mongo.Client) *AttachmentMongoStorage {
+	return &AttachmentMongoStorage{
+		coll: client.Database(DBName).Collection(AttachmentsCollName),
+	}
+}
+
+// EnsureIndexes создаёт индекс для поиска непривязанных вложений
+func (s *AttachmentMongoStorage) EnsureIndexes(ctx context.Context) error {
+	_, err := s.coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "postId", Value: 1}, {Key: "createdAt", Value: 1}},
+	})
+	return err
+}
+
+func (s *AttachmentMongoStorage) CreateAttachment(ctx context.Context, a models.Attachment) error {
+	_, err := s.coll.InsertOne(ctx, a)
+	return err
+}
+
+func (s *AttachmentMongoStorage) GetAttachment(ctx context.Context, id bson.ObjectID) (*models.Attachment, error) {
+	var a models.Attachment
+	if err := s.coll.FindOne(ctx, bson.M{"_id": id}).Decode(&a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (s *AttachmentMongoStorage) ClaimAttachments(
+	ctx context.Context,
+	ids []bson.ObjectID,
+	ownerID int64,
+	postID bson.ObjectID,
+) (int, error) {
+	res, err := s.coll.UpdateMany(
+		ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "ownerId": ownerID, "postId": nil},
+		bson.M{"$set": bson.M{"postId": postID}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.ModifiedCount), nil
+}
+
+// ReleaseAttachments отвязывает вложения поста, который так и не был создан
+func (s *AttachmentMongoStorage) ReleaseAttachments(ctx context.Context, postID bson.ObjectID) error {
+	_, err := s.coll.UpdateMany(ctx, bson.M{"postId": postID}, bson.M{"$unset": bson.M{"postId": ""}})
+	return err
+}
+
+// ListOrphanAttachments — вложения, загруженные раньше before и не попавшие ни в один пост
+func (s *AttachmentMongoStorage) ListOrphanAttachments(
+	ctx context.Context,
+	before time.Time,
+	limit int,
+) ([]models.Attachment, error) {
+	cursor, err := s.coll.Find(
+		ctx,
+		bson.M{"postId": nil, "createdAt": bson.M{"$lt": before}},
+		options.Find().SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var attachments []models.Attachment
+	if err = cursor.All(ctx, &attachments); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+func (s *AttachmentMongoStorage) DeleteAttachment(ctx context.Context, id bson.ObjectID) error {
+	_, err := s.coll.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"crypto-analytics/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AuthAuditPostgresStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewAuthAuditPostgresStorage(pool *pgxpool.Pool) *AuthAuditPostgresStorage {
+	return &AuthAuditPostgresStorage{pool: pool}
+}
+
+func (s *AuthAuditPostgresStorage) LogAuthEvent(event *models.AuthEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO auth_audit_log (event, login, ip, user_agent)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`,
+		string(event.Event), event.Login, event.IP, event.UserAgent,
+	).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to write auth audit log: %w", err)
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"time"
@@ -24,8 +25,8 @@ func NewContactPostgresStorage(pool *pgxpool.Pool) *ContStorage {
 func (s *ContStorage) SaveContactFrom(contact *models.ContactForm) error {
 
 	query := `
-    INSERT INTO contacts (name, email, message)
-    VALUES ($1, $2, $3)
+    INSERT INTO contacts (name, email, message, ip, user_agent)
+    VALUES ($1, $2, $3, $4, $5)
     `
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -35,9 +36,10 @@ func (s *ContStorage) SaveContactFrom(contact *models.ContactForm) error {
 		"name", contact.Name,
 		"email", contact.Email,
 		"message", contact.Message,
+		"ip", contact.IP,
 	)
 
-	result, err := s.pool.Exec(ctx, query, contact.Name, contact.Email, contact.Message)
+	result, err := s.pool.Exec(ctx, query, contact.Name, contact.Email, contact.Message, contact.IP, contact.UserAgent)
 	if err != nil {
 		slog.Error("SQL query execution failed",
 			"error", err,
@@ -60,27 +62,42 @@ func (s *ContStorage) Close() {
 }
 
 func (s *ContStorage) ExportContactsToJSON(filename string) error {
+	// Создаем файл для записи
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("ошибка создания файла: %w", err)
+	}
+	defer file.Close()
+
+	contactCount, err := s.ExportContacts(file)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Export completed successfully",
+		"contacts_exported", contactCount,
+		"filename", filename,
+		"format", "json",
+	)
+	return nil
+}
+
+// ExportContacts пишет все обращения в w в виде JSON-массива
+func (s *ContStorage) ExportContacts(w io.Writer) (int, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	rows, err := s.pool.Query(ctx, `
-		SELECT id, name, email, message, created_at
+		SELECT id, name, email, message, ip, created_at
 		FROM contacts
 		ORDER BY created_at DESC
 	`)
 	if err != nil {
-		return fmt.Errorf("ошибка выполнения запроса: %w", err)
+		return 0, fmt.Errorf("ошибка выполнения запроса: %w", err)
 	}
 	defer rows.Close()
 
-	// Создаем файл для записи
-	file, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("ошибка создания файла: %w", err)
-	}
-	defer file.Close()
-
-	var contacts []map[string]interface{}
+	contacts := []map[string]interface{}{}
 	var contactCount int
 
 	// Обрабатываем каждую строку результата
@@ -90,10 +107,11 @@ func (s *ContStorage) ExportContactsToJSON(filename string) error {
 			Name      string
 			Email     string
 			Message   string
+			IP        string
 			CreatedAt time.Time
 		}
 
-		err := rows.Scan(&contact.ID, &contact.Name, &contact.Email, &contact.Message, &contact.CreatedAt)
+		err := rows.Scan(&contact.ID, &contact.Name, &contact.Email, &contact.Message, &contact.IP, &contact.CreatedAt)
 		if err != nil {
 			slog.Warn("Ошибка чтения строки:", "error", err)
 			continue
@@ -105,6 +123,7 @@ func (s *ContStorage) ExportContactsToJSON(filename string) error {
 			"name":       contact.Name,
 			"email":      contact.Email,
 			"message":    contact.Message,
+			"ip":         contact.IP,
 			"created_at": contact.CreatedAt.Format(time.RFC3339),
 		}
 		contacts = append(contacts, contactData)
@@ -112,21 +131,15 @@ func (s *ContStorage) ExportContactsToJSON(filename string) error {
 	}
 
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("ошибка при чтении строк: %w", err)
+		return 0, fmt.Errorf("ошибка при чтении строк: %w", err)
 	}
 
-	encoder := json.NewEncoder(file)
+	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "  ")
 	if err := encoder.Encode(contacts); err != nil {
-		return fmt.Errorf("ошибка кодирования JSON: %w", err)
+		return 0, fmt.Errorf("ошибка кодирования JSON: %w", err)
 	}
-
-	slog.Info("Export completed successfully",
-		"contacts_exported", contactCount,
-		"filename", filename,
-		"format", "json",
-	)
-	return nil
+	return contactCount, nil
 }
 
 func (s *ContStorage) GetContactsStats() (map[string]interface{}, error) {
@@ -142,6 +155,45 @@ func (s *ContStorage) GetContactsStats() (map[string]interface{}, error) {
 	}
 	stats["total_contacts"] = totalContacts
 
+	// Разбивка по статусам и очередь без исполнителя — для панели администратора
+	byStatus := map[string]int{
+		string(models.TicketNew):        0,
+		string(models.TicketInProgress): 0,
+		string(models.TicketResolved):   0,
+	}
+	rows, err := s.pool.Query(ctx, "SELECT status, COUNT(*) FROM contacts GROUP BY status")
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения статусов обращений: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("ошибка чтения статусов обращений: %w", err)
+		}
+		byStatus[status] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения статусов обращений: %w", err)
+	}
+	stats["by_status"] = byStatus
+
+	var unassigned int
+	err = s.pool.QueryRow(ctx,
+		"SELECT COUNT(*) FROM contacts WHERE status <> 'resolved' AND assignee_id IS NULL").Scan(&unassigned)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения очереди обращений: %w", err)
+	}
+	stats["open_unassigned"] = unassigned
+
+	var avgResolve *float64
+	err = s.pool.QueryRow(ctx,
+		"SELECT AVG(EXTRACT(EPOCH FROM resolved_at - created_at)) / 3600 FROM contacts WHERE resolved_at IS NOT NULL").Scan(&avgResolve)
+	if err == nil && avgResolve != nil {
+		stats["avg_resolve_hours"] = *avgResolve
+	}
+
 	var oldestContact time.Time
 	err = s.pool.QueryRow(ctx, "SELECT MIN(created_at) FROM contacts").Scan(&oldestContact)
 	if err == nil {
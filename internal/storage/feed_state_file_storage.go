@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"crypto-analytics/internal/models"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+type FeedStateFileStorage struct {
+	filename string
+	mu       sync.Mutex
+}
+
+func NewFeedStateFileStorage(filename string) *FeedStateFileStorage {
+	return &FeedStateFileStorage{
+		filename: filename,
+	}
+}
+
+func (s *FeedStateFileStorage) load() (map[string]models.FeedState, error) {
+	data, err := os.ReadFile(s.filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]models.FeedState{}, nil
+		}
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	states := make(map[string]models.FeedState)
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	return states, nil
+}
+
+func (s *FeedStateFileStorage) GetFeedStates() (map[string]models.FeedState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.load()
+}
+
+func (s *FeedStateFileStorage) SaveFeedState(state models.FeedState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states, err := s.load()
+	if err != nil {
+		return err
+	}
+	states[state.URL] = state
+
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	if err := os.WriteFile(s.filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
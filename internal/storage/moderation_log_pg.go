@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"crypto-analytics/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ModerationLogPostgresStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewModerationLogPostgresStorage(pool *pgxpool.Pool) *ModerationLogPostgresStorage {
+	return &ModerationLogPostgresStorage{pool: pool}
+}
+
+func (s *ModerationLogPostgresStorage) LogModeration(entry *models.ModerationEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO moderation_log (actor_id, action, target, target_id, reason)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		entry.ActorID, string(entry.Action), string(entry.Target), entry.TargetID, entry.Reason,
+	).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to write moderation log: %w", err)
+	}
+	return nil
+}
+
+// ListModeration возвращает последние записи журнала, новые первыми
+func (s *ModerationLogPostgresStorage) ListModeration(limit int) ([]models.ModerationEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, COALESCE(actor_id, 0), action, target, target_id, reason, created_at
+		FROM moderation_log
+		ORDER BY id DESC
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list moderation log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.ModerationEntry{}
+	for rows.Next() {
+		var e models.ModerationEntry
+		var action, target string
+		if err := rows.Scan(&e.ID, &e.ActorID, &action, &target, &e.TargetID, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan moderation log: %w", err)
+		}
+		e.Action = models.ModerationAction(action)
+		e.Target = models.ModerationTarget(target)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
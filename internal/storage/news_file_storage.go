@@ -72,13 +72,23 @@ func (s *NewsFileStorage) generateID(item models.NewsItem) string {
 }
 
 func (s *NewsFileStorage) AddNews(items []models.NewsItem) error {
+	_, err := s.UpdateNews(items)
+	return err
+}
+
+func (s *NewsFileStorage) GetAllNews() ([]models.NewsItem, error) {
+	return s.loadNews()
+}
+
+// UpdateNews дописывает в кэш новости, которых там ещё нет, и возвращает только их
+func (s *NewsFileStorage) UpdateNews(items []models.NewsItem) ([]models.NewsItem, error) {
 	if len(items) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	existingNews, err := s.loadNews()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	existingMap := make(map[string]bool)
@@ -86,22 +96,22 @@ func (s *NewsFileStorage) AddNews(items []models.NewsItem) error {
 		existingMap[s.generateID(item)] = true
 	}
 
+	var added []models.NewsItem
 	for _, item := range items {
 		id := s.generateID(item)
 		if !existingMap[id] {
-			existingNews = append(existingNews, item)
+			added = append(added, item)
 			existingMap[id] = true
 		}
 	}
+	if len(added) == 0 {
+		return nil, nil
+	}
 
-	return s.saveNews(existingNews)
-}
-
-func (s *NewsFileStorage) GetAllNews() ([]models.NewsItem, error) {
-	return s.loadNews()
+	return added, s.saveNews(append(existingNews, added...))
 }
 
-func (s *NewsFileStorage) UpdateNews(items []models.NewsItem) error {
-
-	return s.AddNews(items)
+// ReplaceNews перезаписывает кэш целиком (используется при нормализации старых записей)
+func (s *NewsFileStorage) ReplaceNews(items []models.NewsItem) error {
+	return s.saveNews(items)
 }
@@ -0,0 +1,205 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"crypto-analytics/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrChannelNotFound = errors.New("notification channel not found")
+
+type NotificationPostgresStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewNotificationPostgresStorage(pool *pgxpool.Pool) *NotificationPostgresStorage {
+	return &NotificationPostgresStorage{pool: pool}
+}
+
+func (s *NotificationPostgresStorage) ListChannels(userID int64) ([]models.ChannelPreference, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT user_id, channel, address, events, enabled
+		FROM notification_channels
+		WHERE user_id = $1
+		ORDER BY channel`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notification channels: %w", err)
+	}
+	defer rows.Close()
+
+	prefs := []models.ChannelPreference{}
+	for rows.Next() {
+		var p models.ChannelPreference
+		var events []string
+		if err := rows.Scan(&p.UserID, &p.Channel, &p.Address, &events, &p.Enabled); err != nil {
+			return nil, fmt.Errorf("failed to scan notification channel: %w", err)
+		}
+		p.Events = make([]models.NotificationEvent, 0, len(events))
+		for _, e := range events {
+			p.Events = append(p.Events, models.NotificationEvent(e))
+		}
+		prefs = append(prefs, p)
+	}
+	return prefs, rows.Err()
+}
+
+// SaveChannel создаёт или заменяет настройку канала пользователя
+func (s *NotificationPostgresStorage) SaveChannel(pref *models.ChannelPreference) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events := make([]string, 0, len(pref.Events))
+	for _, e := range pref.Events {
+		events = append(events, string(e))
+	}
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO notification_channels (user_id, channel, address, events, enabled)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, channel) DO UPDATE
+		SET address = EXCLUDED.address,
+			events = EXCLUDED.events,
+			enabled = EXCLUDED.enabled,
+			updated_at = CURRENT_TIMESTAMP`,
+		pref.UserID, string(pref.Channel), pref.Address, events, pref.Enabled)
+	if err != nil {
+		return fmt.Errorf("failed to save notification channel: %w", err)
+	}
+	return nil
+}
+
+func (s *NotificationPostgresStorage) DeleteChannel(userID int64, channel models.NotificationChannel) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := s.pool.Exec(ctx, `
+		DELETE FROM notification_channels WHERE user_id = $1 AND channel = $2`, userID, string(channel))
+	if err != nil {
+		return fmt.Errorf("failed to delete notification channel: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return ErrChannelNotFound
+	}
+	return nil
+}
+
+// Enqueue кладёт доставки в outbox одной транзакцией
+func (s *NotificationPostgresStorage) Enqueue(items []models.OutboxItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, item := range items {
+		message, err := json.Marshal(item.Message)
+		if err != nil {
+			return fmt.Errorf("failed to encode notification: %w", err)
+		}
+		var userID *int64
+		if item.UserID != 0 {
+			userID = &item.UserID
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO notification_outbox (user_id, channel, address, message)
+			VALUES ($1, $2, $3, $4)`,
+			userID, string(item.Channel), item.Address, message)
+		if err != nil {
+			return fmt.Errorf("failed to enqueue notification: %w", err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// ClaimDue забирает до limit доставок, срок которых наступил, и откладывает их на lease:
+// если процесс упадёт посреди отправки, доставка вернётся в очередь сама.
+// SKIP LOCKED позволяет нескольким экземплярам приложения разбирать очередь параллельно.
+func (s *NotificationPostgresStorage) ClaimDue(limit int, lease time.Duration) ([]models.OutboxItem, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, `
+		UPDATE notification_outbox
+		SET attempts = attempts + 1,
+			next_attempt_at = CURRENT_TIMESTAMP + $2 * INTERVAL '1 second'
+		WHERE id IN (
+			SELECT id FROM notification_outbox
+			WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, COALESCE(user_id, 0), channel, address, message, status, attempts,
+			next_attempt_at, last_error, created_at`,
+		limit, int64(lease/time.Second))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var items []models.OutboxItem
+	for rows.Next() {
+		var item models.OutboxItem
+		var message []byte
+		if err := rows.Scan(&item.ID, &item.UserID, &item.Channel, &item.Address, &message, &item.Status,
+			&item.Attempts, &item.NextAttemptAt, &item.LastError, &item.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		if err := json.Unmarshal(message, &item.Message); err != nil {
+			return nil, fmt.Errorf("failed to decode notification %d: %w", item.ID, err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (s *NotificationPostgresStorage) MarkSent(id int64) error {
+	return s.updateOutbox(`
+		UPDATE notification_outbox
+		SET status = 'sent', sent_at = CURRENT_TIMESTAMP, last_error = ''
+		WHERE id = $1`, id)
+}
+
+// RetryLater возвращает доставку в очередь через delay
+func (s *NotificationPostgresStorage) RetryLater(id int64, lastErr string, delay time.Duration) error {
+	return s.updateOutbox(`
+		UPDATE notification_outbox
+		SET last_error = $2, next_attempt_at = CURRENT_TIMESTAMP + $3 * INTERVAL '1 second'
+		WHERE id = $1`, id, lastErr, int64(delay/time.Second))
+}
+
+// MarkFailed снимает доставку с очереди: попытки кончились или адрес заведомо неверный
+func (s *NotificationPostgresStorage) MarkFailed(id int64, lastErr string) error {
+	return s.updateOutbox(`
+		UPDATE notification_outbox
+		SET status = 'failed', last_error = $2
+		WHERE id = $1`, id, lastErr)
+}
+
+func (s *NotificationPostgresStorage) updateOutbox(query string, args ...any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := s.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update notification: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("notification %v not found", args[0])
+	}
+	return nil
+}
@@ -11,27 +11,43 @@ import (
 )
 
 const (
-	DBName           = "cryptodb"
-	PostsCollName    = "posts"
-	CommentsCollName = "comments"
+	DBName             = "cryptodb"
+	PostsCollName      = "posts"
+	CommentsCollName   = "comments"
+	VotesCollName      = "votes"
+	ReactionsCollName  = "reactions"
+	ReputationCollName = "reputation"
+	RevisionsCollName  = "revisions"
 )
 
 type PostMongoStorage struct {
-	client    *mongo.Client
-	collPosts *mongo.Collection
-	collComm  *mongo.Collection
+	client        *mongo.Client
+	collPosts     *mongo.Collection
+	collComm      *mongo.Collection
+	collVotes     *mongo.Collection
+	collReactions *mongo.Collection
+	collRep       *mongo.Collection
+	collRevisions *mongo.Collection
 }
 
 func NewPostsMongoStorage(client *mongo.Client) *PostMongoStorage {
+	db := client.Database(DBName)
 	return &PostMongoStorage{
-		client:    client,
-		collPosts: client.Database(DBName).Collection(PostsCollName),
-		collComm:  client.Database(DBName).Collection(CommentsCollName),
+		client:        client,
+		collPosts:     db.Collection(PostsCollName),
+		collComm:      db.Collection(CommentsCollName),
+		collVotes:     db.Collection(VotesCollName),
+		collReactions: db.Collection(ReactionsCollName),
+		collRep:       db.Collection(ReputationCollName),
+		collRevisions: db.Collection(RevisionsCollName),
 	}
 }
 
+// CreatePost сохраняет пост; ID, заданный заранее (например, для привязки вложений), сохраняется
 func (p *PostMongoStorage) CreatePost(ctx context.Context, post models.Post) (bson.ObjectID, error) {
-	post.ID = bson.NewObjectID()
+	if post.ID.IsZero() {
+		post.ID = bson.NewObjectID()
+	}
 	_, err := p.collPosts.InsertOne(ctx, post)
 	if err != nil {
 		return bson.ObjectID{}, err
@@ -42,31 +58,82 @@ func (p *PostMongoStorage) CreatePost(ctx context.Context, post models.Post) (bs
 func (p *PostMongoStorage) CreateComment(
 	ctx context.Context,
 	comment models.Comment,
-) error {
-	comment.ID = bson.NewObjectID()
+) (bson.ObjectID, error) {
+	// Комментировать удалённый или скрытый от автора пост нельзя
+	err := p.collPosts.FindOne(ctx, visibleTo(bson.M{"_id": comment.PostID, "deletedAt": nil}, comment.AuthorID)).Err()
+	if err != nil {
+		return bson.ObjectID{}, err
+	}
 
-	_, err := p.collComm.InsertOne(ctx, comment)
+	comment.ID = bson.NewObjectID()
+	_, err = p.collComm.InsertOne(ctx, comment)
 	if err != nil {
-		return err
+		return bson.ObjectID{}, err
+	}
+	if comment.Shadowed {
+		// Счётчик комментариев выдал бы теневой бан остальным читателям
+		return comment.ID, nil
 	}
 
 	_, err = p.collPosts.UpdateOne(
 		ctx,
 		bson.M{"_id": comment.PostID},
-		bson.M{"$push": bson.M{"commentIds": comment.ID}},
+		bson.M{
+			"$push": bson.M{"commentIds": comment.ID},
+			"$inc":  bson.M{"commentCount": 1},
+		},
 	)
 	if err != nil {
-		return err
+		return bson.ObjectID{}, err
 	}
 
-	return nil
+	return comment.ID, nil
+}
+
+// GetPost возвращает не удалённый пост, если viewerID его видит (см. visibleTo)
+func (p *PostMongoStorage) GetPost(ctx context.Context, postID bson.ObjectID, viewerID int64) (*models.Post, error) {
+	var post models.Post
+	filter := visibleTo(bson.M{"_id": postID, "deletedAt": nil}, viewerID)
+	if err := p.collPosts.FindOne(ctx, filter).Decode(&post); err != nil {
+		return nil, err
+	}
+	return &post, nil
 }
 
-func (p *PostMongoStorage) GetLastPosts(ctx context.Context) ([]models.Post, error) {
+// GetComment возвращает не удалённый комментарий, если viewerID его видит
+func (p *PostMongoStorage) GetComment(ctx context.Context, commentID bson.ObjectID, viewerID int64) (*models.Comment, error) {
+	var comment models.Comment
+	filter := visibleTo(bson.M{"_id": commentID, "deletedAt": nil}, viewerID)
+	if err := p.collComm.FindOne(ctx, filter).Decode(&comment); err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// ListPosts возвращает не удалённые посты по фильтрам q, начиная после q.After
+func (p *PostMongoStorage) ListPosts(ctx context.Context, q models.PostQuery) ([]models.Post, error) {
+	filter := visibleTo(bson.M{"deletedAt": nil}, q.ViewerID)
+	if q.AuthorID != 0 {
+		filter["authorId"] = q.AuthorID
+	}
+	if len(q.Tags) > 0 {
+		filter["tags"] = bson.M{"$in": q.Tags}
+	}
+
+	key := ""
+	switch q.Sort {
+	case models.PostSortComments:
+		key = "commentCount"
+	case models.PostSortVotes:
+		key = "voteScore"
+	case models.PostSortTop:
+		key = "hotScore"
+	}
+
 	cursor, err := p.collPosts.Find(
 		ctx,
-		bson.D{},
-		options.Find().SetSort(bson.D{{Key: "date", Value: -1}}).SetLimit(100),
+		afterCursor(filter, key, true, q.After),
+		options.Find().SetSort(pageSort(key, true)).SetLimit(int64(q.Limit)),
 	)
 	if err != nil {
 		return nil, err
@@ -80,13 +147,22 @@ func (p *PostMongoStorage) GetLastPosts(ctx context.Context) ([]models.Post, err
 	return posts, nil
 }
 
-func (p *PostMongoStorage) GetLastCommentsByPost(ctx context.Context, postID bson.ObjectID) ([]models.Comment, error) {
-	filter := bson.M{"postId": postID}
+// ListComments возвращает не удалённые комментарии поста, начиная после q.After
+func (p *PostMongoStorage) ListComments(ctx context.Context, q models.CommentQuery) ([]models.Comment, error) {
+	filter := visibleTo(bson.M{"postId": q.PostID, "deletedAt": nil}, q.ViewerID)
+
+	key, desc := "", true
+	switch q.Sort {
+	case models.CommentSortOldest:
+		desc = false
+	case models.CommentSortVotes:
+		key = "voteScore"
+	}
 
 	cursor, err := p.collComm.Find(
 		ctx,
-		filter,
-		options.Find().SetSort(bson.D{{Key: "date", Value: -1}}).SetLimit(100),
+		afterCursor(filter, key, desc, q.After),
+		options.Find().SetSort(pageSort(key, desc)).SetLimit(int64(q.Limit)),
 	)
 	if err != nil {
 		return nil, err
@@ -100,105 +176,157 @@ func (p *PostMongoStorage) GetLastCommentsByPost(ctx context.Context, postID bso
 	return comments, nil
 }
 
-func (p *PostMongoStorage) DeletePost(ctx context.Context, postID bson.ObjectID, author string) error {
-	var post models.Post
-	err := p.collPosts.FindOne(ctx, bson.M{"_id": postID, "person": author}).Decode(&post)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return mongo.ErrNoDocuments
-		}
-		return err
+// visibleTo скрывает записи пользователей в теневом бане от всех, кроме самих авторов.
+// viewerID 0 — аноним.
+func visibleTo(filter bson.M, viewerID int64) bson.M {
+	if viewerID == 0 {
+		filter["shadowed"] = bson.M{"$ne": true}
+		return filter
 	}
+	filter["$nor"] = bson.A{bson.M{"shadowed": true, "authorId": bson.M{"$ne": viewerID}}}
+	return filter
+}
 
-	_, err = p.collComm.DeleteMany(ctx, bson.M{"postId": postID})
-	if err != nil {
-		return err
+// pageSort — сортировка по ключу (если есть) и затем по _id, чтобы порядок был полным
+func pageSort(key string, desc bool) bson.D {
+	dir := 1
+	if desc {
+		dir = -1
 	}
-	_, err = p.collPosts.DeleteOne(ctx, bson.M{"_id": postID})
-	if err != nil {
-		return err
+	if key == "" {
+		return bson.D{{Key: "_id", Value: dir}}
 	}
+	return bson.D{{Key: key, Value: dir}, {Key: "_id", Value: dir}}
+}
 
-	return nil
+// afterCursor добавляет к filter условие «строго после курсора» в порядке pageSort
+func afterCursor(filter bson.M, key string, desc bool, after *models.PageCursor) bson.M {
+	if after == nil {
+		return filter
+	}
+	op := "$gt"
+	if desc {
+		op = "$lt"
+	}
+	if key == "" {
+		filter["_id"] = bson.M{op: after.ID}
+		return filter
+	}
+	filter["$or"] = bson.A{
+		bson.M{key: bson.M{op: after.Key}},
+		bson.M{key: after.Key, "_id": bson.M{op: after.ID}},
+	}
+	return filter
 }
 
-func (p *PostMongoStorage) DeleteComment(ctx context.Context, commentID bson.ObjectID, author string) error {
-	var comment models.Comment
-	err := p.collComm.FindOne(ctx, bson.M{"_id": commentID, "person": author}).Decode(&comment)
+// EnsureIndexes создаёт индексы под запросы ListPosts и ListComments.
+// CreateMany идемпотентен, поэтому вызывается при каждом старте.
+func (p *PostMongoStorage) EnsureIndexes(ctx context.Context) error {
+	_, err := p.collPosts.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "authorId", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "tags", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "commentCount", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "voteScore", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "hotScore", Value: -1}, {Key: "_id", Value: -1}}},
+	})
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return mongo.ErrNoDocuments
-		}
 		return err
 	}
 
-	_, err = p.collComm.DeleteOne(ctx, bson.M{"_id": commentID})
+	_, err = p.collComm.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "postId", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "postId", Value: 1}, {Key: "voteScore", Value: -1}, {Key: "_id", Value: -1}}},
+	})
 	if err != nil {
 		return err
 	}
 
-	_, err = p.collPosts.UpdateOne(
-		ctx,
-		bson.M{"_id": comment.PostID},
-		bson.M{"$pull": bson.M{"commentIds": commentID}},
-	)
+	// Уникальные индексы и есть гарантия «один голос / одна реакция каждого вида на пользователя»
+	_, err = p.collVotes.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "targetId", Value: 1}, {Key: "userId", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
 	if err != nil {
 		return err
 	}
-
-	return nil
+	_, err = p.collReactions.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "targetId", Value: 1}, {Key: "userId", Value: 1}, {Key: "emoji", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = p.collRevisions.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "targetId", Value: 1}, {Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
 }
 
-func (p *PostMongoStorage) UpdatePost(
-	ctx context.Context,
-	postID bson.ObjectID,
-	author string,
-	title string,
-	content string,
-) error {
-
-	var existingPost models.Post
-	err := p.collPosts.FindOne(ctx, bson.M{"_id": postID}).Decode(&existingPost)
+// BackfillCounters заполняет commentCount и voteScore у документов, созданных до
+// появления счётчиков: $lt/$gt курсора не находят записи без поля
+func (p *PostMongoStorage) BackfillCounters(ctx context.Context) (int, error) {
+	updated := 0
+
+	res, err := p.collPosts.UpdateMany(
+		ctx,
+		bson.M{"commentCount": bson.M{"$exists": false}},
+		bson.A{bson.M{"$set": bson.M{"commentCount": bson.M{"$size": bson.M{"$ifNull": bson.A{"$commentIds", bson.A{}}}}}}},
+	)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		return updated, err
+	}
+	updated += int(res.ModifiedCount)
 
-			return mongo.ErrNoDocuments
+	for _, coll := range []*mongo.Collection{p.collPosts, p.collComm} {
+		for field, value := range map[string]int{"voteScore": 0, "revision": 1} {
+			res, err := coll.UpdateMany(
+				ctx,
+				bson.M{field: bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{field: value}},
+			)
+			if err != nil {
+				return updated, err
+			}
+			updated += int(res.ModifiedCount)
 		}
-
-		return err
 	}
 
-	if existingPost.Person != author {
-		return mongo.ErrNoDocuments
+	res, err = p.collPosts.UpdateMany(
+		ctx,
+		bson.M{"hotScore": bson.M{"$exists": false}},
+		bson.A{bson.M{"$set": bson.M{"hotScore": hotScoreExpr}}},
+	)
+	if err != nil {
+		return updated, err
 	}
+	updated += int(res.ModifiedCount)
+	return updated, nil
+}
 
-	_, err = p.collPosts.UpdateOne(
+// DeletePost мягко удаляет пост автора: запись остаётся в базе, но пропадает из выдачи
+func (p *PostMongoStorage) DeletePost(ctx context.Context, postID bson.ObjectID, authorID int64) error {
+	res, err := p.collPosts.UpdateOne(
 		ctx,
-		bson.M{"_id": postID, "person": author},
-		bson.M{"$set": bson.M{
-			"heading":   title,
-			"mainText":  content,
-			"updatedAt": time.Now(),
-		}},
+		bson.M{"_id": postID, "authorId": authorID, "deletedAt": nil},
+		bson.M{"$set": bson.M{"deletedAt": time.Now(), "deletedBy": authorID}},
 	)
 	if err != nil {
 		return err
 	}
-
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
 	return nil
 }
 
-func (p *PostMongoStorage) UpdateComment(
-	ctx context.Context,
-	commentID bson.ObjectID,
-	author string,
-	content string,
-) error {
-	res, err := p.collComm.UpdateOne(
-		ctx,
-		bson.M{"_id": commentID, "person": author},
-		bson.M{"$set": bson.M{"mainText": content}},
-	)
+func (p *PostMongoStorage) DeleteComment(ctx context.Context, commentID bson.ObjectID, authorID int64) error {
+	return p.setCommentDeleted(ctx, bson.M{"_id": commentID, "authorId": authorID, "deletedAt": nil}, authorID, true)
+}
+
+// SetPostDeleted скрывает или возвращает любой пост (для модераторов)
+func (p *PostMongoStorage) SetPostDeleted(ctx context.Context, postID bson.ObjectID, actorID int64, deleted bool) error {
+	res, err := p.collPosts.UpdateOne(ctx, bson.M{"_id": postID}, deletedUpdate(actorID, deleted))
 	if err != nil {
 		return err
 	}
@@ -208,6 +336,93 @@ func (p *PostMongoStorage) UpdateComment(
 	return nil
 }
 
+// SetCommentDeleted скрывает или возвращает любой комментарий (для модераторов)
+func (p *PostMongoStorage) SetCommentDeleted(ctx context.Context, commentID bson.ObjectID, actorID int64, deleted bool) error {
+	return p.setCommentDeleted(ctx, bson.M{"_id": commentID}, actorID, deleted)
+}
+
+// setCommentDeleted меняет пометку удаления и держит commentIds и commentCount поста
+// в согласии с ней, чтобы счётчик комментариев не учитывал скрытые
+func (p *PostMongoStorage) setCommentDeleted(ctx context.Context, filter bson.M, actorID int64, deleted bool) error {
+	// FindOneAndUpdate отдаёт документ до изменения: по нему видно, поменялось ли состояние
+	var comment models.Comment
+	err := p.collComm.FindOneAndUpdate(ctx, filter, deletedUpdate(actorID, deleted)).Decode(&comment)
+	if err != nil {
+		return err
+	}
+
+	update := commentListUpdate(&comment, deleted)
+	if update == nil {
+		return nil
+	}
+	_, err = p.collPosts.UpdateOne(ctx, bson.M{"_id": comment.PostID}, update)
+	return err
+}
+
+// commentListUpdate — изменение commentIds и commentCount поста при удалении или
+// восстановлении комментария (по его состоянию до изменения); nil — пост не трогаем.
+// Теневые комментарии в список и счётчик не попадают и при создании (см. CreateComment):
+// иначе удаление уводило бы счётчик в минус, а восстановление выдавало бы теневой бан.
+func commentListUpdate(before *models.Comment, deleted bool) bson.M {
+	if before.Shadowed || (before.DeletedAt != nil) == deleted {
+		return nil
+	}
+	if deleted {
+		return bson.M{
+			"$pull": bson.M{"commentIds": before.ID},
+			"$inc":  bson.M{"commentCount": -1},
+		}
+	}
+	return bson.M{
+		"$addToSet": bson.M{"commentIds": before.ID},
+		"$inc":      bson.M{"commentCount": 1},
+	}
+}
+
+func deletedUpdate(actorID int64, deleted bool) bson.M {
+	if deleted {
+		return bson.M{"$set": bson.M{"deletedAt": time.Now(), "deletedBy": actorID}}
+	}
+	return bson.M{"$unset": bson.M{"deletedAt": "", "deletedBy": ""}}
+}
+
+// BackfillAuthorIDs проставляет authorId постам и комментариям, созданным
+// до появления идентификаторов пользователей (у них есть только person).
+func (p *PostMongoStorage) BackfillAuthorIDs(
+	ctx context.Context,
+	resolve func(person string) (int64, bool),
+) (int, error) {
+	missing := bson.M{"$or": bson.A{
+		bson.M{"authorId": bson.M{"$exists": false}},
+		bson.M{"authorId": 0},
+	}}
+
+	updated := 0
+	for _, coll := range []*mongo.Collection{p.collPosts, p.collComm} {
+		var persons []string
+		if err := coll.Distinct(ctx, "person", missing).Decode(&persons); err != nil {
+			return updated, err
+		}
+
+		for _, person := range persons {
+			authorID, ok := resolve(person)
+			if !ok {
+				continue
+			}
+			res, err := coll.UpdateMany(
+				ctx,
+				bson.M{"$and": bson.A{missing, bson.M{"person": person}}},
+				bson.M{"$set": bson.M{"authorId": authorID}},
+			)
+			if err != nil {
+				return updated, err
+			}
+			updated += int(res.ModifiedCount)
+		}
+	}
+	return updated, nil
+}
+
 func (p *PostMongoStorage) Close() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
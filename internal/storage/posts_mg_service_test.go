@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"crypto-analytics/internal/models"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestCommentListUpdate(t *testing.T) {
+	deletedAt := time.Now()
+	id := bson.NewObjectID()
+
+	tests := []struct {
+		name    string
+		before  models.Comment
+		deleted bool
+		want    int // изменение commentCount, 0 — пост не трогаем
+	}{
+		{"delete visible", models.Comment{ID: id}, true, -1},
+		{"restore visible", models.Comment{ID: id, DeletedAt: &deletedAt}, false, 1},
+		{"delete already deleted", models.Comment{ID: id, DeletedAt: &deletedAt}, true, 0},
+		{"restore not deleted", models.Comment{ID: id}, false, 0},
+		{"delete shadowed", models.Comment{ID: id, Shadowed: true}, true, 0},
+		{"restore shadowed", models.Comment{ID: id, Shadowed: true, DeletedAt: &deletedAt}, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			update := commentListUpdate(&tt.before, tt.deleted)
+			if tt.want == 0 {
+				if update != nil {
+					t.Errorf("expected no post update, got %v", update)
+				}
+				return
+			}
+			inc, _ := update["$inc"].(bson.M)
+			if inc["commentCount"] != tt.want {
+				t.Errorf("expected commentCount %+d, got %v", tt.want, update)
+			}
+			list := "$addToSet"
+			if tt.deleted {
+				list = "$pull"
+			}
+			if ids, _ := update[list].(bson.M); ids["commentIds"] != id {
+				t.Errorf("expected %s of the comment id, got %v", list, update)
+			}
+		})
+	}
+}
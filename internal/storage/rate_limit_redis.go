@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const rateLimitKeyPrefix = "ratelimit:"
+
+type RateLimitRedisStorage struct {
+	rdb *redis.Client
+}
+
+func NewRateLimitRedisStorage(client *redis.Client) *RateLimitRedisStorage {
+	return &RateLimitRedisStorage{rdb: client}
+}
+
+// Hit увеличивает счётчик key в фиксированном окне window.
+// Возвращает новое значение и сколько осталось до сброса окна.
+func (s *RateLimitRedisStorage) Hit(key string, window time.Duration) (int64, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	key = rateLimitKeyPrefix + key
+	pipe := s.rdb.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, window)
+	ttl := pipe.PTTL(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, 0, fmt.Errorf("failed to hit rate limit counter: %w", err)
+	}
+	return incr.Val(), max(ttl.Val(), 0), nil
+}
+
+// SetLock ставит блокировку key на ttl
+func (s *RateLimitRedisStorage) SetLock(key string, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := s.rdb.Set(ctx, rateLimitKeyPrefix+key, 1, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to set lock: %w", err)
+	}
+	return nil
+}
+
+// LockTTL возвращает оставшееся время блокировки (0 — блокировки нет)
+func (s *RateLimitRedisStorage) LockTTL(key string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	ttl, err := s.rdb.PTTL(ctx, rateLimitKeyPrefix+key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get lock ttl: %w", err)
+	}
+	// -2 — ключа нет, -1 — ключ без срока (не наш случай)
+	return max(ttl, 0), nil
+}
+
+func (s *RateLimitRedisStorage) Reset(keys ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	full := make([]string, len(keys))
+	for i, key := range keys {
+		full[i] = rateLimitKeyPrefix + key
+	}
+	if err := s.rdb.Del(ctx, full...).Err(); err != nil {
+		return fmt.Errorf("failed to reset rate limit keys: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"crypto-analytics/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ReportPostgresStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewReportPostgresStorage(pool *pgxpool.Pool) *ReportPostgresStorage {
+	return &ReportPostgresStorage{pool: pool}
+}
+
+func (s *ReportPostgresStorage) CreateReport(report *models.Report) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO reports (reporter_id, target, target_id, reason, details)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (reporter_id, target, target_id) WHERE status = 'open' DO NOTHING
+		RETURNING id, status, created_at`,
+		report.ReporterID, string(report.Target), report.TargetID, string(report.Reason), report.Details,
+	).Scan(&report.ID, &report.Status, &report.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Жалоба уже в очереди
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+	return nil
+}
+
+// ListOpenReports группирует открытые жалобы по объектам: сначала те, на которые жалуются чаще
+func (s *ReportPostgresStorage) ListOpenReports(limit int) ([]models.ReportQueueItem, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT target, target_id, COUNT(*),
+			array_agg(reason ORDER BY id),
+			array_agg(details ORDER BY id) FILTER (WHERE details <> ''),
+			MIN(created_at), MAX(created_at)
+		FROM reports
+		WHERE status = 'open'
+		GROUP BY target, target_id
+		ORDER BY COUNT(*) DESC, MIN(created_at)
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reports: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.ReportQueueItem{}
+	for rows.Next() {
+		var item models.ReportQueueItem
+		var target string
+		var reasons, details []string
+		if err := rows.Scan(&target, &item.TargetID, &item.Count, &reasons, &details,
+			&item.FirstReportedAt, &item.LastReportedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan reports: %w", err)
+		}
+		item.Target = models.ModerationTarget(target)
+		for _, r := range reasons {
+			item.Reasons = append(item.Reasons, models.ReportReason(r))
+		}
+		item.Details = details
+		if item.Details == nil {
+			item.Details = []string{}
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (s *ReportPostgresStorage) CloseReports(
+	target models.ModerationTarget,
+	targetID string,
+	status models.ReportStatus,
+	actorID int64,
+) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := s.pool.Exec(ctx, `
+		UPDATE reports
+		SET status = $1, resolved_by = $2, resolved_at = CURRENT_TIMESTAMP
+		WHERE target = $3 AND target_id = $4 AND status = 'open'`,
+		string(status), actorID, string(target), targetID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to close reports: %w", err)
+	}
+	return int(res.RowsAffected()), nil
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"crypto-analytics/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var ErrEditConflict = errors.New("document was edited concurrently")
+
+// UpdatePost меняет заголовок и текст поста автора, сохраняя прежнюю версию в revisions
+func (p *PostMongoStorage) UpdatePost(
+	ctx context.Context,
+	postID bson.ObjectID,
+	authorID int64,
+	title string,
+	content string,
+) error {
+	var current models.Post
+	err := p.collPosts.FindOne(ctx, bson.M{"_id": postID, "authorId": authorID, "deletedAt": nil}).Decode(&current)
+	if err != nil {
+		return err
+	}
+
+	prev := models.Revision{
+		Target:    models.VoteTargetPost,
+		TargetID:  postID,
+		Version:   current.Revision,
+		Heading:   current.Heading,
+		MainText:  current.MainText,
+		CreatedAt: current.CreatedAt,
+	}
+	if current.UpdatedAt != nil {
+		prev.CreatedAt = *current.UpdatedAt
+	}
+	return p.replaceVersion(ctx, p.collPosts, prev, bson.M{
+		"heading":  title,
+		"mainText": content,
+	})
+}
+
+// UpdateComment меняет текст комментария автора, сохраняя прежнюю версию в revisions
+func (p *PostMongoStorage) UpdateComment(
+	ctx context.Context,
+	commentID bson.ObjectID,
+	authorID int64,
+	content string,
+) error {
+	var current models.Comment
+	err := p.collComm.FindOne(ctx, bson.M{"_id": commentID, "authorId": authorID, "deletedAt": nil}).Decode(&current)
+	if err != nil {
+		return err
+	}
+
+	prev := models.Revision{
+		Target:    models.VoteTargetComment,
+		TargetID:  commentID,
+		Version:   current.Revision,
+		MainText:  current.MainText,
+		CreatedAt: current.CreatedAt,
+	}
+	if current.UpdatedAt != nil {
+		prev.CreatedAt = *current.UpdatedAt
+	}
+	return p.replaceVersion(ctx, p.collComm, prev, bson.M{"mainText": content})
+}
+
+// replaceVersion сначала сохраняет текущую версию, затем заменяет её, только если
+// документ всё ещё на той же версии. Так история не теряет ни одной версии,
+// а из двух одновременных правок проходит одна.
+func (p *PostMongoStorage) replaceVersion(
+	ctx context.Context,
+	coll *mongo.Collection,
+	prev models.Revision,
+	set bson.M,
+) error {
+	if prev.Version == 0 {
+		prev.Version = 1 // документ старше счётчика версий
+	}
+	_, err := p.collRevisions.InsertOne(ctx, prev)
+	// Версию уже сохранила параллельная правка — её исход решит условие ниже
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+
+	filter := bson.M{"_id": prev.TargetID, "deletedAt": nil, "revision": prev.Version}
+	if prev.Version == 1 {
+		filter["revision"] = bson.M{"$in": bson.A{1, nil}}
+	}
+	set["updatedAt"] = time.Now()
+	set["edited"] = true
+	set["revision"] = prev.Version + 1
+
+	res, err := coll.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrEditConflict
+	}
+	return nil
+}
+
+// ListRevisions возвращает прежние версии объекта по возрастанию номера
+func (p *PostMongoStorage) ListRevisions(
+	ctx context.Context,
+	target models.VoteTarget,
+	targetID bson.ObjectID,
+) ([]models.Revision, error) {
+	cursor, err := p.collRevisions.Find(
+		ctx,
+		bson.M{"targetId": targetID, "target": target},
+		options.Find().SetSort(bson.D{{Key: "version", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var revisions []models.Revision
+	if err = cursor.All(ctx, &revisions); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
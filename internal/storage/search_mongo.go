@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"context"
+	"sort"
+
+	"crypto-analytics/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// SearchMongoStorage ищет по текстовым индексам коллекций постов и комментариев
+type SearchMongoStorage struct {
+	collPosts *mongo.Collection
+	collComm  *mongo.Collection
+}
+
+func NewSearchMongoStorage(client *mongo.Client) *SearchMongoStorage {
+	db := client.Database(DBName)
+	return &SearchMongoStorage{
+		collPosts: db.Collection(PostsCollName),
+		collComm:  db.Collection(CommentsCollName),
+	}
+}
+
+// EnsureIndexes создаёт текстовые индексы. Язык "none": посты пишут и на русском,
+// и на английском, а стемминг одного языка портит слова другого. Зато найденное
+// совпадает с тем, что подсвечивает сервис.
+func (s *SearchMongoStorage) EnsureIndexes(ctx context.Context) error {
+	_, err := s.collPosts.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "heading", Value: "text"}, {Key: "mainText", Value: "text"}},
+		Options: options.Index().
+			SetName("posts_text").
+			SetWeights(bson.D{{Key: "heading", Value: 3}, {Key: "mainText", Value: 1}}).
+			SetDefaultLanguage("none"),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.collComm.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "mainText", Value: "text"}},
+		Options: options.Index().
+			SetName("comments_text").
+			SetDefaultLanguage("none"),
+	})
+	return err
+}
+
+type postTextHit struct {
+	models.Post `bson:",inline"`
+	Score       float64 `bson:"score"`
+}
+
+type commentTextHit struct {
+	models.Comment `bson:",inline"`
+	Score          float64 `bson:"score"`
+}
+
+// Search ищет посты и комментарии отдельно и сливает их по релевантности.
+// Комментарии к удалённым или скрытым постам отбрасываются.
+func (s *SearchMongoStorage) Search(ctx context.Context, q models.SearchQuery) ([]models.SearchHit, error) {
+	var hits []models.SearchHit
+
+	if q.Kind != models.SearchComments {
+		var posts []postTextHit
+		if err := s.find(ctx, s.collPosts, q, &posts); err != nil {
+			return nil, err
+		}
+		for _, p := range posts {
+			hits = append(hits, models.SearchHit{
+				Kind:      models.SearchPosts,
+				ID:        p.ID,
+				PostID:    p.ID,
+				Heading:   p.Heading,
+				Text:      p.MainText,
+				AuthorID:  p.AuthorID,
+				Person:    p.Person,
+				CreatedAt: p.CreatedAt,
+				Score:     p.Score,
+			})
+		}
+	}
+
+	if q.Kind != models.SearchPosts {
+		var comments []commentTextHit
+		if err := s.find(ctx, s.collComm, q, &comments); err != nil {
+			return nil, err
+		}
+		headings, err := s.postHeadings(ctx, comments, q.ViewerID)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range comments {
+			heading, ok := headings[c.PostID]
+			if !ok {
+				continue
+			}
+			hits = append(hits, models.SearchHit{
+				Kind:      models.SearchComments,
+				ID:        c.ID,
+				PostID:    c.PostID,
+				Heading:   heading,
+				Text:      c.MainText,
+				AuthorID:  c.AuthorID,
+				Person:    c.Person,
+				CreatedAt: c.CreatedAt,
+				Score:     c.Score,
+			})
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if q.Limit > 0 && len(hits) > q.Limit {
+		hits = hits[:q.Limit]
+	}
+	return hits, nil
+}
+
+func (s *SearchMongoStorage) find(ctx context.Context, coll *mongo.Collection, q models.SearchQuery, out any) error {
+	filter := visibleTo(bson.M{"$text": bson.M{"$search": q.Text}, "deletedAt": nil}, q.ViewerID)
+	if q.AuthorID != 0 {
+		filter["authorId"] = q.AuthorID
+	}
+	if q.From != nil || q.To != nil {
+		created := bson.M{}
+		if q.From != nil {
+			created["$gte"] = *q.From
+		}
+		if q.To != nil {
+			created["$lt"] = *q.To
+		}
+		filter["createdAt"] = created
+	}
+
+	score := bson.M{"$meta": "textScore"}
+	cursor, err := coll.Find(ctx, filter, options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}}).
+		SetLimit(int64(q.Limit)))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	return cursor.All(ctx, out)
+}
+
+// postHeadings — заголовки видимых постов, к которым относятся комментарии
+func (s *SearchMongoStorage) postHeadings(
+	ctx context.Context,
+	comments []commentTextHit,
+	viewerID int64,
+) (map[bson.ObjectID]string, error) {
+	headings := map[bson.ObjectID]string{}
+	if len(comments) == 0 {
+		return headings, nil
+	}
+	ids := make([]bson.ObjectID, 0, len(comments))
+	for _, c := range comments {
+		ids = append(ids, c.PostID)
+	}
+
+	cursor, err := s.collPosts.Find(
+		ctx,
+		visibleTo(bson.M{"_id": bson.M{"$in": ids}, "deletedAt": nil}, viewerID),
+		options.Find().SetProjection(bson.M{"heading": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var posts []models.Post
+	if err := cursor.All(ctx, &posts); err != nil {
+		return nil, err
+	}
+	for _, p := range posts {
+		headings[p.ID] = p.Heading
+	}
+	return headings, nil
+}
@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"crypto-analytics/internal/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	sessionKeyPrefix     = "session:"
+	userSessionKeyPrefix = "user_sessions:"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+type SessionRedisStorage struct {
+	rdb *redis.Client
+}
+
+func NewSessionRedisStorage(client *redis.Client) *SessionRedisStorage {
+	return &SessionRedisStorage{rdb: client}
+}
+
+func sessionKey(id string) string {
+	return sessionKeyPrefix + id
+}
+
+func userSessionsKey(userID int64) string {
+	return userSessionKeyPrefix + strconv.FormatInt(userID, 10)
+}
+
+// SaveSession сохраняет запись и, если сессия принадлежит пользователю, индексирует её по userID.
+// ttl == 0 сохраняет текущий срок жизни записи.
+func (s *SessionRedisStorage) SaveSession(session *models.Session, ttl time.Duration) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	expiration := ttl
+	if ttl == 0 {
+		expiration = redis.KeepTTL
+	}
+
+	pipe := s.rdb.TxPipeline()
+	pipe.Set(ctx, sessionKey(session.ID), data, expiration)
+	if session.UserID > 0 {
+		key := userSessionsKey(session.UserID)
+		pipe.SAdd(ctx, key, session.ID)
+		if ttl > 0 {
+			// Индекс живёт столько же, сколько самая свежая сессия пользователя
+			pipe.ExpireGT(ctx, key, ttl)
+			pipe.ExpireNX(ctx, key, ttl)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to save session to redis: %w", err)
+	}
+	return nil
+}
+
+func (s *SessionRedisStorage) GetSession(id string) (*models.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	data, err := s.rdb.Get(ctx, sessionKey(id)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get session from redis: %w", err)
+	}
+
+	var session models.Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal session: %w", err)
+	}
+	return &session, nil
+}
+
+func (s *SessionRedisStorage) DeleteSession(id string) error {
+	session, err := s.GetSession(id)
+	if err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	pipe := s.rdb.TxPipeline()
+	pipe.Del(ctx, sessionKey(id))
+	if session.UserID > 0 {
+		pipe.SRem(ctx, userSessionsKey(session.UserID), id)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// ListUserSessions возвращает живые сессии пользователя; протухшие ID заодно вычищаются из индекса
+func (s *SessionRedisStorage) ListUserSessions(userID int64) ([]models.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	ids, err := s.rdb.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	if len(ids) == 0 {
+		return []models.Session{}, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = sessionKey(id)
+	}
+	values, err := s.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sessions: %w", err)
+	}
+
+	sessions := make([]models.Session, 0, len(values))
+	var stale []interface{}
+	for i, v := range values {
+		raw, ok := v.(string)
+		if !ok {
+			stale = append(stale, ids[i])
+			continue
+		}
+		var session models.Session
+		if err := json.Unmarshal([]byte(raw), &session); err != nil || session.UserID != userID {
+			stale = append(stale, ids[i])
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+
+	if len(stale) > 0 {
+		s.rdb.SRem(ctx, userSessionsKey(userID), stale...)
+	}
+	return sessions, nil
+}
+
+// DeleteUserSessions удаляет все сессии пользователя, кроме exceptID (пустая строка — удалить все)
+func (s *SessionRedisStorage) DeleteUserSessions(userID int64, exceptID string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	ids, err := s.rdb.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	pipe := s.rdb.TxPipeline()
+	removed := 0
+	for _, id := range ids {
+		if id == exceptID {
+			continue
+		}
+		pipe.Del(ctx, sessionKey(id))
+		pipe.SRem(ctx, userSessionsKey(userID), id)
+		removed++
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	return removed, nil
+}
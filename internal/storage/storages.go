@@ -3,6 +3,8 @@ package storage
 import (
 	"context"
 	"crypto-analytics/internal/models"
+	"io"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -12,23 +14,144 @@ import (
 type FormStorage interface {
 	SaveContactFrom(contact *models.ContactForm) error
 	ExportContactsToJSON(filename string) error
+	ExportContacts(w io.Writer) (int, error)
+	GetContactsStats() (map[string]interface{}, error)
 	Close()
 }
 
+// TicketStorage — обращения из формы обратной связи как заявки: статус, исполнитель, заметки.
+// Хранится в той же таблице contacts, что и FormStorage.
+type TicketStorage interface {
+	ListTickets(q models.TicketQuery) ([]models.Ticket, error)
+	// GetTicket возвращает ErrTicketNotFound, если обращения нет
+	GetTicket(id int64) (*models.Ticket, error)
+	SetTicketStatus(id int64, status models.TicketStatus) error
+	AssignTicket(id int64, assigneeID *int64) error
+	AddTicketNote(note *models.TicketNote) error
+}
+
 type UserStorage interface {
 	CreateUser(user *models.User) error
+	GetUserByID(id int64) (*models.User, error)
 	GetUserByName(nameU string) (*models.User, error)
-	GetAllFavoriteCoins(nameU string) ([]string, error)
-	NewFavoriteCoin(nameU string, nameCoin string) error
-	RemoveFavoriteCoin(nameU string, nameCoin string) error
+	GetUserByEmail(email string) (*models.User, error)
+	ListUsersByRole(role models.Role) ([]models.User, error)
+	UpdateProfile(userID int64, displayName, email string) error
+	UpdatePassword(userID int64, passwordHash string) error
+	MarkEmailVerified(userID int64) error
+	SetRole(userID int64, role models.Role) error
+	SetBan(userID int64, until *time.Time, shadow bool) error
+	GetAllFavoriteCoins(userID int64) ([]string, error)
+	NewFavoriteCoin(userID int64, nameCoin string) error
+	RemoveFavoriteCoin(userID int64, nameCoin string) error
 	ExportUsersToJSON(filename string) error
+	ExportUsers(w io.Writer) (int, error)
 	Close()
 }
 
+type TokenStorage interface {
+	CreateToken(token *models.UserToken) error
+	ConsumeToken(tokenHash string, purpose models.TokenPurpose) (*models.UserToken, error)
+	InvalidateTokens(userID int64, purpose models.TokenPurpose) error
+}
+
+type TwoFactorStorage interface {
+	GetTOTPSecret(userID int64) (string, error)
+	SetPendingTOTPSecret(userID int64, secret string) error
+	EnableTOTP(userID int64, counter int64, recoveryHashes []string) error
+	DisableTOTP(userID int64) error
+	ConsumeTOTPCounter(userID int64, counter int64) error
+	ReplaceRecoveryCodes(userID int64, hashes []string) error
+	ConsumeRecoveryCode(userID int64, hash string) error
+	CountRecoveryCodes(userID int64) (int, error)
+}
+
+type APITokenStorage interface {
+	CreateAPIToken(token *models.APIToken) error
+	GetAPITokenByHash(tokenHash string) (*models.APIToken, error)
+	ListAPITokens(userID int64) ([]models.APIToken, error)
+	RevokeAPIToken(userID, tokenID int64) error
+	TouchAPIToken(tokenID int64) error
+}
+
+type SessionStorage interface {
+	SaveSession(session *models.Session, ttl time.Duration) error
+	GetSession(id string) (*models.Session, error)
+	DeleteSession(id string) error
+	ListUserSessions(userID int64) ([]models.Session, error)
+	DeleteUserSessions(userID int64, exceptID string) (int, error)
+}
+
+type RateLimitStorage interface {
+	Hit(key string, window time.Duration) (int64, time.Duration, error)
+	SetLock(key string, ttl time.Duration) error
+	LockTTL(key string) (time.Duration, error)
+	Reset(keys ...string) error
+}
+
+type AuthAuditStorage interface {
+	LogAuthEvent(event *models.AuthEvent) error
+}
+
+type ModerationLogStorage interface {
+	LogModeration(entry *models.ModerationEntry) error
+	ListModeration(limit int) ([]models.ModerationEntry, error)
+}
+
+type ReportStorage interface {
+	// CreateReport сохраняет жалобу; повторная открытая жалоба того же пользователя игнорируется
+	CreateReport(report *models.Report) error
+	ListOpenReports(limit int) ([]models.ReportQueueItem, error)
+	// CloseReports закрывает открытые жалобы на объект и возвращает их число
+	CloseReports(target models.ModerationTarget, targetID string, status models.ReportStatus, actorID int64) (int, error)
+}
+
+// NotificationStorage — каналы уведомлений пользователей и очередь доставки (outbox)
+type NotificationStorage interface {
+	ListChannels(userID int64) ([]models.ChannelPreference, error)
+	SaveChannel(pref *models.ChannelPreference) error
+	// DeleteChannel возвращает ErrChannelNotFound, если канала нет
+	DeleteChannel(userID int64, channel models.NotificationChannel) error
+	Enqueue(items []models.OutboxItem) error
+	// ClaimDue забирает доставки, срок которых наступил, и откладывает их на lease
+	ClaimDue(limit int, lease time.Duration) ([]models.OutboxItem, error)
+	MarkSent(id int64) error
+	RetryLater(id int64, lastErr string, delay time.Duration) error
+	MarkFailed(id int64, lastErr string) error
+}
+
+// WebhookStorage — исходящие вебхуки пользователей, очередь и журнал их доставок
+type WebhookStorage interface {
+	CreateEndpoint(ep *models.WebhookEndpoint) error
+	ListEndpoints(userID int64) ([]models.WebhookEndpoint, error)
+	ListSubscribers(event models.WebhookEvent) ([]models.WebhookEndpoint, error)
+	// GetEndpoint, UpdateEndpoint, SetEndpointSecret и DeleteEndpoint работают только
+	// с вебхуками userID и возвращают ErrWebhookNotFound для чужих
+	GetEndpoint(userID, id int64) (*models.WebhookEndpoint, error)
+	UpdateEndpoint(ep *models.WebhookEndpoint) error
+	SetEndpointSecret(userID, id int64, secret string) error
+	DeleteEndpoint(userID, id int64) error
+	EnqueueDeliveries(deliveries []models.WebhookDelivery) error
+	RecordDelivery(d *models.WebhookDelivery) error
+	ClaimDueDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error)
+	CompleteDelivery(id int64, attempt models.WebhookAttempt) error
+	RetryDelivery(id int64, attempt models.WebhookAttempt, delay time.Duration) error
+	FailDelivery(id int64, attempt models.WebhookAttempt) error
+	ListDeliveries(endpointID int64, limit int) ([]models.WebhookDelivery, error)
+	PurgeDeliveries(olderThan time.Duration) (int, error)
+}
+
 type NewsStorage interface {
 	AddNews([]models.NewsItem) error
 	GetAllNews() ([]models.NewsItem, error)
-	UpdateNews([]models.NewsItem) error
+	// UpdateNews добавляет новости, которых ещё нет, и возвращает добавленные
+	UpdateNews([]models.NewsItem) ([]models.NewsItem, error)
+	ReplaceNews([]models.NewsItem) error
+}
+
+type FeedStateStorage interface {
+	GetFeedStates() (map[string]models.FeedState, error)
+	SaveFeedState(state models.FeedState) error
 }
 
 type CacheStorage interface {
@@ -46,29 +169,77 @@ type PostStorage interface {
 	CreateComment(
 		ctx context.Context,
 		comment models.Comment,
-	) error
-	GetLastPosts(ctx context.Context) ([]models.Post, error)
-	GetLastCommentsByPost(ctx context.Context,
-		postID bson.ObjectID,
-	) ([]models.Comment, error)
-	DeletePost(ctx context.Context, postID bson.ObjectID, author string) error
-	DeleteComment(ctx context.Context, commentID bson.ObjectID, author string) error
+	) (bson.ObjectID, error)
+	// GetPost и GetComment скрывают записи в теневом бане от всех, кроме автора (viewerID 0 — аноним)
+	GetPost(ctx context.Context, postID bson.ObjectID, viewerID int64) (*models.Post, error)
+	GetComment(ctx context.Context, commentID bson.ObjectID, viewerID int64) (*models.Comment, error)
+	ListPosts(ctx context.Context, q models.PostQuery) ([]models.Post, error)
+	ListComments(ctx context.Context, q models.CommentQuery) ([]models.Comment, error)
+	DeletePost(ctx context.Context, postID bson.ObjectID, authorID int64) error
+	DeleteComment(ctx context.Context, commentID bson.ObjectID, authorID int64) error
+	SetPostDeleted(ctx context.Context, postID bson.ObjectID, actorID int64, deleted bool) error
+	SetCommentDeleted(ctx context.Context, commentID bson.ObjectID, actorID int64, deleted bool) error
 	UpdatePost(
 		ctx context.Context,
 		postID bson.ObjectID,
-		author string,
+		authorID int64,
 		title string,
 		content string,
 	) error
 	UpdateComment(
 		ctx context.Context,
 		commentID bson.ObjectID,
-		author string,
+		authorID int64,
 		content string,
 	) error
+	BackfillAuthorIDs(ctx context.Context, resolve func(person string) (int64, bool)) (int, error)
+	TargetAuthor(ctx context.Context, target models.VoteTarget, id bson.ObjectID, viewerID int64) (int64, error)
+	Vote(
+		ctx context.Context,
+		target models.VoteTarget,
+		targetID bson.ObjectID,
+		authorID int64,
+		userID int64,
+		value int,
+	) (int, error)
+	React(
+		ctx context.Context,
+		target models.VoteTarget,
+		targetID bson.ObjectID,
+		userID int64,
+		emoji string,
+		on bool,
+	) (map[string]int, error)
+	Reputation(ctx context.Context, userID int64) (int, error)
+	ListRevisions(ctx context.Context, target models.VoteTarget, targetID bson.ObjectID) ([]models.Revision, error)
 	Close()
 }
 
+// SearchEngine — полнотекстовый поиск по постам и комментариям. Удалённое и скрытое
+// не находится; результат отсортирован по убыванию Score.
+type SearchEngine interface {
+	Search(ctx context.Context, q models.SearchQuery) ([]models.SearchHit, error)
+}
+
+type AttachmentStorage interface {
+	CreateAttachment(ctx context.Context, a models.Attachment) error
+	GetAttachment(ctx context.Context, id bson.ObjectID) (*models.Attachment, error)
+	// ClaimAttachments привязывает к посту свободные вложения владельца и возвращает их число
+	ClaimAttachments(ctx context.Context, ids []bson.ObjectID, ownerID int64, postID bson.ObjectID) (int, error)
+	ReleaseAttachments(ctx context.Context, postID bson.ObjectID) error
+	ListOrphanAttachments(ctx context.Context, before time.Time, limit int) ([]models.Attachment, error)
+	DeleteAttachment(ctx context.Context, id bson.ObjectID) error
+}
+
+// BlobStore хранит файлы по ключу вида "attachments/<id>/original.png"
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get возвращает ErrBlobNotFound, если ключа нет
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete отсутствующего ключа не считается ошибкой
+	Delete(ctx context.Context, key string) error
+}
+
 type AnalysisTempStorage interface {
 	SaveAnalysisData(data models.AnalysisData) error
 	SavePairs(pairs models.PairsCrypto) error
@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"crypto-analytics/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrTicketNotFound = errors.New("ticket not found")
+
+const ticketColumns = `id, name, email, message, ip, user_agent, status, assignee_id, created_at, updated_at, resolved_at`
+
+func scanTicket(row pgx.Row, t *models.Ticket) error {
+	return row.Scan(&t.ID, &t.Name, &t.Email, &t.Message, &t.IP, &t.UserAgent, &t.Status, &t.AssigneeID,
+		&t.CreatedAt, &t.UpdatedAt, &t.ResolvedAt)
+}
+
+// ListTickets — обращения по фильтрам, новые сверху
+func (s *ContStorage) ListTickets(q models.TicketQuery) ([]models.Ticket, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var where []string
+	var args []any
+	if q.Status != "" {
+		args = append(args, string(q.Status))
+		where = append(where, fmt.Sprintf("status = $%d", len(args)))
+	}
+	switch {
+	case q.AssigneeID < 0:
+		where = append(where, "assignee_id IS NULL")
+	case q.AssigneeID > 0:
+		args = append(args, q.AssigneeID)
+		where = append(where, fmt.Sprintf("assignee_id = $%d", len(args)))
+	}
+	if q.Email != "" {
+		args = append(args, q.Email)
+		where = append(where, fmt.Sprintf("LOWER(email) = LOWER($%d)", len(args)))
+	}
+
+	query := `SELECT ` + ticketColumns + ` FROM contacts`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	args = append(args, q.Limit, q.Offset)
+	query += fmt.Sprintf(` ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tickets: %w", err)
+	}
+	defer rows.Close()
+
+	tickets := []models.Ticket{}
+	for rows.Next() {
+		var t models.Ticket
+		if err := scanTicket(rows, &t); err != nil {
+			return nil, fmt.Errorf("failed to scan ticket: %w", err)
+		}
+		tickets = append(tickets, t)
+	}
+	return tickets, rows.Err()
+}
+
+// GetTicket возвращает обращение вместе с заметками и ответами
+func (s *ContStorage) GetTicket(id int64) (*models.Ticket, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var t models.Ticket
+	err := scanTicket(s.pool.QueryRow(ctx, `SELECT `+ticketColumns+` FROM contacts WHERE id = $1`, id), &t)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTicketNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ticket: %w", err)
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, contact_id, COALESCE(author_id, 0), kind, body, created_at
+		FROM contact_notes
+		WHERE contact_id = $1
+		ORDER BY id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ticket notes: %w", err)
+	}
+	defer rows.Close()
+
+	t.Notes = []models.TicketNote{}
+	for rows.Next() {
+		var n models.TicketNote
+		if err := rows.Scan(&n.ID, &n.TicketID, &n.AuthorID, &n.Kind, &n.Body, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan ticket note: %w", err)
+		}
+		t.Notes = append(t.Notes, n)
+	}
+	return &t, rows.Err()
+}
+
+// SetTicketStatus меняет статус; resolved_at заполняется только у решённых
+func (s *ContStorage) SetTicketStatus(id int64, status models.TicketStatus) error {
+	return s.updateTicket(`
+		UPDATE contacts
+		SET status = $2,
+			resolved_at = CASE WHEN $2 = 'resolved' THEN CURRENT_TIMESTAMP END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`, id, string(status))
+}
+
+// AssignTicket назначает исполнителя; nil снимает назначение
+func (s *ContStorage) AssignTicket(id int64, assigneeID *int64) error {
+	return s.updateTicket(`
+		UPDATE contacts
+		SET assignee_id = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`, id, assigneeID)
+}
+
+func (s *ContStorage) updateTicket(query string, id int64, value any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := s.pool.Exec(ctx, query, id, value)
+	if err != nil {
+		return fmt.Errorf("failed to update ticket: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return ErrTicketNotFound
+	}
+	return nil
+}
+
+func (s *ContStorage) AddTicketNote(note *models.TicketNote) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	res, err := tx.Exec(ctx, `UPDATE contacts SET updated_at = CURRENT_TIMESTAMP WHERE id = $1`, note.TicketID)
+	if err != nil {
+		return fmt.Errorf("failed to touch ticket: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return ErrTicketNotFound
+	}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO contact_notes (contact_id, author_id, kind, body)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`,
+		note.TicketID, note.AuthorID, string(note.Kind), note.Body,
+	).Scan(&note.ID, &note.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add ticket note: %w", err)
+	}
+	return tx.Commit(ctx)
+}
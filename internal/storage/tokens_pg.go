@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"crypto-analytics/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrTokenInvalid = errors.New("token is invalid, expired or already used")
+
+type TokenPostgresStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewTokenPostgresStorage(pool *pgxpool.Pool) *TokenPostgresStorage {
+	return &TokenPostgresStorage{pool: pool}
+}
+
+func (s *TokenPostgresStorage) CreateToken(token *models.UserToken) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`,
+		token.UserID, string(token.Purpose), token.TokenHash, token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create token: %w", err)
+	}
+	return nil
+}
+
+// ConsumeToken атомарно помечает токен использованным. Повторное использование,
+// истёкший срок или чужое назначение дают ErrTokenInvalid.
+func (s *TokenPostgresStorage) ConsumeToken(tokenHash string, purpose models.TokenPurpose) (*models.UserToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	token := &models.UserToken{}
+	var p string
+	err := s.pool.QueryRow(ctx, `
+		UPDATE user_tokens
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE token_hash = $1
+			AND purpose = $2
+			AND used_at IS NULL
+			AND expires_at > CURRENT_TIMESTAMP
+		RETURNING id, user_id, purpose, token_hash, expires_at, used_at, created_at`,
+		tokenHash, string(purpose),
+	).Scan(&token.ID, &token.UserID, &p, &token.TokenHash, &token.ExpiresAt, &token.UsedAt, &token.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTokenInvalid
+		}
+		return nil, fmt.Errorf("failed to consume token: %w", err)
+	}
+	token.Purpose = models.TokenPurpose(p)
+	return token, nil
+}
+
+// InvalidateTokens гасит все ещё не использованные токены пользователя с этим назначением
+func (s *TokenPostgresStorage) InvalidateTokens(userID int64, purpose models.TokenPurpose) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := s.pool.Exec(ctx, `
+		UPDATE user_tokens
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`,
+		userID, string(purpose))
+	if err != nil {
+		return fmt.Errorf("failed to invalidate tokens: %w", err)
+	}
+	return nil
+}
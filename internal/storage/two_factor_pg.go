@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrTOTPNotConfigured   = errors.New("totp secret is not configured")
+	ErrTOTPCounterReused   = errors.New("totp code already used")
+	ErrRecoveryCodeInvalid = errors.New("recovery code is invalid or already used")
+)
+
+type TwoFactorPostgresStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewTwoFactorPostgresStorage(pool *pgxpool.Pool) *TwoFactorPostgresStorage {
+	return &TwoFactorPostgresStorage{pool: pool}
+}
+
+// GetTOTPSecret возвращает зашифрованный секрет (в том числе ещё не подтверждённый)
+func (s *TwoFactorPostgresStorage) GetTOTPSecret(userID int64) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var secret *string
+	err := s.pool.QueryRow(ctx, `SELECT totp_secret FROM users WHERE id = $1`, userID).Scan(&secret)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrUserNotFound
+		}
+		return "", fmt.Errorf("failed to get totp secret: %w", err)
+	}
+	if secret == nil || *secret == "" {
+		return "", ErrTOTPNotConfigured
+	}
+	return *secret, nil
+}
+
+// SetPendingTOTPSecret сохраняет секрет до подтверждения первым кодом; 2FA при этом ещё выключена
+func (s *TwoFactorPostgresStorage) SetPendingTOTPSecret(userID int64, secret string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := s.pool.Exec(ctx, `
+		UPDATE users
+		SET totp_secret = $1, totp_enabled_at = NULL, totp_last_counter = 0
+		WHERE id = $2 AND totp_enabled_at IS NULL`, secret, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set totp secret: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// EnableTOTP включает 2FA и одной транзакцией заменяет резервные коды
+func (s *TwoFactorPostgresStorage) EnableTOTP(userID int64, counter int64, recoveryHashes []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	res, err := tx.Exec(ctx, `
+		UPDATE users
+		SET totp_enabled_at = CURRENT_TIMESTAMP, totp_last_counter = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND totp_secret IS NOT NULL`, counter, userID)
+	if err != nil {
+		return fmt.Errorf("failed to enable totp: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return ErrTOTPNotConfigured
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *TwoFactorPostgresStorage) DisableTOTP(userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE users
+		SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_counter = 0, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to disable totp: %w", err)
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userID, nil); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ConsumeTOTPCounter принимает шаг времени, только если он новее последнего использованного,
+// так перехваченный код нельзя повторить в пределах его окна
+func (s *TwoFactorPostgresStorage) ConsumeTOTPCounter(userID int64, counter int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := s.pool.Exec(ctx, `
+		UPDATE users SET totp_last_counter = $1
+		WHERE id = $2 AND totp_last_counter < $1`, counter, userID)
+	if err != nil {
+		return fmt.Errorf("failed to consume totp counter: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return ErrTOTPCounterReused
+	}
+	return nil
+}
+
+func (s *TwoFactorPostgresStorage) ReplaceRecoveryCodes(userID int64, hashes []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, hashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *TwoFactorPostgresStorage) ConsumeRecoveryCode(userID int64, hash string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := s.pool.Exec(ctx, `
+		UPDATE recovery_codes SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`, userID, hash)
+	if err != nil {
+		return fmt.Errorf("failed to consume recovery code: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return ErrRecoveryCodeInvalid
+	}
+	return nil
+}
+
+func (s *TwoFactorPostgresStorage) CountRecoveryCodes(userID int64) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var count int
+	err := s.pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return count, nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID int64, hashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	for _, hash := range hashes {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash)
+		if err != nil {
+			return fmt.Errorf("failed to insert recovery code: %w", err)
+		}
+	}
+	return nil
+}
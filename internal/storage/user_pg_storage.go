@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"strings"
@@ -29,23 +30,30 @@ func (s *UserPostgresStorage) Close() {
 	s.pool.Close()
 }
 
+var (
+	ErrUserNotFound  = errors.New("user not found")
+	ErrEmailTaken    = errors.New("user already exists")
+	ErrUsernameTaken = errors.New("user name already exists")
+)
+
+const userColumns = `id, email, password, username, display_name, favorite_coins, email_verified_at, role, totp_enabled_at, created_at, banned_until, shadow_banned`
+
 func (s *UserPostgresStorage) CreateUser(user *models.User) error {
 
 	query := `
-		INSERT INTO users (email, password, username, favorite_coins) 
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO users (email, password, username, display_name, favorite_coins, signup_ip, signup_user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, role, created_at
 	`
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	_, err := s.pool.Exec(ctx, query, user.Email, user.Password, user.Username, user.FavoriteCoins)
+	err := s.pool.QueryRow(ctx, query,
+		user.Email, user.Password, user.Username, user.DisplayName, user.FavoriteCoins,
+		user.SignupIP, user.SignupUserAgent,
+	).Scan(&user.ID, &user.Role, &user.CreatedAt)
 	if err != nil {
-		if strings.Contains(err.Error(), "unique constraint") {
-			if strings.Contains(err.Error(), "users_email_key") {
-				return fmt.Errorf("user already exists")
-			}
-			if strings.Contains(err.Error(), "users_username_key") {
-				return fmt.Errorf("user name already exists")
-			}
+		if uniqueErr := uniqueUserError(err); uniqueErr != nil {
+			return uniqueErr
 		}
 		return fmt.Errorf("failed to create user: %w", err)
 	}
@@ -53,49 +61,203 @@ func (s *UserPostgresStorage) CreateUser(user *models.User) error {
 	return nil
 }
 
-func (s *UserPostgresStorage) GetUserByName(nameU string) (*models.User, error) {
-	query := `
-		SELECT email, password, username, favorite_coins 
-		FROM users 
-		WHERE username = $1
-	`
+func uniqueUserError(err error) error {
+	if !strings.Contains(err.Error(), "unique constraint") {
+		return nil
+	}
+	if strings.Contains(err.Error(), "users_email_key") || strings.Contains(err.Error(), "users_email_lower_key") {
+		return ErrEmailTaken
+	}
+	if strings.Contains(err.Error(), "users_username_key") {
+		return ErrUsernameTaken
+	}
+	return nil
+}
 
+func scanUser(row pgx.Row) (*models.User, error) {
 	user := &models.User{}
 	var favoriteCoins []string
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	err := s.pool.QueryRow(ctx, query, nameU).Scan(
+	var createdAt *time.Time
+	err := row.Scan(
+		&user.ID,
 		&user.Email,
 		&user.Password,
 		&user.Username,
+		&user.DisplayName,
 		&favoriteCoins,
+		&user.EmailVerifiedAt,
+		&user.Role,
+		&user.TOTPEnabledAt,
+		&createdAt,
+		&user.BannedUntil,
+		&user.ShadowBanned,
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	user.FavoriteCoins = favoriteCoins
+	if createdAt != nil {
+		user.CreatedAt = *createdAt
+	}
+	if user.DisplayName == "" {
+		user.DisplayName = user.Username
+	}
+	return user, nil
+}
+
+func (s *UserPostgresStorage) getUser(where string, arg interface{}) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE ` + where
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	user, err := scanUser(s.pool.QueryRow(ctx, query, arg))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
-
-	user.FavoriteCoins = favoriteCoins
 	return user, nil
 }
 
-func (s *UserPostgresStorage) GetAllFavoriteCoins(nameU string) ([]string, error) {
+func (s *UserPostgresStorage) GetUserByID(id int64) (*models.User, error) {
+	return s.getUser("id = $1", id)
+}
+
+func (s *UserPostgresStorage) GetUserByName(nameU string) (*models.User, error) {
+	return s.getUser("username = $1", nameU)
+}
+
+func (s *UserPostgresStorage) GetUserByEmail(email string) (*models.User, error) {
+	return s.getUser("LOWER(email) = LOWER($1)", email)
+}
+
+// ListUsersByRole — пользователи с ровно этой ролью, по возрастанию ID
+func (s *UserPostgresStorage) ListUsersByRole(role models.Role) ([]models.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := s.pool.Query(ctx, `SELECT `+userColumns+` FROM users WHERE role = $1 ORDER BY id`, string(role))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users by role: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+func (s *UserPostgresStorage) UpdateProfile(userID int64, displayName, email string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// Смена адреса сбрасывает подтверждение email
+	res, err := s.pool.Exec(ctx, `
+		UPDATE users
+		SET display_name = $1,
+			email = $2,
+			email_verified_at = CASE WHEN LOWER(email) = LOWER($2) THEN email_verified_at ELSE NULL END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3`, displayName, email, userID)
+	if err != nil {
+		if uniqueErr := uniqueUserError(err); uniqueErr != nil {
+			return uniqueErr
+		}
+		return fmt.Errorf("failed to update profile: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (s *UserPostgresStorage) UpdatePassword(userID int64, passwordHash string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	res, err := s.pool.Exec(ctx, `
+		UPDATE users
+		SET password = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2`, passwordHash, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (s *UserPostgresStorage) MarkEmailVerified(userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	res, err := s.pool.Exec(ctx, `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP)
+		WHERE id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark email verified: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (s *UserPostgresStorage) SetRole(userID int64, role models.Role) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	res, err := s.pool.Exec(ctx, `
+		UPDATE users
+		SET role = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2`, string(role), userID)
+	if err != nil {
+		return fmt.Errorf("failed to set role: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// SetBan задаёт ограничения пользователя: until — конец временного бана (nil — снять),
+// shadow — теневой бан
+func (s *UserPostgresStorage) SetBan(userID int64, until *time.Time, shadow bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	res, err := s.pool.Exec(ctx, `
+		UPDATE users
+		SET banned_until = $1, shadow_banned = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3`, until, shadow, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set ban: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (s *UserPostgresStorage) GetAllFavoriteCoins(userID int64) ([]string, error) {
 
 	query := `
 		SELECT favorite_coins 
 		FROM users 
-		WHERE username = $1
+		WHERE id = $1
 	`
 
 	var favoriteCoins []string
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	err := s.pool.QueryRow(ctx, query, nameU).Scan(&favoriteCoins)
+	err := s.pool.QueryRow(ctx, query, userID).Scan(&favoriteCoins)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get favorite coins: %w", err)
 	}
@@ -103,7 +265,7 @@ func (s *UserPostgresStorage) GetAllFavoriteCoins(nameU string) ([]string, error
 	return favoriteCoins, nil
 }
 
-func (s *UserPostgresStorage) NewFavoriteCoin(nameU string, nameCoin string) error {
+func (s *UserPostgresStorage) NewFavoriteCoin(userID int64, nameCoin string) error {
 
 	tx, err := s.pool.Begin(context.Background())
 	if err != nil {
@@ -112,12 +274,12 @@ func (s *UserPostgresStorage) NewFavoriteCoin(nameU string, nameCoin string) err
 	defer tx.Rollback(context.Background())
 
 	var exists bool
-	err = tx.QueryRow(context.Background(), "SELECT EXISTS(SELECT 1 FROM users WHERE username = $1)", nameU).Scan(&exists)
+	err = tx.QueryRow(context.Background(), "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists)
 	if err != nil {
 		return fmt.Errorf("failed to check user existence: %w", err)
 	}
 	if !exists {
-		return fmt.Errorf("user not found")
+		return ErrUserNotFound
 	}
 
 	var coinExists bool
@@ -127,8 +289,8 @@ func (s *UserPostgresStorage) NewFavoriteCoin(nameU string, nameCoin string) err
 	err = tx.QueryRow(ctx, `
 		SELECT EXISTS(
 			SELECT 1 FROM users 
-			WHERE username = $1 AND $2 = ANY(favorite_coins)
-		)`, nameU, nameCoin).Scan(&coinExists)
+			WHERE id = $1 AND $2 = ANY(favorite_coins)
+		)`, userID, nameCoin).Scan(&coinExists)
 	if err != nil {
 		return fmt.Errorf("failed to check coin existence: %w", err)
 	}
@@ -140,7 +302,7 @@ func (s *UserPostgresStorage) NewFavoriteCoin(nameU string, nameCoin string) err
 	_, err = tx.Exec(ctx, `
 		UPDATE users 
 		SET favorite_coins = array_append(favorite_coins, $1) 
-		WHERE username = $2`, nameCoin, nameU)
+		WHERE id = $2`, nameCoin, userID)
 	if err != nil {
 		return fmt.Errorf("failed to add favorite coin: %w", err)
 	}
@@ -152,7 +314,7 @@ func (s *UserPostgresStorage) NewFavoriteCoin(nameU string, nameCoin string) err
 	return nil
 }
 
-func (s *UserPostgresStorage) RemoveFavoriteCoin(nameU string, nameCoin string) error {
+func (s *UserPostgresStorage) RemoveFavoriteCoin(userID int64, nameCoin string) error {
 
 	tx, err := s.pool.Begin(context.Background())
 	if err != nil {
@@ -163,20 +325,20 @@ func (s *UserPostgresStorage) RemoveFavoriteCoin(nameU string, nameCoin string)
 	var exists bool
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	err = tx.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE username = $1)", nameU).Scan(&exists)
+	err = tx.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists)
 	if err != nil {
 		return fmt.Errorf("failed to check user existence: %w", err)
 	}
 	if !exists {
-		return fmt.Errorf("user not found")
+		return ErrUserNotFound
 	}
 
 	var coinExists bool
 	err = tx.QueryRow(context.Background(), `
 		SELECT EXISTS(
 			SELECT 1 FROM users 
-			WHERE username = $1 AND $2 = ANY(favorite_coins)
-		)`, nameU, nameCoin).Scan(&coinExists)
+			WHERE id = $1 AND $2 = ANY(favorite_coins)
+		)`, userID, nameCoin).Scan(&coinExists)
 	if err != nil {
 		return fmt.Errorf("failed to check coin existence: %w", err)
 	}
@@ -187,7 +349,7 @@ func (s *UserPostgresStorage) RemoveFavoriteCoin(nameU string, nameCoin string)
 	_, err = tx.Exec(context.Background(), `
 		UPDATE users 
 		SET favorite_coins = array_remove(favorite_coins, $1) 
-		WHERE username = $2`, nameCoin, nameU)
+		WHERE id = $2`, nameCoin, userID)
 	if err != nil {
 		return fmt.Errorf("failed to remove favorite coin: %w", err)
 	}
@@ -202,57 +364,68 @@ func (s *UserPostgresStorage) RemoveFavoriteCoin(nameU string, nameCoin string)
 type PublicUser struct {
 	ID            int       `json:"id"`
 	Username      string    `json:"username"`
+	Role          string    `json:"role"`
 	FavoriteCoins []string  `json:"favorite_coins"`
 	CreatedAt     time.Time `json:"created_at"`
 }
 
 func (s *UserPostgresStorage) ExportUsersToJSON(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	count, err := s.ExportUsers(file)
+	if err != nil {
+		return err
+	}
+	slog.Info("Successfully exported users to",
+		"amount", count,
+		"filename", filename)
+	return nil
+}
+
+// ExportUsers пишет публичные данные всех пользователей в w в виде JSON-массива
+func (s *UserPostgresStorage) ExportUsers(w io.Writer) (int, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	rows, err := s.pool.Query(ctx, `
-        SELECT id, username, favorite_coins, created_at 
+        SELECT id, username, role, favorite_coins, created_at 
         FROM users 
         ORDER BY id
     `)
 	if err != nil {
-		return fmt.Errorf("failed to query users: %w", err)
+		return 0, fmt.Errorf("failed to query users: %w", err)
 	}
 	defer rows.Close()
 
-	var users []PublicUser
+	users := []PublicUser{}
 
 	for rows.Next() {
 		var user PublicUser
 		err := rows.Scan(
 			&user.ID,
 			&user.Username,
+			&user.Role,
 			&user.FavoriteCoins,
 			&user.CreatedAt,
 		)
 		if err != nil {
-			return fmt.Errorf("failed to scan user: %w", err)
+			return 0, fmt.Errorf("failed to scan user: %w", err)
 		}
 		users = append(users, user)
 	}
 
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("error during rows iteration: %w", err)
+		return 0, fmt.Errorf("error during rows iteration: %w", err)
 	}
 
-	file, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-	defer file.Close()
-
-	encoder := json.NewEncoder(file)
+	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "  ")
 
 	if err := encoder.Encode(users); err != nil {
-		return fmt.Errorf("failed to encode JSON: %w", err)
+		return 0, fmt.Errorf("failed to encode JSON: %w", err)
 	}
-	slog.Info("Successfully exported users to",
-		"amount", len(users),
-		"filename", filename)
-	return nil
+	return len(users), nil
 }
@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"crypto-analytics/internal/models"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// hotScoreExpr — models.HotScore на языке агрегаций: счёт пересчитывается
+// в том же обновлении, что и voteScore, без чтения документа
+var hotScoreExpr = bson.M{"$add": bson.A{
+	bson.M{"$multiply": bson.A{
+		bson.M{"$cmp": bson.A{"$voteScore", 0}},
+		bson.M{"$log10": bson.M{"$max": bson.A{bson.M{"$abs": "$voteScore"}, 1}}},
+	}},
+	bson.M{"$divide": bson.A{
+		bson.M{"$subtract": bson.A{
+			// У самых старых постов нет createdAt — берём время из _id
+			bson.M{"$toLong": bson.M{"$ifNull": bson.A{"$createdAt", bson.M{"$toDate": "$_id"}}}},
+			models.HotEpoch.UnixMilli(),
+		}},
+		models.HotDecay.Milliseconds(),
+	}},
+}}
+
+func (p *PostMongoStorage) targetColl(target models.VoteTarget) *mongo.Collection {
+	if target == models.VoteTargetComment {
+		return p.collComm
+	}
+	return p.collPosts
+}
+
+// TargetAuthor возвращает автора не удалённого поста или комментария, если viewerID его видит
+func (p *PostMongoStorage) TargetAuthor(
+	ctx context.Context,
+	target models.VoteTarget,
+	id bson.ObjectID,
+	viewerID int64,
+) (int64, error) {
+	var doc struct {
+		AuthorID int64 `bson:"authorId"`
+	}
+	err := p.targetColl(target).FindOne(
+		ctx,
+		visibleTo(bson.M{"_id": id, "deletedAt": nil}, viewerID),
+		options.FindOne().SetProjection(bson.M{"authorId": 1}),
+	).Decode(&doc)
+	return doc.AuthorID, err
+}
+
+// Vote ставит (+1/-1), меняет или снимает (0) голос userID и возвращает новый счёт объекта.
+// Один голос на пользователя держит уникальный индекс votes; счёт объекта и репутация
+// автора меняются на разницу со старым голосом.
+func (p *PostMongoStorage) Vote(
+	ctx context.Context,
+	target models.VoteTarget,
+	targetID bson.ObjectID,
+	authorID int64,
+	userID int64,
+	value int,
+) (int, error) {
+	prev, err := p.swapVote(ctx, target, targetID, userID, value)
+	if err != nil {
+		return 0, err
+	}
+
+	var doc struct {
+		VoteScore int `bson:"voteScore"`
+	}
+	coll := p.targetColl(target)
+	delta := value - prev
+	if delta == 0 {
+		err = coll.FindOne(ctx, bson.M{"_id": targetID}).Decode(&doc)
+		return doc.VoteScore, err
+	}
+
+	var update any = bson.M{"$inc": bson.M{"voteScore": delta}}
+	if target == models.VoteTargetPost {
+		update = bson.A{
+			bson.M{"$set": bson.M{"voteScore": bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$voteScore", 0}}, delta}}}},
+			bson.M{"$set": bson.M{"hotScore": hotScoreExpr}},
+		}
+	}
+	err = coll.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": targetID},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&doc)
+	if err != nil {
+		return 0, err
+	}
+
+	if authorID != 0 {
+		_, err = p.collRep.UpdateOne(
+			ctx,
+			bson.M{"_id": authorID},
+			bson.M{"$inc": bson.M{"score": delta}},
+			options.UpdateOne().SetUpsert(true),
+		)
+	}
+	return doc.VoteScore, err
+}
+
+// swapVote записывает новый голос и возвращает прежний (0, если его не было)
+func (p *PostMongoStorage) swapVote(
+	ctx context.Context,
+	target models.VoteTarget,
+	targetID bson.ObjectID,
+	userID int64,
+	value int,
+) (int, error) {
+	filter := bson.M{"targetId": targetID, "userId": userID}
+
+	var prev models.Vote
+	var err error
+	if value == 0 {
+		err = p.collVotes.FindOneAndDelete(ctx, filter).Decode(&prev)
+	} else {
+		upsert := func() error {
+			return p.collVotes.FindOneAndUpdate(
+				ctx,
+				filter,
+				bson.M{
+					"$set":         bson.M{"value": value},
+					"$setOnInsert": bson.M{"target": target, "createdAt": time.Now()},
+				},
+				options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before),
+			).Decode(&prev)
+		}
+		err = upsert()
+		// Два одновременных первых голоса: индекс пропустит только один upsert,
+		// второй при повторе найдёт запись и обновит её
+		if mongo.IsDuplicateKeyError(err) {
+			err = upsert()
+		}
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	}
+	return prev.Value, err
+}
+
+// React ставит (on) или снимает реакцию userID и возвращает счётчики реакций объекта
+func (p *PostMongoStorage) React(
+	ctx context.Context,
+	target models.VoteTarget,
+	targetID bson.ObjectID,
+	userID int64,
+	emoji string,
+	on bool,
+) (map[string]int, error) {
+	delta := 0
+	if on {
+		_, err := p.collReactions.InsertOne(ctx, models.Reaction{
+			Target:    target,
+			TargetID:  targetID,
+			UserID:    userID,
+			Emoji:     emoji,
+			CreatedAt: time.Now(),
+		})
+		switch {
+		case err == nil:
+			delta = 1
+		case !mongo.IsDuplicateKeyError(err):
+			return nil, err
+		}
+	} else {
+		res, err := p.collReactions.DeleteOne(ctx, bson.M{"targetId": targetID, "userId": userID, "emoji": emoji})
+		if err != nil {
+			return nil, err
+		}
+		delta = -int(res.DeletedCount)
+	}
+
+	var doc struct {
+		Reactions map[string]int `bson:"reactions"`
+	}
+	coll := p.targetColl(target)
+	var err error
+	if delta == 0 {
+		err = coll.FindOne(ctx, bson.M{"_id": targetID}).Decode(&doc)
+	} else {
+		err = coll.FindOneAndUpdate(
+			ctx,
+			bson.M{"_id": targetID},
+			bson.M{"$inc": bson.M{"reactions." + emoji: delta}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&doc)
+	}
+	return doc.Reactions, err
+}
+
+// Reputation — сумма голосов, полученных постами и комментариями пользователя
+func (p *PostMongoStorage) Reputation(ctx context.Context, userID int64) (int, error) {
+	var doc struct {
+		Score int `bson:"score"`
+	}
+	err := p.collRep.FindOne(ctx, bson.M{"_id": userID}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	}
+	return doc.Score, err
+}
@@ -0,0 +1,325 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"crypto-analytics/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+type WebhookPostgresStorage struct {
+	pool *pgxpool.Pool
+}
+
+func NewWebhookPostgresStorage(pool *pgxpool.Pool) *WebhookPostgresStorage {
+	return &WebhookPostgresStorage{pool: pool}
+}
+
+const endpointColumns = `id, user_id, url, description, events, enabled, secret, created_at, updated_at`
+
+func scanEndpoint(row pgx.Row, ep *models.WebhookEndpoint) error {
+	var events []string
+	if err := row.Scan(&ep.ID, &ep.UserID, &ep.URL, &ep.Description, &events, &ep.Enabled, &ep.Secret,
+		&ep.CreatedAt, &ep.UpdatedAt); err != nil {
+		return err
+	}
+	ep.Events = webhookEvents(events)
+	return nil
+}
+
+func webhookEvents(events []string) []models.WebhookEvent {
+	out := make([]models.WebhookEvent, 0, len(events))
+	for _, e := range events {
+		out = append(out, models.WebhookEvent(e))
+	}
+	return out
+}
+
+func eventStrings(events []models.WebhookEvent) []string {
+	out := make([]string, 0, len(events))
+	for _, e := range events {
+		out = append(out, string(e))
+	}
+	return out
+}
+
+func (s *WebhookPostgresStorage) CreateEndpoint(ep *models.WebhookEndpoint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO webhook_endpoints (user_id, url, description, events, enabled, secret)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		ep.UserID, ep.URL, ep.Description, eventStrings(ep.Events), ep.Enabled, ep.Secret,
+	).Scan(&ep.ID, &ep.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return nil
+}
+
+func (s *WebhookPostgresStorage) ListEndpoints(userID int64) ([]models.WebhookEndpoint, error) {
+	return s.listEndpoints(`SELECT `+endpointColumns+` FROM webhook_endpoints WHERE user_id = $1 ORDER BY id`, userID)
+}
+
+// ListSubscribers — включённые вебхуки, подписанные на событие
+func (s *WebhookPostgresStorage) ListSubscribers(event models.WebhookEvent) ([]models.WebhookEndpoint, error) {
+	return s.listEndpoints(`SELECT `+endpointColumns+` FROM webhook_endpoints
+		WHERE enabled AND events @> ARRAY[$1]::TEXT[] ORDER BY id`, string(event))
+}
+
+func (s *WebhookPostgresStorage) listEndpoints(query string, arg any) ([]models.WebhookEndpoint, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, query, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	endpoints := []models.WebhookEndpoint{}
+	for rows.Next() {
+		var ep models.WebhookEndpoint
+		if err := scanEndpoint(rows, &ep); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		endpoints = append(endpoints, ep)
+	}
+	return endpoints, rows.Err()
+}
+
+// GetEndpoint возвращает вебхук пользователя; чужой вебхук — ErrWebhookNotFound
+func (s *WebhookPostgresStorage) GetEndpoint(userID, id int64) (*models.WebhookEndpoint, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var ep models.WebhookEndpoint
+	err := scanEndpoint(s.pool.QueryRow(ctx,
+		`SELECT `+endpointColumns+` FROM webhook_endpoints WHERE id = $1 AND user_id = $2`, id, userID), &ep)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return &ep, nil
+}
+
+func (s *WebhookPostgresStorage) UpdateEndpoint(ep *models.WebhookEndpoint) error {
+	return s.execEndpoint(`
+		UPDATE webhook_endpoints
+		SET url = $3, description = $4, events = $5, enabled = $6, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2`,
+		ep.ID, ep.UserID, ep.URL, ep.Description, eventStrings(ep.Events), ep.Enabled)
+}
+
+func (s *WebhookPostgresStorage) SetEndpointSecret(userID, id int64, secret string) error {
+	return s.execEndpoint(`
+		UPDATE webhook_endpoints
+		SET secret = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2`, id, userID, secret)
+}
+
+func (s *WebhookPostgresStorage) DeleteEndpoint(userID, id int64) error {
+	return s.execEndpoint(`DELETE FROM webhook_endpoints WHERE id = $1 AND user_id = $2`, id, userID)
+}
+
+func (s *WebhookPostgresStorage) execEndpoint(query string, args ...any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := s.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// EnqueueDeliveries ставит доставки в очередь одной транзакцией
+func (s *WebhookPostgresStorage) EnqueueDeliveries(deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, d := range deliveries {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO webhook_deliveries (endpoint_id, event, payload)
+			VALUES ($1, $2, $3)`, d.EndpointID, string(d.Event), []byte(d.Payload))
+		if err != nil {
+			return fmt.Errorf("failed to enqueue webhook delivery: %w", err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// RecordDelivery сохраняет в журнал уже выполненную доставку (тестовое событие)
+func (s *WebhookPostgresStorage) RecordDelivery(d *models.WebhookDelivery) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO webhook_deliveries (endpoint_id, event, payload, status, attempts, next_attempt_at,
+			response_status, last_error, duration_ms, delivered_at)
+		VALUES ($1, $2, $3, $4, $5, NULL, $6, $7, $8, $9)
+		RETURNING id, created_at`,
+		d.EndpointID, string(d.Event), []byte(d.Payload), string(d.Status), d.Attempts,
+		d.ResponseStatus, d.LastError, d.DurationMs, d.DeliveredAt,
+	).Scan(&d.ID, &d.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// ClaimDueDeliveries забирает доставки, срок которых наступил, вместе с вебхуками
+// и откладывает их на lease — как NotificationPostgresStorage.ClaimDue
+func (s *WebhookPostgresStorage) ClaimDueDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, `
+		WITH claimed AS (
+			UPDATE webhook_deliveries
+			SET attempts = attempts + 1,
+				next_attempt_at = CURRENT_TIMESTAMP + $2 * INTERVAL '1 second'
+			WHERE id IN (
+				SELECT id FROM webhook_deliveries
+				WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+				ORDER BY next_attempt_at
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, endpoint_id, event, payload, attempts, created_at
+		)
+		SELECT c.id, c.event, c.payload, c.attempts, c.created_at,
+			e.id, e.user_id, e.url, e.description, e.events, e.enabled, e.secret, e.created_at, e.updated_at
+		FROM claimed c
+		JOIN webhook_endpoints e ON e.id = c.endpoint_id`,
+		limit, int64(lease/time.Second))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		d := models.WebhookDelivery{Status: models.OutboxPending, Endpoint: &models.WebhookEndpoint{}}
+		var payload []byte
+		var events []string
+		ep := d.Endpoint
+		if err := rows.Scan(&d.ID, &d.Event, &payload, &d.Attempts, &d.CreatedAt,
+			&ep.ID, &ep.UserID, &ep.URL, &ep.Description, &events, &ep.Enabled, &ep.Secret,
+			&ep.CreatedAt, &ep.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		d.Payload = payload
+		d.EndpointID = ep.ID
+		ep.Events = webhookEvents(events)
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (s *WebhookPostgresStorage) CompleteDelivery(id int64, attempt models.WebhookAttempt) error {
+	return s.execDelivery(`
+		UPDATE webhook_deliveries
+		SET status = 'sent', next_attempt_at = NULL, delivered_at = CURRENT_TIMESTAMP,
+			response_status = $2, last_error = $3, duration_ms = $4
+		WHERE id = $1`, id, attempt.ResponseStatus, attempt.Error, attempt.Duration.Milliseconds())
+}
+
+func (s *WebhookPostgresStorage) RetryDelivery(id int64, attempt models.WebhookAttempt, delay time.Duration) error {
+	return s.execDelivery(`
+		UPDATE webhook_deliveries
+		SET next_attempt_at = CURRENT_TIMESTAMP + $5 * INTERVAL '1 second',
+			response_status = $2, last_error = $3, duration_ms = $4
+		WHERE id = $1`, id, attempt.ResponseStatus, attempt.Error, attempt.Duration.Milliseconds(), int64(delay/time.Second))
+}
+
+func (s *WebhookPostgresStorage) FailDelivery(id int64, attempt models.WebhookAttempt) error {
+	return s.execDelivery(`
+		UPDATE webhook_deliveries
+		SET status = 'failed', next_attempt_at = NULL,
+			response_status = $2, last_error = $3, duration_ms = $4
+		WHERE id = $1`, id, attempt.ResponseStatus, attempt.Error, attempt.Duration.Milliseconds())
+}
+
+func (s *WebhookPostgresStorage) execDelivery(query string, args ...any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := s.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("webhook delivery %v not found", args[0])
+	}
+	return nil
+}
+
+// ListDeliveries — журнал доставок вебхука, новые сверху
+func (s *WebhookPostgresStorage) ListDeliveries(endpointID int64, limit int) ([]models.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, endpoint_id, event, payload, status, attempts, next_attempt_at,
+			response_status, last_error, duration_ms, created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE endpoint_id = $1
+		ORDER BY id DESC
+		LIMIT $2`, endpointID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var payload []byte
+		if err := rows.Scan(&d.ID, &d.EndpointID, &d.Event, &payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.ResponseStatus, &d.LastError, &d.DurationMs, &d.CreatedAt, &d.DeliveredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		d.Payload = payload
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// PurgeDeliveries удаляет завершённые доставки старше olderThan и возвращает их число
+func (s *WebhookPostgresStorage) PurgeDeliveries(olderThan time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	res, err := s.pool.Exec(ctx, `
+		DELETE FROM webhook_deliveries
+		WHERE status <> 'pending' AND created_at < CURRENT_TIMESTAMP - $1 * INTERVAL '1 second'`,
+		int64(olderThan/time.Second))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge webhook deliveries: %w", err)
+	}
+	return int(res.RowsAffected()), nil
+}
@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upUserIdentity, downUserIdentity)
+}
+
+func upUserIdentity(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS display_name TEXT NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE users SET display_name = username WHERE display_name = '';
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Вход по email регистронезависимый, поэтому уникальность тоже по LOWER(email)
+	_, err = tx.ExecContext(ctx, `
+		CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_key ON users (LOWER(email));
+	`)
+	return err
+}
+
+func downUserIdentity(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		DROP INDEX IF EXISTS users_email_lower_key;
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		ALTER TABLE users
+			DROP COLUMN IF EXISTS display_name,
+			DROP COLUMN IF EXISTS updated_at;
+	`)
+	return err
+}
@@ -0,0 +1,68 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upEmailVerificationTokens, downEmailVerificationTokens)
+}
+
+func upEmailVerificationTokens(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP;
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Уже существующие аккаунты считаем подтверждёнными, иначе они разом
+	// потеряют возможность писать посты
+	_, err = tx.ExecContext(ctx, `
+		UPDATE users SET email_verified_at = COALESCE(created_at, CURRENT_TIMESTAMP)
+		WHERE email_verified_at IS NULL;
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	CREATE TABLE user_tokens (
+		id BIGSERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		purpose TEXT NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		expires_at TIMESTAMP NOT NULL,
+		used_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		CREATE INDEX idx_user_tokens_user_purpose ON user_tokens(user_id, purpose);
+	`)
+	if err != nil {
+		return err
+	}
+
+	return grantAppUser(ctx, tx, "user_tokens:user_tokens_id_seq")
+}
+
+func downEmailVerificationTokens(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		DROP TABLE IF EXISTS user_tokens CASCADE;
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
+	`)
+	return err
+}
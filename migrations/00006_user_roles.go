@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upUserRoles, downUserRoles)
+}
+
+func upUserRoles(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user'
+				CONSTRAINT users_role_check CHECK (role IN ('user', 'moderator', 'admin'));
+	`)
+	return err
+}
+
+func downUserRoles(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		ALTER TABLE users DROP COLUMN IF EXISTS role;
+	`)
+	return err
+}
@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upTwoFactor, downTwoFactor)
+}
+
+func upTwoFactor(ctx context.Context, tx *sql.Tx) error {
+	// totp_secret зашифрован приложением; totp_last_counter защищает от повторного использования кода
+	_, err := tx.ExecContext(ctx, `
+		ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS totp_secret TEXT,
+			ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMP,
+			ADD COLUMN IF NOT EXISTS totp_last_counter BIGINT NOT NULL DEFAULT 0;
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	CREATE TABLE recovery_codes (
+		id BIGSERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		code_hash TEXT NOT NULL,
+		used_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (user_id, code_hash)
+	);
+	`)
+	if err != nil {
+		return err
+	}
+
+	return grantAppUser(ctx, tx, "recovery_codes:recovery_codes_id_seq")
+}
+
+func downTwoFactor(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		DROP TABLE IF EXISTS recovery_codes CASCADE;
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		ALTER TABLE users
+			DROP COLUMN IF EXISTS totp_secret,
+			DROP COLUMN IF EXISTS totp_enabled_at,
+			DROP COLUMN IF EXISTS totp_last_counter;
+	`)
+	return err
+}
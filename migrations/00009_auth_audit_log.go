@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAuthAuditLog, downAuthAuditLog)
+}
+
+func upAuthAuditLog(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+	CREATE TABLE auth_audit_log (
+		id BIGSERIAL PRIMARY KEY,
+		event TEXT NOT NULL,
+		login TEXT NOT NULL DEFAULT '',
+		ip TEXT NOT NULL DEFAULT '',
+		user_agent TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		CREATE INDEX idx_auth_audit_log_login ON auth_audit_log(login, created_at);
+		CREATE INDEX idx_auth_audit_log_ip ON auth_audit_log(ip, created_at);
+	`)
+	if err != nil {
+		return err
+	}
+
+	return grantAppUser(ctx, tx, "auth_audit_log:auth_audit_log_id_seq")
+}
+
+func downAuthAuditLog(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		DROP TABLE IF EXISTS auth_audit_log CASCADE;
+	`)
+	return err
+}
@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upModerationLog, downModerationLog)
+}
+
+func upModerationLog(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+	CREATE TABLE moderation_log (
+		id BIGSERIAL PRIMARY KEY,
+		actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		action TEXT NOT NULL,
+		target TEXT NOT NULL,
+		target_id TEXT NOT NULL,
+		reason TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		CREATE INDEX idx_moderation_log_target ON moderation_log(target, target_id);
+	`)
+	if err != nil {
+		return err
+	}
+
+	return grantAppUser(ctx, tx, "moderation_log:moderation_log_id_seq")
+}
+
+func downModerationLog(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		DROP TABLE IF EXISTS moderation_log CASCADE;
+	`)
+	return err
+}
@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upReportsAndBans, downReportsAndBans)
+}
+
+func upReportsAndBans(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS banned_until TIMESTAMP,
+			ADD COLUMN IF NOT EXISTS shadow_banned BOOLEAN NOT NULL DEFAULT FALSE;
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	CREATE TABLE reports (
+		id BIGSERIAL PRIMARY KEY,
+		reporter_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		target TEXT NOT NULL,
+		target_id TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		details TEXT NOT NULL DEFAULT '',
+		status TEXT NOT NULL DEFAULT 'open'
+			CONSTRAINT reports_status_check CHECK (status IN ('open', 'resolved', 'dismissed')),
+		resolved_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		resolved_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Одна открытая жалоба от пользователя на объект: повторные не раздувают очередь
+	_, err = tx.ExecContext(ctx, `
+		CREATE UNIQUE INDEX idx_reports_open_unique ON reports(reporter_id, target, target_id)
+			WHERE status = 'open';
+		CREATE INDEX idx_reports_open_target ON reports(target, target_id) WHERE status = 'open';
+	`)
+	if err != nil {
+		return err
+	}
+
+	return grantAppUser(ctx, tx, "reports:reports_id_seq")
+}
+
+func downReportsAndBans(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		DROP TABLE IF EXISTS reports CASCADE;
+		ALTER TABLE users
+			DROP COLUMN IF EXISTS banned_until,
+			DROP COLUMN IF EXISTS shadow_banned;
+	`)
+	return err
+}
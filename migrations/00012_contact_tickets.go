@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upContactTickets, downContactTickets)
+}
+
+func upContactTickets(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		ALTER TABLE contacts
+			ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'new'
+				CONSTRAINT contacts_status_check CHECK (status IN ('new', 'in_progress', 'resolved')),
+			ADD COLUMN IF NOT EXISTS assignee_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+			ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP,
+			ADD COLUMN IF NOT EXISTS resolved_at TIMESTAMP;
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	CREATE TABLE contact_notes (
+		id BIGSERIAL PRIMARY KEY,
+		contact_id INTEGER NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+		author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		kind TEXT NOT NULL
+			CONSTRAINT contact_notes_kind_check CHECK (kind IN ('note', 'reply')),
+		body TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		CREATE INDEX idx_contacts_status ON contacts(status, created_at DESC);
+		CREATE INDEX idx_contact_notes_contact ON contact_notes(contact_id, id);
+	`)
+	if err != nil {
+		return err
+	}
+
+	return grantAppUser(ctx, tx, "contact_notes:contact_notes_id_seq")
+}
+
+func downContactTickets(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		DROP TABLE IF EXISTS contact_notes CASCADE;
+		DROP INDEX IF EXISTS idx_contacts_status;
+		ALTER TABLE contacts
+			DROP COLUMN IF EXISTS status,
+			DROP COLUMN IF EXISTS assignee_id,
+			DROP COLUMN IF EXISTS updated_at,
+			DROP COLUMN IF EXISTS resolved_at;
+	`)
+	return err
+}
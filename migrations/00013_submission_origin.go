@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upSubmissionOrigin, downSubmissionOrigin)
+}
+
+// upSubmissionOrigin сохраняет IP и User-Agent отправителя обращения и регистрации
+func upSubmissionOrigin(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		ALTER TABLE contacts
+			ADD COLUMN IF NOT EXISTS ip TEXT NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS user_agent TEXT NOT NULL DEFAULT '';
+		ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS signup_ip TEXT NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS signup_user_agent TEXT NOT NULL DEFAULT '';
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		CREATE INDEX idx_contacts_ip ON contacts(ip, created_at);
+		CREATE INDEX idx_users_signup_ip ON users(signup_ip, created_at);
+	`)
+	return err
+}
+
+func downSubmissionOrigin(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		DROP INDEX IF EXISTS idx_contacts_ip;
+		DROP INDEX IF EXISTS idx_users_signup_ip;
+		ALTER TABLE contacts
+			DROP COLUMN IF EXISTS ip,
+			DROP COLUMN IF EXISTS user_agent;
+		ALTER TABLE users
+			DROP COLUMN IF EXISTS signup_ip,
+			DROP COLUMN IF EXISTS signup_user_agent;
+	`)
+	return err
+}
@@ -0,0 +1,68 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upNotifications, downNotifications)
+}
+
+// upNotifications добавляет каналы уведомлений пользователей и очередь доставки (outbox)
+func upNotifications(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+	CREATE TABLE notification_channels (
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		channel TEXT NOT NULL
+			CONSTRAINT notification_channels_channel_check CHECK (channel IN ('email', 'telegram', 'webhook')),
+		address TEXT NOT NULL,
+		events TEXT[] NOT NULL DEFAULT '{}',
+		enabled BOOLEAN NOT NULL DEFAULT TRUE,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, channel)
+	);
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	CREATE TABLE notification_outbox (
+		id BIGSERIAL PRIMARY KEY,
+		user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+		channel TEXT NOT NULL,
+		address TEXT NOT NULL,
+		message JSONB NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending'
+			CONSTRAINT notification_outbox_status_check CHECK (status IN ('pending', 'sent', 'failed')),
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		last_error TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		sent_at TIMESTAMP
+	);
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		CREATE INDEX idx_notification_outbox_due ON notification_outbox(next_attempt_at) WHERE status = 'pending';
+	`)
+	if err != nil {
+		return err
+	}
+
+	return grantAppUser(ctx, tx, "notification_channels", "notification_outbox:notification_outbox_id_seq")
+}
+
+func downNotifications(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		DROP TABLE IF EXISTS notification_outbox CASCADE;
+		DROP TABLE IF EXISTS notification_channels CASCADE;
+	`)
+	return err
+}
@@ -0,0 +1,75 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upWebhooks, downWebhooks)
+}
+
+// upWebhooks добавляет исходящие вебхуки пользователей и журнал их доставок
+func upWebhooks(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+	CREATE TABLE webhook_endpoints (
+		id BIGSERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		url TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		events TEXT[] NOT NULL,
+		enabled BOOLEAN NOT NULL DEFAULT TRUE,
+		secret TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP
+	);
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	CREATE TABLE webhook_deliveries (
+		id BIGSERIAL PRIMARY KEY,
+		endpoint_id BIGINT NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+		event TEXT NOT NULL,
+		payload JSONB NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending'
+			CONSTRAINT webhook_deliveries_status_check CHECK (status IN ('pending', 'sent', 'failed')),
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		response_status INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT NOT NULL DEFAULT '',
+		duration_ms BIGINT NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		delivered_at TIMESTAMP
+	);
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		CREATE INDEX idx_webhook_endpoints_user ON webhook_endpoints(user_id);
+		CREATE INDEX idx_webhook_endpoints_events ON webhook_endpoints USING GIN (events) WHERE enabled;
+		CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+		CREATE INDEX idx_webhook_deliveries_endpoint ON webhook_deliveries(endpoint_id, id DESC);
+	`)
+	if err != nil {
+		return err
+	}
+
+	return grantAppUser(ctx, tx,
+		"webhook_endpoints:webhook_endpoints_id_seq",
+		"webhook_deliveries:webhook_deliveries_id_seq")
+}
+
+func downWebhooks(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		DROP TABLE IF EXISTS webhook_deliveries CASCADE;
+		DROP TABLE IF EXISTS webhook_endpoints CASCADE;
+	`)
+	return err
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// grantAppUser выдаёт пользователю приложения права на новые таблицы и их
+// последовательности. Объекты передаются как "table" или "table:sequence".
+func grantAppUser(ctx context.Context, tx *sql.Tx, objects ...string) error {
+	username := os.Getenv("APP_USER")
+	if username == "" {
+		return fmt.Errorf("APP_USER is not set")
+	}
+	quotedUser := quotePostgresIdentifier(username)
+
+	for _, obj := range objects {
+		table, sequence, _ := strings.Cut(obj, ":")
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(
+			`GRANT SELECT, INSERT, UPDATE, DELETE ON TABLE %s TO %s;`,
+			quotePostgresIdentifier(table), quotedUser))
+		if err != nil {
+			return err
+		}
+		if sequence == "" {
+			continue
+		}
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(
+			`GRANT USAGE, SELECT ON SEQUENCE %s TO %s;`,
+			quotePostgresIdentifier(sequence), quotedUser))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -22,6 +22,8 @@ const currentTimeframeEl = document.getElementById('currentTimeframe');
 const indicatorsContainer = document.getElementById('indicatorsContainer');
 const errorContainer = document.getElementById('errorContainer');
 const lastUpdateEl = document.getElementById('lastUpdate');
+const relatedPostsEl = document.getElementById('relatedPosts');
+const relatedPostsMoreEl = document.getElementById('relatedPostsMore');
 
 const isMobile = /Android|webOS|iPhone|iPad|iPod|BlackBerry|IEMobile|Opera Mini/i.test(navigator.userAgent);
 const INITIAL_CANDLES = isMobile ? 200 : 500;
@@ -90,6 +92,38 @@ async function loadData() {
         showError('Не удалось загрузить данные: ' + err.message);
         console.error(err);
     }
+
+    loadRelatedPosts(pair);
+}
+
+// Посты сообщества с тегом пары или её базовой монеты
+async function loadRelatedPosts(pair) {
+    relatedPostsMoreEl.href = `/static/posts.html?tag=${encodeURIComponent(pair)}`;
+    try {
+        const response = await fetch(`/api/posts/related?pair=${encodeURIComponent(pair)}`);
+        if (!response.ok) throw new Error(`HTTP ${response.status}`);
+        const data = await response.json();
+
+        if (!data.posts || data.posts.length === 0) {
+            relatedPostsEl.innerHTML = '<p class="related-empty">Пока никто не писал об этой паре.</p>';
+            return;
+        }
+        relatedPostsEl.innerHTML = data.posts.map(post => `
+            <div class="related-post">
+                <a href="/static/posts.html?tag=${encodeURIComponent(pair)}">${escapeHtml(post.Heading)}</a>
+                <span class="related-post-meta">${escapeHtml(post.Person)} · ${new Date(post.CreatedAt || post.Date).toLocaleString('ru-RU')}</span>
+            </div>
+        `).join('');
+    } catch (err) {
+        relatedPostsEl.innerHTML = '';
+        console.error('Failed to load related posts:', err);
+    }
+}
+
+function escapeHtml(text) {
+    const div = document.createElement('div');
+    div.textContent = text || '';
+    return div.innerHTML;
 }
 
 function updateDashboard(data) {
@@ -0,0 +1,47 @@
+// Токен формы: без него, а также если форму отправили быстрее чем через пару секунд, сервер отклонит отправку
+const captchaScripts = {
+    recaptcha: { src: 'https://www.google.com/recaptcha/api.js', className: 'g-recaptcha', api: () => window.grecaptcha },
+    hcaptcha: { src: 'https://js.hcaptcha.com/1/api.js', className: 'h-captcha', api: () => window.hcaptcha },
+    turnstile: { src: 'https://challenges.cloudflare.com/turnstile/v0/api.js', className: 'cf-turnstile', api: () => window.turnstile },
+};
+let captchaProvider = null;
+
+// Виджет CAPTCHA сам добавляет в форму поле с ответом, сервер его читает
+function renderCaptcha(form, captcha) {
+    const provider = captchaScripts[captcha.provider];
+    const container = form && form.querySelector('[data-captcha]');
+    if (!provider || !container || container.dataset.sitekey) {
+        return;
+    }
+    container.classList.add(provider.className);
+    container.dataset.sitekey = captcha.siteKey;
+    if (!captchaProvider) {
+        captchaProvider = provider;
+        const script = document.createElement('script');
+        script.src = provider.src;
+        script.async = true;
+        script.defer = true;
+        document.head.appendChild(script);
+    }
+}
+
+// Ответ CAPTCHA одноразовый: после неудачной отправки виджет нужно пройти заново
+function resetCaptcha() {
+    const api = captchaProvider && captchaProvider.api();
+    if (api) {
+        api.reset();
+    }
+}
+
+document.querySelectorAll('input[data-form-token]').forEach(async function (input) {
+    try {
+        const response = await fetch('/api/form-token?form=' + encodeURIComponent(input.dataset.formToken));
+        const data = await response.json();
+        input.value = data.data.token;
+        if (data.data.captcha) {
+            renderCaptcha(input.form, data.data.captcha);
+        }
+    } catch (err) {
+        console.error('Failed to get form token:', err);
+    }
+});
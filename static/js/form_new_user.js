@@ -24,8 +24,40 @@ function toggleConfirmPassword() {
     }
 }
 
+function showError(message) {
+    const errorElement = document.getElementById('errorMessage');
+    errorElement.textContent = message;
+    errorElement.style.display = 'flex';
+}
+
+// Ошибки сервера показываются под соответствующими полями: {"errors": {"password": "..."}}
+function showFieldErrors(errors) {
+    for (const [field, message] of Object.entries(errors)) {
+        const input = document.getElementById(field);
+        const fieldError = input && input.parentElement.querySelector('.error-message');
+        if (!fieldError) {
+            showError('⚠️ ' + message);
+            continue;
+        }
+        fieldError.dataset.defaultText = fieldError.dataset.defaultText || fieldError.textContent;
+        fieldError.textContent = message;
+        fieldError.style.display = 'block';
+    }
+}
+
+function clearFieldErrors() {
+    document.getElementById('errorMessage').style.display = 'none';
+    document.querySelectorAll('#registerForm .error-message').forEach(function (el) {
+        if (el.dataset.defaultText) {
+            el.textContent = el.dataset.defaultText;
+        }
+        el.style.display = '';
+    });
+}
+
 // Валидация паролей
-document.getElementById('registerForm').addEventListener('submit', function (e) {
+document.getElementById('registerForm').addEventListener('submit', async function (e) {
+    clearFieldErrors();
     const username = document.getElementById('username').value;
     const email = document.getElementById('email').value;
     const password = document.getElementById('password').value;
@@ -85,16 +117,39 @@ document.getElementById('registerForm').addEventListener('submit', function (e)
         showError('⚠️ Пароль должен содержать от 8 до 72 символов');
         return;
     }
-});
-
-// Проверка query параметров при загрузке страницы
-document.addEventListener('DOMContentLoaded', function () {
-    const urlParams = new URLSearchParams(window.location.search);
-    const errorType = urlParams.get('err');
 
-    if (errorType === 'alreadyExistsName') {
-        showError('❌ Это имя пользователя уже занято. Пожалуйста, выберите другое.');
-    } else if (errorType === 'alreadyExistsAcc') {
-        showError('❌ Аккаунт с этой почтой уже существует. Попробуйте войти или восстановить пароль.');
+    // Остальное (сложность пароля, утечки, занятость имени) проверяет сервер
+    e.preventDefault();
+    const submitBtn = this.querySelector('button[type="submit"]');
+    submitBtn.disabled = true;
+    try {
+        const response = await fetch('/register', {
+            method: 'POST',
+            body: new URLSearchParams(new FormData(this))
+        });
+        if (response.status === 429) {
+            showError('⏳ Слишком много попыток. Попробуйте позже.');
+            return;
+        }
+        if (response.status === 400) {
+            showError('⚠️ Форма устарела или отправлена слишком быстро. Обновите страницу и попробуйте снова.');
+            return;
+        }
+        const data = await response.json().catch(() => null);
+        if (response.ok && data && data.success) {
+            window.location.href = data.data.redirect;
+            return;
+        }
+        if (data && data.errors) {
+            showFieldErrors(data.errors);
+        } else {
+            showError('❌ Не удалось зарегистрироваться. Попробуйте позже.');
+        }
+    } catch (err) {
+        showError('❌ Сервер недоступен. Проверьте соединение.');
+    } finally {
+        submitBtn.disabled = false;
+        resetCaptcha();
     }
-});
\ No newline at end of file
+});
+
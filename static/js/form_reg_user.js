@@ -86,5 +86,38 @@ document.addEventListener('DOMContentLoaded', function () {
         errorElement.classList.add('has-icon');
         errorElement.style.display = 'flex';
         document.getElementById('username').focus();
+
+    } else if (errorType === 'verifyToken') {
+        errorElement.innerHTML = `
+                    <span class="error-icon">✉️</span>
+                    Ссылка подтверждения недействительна или устарела. Войдите, чтобы получить новую.
+                `;
+        errorElement.classList.add('has-icon');
+        errorElement.style.display = 'flex';
+
+    } else if (errorType === '2faExpired') {
+        errorElement.innerHTML = `
+                    <span class="error-icon">🔐</span>
+                    Время на ввод кода истекло или было слишком много попыток. Войдите снова.
+                `;
+        errorElement.classList.add('has-icon');
+        errorElement.style.display = 'flex';
+
+    } else if (errorType === 'locked') {
+        errorElement.innerHTML = `
+                    <span class="error-icon">⏳</span>
+                    Слишком много неудачных попыток входа. Вход временно заблокирован, попробуйте позже.
+                `;
+        errorElement.classList.add('has-icon');
+        errorElement.style.display = 'flex';
+
+    } else if (urlParams.get('verified') === '1' || urlParams.get('reset') === '1') {
+        errorElement.innerHTML = urlParams.get('verified') === '1'
+            ? `<span class="error-icon">✅</span> Email подтверждён. Теперь вы можете войти.`
+            : `<span class="error-icon">✅</span> Пароль изменён. Войдите с новым паролем.`;
+        errorElement.classList.add('has-icon');
+        errorElement.style.borderColor = 'var(--success-color)';
+        errorElement.style.color = 'var(--success-color)';
+        errorElement.style.display = 'flex';
     }
 });
\ No newline at end of file
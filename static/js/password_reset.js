@@ -0,0 +1,65 @@
+const token = new URLSearchParams(window.location.search).get('token');
+
+function showMessage(text, isError) {
+    const el = document.getElementById('resetMessage');
+    el.textContent = text;
+    el.style.color = isError ? 'var(--error-color)' : 'var(--success-color)';
+    el.style.display = 'block';
+}
+
+async function postJSON(url, body) {
+    const response = await fetch(url, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(body)
+    });
+    if (!response.ok) {
+        const text = (await response.text()).trim();
+        // Ошибки валидации приходят JSON-ом по полям
+        try {
+            const data = JSON.parse(text);
+            if (data.errors) {
+                throw new Error(Object.values(data.errors).join('. '));
+            }
+        } catch (err) {
+            if (!(err instanceof SyntaxError)) throw err;
+        }
+        throw new Error(text || 'Ошибка запроса');
+    }
+    return response.json();
+}
+
+if (token) {
+    document.getElementById('requestForm').style.display = 'none';
+    document.getElementById('confirmForm').style.display = 'block';
+    document.getElementById('resetHint').textContent = 'Придумайте новый пароль';
+}
+
+document.getElementById('requestForm').addEventListener('submit', async function (e) {
+    e.preventDefault();
+    const email = document.getElementById('email').value.trim();
+
+    try {
+        await postJSON('/password-reset', { email });
+        showMessage('Если такой email зарегистрирован, мы отправили на него ссылку.', false);
+    } catch (err) {
+        showMessage(err.message, true);
+    }
+});
+
+document.getElementById('confirmForm').addEventListener('submit', async function (e) {
+    e.preventDefault();
+    const password = document.getElementById('password').value;
+
+    if (password !== document.getElementById('passwordRepeat').value) {
+        showMessage('Пароли не совпадают', true);
+        return;
+    }
+
+    try {
+        await postJSON('/password-reset/confirm', { token, password });
+        window.location.href = '/static/FormRegUser.html?reset=1';
+    } catch (err) {
+        showMessage(err.message, true);
+    }
+});